@@ -0,0 +1,203 @@
+// Command dlq inspects the order messages the engine moved to the dead-letter topic and
+// re-drives them to the order topic.
+//
+//	dlq list [-from offset] [-to offset] [-pair pair] [-class class]
+//	dlq redrive [-from offset] [-to offset] [-pair pair] [-class class] [-dry-run]
+//
+// list writes one JSON line per dead-lettered message, with its offset in the dead-letter
+// topic. redrive publishes the original payload of every selected message to the
+// partition of the order topic it was read from, where the engine picks it up as a new
+// order message.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	deadletterv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/dead-letter/v1"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/pkg/config"
+	"github.com/segmentio/kafka-go"
+)
+
+const usage = "usage: dlq list|redrive [-from offset] [-to offset] [-pair pair] [-class class] [-dry-run]"
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "dlq:", err)
+		os.Exit(1)
+	}
+}
+
+// filter selects dead-letter messages by their offset in the dead-letter topic, pair and class.
+type filter struct {
+	from, to int64
+	pair     string
+	class    string
+}
+
+func (f filter) matches(offset int64, message *deadletterv1.Message) bool {
+	if offset < f.from || (f.to >= 0 && offset > f.to) {
+		return false
+	}
+	if f.pair != "" && message.Pair != f.pair {
+		return false
+	}
+	return f.class == "" || string(message.Class) == f.class
+}
+
+// entry is a dead-letter message as list writes it, with the payload kept readable.
+type entry struct {
+	DeadLetterOffset int64                   `json:"deadLetterOffset"`
+	Pair             string                  `json:"pair"`
+	Class            deadletterv1.ErrorClass `json:"class"`
+	Error            string                  `json:"error"`
+	Attempts         int                     `json:"attempts"`
+	Topic            string                  `json:"topic"`
+	Partition        int                     `json:"partition"`
+	Offset           int64                   `json:"offset"`
+	Payload          string                  `json:"payload"`
+	Timestamp        int64                   `json:"timestamp"`
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	command := args[0]
+
+	cfg := &config.Config{}
+	if err := config.Load(cfg); err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	from := flags.Int64("from", 0, "first dead-letter offset to select")
+	to := flags.Int64("to", -1, "last dead-letter offset to select, -1 selects up to the end of the topic")
+	pair := flags.String("pair", "", "only select messages of this pair")
+	class := flags.String("class", "", "only select messages of this error class, decode or process")
+	dryRun := flags.Bool("dry-run", false, "redrive: print the messages without publishing them")
+	flags.Parse(args[1:])
+
+	selected := filter{from: *from, to: *to, pair: *pair, class: *class}
+	ctx := context.Background()
+
+	switch command {
+	case "list":
+		encoder := json.NewEncoder(os.Stdout)
+		return read(ctx, cfg.DeadLetterConfig, selected, func(offset int64, message *deadletterv1.Message) error {
+			return encoder.Encode(entry{
+				DeadLetterOffset: offset,
+				Pair:             message.Pair,
+				Class:            message.Class,
+				Error:            message.Error,
+				Attempts:         message.Attempts,
+				Topic:            message.Topic,
+				Partition:        message.Partition,
+				Offset:           message.Offset,
+				Payload:          string(message.Payload),
+				Timestamp:        message.Timestamp,
+			})
+		})
+	case "redrive":
+		return redrive(ctx, cfg, selected, *dryRun)
+	}
+	return fmt.Errorf("unknown command %q, %s", command, usage)
+}
+
+// read calls fn for every selected message in the dead-letter topic, up to the end of
+// the topic when the command started.
+func read(ctx context.Context, cfg config.DeadLetterConfig, selected filter, fn func(int64, *deadletterv1.Message) error) error {
+	if len(cfg.Brokers) == 0 {
+		return fmt.Errorf("no dead-letter broker configured, set DEAD_LETTER_BROKER")
+	}
+
+	conn, err := kafka.DialLeader(ctx, "tcp", cfg.Brokers[0], cfg.Topic, 0)
+	if err != nil {
+		return err
+	}
+	end, err := conn.ReadLastOffset()
+	conn.Close()
+	if err != nil {
+		return err
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   cfg.Brokers,
+		Topic:     cfg.Topic,
+		Partition: 0,
+		MinBytes:  1,
+		MaxBytes:  10e6,
+	})
+	defer reader.Close()
+
+	if err := reader.SetOffset(selected.from); err != nil {
+		return err
+	}
+
+	for offset := selected.from; offset < end; {
+		msg, err := reader.ReadMessage(ctx)
+		if err != nil {
+			return err
+		}
+		offset = msg.Offset + 1
+		if selected.to >= 0 && msg.Offset > selected.to {
+			return nil
+		}
+
+		message := deadletterv1.FromBytes(msg.Value)
+		if message == nil {
+			fmt.Fprintf(os.Stderr, "dlq: skipping unreadable dead letter at offset %d\n", msg.Offset)
+			continue
+		}
+		if !selected.matches(msg.Offset, message) {
+			continue
+		}
+		if err := fn(msg.Offset, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redrive publishes the original payload of every selected message to the order topic.
+func redrive(ctx context.Context, cfg *config.Config, selected filter, dryRun bool) error {
+	writer := &kafka.Writer{
+		Addr:  kafka.TCP(cfg.KafkaConfig.Brokers...),
+		Topic: cfg.KafkaConfig.Topic,
+		// Messages go back to the partition they were read from
+		Balancer:     kafka.BalancerFunc(func(msg kafka.Message, _ ...int) int { return msg.Partition }),
+		RequiredAcks: kafka.RequireAll,
+	}
+	defer writer.Close()
+
+	count := 0
+	err := read(ctx, cfg.DeadLetterConfig, selected, func(offset int64, message *deadletterv1.Message) error {
+		if message.Topic != "" && message.Topic != cfg.KafkaConfig.Topic {
+			return fmt.Errorf("dead letter at offset %d came from topic %s, not %s", offset, message.Topic, cfg.KafkaConfig.Topic)
+		}
+
+		fmt.Printf("redrive dead letter %d: %s offset %d, %s\n", offset, message.Pair, message.Offset, message.Error)
+		if dryRun {
+			return nil
+		}
+		err := writer.WriteMessages(ctx, kafka.Message{
+			Partition: message.Partition,
+			Key:       message.Key,
+			Value:     message.Payload,
+		})
+		if err == nil {
+			count++
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("re-drove %d messages to %s\n", count, cfg.KafkaConfig.Topic)
+	return nil
+}
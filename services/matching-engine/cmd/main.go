@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
@@ -10,11 +11,21 @@ import (
 	"github.com/muhammadchandra19/exchange/pkg/logger"
 	"github.com/muhammadchandra19/exchange/pkg/redis"
 	app "github.com/muhammadchandra19/exchange/services/matching-engine/internal/app/engine"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/internal/app/supervisor"
+	deadletterv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/dead-letter/v1"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/internal/rpc"
+	deadletter "github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/dead-letter"
+	fee "github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/fee"
+	journal "github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/journal"
 	matchpublisher "github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/match-publisher"
+	orderpublisher "github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/order-publisher"
 	orderreader "github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/order-reader"
 	orderbook "github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/orderbook"
 	snapshot "github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/snapshot"
+	stopbook "github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/stopbook"
 	"github.com/muhammadchandra19/exchange/services/matching-engine/pkg/config"
+	"google.golang.org/grpc"
 )
 
 var cfg *config.Config
@@ -61,32 +72,171 @@ func main() {
 		return
 	}
 
-	// Initialize components
-	ob := orderbook.NewOrderbook()
-	oReader := orderreader.NewReader(cfg.KafkaConfig, *log)
-	snapshotStore := snapshot.NewSnapshotStore(rclient, cfg.Pair, log)
-	matchPublisher := matchpublisher.NewPublisher(cfg.MatchPublisherConfig, *log)
-	engine := app.NewEngine(
-		ob,
-		oReader,
-		snapshotStore,
-		matchPublisher,
-		log,
-		cfg,
-	)
-
-	// Start the engine
-	if err := engine.Start(ctx); err != nil {
+	if err := orderbookv1.MarketState(cfg.MarketState).Validate(); err != nil {
+		log.Error(err, logger.Field{
+			Key:   "action",
+			Value: "validate_market_state",
+		})
+		return
+	}
+
+	errorPolicy := deadletterv1.Policy{
+		Decode:       deadletterv1.Action(cfg.DeadLetterConfig.DecodePolicy),
+		Process:      deadletterv1.Action(cfg.DeadLetterConfig.ProcessPolicy),
+		MaxRetries:   cfg.DeadLetterConfig.MaxRetries,
+		RetryBackoff: cfg.DeadLetterConfig.RetryBackoff,
+	}
+	if err := errorPolicy.Validate(); err != nil {
+		log.Error(err, logger.Field{
+			Key:   "action",
+			Value: "validate_error_policy",
+		})
+		return
+	}
+
+	if err := cfg.AdminConfig.Validate(); err != nil {
+		log.Error(err, logger.Field{
+			Key:   "action",
+			Value: "validate_admin_config",
+		})
+		return
+	}
+
+	pairs, err := cfg.PairConfigs()
+	if err != nil {
 		log.Error(err, logger.Field{
 			Key:   "action",
-			Value: "start_engine",
+			Value: "validate_pairs",
 		})
 		return
 	}
 
+	// Every pair trades by its own instrument spec, price protection, self-trade
+	// prevention, allocation and fees
+	rules := make(map[string]pairRules, len(pairs))
+	for _, pair := range pairs {
+		pairCfg := cfg.ForPair(pair)
+		r, action, err := newPairRules(pairCfg)
+		if err == nil {
+			action = "validate_fee_schedule"
+			_, err = app.NewFeeSchedule(pairCfg, nil)
+		}
+		if err != nil {
+			log.Error(err, logger.Field{
+				Key:   "action",
+				Value: action,
+			}, logger.Field{
+				Key:   "pair",
+				Value: pair.Pair,
+			})
+			return
+		}
+		rules[pair.Pair] = r
+	}
+
+	// Publishers are shared by every pair; each pair gets its own order book, reader,
+	// snapshot key and journal
+	matchPublisher := matchpublisher.NewPublisher(cfg.MatchPublisherConfig, *log)
+	orderPublisher := orderpublisher.NewPublisher(cfg.OrderPublisherConfig, *log)
+	deadLetters := deadletter.NewPublisher(cfg.DeadLetterConfig, *log)
+
+	pairConfigs := make(map[string]config.PairConfig, len(pairs))
+	names := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		pairConfigs[pair.Pair] = pair
+		names = append(names, pair.Pair)
+	}
+
+	newPairEngine := func(name string) (supervisor.Engine, error) {
+		pairCfg := cfg.ForPair(pairConfigs[name])
+		r := rules[name]
+
+		engineOptions := app.DefaultEngineOptions()
+		engineOptions.ErrorPolicy = errorPolicy
+		engineOptions.DeadLetters = deadLetters
+		if cfg.FeeConfig.StoreKey != "" {
+			engineOptions.FeeTiers = fee.NewTierStore(rclient, cfg.FeeConfig.StoreKey, log)
+		}
+
+		var orderJournal *journal.Journal
+		if pairCfg.JournalConfig.Path != "" {
+			j, err := journal.NewJournal(pairCfg.JournalConfig.Path, pairCfg.JournalConfig.Sync, log)
+			if err != nil {
+				return nil, err
+			}
+			orderJournal = j
+			engineOptions.Journal = j
+		}
+
+		ob := orderbook.NewOrderbookWithOptions(&orderbook.Options{
+			TickSize:            r.spec.TickSize,
+			Scale:               r.spec.Scale,
+			LotSize:             r.spec.LotSize,
+			SelfTradePrevention: r.selfTradePrevention,
+			Allocation:          r.allocation,
+		})
+		oReader := orderreader.NewReader(pairCfg.KafkaConfig, *log)
+		engine, err := app.OpenEngine(
+			ob,
+			stopbook.NewStopBook(),
+			oReader,
+			snapshot.NewSnapshotStore(rclient, pairCfg.Pair, r.spec.Scale, log),
+			matchPublisher,
+			orderPublisher,
+			log,
+			pairCfg,
+			engineOptions,
+		)
+		if err != nil {
+			oReader.Close()
+			if orderJournal != nil {
+				orderJournal.Close()
+			}
+			return nil, err
+		}
+		return pairEngine{Engine: engine, journal: orderJournal}, nil
+	}
+
+	pairSupervisor := supervisor.NewSupervisor(newPairEngine, log, supervisor.DefaultOptions())
+
+	// The operator API is only served when it has an address
+	var adminServer *grpc.Server
+	if cfg.AdminConfig.Address != "" {
+		listener, err := net.Listen("tcp", cfg.AdminConfig.Address)
+		if err != nil {
+			log.Error(err, logger.Field{
+				Key:   "action",
+				Value: "listen_admin_api",
+			})
+			return
+		}
+
+		adminServer = rpc.NewServer(cfg.AdminConfig.Token, rpc.NewAdminRPC(pairSupervisor, log))
+		go func() {
+			if err := adminServer.Serve(listener); err != nil {
+				log.Error(err, logger.Field{
+					Key:   "action",
+					Value: "serve_admin_api",
+				})
+			}
+		}()
+		log.Info("Admin API listening", logger.Field{
+			Key:   "address",
+			Value: cfg.AdminConfig.Address,
+		})
+	}
+
+	// Pairs that fail to start are retried in the background
+	if err := pairSupervisor.Start(ctx, names...); err != nil {
+		log.Error(err, logger.Field{
+			Key:   "action",
+			Value: "start_pairs",
+		})
+	}
+
 	log.Info("Matching service started successfully", logger.Field{
-		Key:   "pair",
-		Value: cfg.Pair,
+		Key:   "pairs",
+		Value: names,
 	})
 
 	// Wait for shutdown signal
@@ -103,11 +253,15 @@ func main() {
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
 
-	// Stop the engine gracefully
-	if err := engine.Stop(shutdownCtx); err != nil {
+	if adminServer != nil {
+		adminServer.GracefulStop()
+	}
+
+	// Stop every pair gracefully
+	if err := pairSupervisor.Stop(shutdownCtx); err != nil {
 		log.Error(err, logger.Field{
 			Key:   "action",
-			Value: "stop_engine",
+			Value: "stop_pairs",
 		})
 	}
 
@@ -123,3 +277,63 @@ func main() {
 
 	log.Info("Matching service shutdown complete")
 }
+
+// pairEngine closes the journal of a pair once its engine stopped.
+type pairEngine struct {
+	*app.Engine
+	journal *journal.Journal
+}
+
+// Stop stops the engine and closes its journal.
+func (p pairEngine) Stop(ctx context.Context) error {
+	err := p.Engine.Stop(ctx)
+	if p.journal != nil {
+		p.journal.Close()
+	}
+	return err
+}
+
+// pairRules holds the trading rules a pair's order book is built with.
+type pairRules struct {
+	spec                orderbookv1.InstrumentSpec
+	selfTradePrevention orderbookv1.SelfTradePrevention
+	allocation          orderbookv1.Allocation
+}
+
+// newPairRules validates the trading rules of a pair's configuration. On error it also
+// returns the action that failed, for the log.
+func newPairRules(cfg *config.Config) (pairRules, string, error) {
+	selfTradePrevention := orderbookv1.SelfTradePrevention(cfg.SelfTradePrevention)
+	if err := selfTradePrevention.Validate(); err != nil {
+		return pairRules{}, "validate_self_trade_prevention", err
+	}
+
+	if _, err := orderbookv1.NewPriceBand(cfg.PriceBandConfig.Percent, orderbookv1.PriceReference(cfg.PriceBandConfig.Reference), cfg.PriceBandConfig.AverageTrades); err != nil {
+		return pairRules{}, "validate_price_band", err
+	}
+
+	if _, err := orderbookv1.NewCircuitBreaker(cfg.CircuitBreakerConfig.MovePercent, cfg.CircuitBreakerConfig.Window, cfg.CircuitBreakerConfig.CoolDown); err != nil {
+		return pairRules{}, "validate_circuit_breaker", err
+	}
+
+	scale, err := orderbookv1.NewScale(cfg.PriceDecimals, cfg.SizeDecimals)
+	if err != nil {
+		return pairRules{}, "validate_scale", err
+	}
+
+	spec, err := orderbookv1.NewInstrumentSpec(scale, cfg.TickSize, cfg.LotSize, cfg.MinSize, cfg.MinNotional, cfg.MaxNotional)
+	if err != nil {
+		return pairRules{}, "validate_instrument_spec", err
+	}
+
+	strategy, err := cfg.AllocationConfig.StrategyOf(cfg.Pair)
+	if err != nil {
+		return pairRules{}, "validate_allocation", err
+	}
+	allocation, err := orderbookv1.NewAllocation(spec, orderbookv1.AllocationStrategy(strategy), cfg.AllocationConfig.MinAllocation, orderbookv1.RemainderRule(cfg.AllocationConfig.Remainder))
+	if err != nil {
+		return pairRules{}, "validate_allocation", err
+	}
+
+	return pairRules{spec: spec, selfTradePrevention: selfTradePrevention, allocation: allocation}, "", nil
+}
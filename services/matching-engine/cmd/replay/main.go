@@ -0,0 +1,193 @@
+// Command replay rebuilds the order book of a pair from a snapshot and the order journal,
+// without reading from or publishing to Kafka. It writes the resulting state and the
+// events the replayed journal entries produced; replaying the same snapshot and journal
+// twice writes byte-identical files.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/muhammadchandra19/exchange/pkg/logger"
+	"github.com/muhammadchandra19/exchange/pkg/redis"
+	app "github.com/muhammadchandra19/exchange/services/matching-engine/internal/app/engine"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+	snapshotv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/snapshot/v1"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/journal"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/orderbook"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/snapshot"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/stopbook"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/pkg/config"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "replay:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	cfg := &config.Config{}
+	if err := config.Load(cfg); err != nil {
+		return err
+	}
+
+	pair := flag.String("pair", cfg.Pair, "pair to replay, required when the process hosts several pairs")
+	journalPath := flag.String("journal", "", "journal file to replay; empty uses the pair's JOURNAL_PATH")
+	snapshotPath := flag.String("snapshot", "", "snapshot file to start from; empty loads the pair's latest snapshot from Redis")
+	statePath := flag.String("state", "replay-state.json", "file the replayed state is written to")
+	eventsPath := flag.String("events", "replay-events.jsonl", "file the replayed events are written to, one JSON entry per line")
+	flag.Parse()
+
+	if *pair == "" {
+		return fmt.Errorf("no pair given, set -pair or PAIR")
+	}
+	// The pair replays by the trading rules it is hosted with
+	pairConfig, err := cfg.LookupPair(*pair)
+	if err != nil {
+		return err
+	}
+	if *journalPath != "" {
+		pairConfig.JournalPath = *journalPath
+	}
+	if pairConfig.JournalPath == "" {
+		return fmt.Errorf("no journal given, set -journal or JOURNAL_PATH")
+	}
+	cfg = cfg.ForPair(pairConfig)
+
+	log, err := logger.NewLogger()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	selfTradePrevention := orderbookv1.SelfTradePrevention(cfg.SelfTradePrevention)
+	if err := selfTradePrevention.Validate(); err != nil {
+		return err
+	}
+	scale, err := orderbookv1.NewScale(cfg.PriceDecimals, cfg.SizeDecimals)
+	if err != nil {
+		return err
+	}
+	spec, err := orderbookv1.NewInstrumentSpec(scale, cfg.TickSize, cfg.LotSize, cfg.MinSize, cfg.MinNotional, cfg.MaxNotional)
+	if err != nil {
+		return err
+	}
+	strategy, err := cfg.AllocationConfig.StrategyOf(cfg.Pair)
+	if err != nil {
+		return err
+	}
+	allocation, err := orderbookv1.NewAllocation(spec, orderbookv1.AllocationStrategy(strategy), cfg.AllocationConfig.MinAllocation, orderbookv1.RemainderRule(cfg.AllocationConfig.Remainder))
+	if err != nil {
+		return err
+	}
+
+	var snapshotStore snapshotv1.Store = fileStore{path: *snapshotPath}
+	if *snapshotPath == "" {
+		redisConfig := redis.DefaultConfig()
+		redisConfig.Addrs = []string{cfg.RedisConfig.Addrs}
+		redisConfig.Password = cfg.RedisConfig.Password
+		redisConfig.Username = cfg.RedisConfig.Username
+		redisConfig.DB = cfg.RedisConfig.DB
+		rclient := redis.NewClient(log, redisConfig)
+		if err := rclient.Connect(ctx); err != nil {
+			return err
+		}
+		snapshotStore = snapshot.NewSnapshotStore(rclient, cfg.Pair, scale, log)
+	}
+
+	// Replay only reads the journal, so appends need no fsync
+	orderJournal, err := journal.NewJournal(cfg.JournalConfig.Path, false, log)
+	if err != nil {
+		return err
+	}
+	defer orderJournal.Close()
+
+	options := app.DefaultEngineOptions()
+	options.Journal = orderJournal
+
+	ob := orderbook.NewOrderbookWithOptions(&orderbook.Options{
+		TickSize:            spec.TickSize,
+		Scale:               spec.Scale,
+		LotSize:             spec.LotSize,
+		SelfTradePrevention: selfTradePrevention,
+		Allocation:          allocation,
+	})
+	engine := app.NewEngineWithOptions(ob, stopbook.NewStopBook(), nil, snapshotStore, nil, nil, log, cfg, options)
+
+	events, err := engine.Replay(ctx)
+	if err != nil {
+		return err
+	}
+
+	state, err := json.Marshal(engine.Snapshot())
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*statePath, state, 0o644); err != nil {
+		return err
+	}
+
+	file, err := os.Create(*eventsPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		writer.Write(line)
+		writer.WriteByte('\n')
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	log.Info("Replay written",
+		logger.Field{Key: "state", Value: *statePath},
+		logger.Field{Key: "events", Value: *eventsPath},
+		logger.Field{Key: "eventCount", Value: len(events)},
+	)
+	return nil
+}
+
+// fileStore reads the snapshot to start from out of a JSON file.
+type fileStore struct {
+	path string
+}
+
+// Store writes the snapshot to the file.
+func (s fileStore) Store(_ context.Context, snap *snapshotv1.Snapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0o644)
+}
+
+// LoadStore reads the snapshot from the file.
+func (s fileStore) LoadStore(_ context.Context) (*snapshotv1.Snapshot, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var snap snapshotv1.Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, err
+	}
+	if snap.Version < snapshotv1.CurrentVersion {
+		return nil, fmt.Errorf("snapshot version %d is not supported, load it through the engine first", snap.Version)
+	}
+	return &snap, nil
+}
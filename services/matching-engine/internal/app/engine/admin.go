@@ -0,0 +1,223 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/muhammadchandra19/exchange/pkg/logger"
+	journalv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/journal/v1"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+	snapshotv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/snapshot/v1"
+	stopbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/stopbook/v1"
+)
+
+// ErrPaused is returned when pausing a pair that is already paused.
+var ErrPaused = errors.New("pair is paused")
+
+// BookView is a copy of the book as of an order offset.
+type BookView struct {
+	Bids        []orderbookv1.Level // Best price first
+	Asks        []orderbookv1.Level // Best price first
+	OrderOffset int64
+}
+
+// Book returns the best depth levels of each side, every level when depth is zero.
+// withOrders also copies the orders of every level in queue order.
+func (e *Engine) Book(depth int, withOrders bool) *BookView {
+	e.applyMu.Lock()
+	defer e.applyMu.Unlock()
+
+	levels := func(limits []*orderbookv1.Limit) []orderbookv1.Level {
+		if depth > 0 && len(limits) > depth {
+			limits = limits[:depth]
+		}
+		levels := make([]orderbookv1.Level, 0, len(limits))
+		for _, limit := range limits {
+			levels = append(levels, orderbookv1.NewLevel(limit, withOrders))
+		}
+		return levels
+	}
+
+	return &BookView{
+		Bids:        levels(e.orderbook.Bids()),
+		Asks:        levels(e.orderbook.Asks()),
+		OrderOffset: e.getOrderOffset(),
+	}
+}
+
+// GetOrder returns a copy of an order resting in the book.
+func (e *Engine) GetOrder(orderID string) (*orderbookv1.Order, error) {
+	return e.orderbook.GetOrder(orderID)
+}
+
+// GetStopOrder returns a copy of an order waiting in the stop book.
+func (e *Engine) GetStopOrder(orderID string) (*stopbookv1.StopOrder, error) {
+	return e.stopBook.GetStopOrder(orderID)
+}
+
+// ForceSnapshot stores a snapshot now, regardless of the snapshot interval, and returns it.
+func (e *Engine) ForceSnapshot(ctx context.Context) (*snapshotv1.Snapshot, error) {
+	return e.storeSnapshot(ctx)
+}
+
+// Pause stops the pair from consuming orders until Resume is called. The message being
+// processed is finished first; the book can still be read and snapshotted meanwhile.
+func (e *Engine) Pause(reason string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.resume != nil {
+		return ErrPaused
+	}
+	e.resume = make(chan struct{})
+
+	e.logger.Warn("Pair paused",
+		logger.Field{Key: "pair", Value: e.config.Pair},
+		logger.Field{Key: "reason", Value: reason},
+	)
+	return nil
+}
+
+// Resume lets a paused pair consume orders again. It does nothing if the pair is not paused.
+func (e *Engine) Resume() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.resume == nil {
+		return
+	}
+	close(e.resume)
+	e.resume = nil
+
+	e.logger.Info("Pair resumed", logger.Field{Key: "pair", Value: e.config.Pair})
+}
+
+// IsPaused reports whether the pair was paused.
+func (e *Engine) IsPaused() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.resume != nil
+}
+
+// waitResumed blocks while the pair is paused and reports whether it may go on, false
+// once the engine stops.
+func (e *Engine) waitResumed() bool {
+	e.mu.RLock()
+	resume := e.resume
+	e.mu.RUnlock()
+
+	if resume == nil {
+		return true
+	}
+	select {
+	case <-resume:
+		return true
+	case <-e.ctx.Done():
+		return false
+	}
+}
+
+// CancelUserOrders cancels every resting and stop order of a user and returns the IDs of
+// the cancelled orders. The resting orders are pulled by a mass cancel and the stop orders
+// by a cancel each, journaled like requests from the user without an order offset, and
+// the user is notified like for those requests.
+func (e *Engine) CancelUserOrders(userID string) ([]string, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user ID cannot be empty")
+	}
+
+	e.applyMu.Lock()
+	defer e.applyMu.Unlock()
+
+	// Replay processes the cancels like any other, so they follow the market state too
+	if state := e.getMarketState(); !state.Accepts(orderbookv1.OrderTypeCancel) {
+		return nil, state.Refuse(orderbookv1.OrderTypeCancel)
+	}
+
+	e.inputOffset = -1
+	timestamp := time.Now().UnixNano()
+	massCancel := &orderbookv1.PlaceOrderRequest{
+		UserID:    userID,
+		Type:      orderbookv1.OrderTypeMassCancel,
+		Offset:    -1,
+		Timestamp: timestamp,
+	}
+	if err := e.appendCancel(massCancel); err != nil {
+		return nil, err
+	}
+	orders, err := e.massCancel(massCancel)
+	e.publishIndicative(timestamp)
+	e.flushEvents(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	cancelled := make([]string, 0, len(orders))
+	for _, order := range orders {
+		cancelled = append(cancelled, order.ID)
+	}
+
+	// Stop orders are not resting, so the mass cancel leaves them to be cancelled one by one
+	for _, stop := range e.stopBook.UserStopOrders(userID) {
+		request := &orderbookv1.PlaceOrderRequest{
+			OrderID:   stop.OrderID,
+			UserID:    userID,
+			Type:      orderbookv1.OrderTypeCancel,
+			Offset:    -1,
+			Timestamp: timestamp,
+		}
+		if err := e.appendCancel(request); err != nil {
+			return cancelled, err
+		}
+		err := e.cancelOrder(request)
+		e.flushEvents(-1)
+		if err != nil {
+			return cancelled, err
+		}
+		cancelled = append(cancelled, stop.OrderID)
+	}
+
+	e.logger.Info("User orders cancelled",
+		logger.Field{Key: "pair", Value: e.config.Pair},
+		logger.Field{Key: "userID", Value: userID},
+		logger.Field{Key: "count", Value: len(cancelled)},
+	)
+	return cancelled, nil
+}
+
+// SetMarketState moves the pair to state. The change is journaled like a market state
+// message from the order topic, without an order offset, and published like one.
+func (e *Engine) SetMarketState(state orderbookv1.MarketState) error {
+	if err := state.Validate(); err != nil {
+		return err
+	}
+
+	return e.applyOrder(&orderbookv1.PlaceOrderRequest{
+		Type:        orderbookv1.OrderTypeMarketState,
+		MarketState: state,
+		Offset:      -1,
+	})
+}
+
+// appendCancel journals a cancel the engine makes on its own, which consumes no order
+// offset. Caller must hold the apply lock.
+func (e *Engine) appendCancel(request *orderbookv1.PlaceOrderRequest) error {
+	return e.appendInput(&journalv1.Entry{
+		Type:      journalv1.EntryTypeOrder,
+		Offset:    -1,
+		Timestamp: request.Timestamp,
+		Order:     request,
+	})
+}
+
+// Lag returns the number of order messages of the pair that were not read yet.
+func (e *Engine) Lag(ctx context.Context) (int64, error) {
+	return e.orderReader.Lag(ctx)
+}
+
+// Scale returns the precision of the pair's prices and sizes.
+func (e *Engine) Scale() orderbookv1.Scale {
+	return e.scale
+}
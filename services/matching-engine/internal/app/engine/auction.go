@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/muhammadchandra19/exchange/pkg/logger"
+	matchpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/match-publisher/v1"
+	orderpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-publisher/v1"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// publishIndicative publishes the price and volume the pair would uncross at as of
+// timestamp, if they changed since they were last published. It does nothing outside an
+// auction. Caller must hold the apply lock.
+func (e *Engine) publishIndicative(timestamp int64) {
+	if e.getMarketState() != orderbookv1.MarketStateAuction {
+		return
+	}
+
+	indicative := e.orderbook.IndicativeUncross(e.getLastTradePrice())
+	if indicative.Price == e.indicative.Price && indicative.Volume == e.indicative.Volume {
+		return
+	}
+	e.indicative = indicative
+
+	orderEvent := orderpublisherv1.CreateIndicativeEvent(e.config.Pair, indicative, timestamp, e.scale)
+	if err := e.publishOrderEvent(orderEvent); err != nil {
+		e.logger.ErrorContext(e.ctx, err, logger.Field{
+			Key:   "action",
+			Value: "publish_auction_indicative",
+		})
+	}
+}
+
+// uncross ends the call period of an auction at timestamp: every crossing order executes
+// at the equilibrium price, and orders left over keep resting. Caller must hold the apply lock.
+func (e *Engine) uncross(timestamp int64) {
+	uncross, matches, cancels := e.orderbook.Uncross(e.getLastTradePrice(), timestamp)
+	e.orderbook.SetAuction(false)
+	e.indicative = orderbookv1.Uncross{}
+
+	for _, cancel := range cancels {
+		e.publishCancelled(cancel.Order, cancel.Price, cancel.Size, orderbookv1.ErrSelfTradePrevented, timestamp)
+	}
+	if len(matches) > 0 {
+		e.logUncrossMatches(matches, timestamp)
+	}
+
+	e.logger.Info("Auction uncrossed",
+		logger.Field{Key: "pair", Value: e.config.Pair},
+		logger.Field{Key: "price", Value: uncross.Price},
+		logger.Field{Key: "volume", Value: uncross.Volume},
+		logger.Field{Key: "surplus", Value: uncross.Surplus},
+		logger.Field{Key: "matchCount", Value: len(matches)},
+	)
+}
+
+// logUncrossMatches publishes the trades of an uncross at timestamp. Neither side of an
+// auction trade took liquidity when it arrived, so the order that made the book cross,
+// the later one, is the taker.
+func (e *Engine) logUncrossMatches(matches []orderbookv1.Match, timestamp int64) {
+	e.matchesMutex.Lock()
+	e.totalMatches += int64(len(matches))
+	e.matchesMutex.Unlock()
+
+	e.setLastTradePrice(matches[len(matches)-1].Price)
+
+	for i, match := range matches {
+		taker := match.Bid
+		if match.Ask.Timestamp > match.Bid.Timestamp {
+			taker = match.Ask
+		}
+
+		e.recordTrade(match.Price, timestamp)
+		matchEvent := matchpublisherv1.CreateFromMatch(&match, taker, e.config.Pair, e.nextTradeSequence(), e.scale)
+		matchEvent.Timestamp = timestamppb.New(time.Unix(0, timestamp))
+		e.stampFees(matchEvent)
+		e.queueMatchEvent(matchEvent)
+		e.publishFills(&match, taker, timestamp)
+		e.logger.Info("Auction trade executed",
+			logger.Field{Key: "matchIndex", Value: i + 1},
+			logger.Field{Key: "tradeID", Value: matchEvent.MatchID},
+			logger.Field{Key: "price", Value: match.Price},
+			logger.Field{Key: "size", Value: match.SizeFilled},
+			logger.Field{Key: "bidOrderID", Value: match.Bid.ID},
+			logger.Field{Key: "askOrderID", Value: match.Ask.ID},
+		)
+	}
+}
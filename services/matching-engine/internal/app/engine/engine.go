@@ -2,15 +2,24 @@ package engine
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/muhammadchandra19/exchange/pkg/logger"
+	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
+	deadletterv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/dead-letter/v1"
+	feev1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/fee/v1"
+	journalv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/journal/v1"
 	matchpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/match-publisher/v1"
+	orderpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-publisher/v1"
 	orderreaderv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-reader/v1"
 	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
 	snapshotv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/snapshot/v1"
+	stopbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/stopbook/v1"
 	"github.com/muhammadchandra19/exchange/services/matching-engine/pkg/config"
+	"github.com/segmentio/kafka-go"
 	"go.uber.org/zap/zapcore"
 )
 
@@ -18,25 +27,67 @@ import (
 type Engine struct {
 	// Core components
 	orderbook      orderbookv1.Orderbook
+	stopBook       stopbookv1.StopBook
 	orderReader    orderreaderv1.OrderReader
 	matchPublisher matchpublisherv1.MatchPublisher
+	orderPublisher orderpublisherv1.OrderPublisher
 	snapshotStore  snapshotv1.Store
 	logger         *logger.Logger
 	config         *config.Config
+	scale          orderbookv1.Scale          // Precision of prices and sizes of the pair
+	spec           orderbookv1.InstrumentSpec // Trading rules every order is checked against
+	journal        journalv1.Journal          // Append-only log of inputs and events, nil when journaling is off
+	errorPolicy    deadletterv1.Policy        // What happens to order messages that fail
+	deadLetters    deadletterv1.Publisher     // Receives dead-lettered order messages, nil logs and skips them
+	fees           *feev1.Schedule            // Fees stamped on the pair's trades
+
+	// Journal state. applyMu serializes changes to the book so the journal records them
+	// in the order they were applied, and snapshots see a consistent state.
+	applyMu     sync.Mutex
+	logSequence int64                   // Sequence of the last journal entry reflected in the book
+	events      []*journalv1.Entry      // Events of the input being applied, journaled after it
+	inputOffset int64                   // Order offset of the input being applied, -1 when it has none
+	unpublished []*pb.OrderEventPayload // Order events of the input being applied the publisher refused
+	replaying   bool                    // Events are recorded but not published while replaying
+	replayed    bool                    // The journal was replayed since the snapshot was loaded
+
+	// Match events waiting for the publisher to confirm them, oldest first. A message's
+	// offset is only consumed once the outbox is empty, so no trade is lost.
+	outbox []*pb.MatchEventPayload
+
+	// Price protection. The moving average, the circuit breaker's window and the end of its
+	// cool-down are guarded by applyMu and persisted in snapshots.
+	priceBand      orderbookv1.PriceBand
+	circuitBreaker orderbookv1.CircuitBreaker
+	averagePrices  []int64                  // Prices of the last trades, oldest first
+	breakerHighs   []orderbookv1.TradePrint // Falling highs of the trades within the window
+	breakerLows    []orderbookv1.TradePrint // Rising lows of the trades within the window
+	haltedUntil    int64                    // End of the circuit breaker's cool-down, zero when not halted by it
+
+	// Uncross last published during an auction, guarded by applyMu and persisted in snapshots
+	indicative orderbookv1.Uncross
 
 	// Simple state management with mutex instead of atomics
 	mu                 sync.RWMutex
 	orderOffset        int64
 	lastSnapshotOffset int64
+	lastTradePrice     int64
+	tradeSequence      int64                   // Sequence of the last trade of the pair, persisted in snapshots
+	marketState        orderbookv1.MarketState // Trading phase of the pair, persisted in snapshots
+	haltErr            error                   // Failure that halted the pair, nil while orders are consumed
+	resume             chan struct{}           // Closed when a paused pair resumes, nil while it is not paused
 
 	// Simple shutdown coordination
 	ctx    context.Context
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
+	done   chan struct{} // Closed when the order processor exits
 
 	// Configuration
-	snapshotInterval    time.Duration
-	snapshotOffsetDelta int64
+	snapshotInterval     time.Duration
+	snapshotOffsetDelta  int64
+	expirySweepInterval  time.Duration
+	publishRetryInterval time.Duration
 
 	// Match statistics
 	totalMatches int64
@@ -46,57 +97,152 @@ type Engine struct {
 // NewEngine creates a new instance of Engine with the provided dependencies.
 func NewEngine(
 	orderbook orderbookv1.Orderbook,
+	stopBook stopbookv1.StopBook,
 	orderReader orderreaderv1.OrderReader,
 	snapshotStore snapshotv1.Store,
 	matchPublisher matchpublisherv1.MatchPublisher,
+	orderPublisher orderpublisherv1.OrderPublisher,
 	logger *logger.Logger,
 	config *config.Config,
 ) *Engine {
-	return NewEngineWithOptions(orderbook, orderReader, snapshotStore, matchPublisher, logger, config, DefaultEngineOptions())
+	return NewEngineWithOptions(orderbook, stopBook, orderReader, snapshotStore, matchPublisher, orderPublisher, logger, config, DefaultEngineOptions())
 }
 
 // NewEngineWithOptions creates a new engine with custom options
 func NewEngineWithOptions(
 	orderbook orderbookv1.Orderbook,
+	stopBook stopbookv1.StopBook,
 	orderReader orderreaderv1.OrderReader,
 	snapshotStore snapshotv1.Store,
 	matchPublisher matchpublisherv1.MatchPublisher,
+	orderPublisher orderpublisherv1.OrderPublisher,
 	logger *logger.Logger,
 	config *config.Config,
 	options *Options,
 ) *Engine {
+	e, err := OpenEngine(orderbook, stopBook, orderReader, snapshotStore, matchPublisher, orderPublisher, logger, config, options)
+	if err != nil {
+		logger.GetZap().Fatal("Failed to create engine", zapcore.Field{
+			Key:       "error",
+			Interface: err,
+		})
+	}
+	return e
+}
+
+// OpenEngine creates a new engine with custom options and loads its snapshot. Unlike
+// NewEngineWithOptions it returns an error instead of exiting the process, so a
+// supervised pair that fails to open does not take the other pairs down.
+func OpenEngine(
+	orderbook orderbookv1.Orderbook,
+	stopBook stopbookv1.StopBook,
+	orderReader orderreaderv1.OrderReader,
+	snapshotStore snapshotv1.Store,
+	matchPublisher matchpublisherv1.MatchPublisher,
+	orderPublisher orderpublisherv1.OrderPublisher,
+	logger *logger.Logger,
+	config *config.Config,
+	options *Options,
+) (*Engine, error) {
+	expirySweepInterval := options.ExpirySweepInterval
+	if expirySweepInterval <= 0 {
+		expirySweepInterval = DefaultEngineOptions().ExpirySweepInterval
+	}
+	publishRetryInterval := options.PublishRetryInterval
+	if publishRetryInterval <= 0 {
+		publishRetryInterval = DefaultEngineOptions().PublishRetryInterval
+	}
+
+	scale := orderbookv1.Scale{
+		PriceDecimals: config.PriceDecimals,
+		SizeDecimals:  config.SizeDecimals,
+	}
+	spec, err := orderbookv1.NewInstrumentSpec(scale, config.TickSize, config.LotSize, config.MinSize, config.MinNotional, config.MaxNotional)
+	if err != nil {
+		return nil, fmt.Errorf("invalid instrument specification: %w", err)
+	}
+	marketState := orderbookv1.MarketState(config.MarketState)
+	if marketState == "" {
+		marketState = orderbookv1.MarketStateContinuous
+	}
+	if err := marketState.Validate(); err != nil {
+		return nil, err
+	}
+	priceBand, err := orderbookv1.NewPriceBand(config.PriceBandConfig.Percent, orderbookv1.PriceReference(config.PriceBandConfig.Reference), config.PriceBandConfig.AverageTrades)
+	if err != nil {
+		return nil, err
+	}
+	circuitBreaker, err := orderbookv1.NewCircuitBreaker(config.CircuitBreakerConfig.MovePercent, config.CircuitBreakerConfig.Window, config.CircuitBreakerConfig.CoolDown)
+	if err != nil {
+		return nil, err
+	}
+	var storedTiers map[string]string
+	if options.FeeTiers != nil {
+		if storedTiers, err = options.FeeTiers.LoadUserTiers(context.Background()); err != nil {
+			return nil, fmt.Errorf("failed to load fee tiers: %w", err)
+		}
+	}
+	fees, err := NewFeeSchedule(config, storedTiers)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fee schedule: %w", err)
+	}
+
 	e := &Engine{
 		orderbook:      orderbook,
+		stopBook:       stopBook,
 		orderReader:    orderReader,
 		snapshotStore:  snapshotStore,
 		matchPublisher: matchPublisher,
+		orderPublisher: orderPublisher,
 		logger:         logger,
 		config:         config,
+		scale:          scale,
+		spec:           spec,
+		journal:        options.Journal,
+		errorPolicy:    options.ErrorPolicy,
+		deadLetters:    options.DeadLetters,
+		fees:           fees,
+		priceBand:      priceBand,
+		circuitBreaker: circuitBreaker,
 
-		snapshotInterval:    options.SnapshotInterval,
-		snapshotOffsetDelta: options.SnapshotOffsetDelta,
-		orderOffset:         -1,
+		snapshotInterval:     options.SnapshotInterval,
+		snapshotOffsetDelta:  options.SnapshotOffsetDelta,
+		expirySweepInterval:  expirySweepInterval,
+		publishRetryInterval: publishRetryInterval,
+		orderOffset:          -1,
+		inputOffset:          -1,
+		marketState:          marketState,
+		done:                 make(chan struct{}),
 	}
 
 	// Load snapshot during initialization
 	if err := e.loadSnapshot(context.Background()); err != nil {
-		e.logger.GetZap().Fatal("Failed to load snapshot", zapcore.Field{
-			Key:       "error",
-			Interface: err,
-		})
+		return nil, fmt.Errorf("failed to load snapshot: %w", err)
 	}
 
-	return e
+	return e, nil
 }
 
-// Start initializes the engine and starts processing routines.
+// Start initializes the engine and starts processing routines. The journal is replayed
+// first, so consuming resumes after the last order it recorded, and the matches of the
+// replayed orders are published again under their original trade IDs, since the engine
+// may have stopped before the publisher confirmed them.
 func (e *Engine) Start(ctx context.Context) error {
 	// Create cancellable context
 	e.ctx, e.cancel = context.WithCancel(ctx)
 
-	e.wg.Add(2) // Reduced from 3 to 2 (no match consumer needed)
+	events, err := e.Replay(e.ctx)
+	if err != nil {
+		return err
+	}
+	if err := e.republish(events); err != nil {
+		return err
+	}
+
+	e.wg.Add(3)
 	go e.runOrderProcessor()
 	go e.runSnapshotManager()
+	go e.runExpirySweeper()
 
 	e.logger.Info("Simplified engine started", logger.Field{
 		Key:   "pair",
@@ -132,6 +278,9 @@ func (e *Engine) Stop(ctx context.Context) error {
 // runOrderProcessor combines order reading and processing in a single goroutine
 func (e *Engine) runOrderProcessor() {
 	defer e.wg.Done()
+	defer close(e.done)
+	defer e.orderReader.Close()
+	defer e.recoverPanic()
 
 	e.logger.Info("Starting order processor", logger.Field{
 		Key:   "pair",
@@ -145,22 +294,19 @@ func (e *Engine) runOrderProcessor() {
 	}
 
 	if err := e.orderReader.SetOffset(currentOffset); err != nil {
-		e.logger.GetZap().Fatal("Failed to set offset for order reader", zapcore.Field{
-			Key:       "error",
-			Interface: err,
-		})
+		e.halt(fmt.Errorf("failed to set offset for order reader: %w", err))
+		return
 	}
 
 	for {
 		select {
 		case <-e.ctx.Done():
 			e.logger.Info("Order processor shutting down")
-			e.orderReader.Close()
 			return
 		default:
 			// Read message directly
 			msg, orderRequest, err := e.orderReader.ReadMessage(e.ctx)
-			if err != nil {
+			if err != nil && !errors.Is(err, orderreaderv1.ErrInvalidMessage) {
 				e.logger.ErrorContext(e.ctx, err, logger.Field{
 					Key:   "action",
 					Value: "read_order_message",
@@ -170,39 +316,78 @@ func (e *Engine) runOrderProcessor() {
 				continue
 			}
 
-			// Commit message
-			if err := e.orderReader.CommitMessages(e.ctx, msg); err != nil {
-				e.logger.ErrorContext(e.ctx, err, logger.Field{
-					Key:   "action",
-					Value: "commit_order_message",
-				})
+			// A paused pair holds the message it read until it is resumed
+			if !e.waitResumed() {
+				continue
 			}
 
-			obRequest := orderbookv1.PlaceOrderRequest{}
+			if err != nil {
+				err = e.handleFailure(deadletterv1.ErrorClassDecode, msg, err, nil)
+			} else {
+				err = e.processMessage(msg, orderRequest)
+			}
 
-			// Process order immediately
-			if err := e.processOrder(obRequest.FromKafkaPayload(orderRequest)); err != nil {
+			// The message is only committed once its trades are confirmed, and stays
+			// unconsumed when the engine stops or the pair halts while handling it
+			if errors.Is(err, deadletterv1.ErrHalted) {
+				e.halt(err)
+				return
+			}
+			if e.ctx.Err() != nil || errors.Is(err, matchpublisherv1.ErrMatchesPending) {
+				continue
+			}
+			if err := e.orderReader.CommitMessages(e.ctx, msg); err != nil {
 				e.logger.ErrorContext(e.ctx, err, logger.Field{
 					Key:   "action",
-					Value: "process_order",
+					Value: "commit_order_message",
 				})
-
-				// 🔴 CRITICAL: Handle error properly
-				// Option 1: Retry with backoff
-				// Option 2: Send to dead letter queue
-				// Option 3: Alert operations team
-				continue
 			}
+		}
+	}
+}
 
-			// Update offset
-			e.setOrderOffset(msg.Offset)
+// processMessage applies a decoded order message, handing a failure to the error policy.
+func (e *Engine) processMessage(msg kafka.Message, orderRequest *pb.PlaceOrderPayload) error {
+	obRequest := orderbookv1.PlaceOrderRequest{}
+	placeRequest, err := obRequest.FromKafkaPayload(orderRequest, e.scale)
+	if err != nil {
+		// Values finer than the pair precision are refused, never rounded
+		rejected := orderbookv1.NewOrder(orderRequest.UserID, 0, orderRequest.Bid, orderRequest.OrderID)
+		err = e.apply(msg.Offset, nil, func() error { return e.rejectOrder(rejected, 0, err) })
+		if err != nil {
+			e.logger.ErrorContext(e.ctx, err, logger.Field{
+				Key:   "action",
+				Value: "convert_order_message",
+			})
 		}
+		return err
 	}
+
+	// The request is where it was read from the topic, whatever offset the producer wrote
+	placeRequest.Offset = msg.Offset
+	err = e.applyOrder(placeRequest)
+	if err == nil || errors.Is(err, matchpublisherv1.ErrMatchesPending) {
+		return err
+	}
+
+	// Once the request changed the book, applying it again would apply it twice
+	var (
+		retry   func() error
+		failure *publishFailure
+	)
+	switch {
+	case errors.Is(err, errNotApplied):
+		retry = func() error { return e.applyOrder(placeRequest) }
+	case errors.As(err, &failure):
+		retry = func() error { return e.publishAgain(failure) }
+	}
+	return e.handleFailure(deadletterv1.ErrorClassProcess, msg, err, retry)
 }
 
 // runSnapshotManager handles periodic snapshots
 func (e *Engine) runSnapshotManager() {
 	defer e.wg.Done()
+	defer e.recoverPanic()
 
 	ticker := time.NewTicker(e.snapshotInterval)
 	defer ticker.Stop()
@@ -222,38 +407,843 @@ func (e *Engine) runSnapshotManager() {
 	}
 }
 
-// processOrder processes a single order request
+// runExpirySweeper periodically removes expired good-till-date orders from the book and
+// resumes the pair once a circuit breaker cool-down is over
+func (e *Engine) runExpirySweeper() {
+	defer e.wg.Done()
+	defer e.recoverPanic()
+
+	ticker := time.NewTicker(e.expirySweepInterval)
+	defer ticker.Stop()
+
+	e.logger.Info("Starting expiry sweeper")
+
+	for {
+		select {
+		case <-e.ctx.Done():
+			e.logger.Info("Expiry sweeper shutting down")
+			return
+		case now := <-ticker.C:
+			e.expireOrders(now)
+			e.endCoolDown(now)
+		}
+	}
+}
+
+// expireOrders removes the GTD orders and GTD stop orders that expired at or before now.
+// A sweep that has something to expire is journaled before it changes the book, so replay
+// expires the same orders; if the journal cannot take it, nothing expires.
+func (e *Engine) expireOrders(now time.Time) {
+	timestamp := now.UnixNano()
+	if !e.orderbook.HasExpiredOrders(timestamp) && !e.stopBook.HasExpiredStopOrders(timestamp) {
+		return
+	}
+
+	input := &journalv1.Entry{
+		Type:      journalv1.EntryTypeExpiry,
+		Offset:    -1,
+		Timestamp: timestamp,
+	}
+	if err := e.apply(-1, input, func() error {
+		e.expire(timestamp)
+		return nil
+	}); err != nil {
+		e.logger.ErrorContext(e.ctx, err, logger.Field{
+			Key:   "action",
+			Value: "expire_orders",
+		})
+	}
+}
+
+// expire removes the orders and stop orders that expired at or before now and publishes
+// their cancels. Caller must hold the apply lock.
+func (e *Engine) expire(now int64) {
+	e.publishExpired(e.orderbook.ExpireOrders(now), e.stopBook.ExpireStopOrders(now), now)
+}
+
+// publishExpired notifies the owners of orders removed from the book and of stop orders
+// removed from the stop book on expiry at now
+func (e *Engine) publishExpired(expired []*orderbookv1.Order, stops []*stopbookv1.StopOrder, now int64) {
+	for _, order := range expired {
+		size := order.TotalSize()
+		order.Size, order.HiddenSize = 0, 0
+		e.publishCancelled(order, order.Price, size, orderbookv1.ErrOrderExpired, now)
+
+		e.logger.Info("Order expired",
+			logger.Field{Key: "orderID", Value: order.ID},
+			logger.Field{Key: "userID", Value: order.UserID},
+			logger.Field{Key: "remainingSize", Value: size},
+			logger.Field{Key: "expireAt", Value: order.ExpireAt},
+		)
+	}
+	for _, stop := range stops {
+		order := newOrder(stop.ToPlaceOrderRequest())
+		order.Size = 0
+		e.publishCancelled(order, stop.LimitPrice, stop.Size, orderbookv1.ErrOrderExpired, now)
+
+		e.logger.Info("Stop order expired",
+			logger.Field{Key: "orderID", Value: stop.OrderID},
+			logger.Field{Key: "userID", Value: stop.UserID},
+			logger.Field{Key: "remainingSize", Value: stop.Size},
+			logger.Field{Key: "expireAt", Value: stop.ExpireAt},
+		)
+	}
+	e.publishIndicative(now)
+}
+
+// applyOrder journals an accepted order request, stamped with the time it was accepted,
+// and processes it.
+func (e *Engine) applyOrder(orderRequest *orderbookv1.PlaceOrderRequest) error {
+	if orderRequest.Timestamp == 0 {
+		orderRequest.Timestamp = time.Now().UnixNano()
+	}
+
+	input := &journalv1.Entry{
+		Type:      journalv1.EntryTypeOrder,
+		Offset:    orderRequest.Offset,
+		Timestamp: orderRequest.Timestamp,
+		Order:     orderRequest,
+	}
+	return e.apply(orderRequest.Offset, input, func() error { return e.processOrder(orderRequest) })
+}
+
+// apply runs fn, which handles the message at offset, under the apply lock. input, when
+// set, is journaled before fn runs and the events fn publishes are journaled after it.
+// The match events fn produced are then published until the publisher confirms them;
+// if the engine stops first, the offset is not consumed and ErrMatchesPending is
+// returned. Once a message is in the journal its offset is consumed, even if fn fails,
+// because replay will process it again; without a journal only a successful fn consumes it.
+// An input that did not come from the order topic has a negative offset and consumes none.
+func (e *Engine) apply(offset int64, input *journalv1.Entry, fn func() error) error {
+	e.applyMu.Lock()
+	defer e.applyMu.Unlock()
+
+	return e.applyLocked(offset, input, fn)
+}
+
+// applyLocked is apply for callers that hold the apply lock, to decide on an input and
+// apply it without another input getting in between.
+func (e *Engine) applyLocked(offset int64, input *journalv1.Entry, fn func() error) error {
+	e.inputOffset = offset
+	e.unpublished = nil
+	if input != nil {
+		if err := e.appendInput(input); err != nil {
+			return fmt.Errorf("%w: %w", errNotApplied, err)
+		}
+	}
+
+	err := fn()
+	e.flushEvents(offset)
+	if err := e.flushOutbox(); err != nil {
+		return err
+	}
+	if err != nil && len(e.unpublished) > 0 {
+		err = &publishFailure{events: e.unpublished, err: err}
+	}
+	e.unpublished = nil
+	if offset >= 0 && (err == nil || e.journal != nil) {
+		e.setOrderOffset(offset)
+	}
+	return err
+}
+
+// appendInput journals an input before it is applied. Nothing may change the book
+// without being journaled, so a failing journal is retried until the engine stops.
+// Caller must hold the apply lock.
+func (e *Engine) appendInput(input *journalv1.Entry) error {
+	for {
+		err := e.appendJournal(input)
+		if err == nil {
+			return nil
+		}
+
+		e.logger.ErrorContext(e.ctx, err, logger.Field{
+			Key:   "action",
+			Value: "journal_input",
+		})
+
+		select {
+		case <-e.ctx.Done():
+			return e.ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// appendJournal writes entries to the journal, if there is one. Caller must hold the apply lock.
+func (e *Engine) appendJournal(entries ...*journalv1.Entry) error {
+	if e.journal == nil || e.replaying {
+		return nil
+	}
+
+	if err := e.journal.Append(entries...); err != nil {
+		return err
+	}
+	e.logSequence = entries[len(entries)-1].Sequence
+	return nil
+}
+
+// flushEvents journals the events recorded while an input was applied. They were already
+// published, and replay produces them again, so a failure is only logged.
+// Caller must hold the apply lock.
+func (e *Engine) flushEvents(offset int64) {
+	events := e.events
+	e.events = nil
+	if len(events) == 0 {
+		return
+	}
+
+	for _, event := range events {
+		event.Offset = offset
+	}
+	if err := e.appendJournal(events...); err != nil {
+		e.logger.ErrorContext(e.ctx, err, logger.Field{
+			Key:   "action",
+			Value: "journal_events",
+		})
+	}
+}
+
+// recordEvent keeps an event for the journal
+func (e *Engine) recordEvent(event *journalv1.Entry) {
+	if e.journal == nil {
+		return
+	}
+	e.events = append(e.events, event)
+}
+
+// queueMatchEvent records a match event for the journal and adds it to the outbox,
+// unless the journal is being replayed
+func (e *Engine) queueMatchEvent(matchEvent *pb.MatchEventPayload) {
+	e.recordEvent(&journalv1.Entry{
+		Type:      journalv1.EntryTypeMatch,
+		Timestamp: matchEvent.Timestamp.AsTime().UnixNano(),
+		Match:     matchEvent,
+	})
+	if e.replaying {
+		return
+	}
+	e.outbox = append(e.outbox, matchEvent)
+}
+
+// flushOutbox publishes the match events in the outbox in order, retrying each one until
+// the publisher confirms it. It gives up only when the engine stops, leaving the rest in
+// the outbox. Caller must hold the apply lock.
+func (e *Engine) flushOutbox() error {
+	for len(e.outbox) > 0 {
+		matchEvent := e.outbox[0]
+		err := e.matchPublisher.PublishMatchEvent(e.ctx, matchEvent)
+		if err == nil {
+			e.outbox[0] = nil
+			e.outbox = e.outbox[1:]
+			continue
+		}
+
+		e.logger.ErrorContext(e.ctx, err,
+			logger.Field{Key: "action", Value: "publish_match_event"},
+			logger.Field{Key: "tradeID", Value: matchEvent.MatchID},
+			logger.Field{Key: "pending", Value: len(e.outbox)},
+		)
+
+		select {
+		case <-e.ctx.Done():
+			return fmt.Errorf("%w: %d pending from trade %s", matchpublisherv1.ErrMatchesPending, len(e.outbox), matchEvent.MatchID)
+		case <-time.After(e.publishRetryInterval):
+		}
+	}
+	e.outbox = nil
+	return nil
+}
+
+// republish publishes the matches of replayed journal entries again. Consumers see the
+// same trade IDs and sequences as before, so they can drop the ones they already have.
+func (e *Engine) republish(events []*journalv1.Entry) error {
+	e.applyMu.Lock()
+	defer e.applyMu.Unlock()
+
+	for _, event := range events {
+		if event.Type == journalv1.EntryTypeMatch {
+			e.outbox = append(e.outbox, event.Match)
+		}
+	}
+	if len(e.outbox) == 0 {
+		return nil
+	}
+
+	e.logger.Info("Republishing replayed matches", logger.Field{Key: "count", Value: len(e.outbox)})
+	return e.flushOutbox()
+}
+
+// publishOrderEvent records an order event for the journal and publishes it, unless the journal is being replayed
+func (e *Engine) publishOrderEvent(orderEvent *pb.OrderEventPayload) error {
+	e.recordEvent(&journalv1.Entry{
+		Type:       journalv1.EntryTypeOrderEvent,
+		Timestamp:  orderEvent.Timestamp.AsTime().UnixNano(),
+		OrderEvent: orderEvent,
+	})
+	if e.replaying {
+		return nil
+	}
+	if err := e.orderPublisher.PublishOrderEvent(e.ctx, orderEvent); err != nil {
+		e.unpublished = append(e.unpublished, orderEvent)
+		return err
+	}
+	return nil
+}
+
+// Replay brings the book up to date with the journal entries written after the loaded
+// snapshot. Orders are processed again without publishing anything, so the book, the
+// last trade price and the order offset end up where they were when the entries were
+// written. It returns the events the replayed entries produced, in order; replaying the
+// same snapshot and journal always produces the same state and events. The journal is
+// replayed once, later calls do nothing.
+func (e *Engine) Replay(ctx context.Context) ([]*journalv1.Entry, error) {
+	e.applyMu.Lock()
+	defer e.applyMu.Unlock()
+
+	if e.journal == nil || e.replayed {
+		return nil, nil
+	}
+	if e.ctx == nil {
+		// Replaying without Start, e.g. from the replay command
+		e.ctx = ctx
+	}
+
+	if last := e.journal.LastSequence(); last < e.logSequence {
+		return nil, fmt.Errorf("%w: journal ends at %d, snapshot is at %d", journalv1.ErrBehindSnapshot, last, e.logSequence)
+	}
+
+	e.replaying = true
+	defer func() {
+		e.replaying = false
+		e.events = nil
+	}()
+
+	from := e.logSequence
+	inputs := 0
+	err := e.journal.Read(from, func(entry *journalv1.Entry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if entry.IsInput() {
+			e.inputOffset = entry.Offset
+		}
+		switch entry.Type {
+		case journalv1.EntryTypeOrder:
+			if entry.Order == nil {
+				return fmt.Errorf("%w: order entry %d has no order", journalv1.ErrCorruptEntry, entry.Sequence)
+			}
+			// An order that failed when it was journaled fails the same way again
+			request := *entry.Order
+			if err := e.processOrder(&request); err != nil {
+				e.logger.Debug("Replayed order failed",
+					logger.Field{Key: "sequence", Value: entry.Sequence},
+					logger.Field{Key: "orderID", Value: request.OrderID},
+					logger.Field{Key: "error", Value: err.Error()},
+				)
+			}
+		case journalv1.EntryTypeExpiry:
+			e.expire(entry.Timestamp)
+		}
+
+		if entry.IsInput() {
+			inputs++
+		}
+		if entry.Offset > e.getOrderOffset() {
+			e.setOrderOffset(entry.Offset)
+		}
+		e.logSequence = entry.Sequence
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	e.replayed = true
+	e.logger.Info("Journal replayed",
+		logger.Field{Key: "fromSequence", Value: from},
+		logger.Field{Key: "toSequence", Value: e.logSequence},
+		logger.Field{Key: "inputs", Value: inputs},
+		logger.Field{Key: "orderOffset", Value: e.getOrderOffset()},
+	)
+	return e.events, nil
+}
+
+// processOrder processes a single order request. Caller must hold the apply lock, as
+// matching mutates resting orders that event building reads.
 func (e *Engine) processOrder(orderRequest *orderbookv1.PlaceOrderRequest) error {
+	if orderRequest.Timestamp == 0 {
+		orderRequest.Timestamp = time.Now().UnixNano()
+	}
+
 	e.logger.Debug("Processing order",
 		logger.Field{Key: "orderOffset", Value: orderRequest.Offset},
 		logger.Field{Key: "userID", Value: orderRequest.UserID},
 		logger.Field{Key: "bid", Value: orderRequest.Bid},
 	)
 
+	// Control messages change the market state, which decides the requests that are accepted
+	if orderRequest.Type == orderbookv1.OrderTypeMarketState {
+		if err := e.changeMarketState(orderRequest.MarketState, orderRequest.Timestamp); err != nil {
+			return err
+		}
+		// Stops wait while the pair does not trade, and an uncross may have triggered some
+		e.triggerStops(orderRequest.Timestamp)
+		return nil
+	}
+	state := e.getMarketState()
+	if !state.Accepts(orderRequest.Type) {
+		rejected := newOrder(orderRequest)
+		return e.rejectOrder(rejected, orderRequest.Price, state.Refuse(orderRequest.Type))
+	}
+	if state == orderbookv1.MarketStateAuction {
+		// Orders that must trade at once or must not trade at all have no place in a call
+		if orderRequest.TimeInForce.IsImmediate() || orderRequest.PostOnly {
+			rejected := newOrder(orderRequest)
+			return e.rejectOrder(rejected, orderRequest.Price, fmt.Errorf("%w: %w", orderbookv1.ErrMarketState, orderbookv1.ErrAuctionOrder))
+		}
+		defer e.publishIndicative(orderRequest.Timestamp)
+	}
+
+	if err := e.spec.Check(orderRequest); err != nil {
+		rejected := newOrder(orderRequest)
+		return e.rejectOrder(rejected, orderRequest.Price, err)
+	}
+
+	switch orderRequest.Type {
+	case orderbookv1.OrderTypeLimit, orderbookv1.OrderTypeMarket:
+		if err := e.executeOrder(orderRequest, true); err != nil {
+			return err
+		}
+	case orderbookv1.OrderTypeStop, orderbookv1.OrderTypeStopLimit:
+		if err := e.stopBook.AddStopOrder(stopbookv1.NewStopOrder(orderRequest)); err != nil {
+			rejected := newOrder(orderRequest)
+			return e.rejectOrder(rejected, orderRequest.Price, err)
+		}
+		e.publishAccepted(newOrder(orderRequest), orderRequest.Type, orderRequest.Price)
+	case orderbookv1.OrderTypeCancel:
+		return e.cancelOrder(orderRequest)
+	case orderbookv1.OrderTypeMassCancel:
+		_, err := e.massCancel(orderRequest)
+		return err
+	case orderbookv1.OrderTypeReplace:
+		if err := e.replaceOrder(orderRequest); err != nil {
+			return err
+		}
+	}
+
+	// A new stop may already be triggered, and new trades may trigger resting stops
+	e.triggerStops(orderRequest.Timestamp)
+	return nil
+}
+
+// changeMarketState moves the pair to state at timestamp and publishes the change. Moving
+// to the state the pair is in does nothing. Any change ends a circuit breaker cool-down, so
+// an operator's decision is not overridden when it is over. Leaving an auction uncrosses
+// the book before the pair moves on.
+func (e *Engine) changeMarketState(state orderbookv1.MarketState, timestamp int64) error {
+	if err := state.Validate(); err != nil {
+		return err
+	}
+	e.haltedUntil = 0
+
+	previous := e.getMarketState()
+	if previous == state {
+		return nil
+	}
+	if previous == orderbookv1.MarketStateAuction {
+		e.uncross(timestamp)
+	}
+
+	e.mu.Lock()
+	e.marketState = state
+	e.mu.Unlock()
+	e.orderbook.SetAuction(state == orderbookv1.MarketStateAuction)
+
+	// The state changed whether or not consumers hear of it
+	orderEvent := orderpublisherv1.CreateMarketStateEvent(e.config.Pair, previous, state, timestamp)
+	if err := e.publishOrderEvent(orderEvent); err != nil {
+		e.logger.ErrorContext(e.ctx, err, logger.Field{
+			Key:   "action",
+			Value: "publish_market_state_changed",
+		})
+	}
+
+	e.logger.Info("Market state changed",
+		logger.Field{Key: "pair", Value: e.config.Pair},
+		logger.Field{Key: "from", Value: previous},
+		logger.Field{Key: "to", Value: state},
+	)
+
+	// A book that already crosses has an indicative price from the start of the call
+	e.publishIndicative(timestamp)
+	return nil
+}
+
+// newOrder creates the order a request places, stamped with the time the request was accepted
+func newOrder(orderRequest *orderbookv1.PlaceOrderRequest) *orderbookv1.Order {
 	order := orderbookv1.NewOrder(orderRequest.UserID, orderRequest.Size, orderRequest.Bid, orderRequest.OrderID)
+	if orderRequest.Timestamp != 0 {
+		order.Timestamp = orderRequest.Timestamp
+	}
+	return order
+}
+
+// executeOrder places a limit or market order against the book. accept publishes that the
+// order was accepted once it is placed; a triggered stop was accepted when it was placed
+// in the stop book, so an order the book refuses is cancelled rather than rejected.
+func (e *Engine) executeOrder(orderRequest *orderbookv1.PlaceOrderRequest, accept bool) error {
+	order := newOrder(orderRequest)
+	order.TimeInForce = orderRequest.TimeInForce
+	order.ExpireAt = orderRequest.ExpireAt
+	order.PostOnly = orderRequest.PostOnly
+	order.PostOnlyMode = orderRequest.PostOnlyMode
+	order.DisplaySize = orderRequest.DisplaySize
+	order.SelfTradePrevention = orderRequest.SelfTradePrevention
+
+	refuse := e.rejectOrder
+	if !accept {
+		refuse = e.cancelTriggered
+	}
+
+	if order.TimeInForce == orderbookv1.TimeInForceGTD && order.IsExpired(order.Timestamp) {
+		// A triggered stop may have expired while it waited in the stop book
+		if !accept {
+			return refuse(order, orderRequest.Price, orderbookv1.ErrOrderExpired)
+		}
+		return refuse(order, orderRequest.Price, orderbookv1.ErrInvalidExpireAt)
+	}
 
 	switch orderRequest.Type {
 	case orderbookv1.OrderTypeLimit:
-		return e.orderbook.PlaceLimitOrder(orderRequest.Price, order)
+		if err := e.checkPriceBand("price", orderRequest.Price); err != nil {
+			return refuse(order, orderRequest.Price, err)
+		}
+		// The book refuses an order it cannot place before changing anything
+		matches, err := e.orderbook.PlaceLimitOrder(orderRequest.Price, order)
+		if err != nil {
+			return refuse(order, orderRequest.Price, err)
+		}
+		if accept {
+			e.publishAccepted(order, orderRequest.Type, orderRequest.Price)
+		}
+
+		if len(matches) > 0 {
+			e.logMatches(matches, order)
+		}
+		e.publishSelfTradeCancels(order, orderRequest.Price)
+		e.publishRemainder(order, orderRequest.Price)
 	case orderbookv1.OrderTypeMarket:
+		order.QuoteSize = orderRequest.QuoteSize
+		order.WorstPrice = orderRequest.WorstPrice
+		order.MaxSlippageBps = orderRequest.MaxSlippageBps
+		order.ProtectionPrice = e.protectionPrice(order.Bid)
 		matches, err := e.orderbook.PlaceMarketOrder(order)
 		if err != nil {
-			return err
+			return refuse(order, 0, err)
+		}
+		if accept {
+			e.publishAccepted(order, orderRequest.Type, 0)
 		}
 
 		// SIMPLIFIED: Just log matches directly instead of using channel
 		if len(matches) > 0 {
 			e.logMatches(matches, order)
 		}
-	case orderbookv1.OrderTypeCancel:
-		err := e.orderbook.CancelOrder(orderRequest.OrderID)
+		e.publishSelfTradeCancels(order, 0)
+		e.publishRemainder(order, 0)
+	}
+	return nil
+}
+
+// cancelOrder cancels a resting or pending stop order at its owner's request. A cancel for an
+// order the engine does not hold is rejected.
+func (e *Engine) cancelOrder(orderRequest *orderbookv1.PlaceOrderRequest) error {
+	var (
+		order *orderbookv1.Order
+		price int64
+	)
+	if e.stopBook.HasStopOrder(orderRequest.OrderID) {
+		stop, err := e.stopBook.CancelStopOrder(orderRequest.OrderID)
 		if err != nil {
-			return err
+			return e.rejectOrder(newOrder(orderRequest), 0, err)
 		}
+		order = newOrder(stop.ToPlaceOrderRequest())
+		price = stop.LimitPrice
+	} else {
+		cancelled, err := e.orderbook.CancelOrder(orderRequest.OrderID)
+		if err != nil {
+			return e.rejectOrder(newOrder(orderRequest), 0, err)
+		}
+		order = cancelled
+		price = cancelled.Price
 	}
+
+	// The book no longer holds the order, what it had left is what was cancelled
+	size := order.TotalSize()
+	order.Size, order.HiddenSize = 0, 0
+	e.publishCancelled(order, price, size, orderbookv1.ErrCancelRequested, orderRequest.Timestamp)
+
+	e.logger.Info("Order cancelled",
+		logger.Field{Key: "orderID", Value: order.ID},
+		logger.Field{Key: "userID", Value: order.UserID},
+		logger.Field{Key: "cancelledSize", Value: size},
+	)
+	return nil
+}
+
+// massCancel cancels every resting order of the requesting user that matches the side
+// and price range of the request, publishing a cancel for each, and returns the cancelled
+// orders. Stop orders are not resting and are left alone. An invalid filter is rejected.
+func (e *Engine) massCancel(orderRequest *orderbookv1.PlaceOrderRequest) ([]*orderbookv1.Order, error) {
+	cancelled, err := e.orderbook.MassCancel(orderRequest.MassCancelFilter())
+	if err != nil {
+		rejected := newOrder(orderRequest)
+		return nil, e.rejectOrder(rejected, 0, err)
+	}
+
+	for _, order := range cancelled {
+		// The book no longer holds the order, what it had left is what was cancelled
+		size := order.TotalSize()
+		order.Size, order.HiddenSize = 0, 0
+		e.publishCancelled(order, order.Price, size, orderbookv1.ErrMassCancelled, orderRequest.Timestamp)
+	}
+
+	e.logger.Info("Orders mass cancelled",
+		logger.Field{Key: "userID", Value: orderRequest.UserID},
+		logger.Field{Key: "side", Value: orderRequest.Side},
+		logger.Field{Key: "minPrice", Value: orderRequest.MinPrice},
+		logger.Field{Key: "maxPrice", Value: orderRequest.MaxPrice},
+		logger.Field{Key: "count", Value: len(cancelled)},
+	)
+	return cancelled, nil
+}
+
+// replaceOrder amends the price and size of a resting order and publishes the result
+func (e *Engine) replaceOrder(orderRequest *orderbookv1.PlaceOrderRequest) error {
+	if err := e.checkPriceBand("price", orderRequest.Price); err != nil {
+		rejected := newOrder(orderRequest)
+		return e.rejectOrder(rejected, orderRequest.Price, err)
+	}
+
+	order, matches, err := e.orderbook.AmendOrder(orderRequest.OrderID, orderRequest.UserID, orderRequest.Price, orderRequest.Size, orderRequest.Timestamp)
+	if err != nil {
+		rejected := newOrder(orderRequest)
+		return e.rejectOrder(rejected, orderRequest.Price, err)
+	}
+
+	if len(matches) > 0 {
+		e.logMatches(matches, order)
+	}
+	e.publishSelfTradeCancels(order, orderRequest.Price)
+
+	price := orderRequest.Price
+	if order.Limit != nil {
+		price = order.Limit.Price
+	}
+
+	orderEvent := orderpublisherv1.CreateReplacedEvent(order, price, e.scale)
+	orderEvent.Symbol = e.config.Pair
+	if err := e.publishOrderEvent(orderEvent); err != nil {
+		return err
+	}
+
+	e.logger.Info("Order replaced",
+		logger.Field{Key: "orderID", Value: order.ID},
+		logger.Field{Key: "userID", Value: order.UserID},
+		logger.Field{Key: "price", Value: price},
+		logger.Field{Key: "remainingSize", Value: order.TotalSize()},
+	)
+	return nil
+}
+
+// triggerStops releases every stop order triggered by the last trade price, placing them
+// at timestamp, the time of the request that moved the price. Trades made by a released
+// stop move the price again, so it repeats until no stop fires. Stops stay in the stop
+// book while the pair does not trade, such as after the circuit breaker halted it.
+func (e *Engine) triggerStops(timestamp int64) {
+	for {
+		if !e.getMarketState().Accepts(orderbookv1.OrderTypeMarket) {
+			return
+		}
+
+		triggered := e.stopBook.TriggerStops(e.getLastTradePrice())
+		if len(triggered) == 0 {
+			return
+		}
+
+		for _, stop := range triggered {
+			e.logger.Info("Stop order triggered",
+				logger.Field{Key: "orderID", Value: stop.OrderID},
+				logger.Field{Key: "userID", Value: stop.UserID},
+				logger.Field{Key: "stopPrice", Value: stop.StopPrice},
+				logger.Field{Key: "type", Value: stop.Type},
+			)
+
+			request := stop.ToPlaceOrderRequest()
+			request.Timestamp = timestamp
+			if err := e.executeOrder(request, false); err != nil {
+				e.logger.ErrorContext(e.ctx, err, logger.Field{
+					Key:   "action",
+					Value: "execute_triggered_stop",
+				})
+			}
+		}
+	}
+}
+
+// cancelTriggered notifies the owner of a triggered stop that the book refused the order
+// it released, for reason. The order's owner saw it accepted, so it is cancelled.
+func (e *Engine) cancelTriggered(order *orderbookv1.Order, price int64, reason error) error {
+	size := order.TotalSize()
+	order.Size, order.HiddenSize = 0, 0
+	e.publishCancelled(order, price, size, reason, order.Timestamp)
+
+	e.logger.Info("Triggered stop order cancelled",
+		logger.Field{Key: "orderID", Value: order.ID},
+		logger.Field{Key: "userID", Value: order.UserID},
+		logger.Field{Key: "reason", Value: reason.Error()},
+	)
+	return nil
+}
+
+// rejectOrder notifies the order's owner that the engine refused the order
+func (e *Engine) rejectOrder(order *orderbookv1.Order, price int64, reason error) error {
+	orderEvent := orderpublisherv1.CreateRejectedEvent(order, price, reason, e.inputOffset, e.scale)
+	orderEvent.Symbol = e.config.Pair
+	if err := e.publishOrderEvent(orderEvent); err != nil {
+		return err
+	}
+
+	e.logger.Info("Order rejected",
+		logger.Field{Key: "orderID", Value: order.ID},
+		logger.Field{Key: "userID", Value: order.UserID},
+		logger.Field{Key: "reason", Value: reason.Error()},
+	)
 	return nil
 }
 
+// publishSelfTradeCancels notifies the owners of orders cancelled by self-trade prevention
+// while the order was matched. An order decrement_and_cancel only reduced gets a partial
+// cancel that leaves it with size. price is the incoming order's limit price, zero for
+// market orders.
+func (e *Engine) publishSelfTradeCancels(order *orderbookv1.Order, price int64) {
+	for _, cancel := range order.SelfTradeCancels {
+		cancelPrice := cancel.Price
+		if cancel.Order == order {
+			cancelPrice = price
+		}
+
+		e.publishCancelled(cancel.Order, cancelPrice, cancel.Size, orderbookv1.ErrSelfTradePrevented, order.Timestamp)
+		if cancel.Order == order && !cancel.Partial {
+			// The budget of a quote-size order was given up with it
+			order.QuoteRemaining = 0
+		}
+
+		e.logger.Info("Self-trade prevented",
+			logger.Field{Key: "orderID", Value: cancel.Order.ID},
+			logger.Field{Key: "userID", Value: cancel.Order.UserID},
+			logger.Field{Key: "mode", Value: order.SelfTradePrevention},
+			logger.Field{Key: "cancelledSize", Value: cancel.Size},
+		)
+	}
+}
+
+// publishAccepted notifies the order's owner that the engine took the order in. price is
+// the order's limit price, zero for market and stop orders.
+func (e *Engine) publishAccepted(order *orderbookv1.Order, orderType orderbookv1.OrderType, price int64) {
+	orderEvent := orderpublisherv1.CreateAcceptedEvent(order, orderType, price, e.inputOffset, e.scale)
+	orderEvent.Symbol = e.config.Pair
+	if err := e.publishOrderEvent(orderEvent); err != nil {
+		e.logger.ErrorContext(e.ctx, err, logger.Field{
+			Key:   "action",
+			Value: "publish_order_accepted",
+		})
+	}
+}
+
+// publishCancelled notifies the order's owner that size was cancelled from the order at timestamp
+func (e *Engine) publishCancelled(order *orderbookv1.Order, price, size int64, reason error, timestamp int64) {
+	orderEvent := orderpublisherv1.CreateCancelledEvent(order, price, size, reason, timestamp, e.scale)
+	orderEvent.Symbol = e.config.Pair
+	if err := e.publishOrderEvent(orderEvent); err != nil {
+		e.logger.ErrorContext(e.ctx, err, logger.Field{
+			Key:   "action",
+			Value: "publish_order_cancelled",
+		})
+	}
+}
+
+// publishRemainder publishes what happened to the part of a placed order that did not
+// trade: it rested in the book, or, for IOC, FOK and market orders, it was cancelled.
+// price is the order's limit price, zero for market orders. A market order stopped by its
+// worst acceptable price or its protection price is cancelled for that reason.
+func (e *Engine) publishRemainder(order *orderbookv1.Order, price int64) {
+	if order.IsQuote() {
+		e.publishQuoteRemainder(order)
+		return
+	}
+	if order.TotalSize() <= 0 {
+		return
+	}
+
+	if order.Limit != nil {
+		orderEvent := orderpublisherv1.CreateRestedEvent(order, e.inputOffset, e.scale)
+		orderEvent.Symbol = e.config.Pair
+		if err := e.publishOrderEvent(orderEvent); err != nil {
+			e.logger.ErrorContext(e.ctx, err, logger.Field{
+				Key:   "action",
+				Value: "publish_order_rested",
+			})
+		}
+		return
+	}
+
+	// The order never rested, so nothing is left of it
+	size := order.TotalSize()
+	order.Size, order.HiddenSize = 0, 0
+	e.publishCancelled(order, price, size, remainderReason(order), order.Timestamp)
+
+	e.logger.Info("Unfilled remainder cancelled",
+		logger.Field{Key: "orderID", Value: order.ID},
+		logger.Field{Key: "userID", Value: order.UserID},
+		logger.Field{Key: "timeInForce", Value: order.TimeInForce},
+		logger.Field{Key: "cancelledSize", Value: size},
+	)
+}
+
+// publishQuoteRemainder cancels the budget a quote-size market order has left. A budget
+// too small to buy another lot is residual once the order has filled, and was already
+// published with its last fill.
+func (e *Engine) publishQuoteRemainder(order *orderbookv1.Order) {
+	if order.QuoteRemaining <= 0 || order.QuoteDust && order.QuoteRemaining < order.QuoteSize {
+		return
+	}
+
+	budget := order.QuoteRemaining
+	e.publishCancelled(order, 0, 0, remainderReason(order), order.Timestamp)
+	order.QuoteRemaining = 0
+
+	e.logger.Info("Unspent quote budget cancelled",
+		logger.Field{Key: "orderID", Value: order.ID},
+		logger.Field{Key: "userID", Value: order.UserID},
+		logger.Field{Key: "cancelledQuoteSize", Value: budget},
+	)
+}
+
+// remainderReason returns why the unfilled part of an order that never rested was
+// cancelled: the owner's own price limit, the pair's protection price, or neither.
+func remainderReason(order *orderbookv1.Order) error {
+	switch {
+	case order.SlippageLimited:
+		return orderbookv1.ErrSlippageLimit
+	case order.Protected:
+		return orderbookv1.ErrPriceProtection
+	}
+	return orderbookv1.ErrUnfilledRemainder
+}
+
 // logMatches logs the matches and updates statistics
 func (e *Engine) logMatches(matches []orderbookv1.Match, order *orderbookv1.Order) {
 	e.matchesMutex.Lock()
@@ -266,18 +1256,18 @@ func (e *Engine) logMatches(matches []orderbookv1.Match, order *orderbookv1.Orde
 		logger.Field{Key: "totalMatches", Value: currentTotal},
 	)
 
+	e.setLastTradePrice(matches[len(matches)-1].Price)
+
 	// Log each individual match
 	for i, match := range matches {
-		matchEvent := matchpublisherv1.CreateFromMatch(&match, order)
-		matchEvent.Symbol = e.config.Pair
-		if err := e.matchPublisher.PublishMatchEvent(e.ctx, matchEvent); err != nil {
-			e.logger.ErrorContext(e.ctx, err, logger.Field{
-				Key:   "action",
-				Value: "publish_match_event",
-			})
-		}
+		e.recordTrade(match.Price, order.Timestamp)
+		matchEvent := matchpublisherv1.CreateFromMatch(&match, order, e.config.Pair, e.nextTradeSequence(), e.scale)
+		e.stampFees(matchEvent)
+		e.queueMatchEvent(matchEvent)
+		e.publishFills(&match, order, order.Timestamp)
 		e.logger.Info("Trade executed",
 			logger.Field{Key: "matchIndex", Value: i + 1},
+			logger.Field{Key: "tradeID", Value: matchEvent.MatchID},
 			logger.Field{Key: "price", Value: match.Price},
 			logger.Field{Key: "size", Value: match.SizeFilled},
 			logger.Field{Key: "bidUser", Value: match.Bid.UserID},
@@ -290,6 +1280,28 @@ func (e *Engine) logMatches(matches []orderbookv1.Match, order *orderbookv1.Orde
 	}
 }
 
+// publishFills notifies the owners of both sides of a match of their fill at timestamp,
+// the time of the trade.
+func (e *Engine) publishFills(match *orderbookv1.Match, taker *orderbookv1.Order, timestamp int64) {
+	maker := match.Ask
+	if maker == taker {
+		maker = match.Bid
+	}
+
+	for _, fill := range []*pb.OrderEventPayload{
+		orderpublisherv1.CreateFillEvent(taker, match, orderpublisherv1.LiquidityTaker, timestamp, e.scale),
+		orderpublisherv1.CreateFillEvent(maker, match, orderpublisherv1.LiquidityMaker, timestamp, e.scale),
+	} {
+		fill.Symbol = e.config.Pair
+		if err := e.publishOrderEvent(fill); err != nil {
+			e.logger.ErrorContext(e.ctx, err, logger.Field{
+				Key:   "action",
+				Value: "publish_order_fill",
+			})
+		}
+	}
+}
+
 // shouldCreateSnapshot checks if a snapshot should be created
 func (e *Engine) shouldCreateSnapshot() bool {
 	e.mu.RLock()
@@ -305,35 +1317,81 @@ func (e *Engine) shouldCreateSnapshot() bool {
 	return delta >= e.snapshotOffsetDelta
 }
 
-// createAndStoreSnapshot creates and stores a snapshot
-func (e *Engine) createAndStoreSnapshot() {
-	currentOffset := e.getOrderOffset()
+// Snapshot returns a snapshot of the book and the engine state: the order offset and
+// journal sequence it reflects, the stop orders, the last trade price, trade sequence,
+// market state and price protection state.
+func (e *Engine) Snapshot() *snapshotv1.Snapshot {
+	e.applyMu.Lock()
+	defer e.applyMu.Unlock()
 
-	e.logger.Info("Creating snapshot", logger.Field{
-		Key:   "currentOffset",
-		Value: currentOffset,
-	})
+	return e.snapshot()
+}
 
+// snapshot creates the snapshot of the current state. Caller must hold the apply lock.
+func (e *Engine) snapshot() *snapshotv1.Snapshot {
 	snapshot := e.orderbook.CreateSnapshot()
-	snapshot.OrderOffset = currentOffset
+	snapshot.OrderOffset = e.getOrderOffset()
+	snapshot.PriceDecimals = e.scale.PriceDecimals
+	snapshot.SizeDecimals = e.scale.SizeDecimals
+	snapshot.OrderBookSnapshot.StopOrders = e.stopBook.CreateSnapshot()
+	snapshot.OrderBookSnapshot.LastTradePrice = e.getLastTradePrice()
+	snapshot.OrderBookSnapshot.TradeSequence = e.getTradeSequence()
+	snapshot.OrderBookSnapshot.LogSequence = e.logSequence
+	snapshot.OrderBookSnapshot.MarketState = string(e.getMarketState())
+	e.snapshotPriceProtection(&snapshot.OrderBookSnapshot)
+	snapshot.OrderBookSnapshot.IndicativePrice = e.indicative.Price
+	snapshot.OrderBookSnapshot.IndicativeVolume = e.indicative.Volume
+	return snapshot
+}
 
-	if err := e.snapshotStore.Store(e.ctx, snapshot); err != nil {
+// createAndStoreSnapshot creates and stores a snapshot, logging a failure.
+func (e *Engine) createAndStoreSnapshot() {
+	if _, err := e.storeSnapshot(e.ctx); err != nil {
+		if errors.Is(err, matchpublisherv1.ErrMatchesPending) {
+			e.logger.Warn("Snapshot skipped, match events are not confirmed", logger.Field{Key: "error", Value: err.Error()})
+			return
+		}
 		e.logger.ErrorContext(e.ctx, err, logger.Field{
 			Key:   "action",
 			Value: "store_snapshot",
 		})
-	} else {
-		e.setLastSnapshotOffset(currentOffset)
-		e.logger.Info("Snapshot stored successfully", logger.Field{
-			Key:   "pair",
-			Value: e.config.Pair,
-		}, logger.Field{
-			Key:   "offset",
-			Value: currentOffset,
-		})
 	}
 }
 
+// storeSnapshot creates, stores and returns a snapshot. No snapshot is taken while
+// matches wait in the outbox: the book already holds their order, whose offset was not
+// consumed, and restoring it would process the order twice.
+func (e *Engine) storeSnapshot(ctx context.Context) (*snapshotv1.Snapshot, error) {
+	e.applyMu.Lock()
+	if pending := len(e.outbox); pending > 0 {
+		e.applyMu.Unlock()
+		return nil, fmt.Errorf("%w: %d match events, snapshot not taken", matchpublisherv1.ErrMatchesPending, pending)
+	}
+	snapshot := e.snapshot()
+	e.applyMu.Unlock()
+
+	currentOffset := snapshot.OrderOffset
+
+	e.logger.Info("Creating snapshot", logger.Field{
+		Key:   "currentOffset",
+		Value: currentOffset,
+	})
+
+	if err := e.snapshotStore.Store(ctx, snapshot); err != nil {
+		return nil, err
+	}
+
+	e.setLastSnapshotOffset(currentOffset)
+	e.logger.Info("Snapshot stored successfully", logger.Field{
+		Key:   "pair",
+		Value: e.config.Pair,
+	}, logger.Field{
+		Key:   "offset",
+		Value: currentOffset,
+	})
+	return snapshot, nil
+}
+
 // Thread-safe getters and setters
 func (e *Engine) getOrderOffset() int64 {
 	e.mu.RLock()
@@ -359,6 +1417,38 @@ func (e *Engine) setLastSnapshotOffset(offset int64) {
 	e.lastSnapshotOffset = offset
 }
 
+func (e *Engine) getLastTradePrice() int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.lastTradePrice
+}
+
+func (e *Engine) setLastTradePrice(price int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastTradePrice = price
+}
+
+func (e *Engine) getTradeSequence() int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.tradeSequence
+}
+
+func (e *Engine) getMarketState() orderbookv1.MarketState {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.marketState
+}
+
+// nextTradeSequence advances the trade sequence and returns the sequence of the new trade
+func (e *Engine) nextTradeSequence() int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.tradeSequence++
+	return e.tradeSequence
+}
+
 // loadSnapshot loads and restores the orderbook from snapshot
 func (e *Engine) loadSnapshot(ctx context.Context) error {
 	snapshot, err := e.snapshotStore.LoadStore(ctx)
@@ -367,11 +1457,35 @@ func (e *Engine) loadSnapshot(ctx context.Context) error {
 	}
 
 	if snapshot != nil {
-		e.orderbook.RestoreOrderbook(snapshot)
+		// Units are only meaningful at the precision they were written with
+		if snapshot.PriceDecimals != e.scale.PriceDecimals || snapshot.SizeDecimals != e.scale.SizeDecimals {
+			return fmt.Errorf("%w: snapshot has price/size decimals %d/%d, pair is configured with %d/%d",
+				orderbookv1.ErrInvalidScale, snapshot.PriceDecimals, snapshot.SizeDecimals, e.scale.PriceDecimals, e.scale.SizeDecimals)
+		}
+
+		if err := e.orderbook.RestoreOrderbook(snapshot); err != nil {
+			return err
+		}
+		if err := e.stopBook.RestoreStopBook(snapshot.OrderBookSnapshot.StopOrders); err != nil {
+			return err
+		}
+
 		e.mu.Lock()
 		e.orderOffset = snapshot.OrderOffset
 		e.lastSnapshotOffset = snapshot.OrderOffset
+		e.lastTradePrice = snapshot.OrderBookSnapshot.LastTradePrice
+		e.tradeSequence = snapshot.OrderBookSnapshot.TradeSequence
+		// Snapshots written before market states have none, the configured state applies
+		if state := orderbookv1.MarketState(snapshot.OrderBookSnapshot.MarketState); state != "" {
+			e.marketState = state
+		}
 		e.mu.Unlock()
+		e.logSequence = snapshot.OrderBookSnapshot.LogSequence
+		e.restorePriceProtection(&snapshot.OrderBookSnapshot)
+		e.indicative = orderbookv1.Uncross{
+			Price:  snapshot.OrderBookSnapshot.IndicativePrice,
+			Volume: snapshot.OrderBookSnapshot.IndicativeVolume,
+		}
 
 		e.logger.Info("Orderbook restored from snapshot", logger.Field{
 			Key:   "orderOffset",
@@ -379,6 +1493,8 @@ func (e *Engine) loadSnapshot(ctx context.Context) error {
 		})
 	}
 
+	// The book only keeps crossing orders apart while the pair is in an auction
+	e.orderbook.SetAuction(e.getMarketState() == orderbookv1.MarketStateAuction)
 	return nil
 }
 
@@ -392,6 +1508,21 @@ func (e *Engine) GetLastSnapshotOffset() int64 {
 	return e.getLastSnapshotOffset()
 }
 
+// GetLastTradePrice returns the price of the most recent trade in price units
+func (e *Engine) GetLastTradePrice() int64 {
+	return e.getLastTradePrice()
+}
+
+// GetTradeSequence returns the sequence of the most recent trade of the pair
+func (e *Engine) GetTradeSequence() int64 {
+	return e.getTradeSequence()
+}
+
+// MarketState returns the market state of the pair
+func (e *Engine) MarketState() orderbookv1.MarketState {
+	return e.getMarketState()
+}
+
 // GetTotalMatches returns the total number of matches processed
 func (e *Engine) GetTotalMatches() int64 {
 	e.matchesMutex.RLock()
@@ -0,0 +1,185 @@
+package engine
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
+	matchpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/match-publisher/v1"
+	orderpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-publisher/v1"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+	snapshotv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/snapshot/v1"
+	stopbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/stopbook/v1"
+)
+
+func TestEngine_Book(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+	fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(nil, nil)
+	fixture.recordOrderEvents()
+	engine := createTestEngine(fixture)
+
+	requests := []orderbookv1.PlaceOrderRequest{
+		createTestOrderRequest("alice", orderbookv1.OrderTypeLimit, true, 5, 99, 1),
+		createTestOrderRequest("bob", orderbookv1.OrderTypeLimit, true, 3, 99, 2),
+		createTestOrderRequest("carol", orderbookv1.OrderTypeLimit, true, 2, 98, 3),
+		createTestOrderRequest("dave", orderbookv1.OrderTypeLimit, false, 10, 101, 4),
+	}
+	requests[3].DisplaySize = 4
+	for i := range requests {
+		require.NoError(t, engine.applyOrder(&requests[i]))
+	}
+
+	l2 := engine.Book(0, false)
+	assert.Equal(t, int64(4), l2.OrderOffset)
+	require.Len(t, l2.Bids, 2)
+	assert.Equal(t, orderbookv1.Level{Price: 99, Size: 8, OrderCount: 2}, l2.Bids[0])
+	assert.Equal(t, orderbookv1.Level{Price: 98, Size: 2, OrderCount: 1}, l2.Bids[1])
+	require.Len(t, l2.Asks, 1)
+	assert.Equal(t, orderbookv1.Level{Price: 101, Size: 4, HiddenSize: 6, OrderCount: 1}, l2.Asks[0])
+
+	l3 := engine.Book(1, true)
+	require.Len(t, l3.Bids, 1)
+	require.Len(t, l3.Bids[0].Orders, 2)
+	assert.Equal(t, "alice-1", l3.Bids[0].Orders[0].ID)
+	assert.Equal(t, "bob-2", l3.Bids[0].Orders[1].ID)
+
+	// The view is a copy the book does not share
+	l3.Bids[0].Orders[0].Size = 0
+	order, err := engine.GetOrder("alice-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), order.Size)
+	assert.Nil(t, order.Limit)
+
+	_, err = engine.GetOrder("missing")
+	assert.ErrorIs(t, err, orderbookv1.ErrUnknownOrder)
+}
+
+func TestEngine_CancelUserOrders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.journal")
+	engine := newJournalTestEngine(t, path, nil)
+
+	stop := createTestOrderRequest("alice", orderbookv1.OrderTypeStop, true, 1, 0, 4)
+	stop.StopPrice = 120
+	requests := []orderbookv1.PlaceOrderRequest{
+		createTestOrderRequest("alice", orderbookv1.OrderTypeLimit, true, 5, 99, 1),
+		createTestOrderRequest("bob", orderbookv1.OrderTypeLimit, true, 3, 99, 2),
+		createTestOrderRequest("alice", orderbookv1.OrderTypeLimit, false, 2, 105, 3),
+		stop,
+	}
+	for i := range requests {
+		require.NoError(t, engine.applyOrder(&requests[i]))
+	}
+
+	cancelled, err := engine.CancelUserOrders("alice")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice-1", "alice-3", "alice-4"}, cancelled)
+
+	// The cancels consume no order offset and notify alice like her own mass cancel and
+	// stop cancel would
+	assert.Equal(t, int64(4), engine.GetOrderOffset())
+	reasons := make(map[string]string)
+	for _, event := range engine.orderEvents {
+		if orderpublisherv1.EventType(event.EventType) == orderpublisherv1.EventTypeCancelled {
+			assert.Equal(t, "alice", event.UserID)
+			reasons[event.OrderID] = event.Reason
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"alice-1": orderbookv1.ErrMassCancelled.Error(),
+		"alice-3": orderbookv1.ErrMassCancelled.Error(),
+		"alice-4": orderbookv1.ErrCancelRequested.Error(),
+	}, reasons)
+
+	_, err = engine.GetStopOrder("alice-4")
+	assert.ErrorIs(t, err, stopbookv1.ErrStopOrderNotFound)
+	book := engine.Book(0, true)
+	require.Len(t, book.Bids, 1)
+	require.Len(t, book.Bids[0].Orders, 1)
+	assert.Equal(t, "bob-2", book.Bids[0].Orders[0].ID)
+	assert.Empty(t, book.Asks)
+
+	// Replaying the journal cancels them again
+	replayed := newJournalTestEngine(t, path, nil)
+	_, err = replayed.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, marshalState(t, engine.Engine), marshalState(t, replayed.Engine))
+	assert.Equal(t, int64(4), replayed.GetOrderOffset())
+
+	cancelled, err = engine.CancelUserOrders("alice")
+	require.NoError(t, err)
+	assert.Empty(t, cancelled)
+}
+
+func TestEngine_ForceSnapshot(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+	fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(nil, nil)
+	fixture.recordOrderEvents()
+	engine := createTestEngine(fixture)
+
+	request := createTestOrderRequest("alice", orderbookv1.OrderTypeLimit, true, 5, 99, 7)
+	require.NoError(t, engine.applyOrder(&request))
+
+	fixture.mockSnapshotStore.EXPECT().Store(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, snapshot *snapshotv1.Snapshot) error {
+			assert.Equal(t, int64(7), snapshot.OrderOffset)
+			assert.Len(t, snapshot.OrderBookSnapshot.Orders, 1)
+			return nil
+		})
+	snapshot, err := engine.ForceSnapshot(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), snapshot.OrderOffset)
+	assert.Equal(t, int64(7), engine.GetLastSnapshotOffset())
+
+	// Unconfirmed matches leave the book ahead of the offset, so no snapshot is taken
+	engine.outbox = append(engine.outbox, &pb.MatchEventPayload{MatchID: "BTC-USD-1"})
+	_, err = engine.ForceSnapshot(context.Background())
+	assert.ErrorIs(t, err, matchpublisherv1.ErrMatchesPending)
+}
+
+func TestEngine_PauseHoldsMessages(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+	fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(nil, nil)
+	fixture.recordOrderEvents()
+	engine := createTestEngine(fixture)
+	engine.ctx, engine.cancel = context.WithCancel(context.Background())
+	defer engine.cancel()
+
+	order := createTestOrderPayload("alice", orderbookv1.OrderTypeLimit, true, 1, 100, 1)
+	fixture.mockOrderReader.EXPECT().SetOffset(int64(-1)).Return(nil)
+	fixture.mockOrderReader.EXPECT().ReadMessage(gomock.Any()).Return(kafka.Message{Offset: 1}, order, nil)
+	fixture.mockOrderReader.EXPECT().ReadMessage(gomock.Any()).
+		DoAndReturn(func(ctx context.Context) (kafka.Message, *pb.PlaceOrderPayload, error) {
+			<-ctx.Done()
+			return kafka.Message{}, nil, ctx.Err()
+		}).AnyTimes()
+	fixture.mockOrderReader.EXPECT().CommitMessages(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	fixture.mockOrderReader.EXPECT().Close()
+
+	require.NoError(t, engine.Pause("maintenance"))
+	assert.ErrorIs(t, engine.Pause("again"), ErrPaused)
+	assert.True(t, engine.IsPaused())
+
+	engine.wg.Add(1)
+	go engine.runOrderProcessor()
+
+	// The message read while paused is held, not applied
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int64(-1), engine.GetOrderOffset())
+
+	engine.Resume()
+	assert.False(t, engine.IsPaused())
+	require.Eventually(t, func() bool { return engine.GetOrderOffset() == 1 }, time.Second, time.Millisecond)
+
+	engine.cancel()
+	engine.wg.Wait()
+}
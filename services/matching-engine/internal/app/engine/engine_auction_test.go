@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
+	orderpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-publisher/v1"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+)
+
+func TestEngine_Auction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.journal")
+	engine := newJournalTestEngine(t, path, nil)
+
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	requests := []orderbookv1.PlaceOrderRequest{
+		{Type: orderbookv1.OrderTypeMarketState, MarketState: orderbookv1.MarketStateAuction},
+		createTestOrderRequest("alice", orderbookv1.OrderTypeLimit, false, 3, 99, 2),
+		createTestOrderRequest("bob", orderbookv1.OrderTypeLimit, true, 5, 101, 3),
+		createTestOrderRequest("carol", orderbookv1.OrderTypeLimit, false, 2, 100, 4),
+		createTestOrderRequest("dave", orderbookv1.OrderTypeLimit, true, 1, 101, 5),
+		createTestOrderRequest("erin", orderbookv1.OrderTypeMarket, true, 1, 0, 6),
+		{Type: orderbookv1.OrderTypeMarketState, MarketState: orderbookv1.MarketStateContinuous},
+	}
+	requests[4].TimeInForce = orderbookv1.TimeInForceIOC
+	for i := range requests {
+		requests[i].Offset = int64(i + 1)
+		requests[i].Timestamp = base.Add(time.Duration(i) * time.Second).UnixNano()
+		require.NoError(t, engine.applyOrder(&requests[i]))
+
+		// Nothing trades during the call
+		if requests[i].MarketState != orderbookv1.MarketStateContinuous {
+			assert.Empty(t, engine.matches)
+		}
+	}
+
+	ofType := func(eventType orderpublisherv1.EventType) []*pb.OrderEventPayload {
+		var events []*pb.OrderEventPayload
+		for _, event := range engine.orderEvents {
+			if event.EventType == string(eventType) {
+				events = append(events, event)
+			}
+		}
+		return events
+	}
+
+	// The indicative uncross is published whenever it changes
+	indicative := ofType(orderpublisherv1.EventTypeAuctionIndicative)
+	require.Len(t, indicative, 2)
+	assert.Equal(t, []float64{101, 3}, []float64{indicative[0].Price, indicative[0].Size})
+	assert.Equal(t, []float64{100, 5}, []float64{indicative[1].Price, indicative[1].Size})
+	assert.Equal(t, "BTC-USD", indicative[0].Symbol)
+
+	// Immediate and market orders are refused during the call
+	rejected := ofType(orderpublisherv1.EventTypeRejected)
+	require.Len(t, rejected, 2)
+	assert.Equal(t, requests[4].OrderID, rejected[0].OrderID)
+	assert.Equal(t, requests[5].OrderID, rejected[1].OrderID)
+	for _, event := range rejected {
+		assert.Equal(t, string(orderbookv1.RejectCodeMarketState), event.ReasonCode)
+	}
+
+	// Leaving the auction executes every crossing order at the equilibrium price
+	uncrossedAt := requests[6].Timestamp
+	require.Len(t, engine.matches, 2)
+	for _, match := range engine.matches {
+		assert.Equal(t, int64(100), match.PriceUnits)
+		assert.Equal(t, uncrossedAt, match.Timestamp.AsTime().UnixNano())
+	}
+	assert.Equal(t, requests[2].OrderID, engine.matches[0].BuyOrderID)
+	assert.Equal(t, requests[1].OrderID, engine.matches[0].SellOrderID)
+	assert.Equal(t, "buy", engine.matches[0].TakerSide)
+	assert.Equal(t, "sell", engine.matches[1].TakerSide)
+	assert.Equal(t, int64(100), engine.GetLastTradePrice())
+	assert.Len(t, ofType(orderpublisherv1.EventTypeFilled), 3)
+	assert.Equal(t, orderbookv1.MarketStateContinuous, engine.MarketState())
+
+	// Replay uncrosses the same way
+	replayed := newJournalTestEngine(t, path, nil)
+	events, err := replayed.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, marshalState(t, engine.Engine), marshalState(t, replayed.Engine))
+	assert.Equal(t, engine.orderEvents, replayedOrderEvents(events))
+	assert.Equal(t, marshalMatches(t, engine.matches), marshalMatches(t, replayedMatches(events)))
+}
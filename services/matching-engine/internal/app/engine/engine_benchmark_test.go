@@ -8,10 +8,12 @@ import (
 
 	"github.com/muhammadchandra19/exchange/pkg/logger"
 	matchpublisherv1_mock "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/match-publisher/v1/mock"
+	orderpublisherv1_mock "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-publisher/v1/mock"
 	orderreadermock "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-reader/v1/mock"
 	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
 	snapshotmock "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/snapshot/v1/mock"
 	"github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/orderbook"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/stopbook"
 	"github.com/muhammadchandra19/exchange/services/matching-engine/pkg/config"
 )
 
@@ -30,6 +32,7 @@ func setupBenchmarkEngine(b *testing.B) *Engine {
 	mockOrderReader := orderreadermock.NewMockOrderReader(ctrl)
 	mockSnapshotStore := snapshotmock.NewMockStore(ctrl)
 	mockMatchPublisher := matchpublisherv1_mock.NewMockMatchPublisher(ctrl)
+	mockOrderPublisher := orderpublisherv1_mock.NewMockOrderPublisher(ctrl)
 
 	ob := orderbook.NewOrderbook()
 	log, err := logger.NewLogger()
@@ -53,7 +56,12 @@ func setupBenchmarkEngine(b *testing.B) *Engine {
 		Return(nil).
 		AnyTimes()
 
-	engine := NewEngine(ob, mockOrderReader, mockSnapshotStore, mockMatchPublisher, log, cfg)
+	mockOrderPublisher.EXPECT().
+		PublishOrderEvent(gomock.Any(), gomock.Any()).
+		Return(nil).
+		AnyTimes()
+
+	engine := NewEngine(ob, stopbook.NewStopBook(), mockOrderReader, mockSnapshotStore, mockMatchPublisher, mockOrderPublisher, log, cfg)
 
 	// Initialize context to avoid nil pointer dereference
 	engine.ctx = context.Background()
@@ -72,8 +80,8 @@ func BenchmarkEngine_ProcessLimitOrder(b *testing.B) {
 					"user",
 					orderbookv1.OrderTypeLimit,
 					i%2 == 0, // Alternate between bid and ask
-					10.0,
-					50000.0+float64(i%100), // Vary price slightly
+					10,
+					50000+int64(i%100), // Vary price slightly
 					int64(i),
 				)
 				_ = e.processOrder(&orderRequest)
@@ -89,11 +97,11 @@ func BenchmarkEngine_ProcessLimitOrder(b *testing.B) {
 					"user",
 					orderbookv1.OrderTypeLimit,
 					i%2 == 0,
-					10.0,
-					50000.0+float64(i%100),
+					10,
+					50000+int64(i%100),
 					int64(i),
 				)
-				_ = e.processOrder(&orderRequest)
+				_ = e.applyOrder(&orderRequest)
 			},
 			cleanup: func(e *Engine) {},
 		},
@@ -138,8 +146,8 @@ func BenchmarkEngine_ProcessMarketOrder(b *testing.B) {
 						"seller",
 						orderbookv1.OrderTypeLimit,
 						false,
-						10.0,
-						50000.0+float64(i),
+						10,
+						50000+int64(i),
 						int64(i),
 					)
 					_ = e.processOrder(&sellOrder)
@@ -148,8 +156,8 @@ func BenchmarkEngine_ProcessMarketOrder(b *testing.B) {
 						"buyer",
 						orderbookv1.OrderTypeLimit,
 						true,
-						10.0,
-						49000.0-float64(i),
+						10,
+						49000-int64(i),
 						int64(i+1000),
 					)
 					_ = e.processOrder(&buyOrder)
@@ -160,8 +168,8 @@ func BenchmarkEngine_ProcessMarketOrder(b *testing.B) {
 					"market_user",
 					orderbookv1.OrderTypeMarket,
 					i%2 == 0, // Alternate between market buy and sell
-					5.0,
-					0.0, // Market orders don't have price
+					5,
+					0, // Market orders don't have price
 					int64(i+2000),
 				)
 				_ = e.processOrder(&orderRequest)
@@ -178,8 +186,8 @@ func BenchmarkEngine_ProcessMarketOrder(b *testing.B) {
 						"seller",
 						orderbookv1.OrderTypeLimit,
 						false,
-						10.0,
-						50000.0+float64(i*10),
+						10,
+						50000+int64(i*10),
 						int64(i),
 					)
 					_ = e.processOrder(&sellOrder)
@@ -188,8 +196,8 @@ func BenchmarkEngine_ProcessMarketOrder(b *testing.B) {
 						"buyer",
 						orderbookv1.OrderTypeLimit,
 						true,
-						10.0,
-						49000.0-float64(i*10),
+						10,
+						49000-int64(i*10),
 						int64(i+100),
 					)
 					_ = e.processOrder(&buyOrder)
@@ -200,11 +208,11 @@ func BenchmarkEngine_ProcessMarketOrder(b *testing.B) {
 					"market_user",
 					orderbookv1.OrderTypeMarket,
 					i%2 == 0,
-					2.0, // Smaller size for parallel test
-					0.0,
+					2, // Smaller size for parallel test
+					0,
 					int64(i+200),
 				)
-				_ = e.processOrder(&orderRequest)
+				_ = e.applyOrder(&orderRequest)
 			},
 			cleanup: func(e *Engine) {},
 		},
@@ -246,6 +254,7 @@ func BenchmarkEngine_SnapshotCreation(b *testing.B) {
 				mockOrderReader := orderreadermock.NewMockOrderReader(ctrl)
 				mockSnapshotStore := snapshotmock.NewMockStore(ctrl)
 				mockMatchPublisher := matchpublisherv1_mock.NewMockMatchPublisher(ctrl)
+				mockOrderPublisher := orderpublisherv1_mock.NewMockOrderPublisher(ctrl)
 
 				ob := orderbook.NewOrderbook()
 				log, _ := logger.NewLogger()
@@ -267,7 +276,12 @@ func BenchmarkEngine_SnapshotCreation(b *testing.B) {
 					Return(nil).
 					AnyTimes()
 
-				engine := NewEngine(ob, mockOrderReader, mockSnapshotStore, mockMatchPublisher, log, cfg)
+				mockOrderPublisher.EXPECT().
+					PublishOrderEvent(gomock.Any(), gomock.Any()).
+					Return(nil).
+					AnyTimes()
+
+				engine := NewEngine(ob, stopbook.NewStopBook(), mockOrderReader, mockSnapshotStore, mockMatchPublisher, mockOrderPublisher, log, cfg)
 				engine.ctx = context.Background()
 				return engine
 			},
@@ -278,8 +292,8 @@ func BenchmarkEngine_SnapshotCreation(b *testing.B) {
 						"user",
 						orderbookv1.OrderTypeLimit,
 						i%2 == 0,
-						10.0,
-						50000.0+float64(i),
+						10,
+						50000+int64(i),
 						int64(i),
 					)
 					_ = e.processOrder(&orderRequest)
@@ -298,6 +312,7 @@ func BenchmarkEngine_SnapshotCreation(b *testing.B) {
 				mockOrderReader := orderreadermock.NewMockOrderReader(ctrl)
 				mockSnapshotStore := snapshotmock.NewMockStore(ctrl)
 				mockMatchPublisher := matchpublisherv1_mock.NewMockMatchPublisher(ctrl)
+				mockOrderPublisher := orderpublisherv1_mock.NewMockOrderPublisher(ctrl)
 
 				ob := orderbook.NewOrderbook()
 				log, _ := logger.NewLogger()
@@ -318,7 +333,12 @@ func BenchmarkEngine_SnapshotCreation(b *testing.B) {
 					Return(nil).
 					AnyTimes()
 
-				engine := NewEngine(ob, mockOrderReader, mockSnapshotStore, mockMatchPublisher, log, cfg)
+				mockOrderPublisher.EXPECT().
+					PublishOrderEvent(gomock.Any(), gomock.Any()).
+					Return(nil).
+					AnyTimes()
+
+				engine := NewEngine(ob, stopbook.NewStopBook(), mockOrderReader, mockSnapshotStore, mockMatchPublisher, mockOrderPublisher, log, cfg)
 				engine.ctx = context.Background()
 				return engine
 			},
@@ -329,8 +349,8 @@ func BenchmarkEngine_SnapshotCreation(b *testing.B) {
 						"user",
 						orderbookv1.OrderTypeLimit,
 						i%2 == 0,
-						10.0,
-						50000.0+float64(i),
+						10,
+						50000+int64(i),
 						int64(i),
 					)
 					_ = e.processOrder(&orderRequest)
@@ -372,8 +392,8 @@ func BenchmarkEngine_MixedOperations(b *testing.B) {
 						"initial_seller",
 						orderbookv1.OrderTypeLimit,
 						false,
-						10.0,
-						50000.0+float64(i*50),
+						10,
+						50000+int64(i*50),
 						int64(i),
 					)
 					_ = e.processOrder(&sellOrder)
@@ -382,8 +402,8 @@ func BenchmarkEngine_MixedOperations(b *testing.B) {
 						"initial_buyer",
 						orderbookv1.OrderTypeLimit,
 						true,
-						10.0,
-						49000.0-float64(i*50),
+						10,
+						49000-int64(i*50),
 						int64(i+50),
 					)
 					_ = e.processOrder(&buyOrder)
@@ -398,8 +418,8 @@ func BenchmarkEngine_MixedOperations(b *testing.B) {
 						"market_user",
 						orderbookv1.OrderTypeMarket,
 						i%2 == 0,
-						5.0,
-						0.0,
+						5,
+						0,
 						int64(i),
 					)
 					_ = e.processOrder(&orderRequest)
@@ -408,8 +428,8 @@ func BenchmarkEngine_MixedOperations(b *testing.B) {
 						"limit_user",
 						orderbookv1.OrderTypeLimit,
 						i%2 == 0,
-						10.0,
-						50000.0+float64((i%1000)-500),
+						10,
+						50000+int64((i%1000)-500),
 						int64(i),
 					)
 					_ = e.processOrder(&orderRequest)
@@ -491,8 +511,8 @@ func BenchmarkEngine_MemoryAllocation(b *testing.B) {
 			"user",
 			orderbookv1.OrderTypeLimit,
 			i%2 == 0,
-			10.0,
-			50000.0+float64(i%100),
+			10,
+			50000+int64(i%100),
 			int64(i),
 		)
 		_ = engine.processOrder(&orderRequest)
@@ -500,7 +520,7 @@ func BenchmarkEngine_MemoryAllocation(b *testing.B) {
 }
 
 // Helper function to create test order requests (uses the same function from engine_test.go)
-// func createTestOrderRequest(userID string, orderType orderbookv1.OrderType, bid bool, size, price float64, offset int64) orderbookv1.PlaceOrderRequest {
+// func createTestOrderRequest(userID string, orderType orderbookv1.OrderType, bid bool, size, price int64, offset int64) orderbookv1.PlaceOrderRequest {
 // 	return orderbookv1.PlaceOrderRequest{
 // 		UserID: userID,
 // 		Type:   orderType,
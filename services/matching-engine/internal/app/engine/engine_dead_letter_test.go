@@ -0,0 +1,293 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
+	deadletterv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/dead-letter/v1"
+	deadlettermock "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/dead-letter/v1/mock"
+	journalv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/journal/v1"
+	orderpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-publisher/v1"
+	orderreaderv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-reader/v1"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/journal"
+)
+
+// errBrokerDown fails every order event the policy test engine publishes
+var errBrokerDown = errors.New("broker down")
+
+// newPolicyTestEngine creates an engine applying policy to failed order messages and
+// dead-lettering them to the returned publisher mock. Order events cannot be published,
+// so every order message fails.
+func newPolicyTestEngine(t *testing.T, fixture *testFixture, policy deadletterv1.Policy) (*Engine, *deadlettermock.MockPublisher) {
+	deadLetters := deadlettermock.NewMockPublisher(fixture.ctrl)
+	fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(nil, nil)
+	fixture.mockMatchPublisher.EXPECT().PublishMatchEvent(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	fixture.mockOrderPublisher.EXPECT().PublishOrderEvent(gomock.Any(), gomock.Any()).Return(errBrokerDown).AnyTimes()
+
+	options := DefaultEngineOptions()
+	options.PublishRetryInterval = time.Millisecond
+	options.ErrorPolicy = policy
+	options.DeadLetters = deadLetters
+
+	engine := NewEngineWithOptions(fixture.orderbook, fixture.stopBook, fixture.mockOrderReader, fixture.mockSnapshotStore,
+		fixture.mockMatchPublisher, fixture.mockOrderPublisher, fixture.logger, fixture.config, options)
+	engine.ctx, engine.cancel = context.WithCancel(context.Background())
+	t.Cleanup(engine.cancel)
+	return engine, deadLetters
+}
+
+// invalidOrderMessage returns a limit order with a negative price, which the engine rejects
+func invalidOrderMessage(offset int64) (kafka.Message, *pb.PlaceOrderPayload) {
+	order := createTestOrderPayload("user1", orderbookv1.OrderTypeLimit, false, 1.0, -1.0, offset)
+	msg := kafka.Message{
+		Topic:  "orders",
+		Offset: offset,
+		Key:    []byte(order.OrderID),
+		Value:  []byte(fmt.Sprintf(`{"orderID":%q,"price":-1}`, order.OrderID)),
+	}
+	return msg, order
+}
+
+func TestEngine_ProcessFailurePolicy(t *testing.T) {
+	testCases := []struct {
+		name             string
+		action           deadletterv1.Action
+		expectedAttempts int // Attempts recorded in the dead letter, zero when nothing is dead-lettered
+		expectedErr      error
+	}{
+		{name: "skip consumes the message", action: deadletterv1.ActionSkip},
+		{name: "dlq publishes the message", action: deadletterv1.ActionDeadLetter, expectedAttempts: 1},
+		{name: "retry dead-letters once retries are used up", action: deadletterv1.ActionRetry, expectedAttempts: 3},
+		{name: "halt leaves the message unconsumed", action: deadletterv1.ActionHalt, expectedErr: deadletterv1.ErrHalted},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fixture := setupTestFixture(t)
+			defer fixture.teardown()
+
+			policy := deadletterv1.Policy{
+				Decode:       deadletterv1.ActionDeadLetter,
+				Process:      tc.action,
+				MaxRetries:   2,
+				RetryBackoff: time.Millisecond,
+			}
+			engine, deadLetters := newPolicyTestEngine(t, fixture, policy)
+			msg, order := invalidOrderMessage(5)
+
+			var published *deadletterv1.Message
+			if tc.expectedAttempts > 0 {
+				deadLetters.EXPECT().
+					PublishDeadLetter(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, message *deadletterv1.Message) error {
+						published = message
+						return nil
+					}).
+					Times(1)
+			}
+
+			err := engine.processMessage(msg, order)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if tc.expectedAttempts == 0 {
+				assert.Nil(t, published)
+				return
+			}
+			require.NotNil(t, published)
+			assert.Equal(t, "BTC-USD", published.Pair)
+			assert.Equal(t, deadletterv1.ErrorClassProcess, published.Class)
+			assert.Equal(t, tc.expectedAttempts, published.Attempts)
+			assert.Equal(t, "orders", published.Topic)
+			assert.Equal(t, int64(5), published.Offset)
+			assert.Equal(t, msg.Value, published.Payload)
+			assert.NotEmpty(t, published.Error)
+		})
+	}
+}
+
+func TestEngine_RetryRecoversTransientFailure(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+
+	policy := deadletterv1.Policy{
+		Decode:       deadletterv1.ActionSkip,
+		Process:      deadletterv1.ActionRetry,
+		MaxRetries:   3,
+		RetryBackoff: time.Millisecond,
+	}
+	engine, _ := newPolicyTestEngine(t, fixture, policy)
+	msg, _ := invalidOrderMessage(1)
+
+	// No dead letter is expected: the second attempt succeeds
+	attempts := 0
+	err := engine.handleFailure(deadletterv1.ErrorClassProcess, msg, errors.New("transient"), func() error {
+		attempts++
+		if attempts < 2 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestEngine_RetryPublishesAfterFill(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+
+	j, err := journal.NewJournal(filepath.Join(t.TempDir(), "orders.journal"), false, fixture.logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { j.Close() })
+
+	// The publisher refuses the replaced event once, after the replace already traded
+	var published []*pb.OrderEventPayload
+	refused := false
+	fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(nil, nil)
+	fixture.mockMatchPublisher.EXPECT().PublishMatchEvent(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	fixture.mockOrderPublisher.EXPECT().PublishOrderEvent(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, event *pb.OrderEventPayload) error {
+			if event.EventType == string(orderpublisherv1.EventTypeReplaced) && !refused {
+				refused = true
+				return errBrokerDown
+			}
+			published = append(published, event)
+			return nil
+		}).AnyTimes()
+
+	options := DefaultEngineOptions()
+	options.Journal = j
+	options.ErrorPolicy = deadletterv1.Policy{
+		Decode:       deadletterv1.ActionSkip,
+		Process:      deadletterv1.ActionRetry,
+		MaxRetries:   3,
+		RetryBackoff: time.Millisecond,
+	}
+	engine := NewEngineWithOptions(fixture.orderbook, fixture.stopBook, fixture.mockOrderReader, fixture.mockSnapshotStore,
+		fixture.mockMatchPublisher, fixture.mockOrderPublisher, fixture.logger, fixture.config, options)
+	engine.ctx, engine.cancel = context.WithCancel(context.Background())
+	t.Cleanup(engine.cancel)
+
+	for _, request := range []orderbookv1.PlaceOrderRequest{
+		createTestOrderRequest("alice", orderbookv1.OrderTypeLimit, false, 2, 100, 1),
+		createTestOrderRequest("bob", orderbookv1.OrderTypeLimit, true, 1, 99, 2),
+	} {
+		require.NoError(t, engine.applyOrder(&request))
+	}
+
+	// Bob raises his bid to 5 at 100, buys alice's 2 and rests with 3
+	replace := createTestOrderPayload("bob", orderbookv1.OrderTypeReplace, true, 5, 100, 3)
+	replace.OrderID = "bob-2"
+	require.NoError(t, engine.processMessage(kafka.Message{Topic: "orders", Offset: 3}, replace))
+
+	// The retry only published the refused event, the replace was not applied again
+	order, err := engine.GetOrder("bob-2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), order.Size)
+	assert.Equal(t, int64(1), engine.GetTotalMatches())
+	require.NotEmpty(t, published)
+	last := published[len(published)-1]
+	assert.Equal(t, string(orderpublisherv1.EventTypeReplaced), last.EventType)
+	assert.Equal(t, float64(3), last.RemainingSize)
+
+	var replaces int
+	require.NoError(t, j.Read(0, func(entry *journalv1.Entry) error {
+		if entry.Type == journalv1.EntryTypeOrder && entry.Order.Type == orderbookv1.OrderTypeReplace {
+			replaces++
+		}
+		return nil
+	}))
+	assert.Equal(t, 1, replaces)
+	assert.Equal(t, int64(3), engine.GetOrderOffset())
+}
+
+func TestEngine_DeadLettersUndecodableMessage(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+
+	policy := deadletterv1.DefaultPolicy()
+	policy.Decode = deadletterv1.ActionDeadLetter
+	engine, deadLetters := newPolicyTestEngine(t, fixture, policy)
+
+	msg := kafka.Message{Topic: "orders", Partition: 0, Offset: 7, Value: []byte("not json")}
+	fixture.mockOrderReader.EXPECT().SetOffset(int64(-1)).Return(nil)
+	fixture.mockOrderReader.EXPECT().
+		ReadMessage(gomock.Any()).
+		Return(msg, nil, fmt.Errorf("%w: invalid character", orderreaderv1.ErrInvalidMessage))
+	fixture.mockOrderReader.EXPECT().
+		ReadMessage(gomock.Any()).
+		DoAndReturn(func(ctx context.Context) (kafka.Message, *pb.PlaceOrderPayload, error) {
+			<-ctx.Done()
+			return kafka.Message{}, nil, ctx.Err()
+		})
+	fixture.mockOrderReader.EXPECT().Close()
+
+	// The publisher fails once; the message is only committed after it is confirmed
+	gomock.InOrder(
+		deadLetters.EXPECT().PublishDeadLetter(gomock.Any(), gomock.Any()).Return(errors.New("broker down")),
+		deadLetters.EXPECT().
+			PublishDeadLetter(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, message *deadletterv1.Message) error {
+				assert.Equal(t, deadletterv1.ErrorClassDecode, message.Class)
+				assert.Equal(t, int64(7), message.Offset)
+				assert.Equal(t, []byte("not json"), message.Payload)
+				assert.Contains(t, message.Error, orderreaderv1.ErrInvalidMessage.Error())
+				return nil
+			}),
+		fixture.mockOrderReader.EXPECT().CommitMessages(gomock.Any(), msg).Return(nil),
+	)
+
+	engine.wg.Add(1)
+	go engine.runOrderProcessor()
+	time.Sleep(50 * time.Millisecond)
+	engine.cancel()
+	engine.wg.Wait()
+
+	assert.NoError(t, engine.HaltError())
+	assert.Equal(t, -1, int(engine.GetOrderOffset()))
+}
+
+func TestEngine_HaltStopsConsuming(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+
+	policy := deadletterv1.DefaultPolicy()
+	policy.Process = deadletterv1.ActionHalt
+	engine, _ := newPolicyTestEngine(t, fixture, policy)
+
+	msg, order := invalidOrderMessage(1)
+	fixture.mockOrderReader.EXPECT().SetOffset(int64(-1)).Return(nil)
+	fixture.mockOrderReader.EXPECT().ReadMessage(gomock.Any()).Return(msg, order, nil).Times(1)
+	fixture.mockOrderReader.EXPECT().Close()
+
+	// No commit and no further reads: the processor returns on its own
+	engine.wg.Add(1)
+	go engine.runOrderProcessor()
+
+	done := make(chan struct{})
+	go func() {
+		engine.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("order processor did not stop after the pair halted")
+	}
+
+	assert.ErrorIs(t, engine.HaltError(), deadletterv1.ErrHalted)
+}
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
+	feev1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/fee/v1"
+	feev1_mock "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/fee/v1/mock"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+)
+
+func TestEngine_Fees(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+	fixture.config.FeeConfig.Pairs = []string{"BTC-USD:-1:5", "ETH-USD:2:6"}
+	fixture.config.FeeConfig.Tiers = []string{"vip:0:3", "pro:-2:4"}
+	fixture.config.FeeConfig.Users = []string{"carol:pro", "dave:pro"}
+	fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(nil, nil)
+	fixture.recordOrderEvents()
+
+	var matches []*pb.MatchEventPayload
+	fixture.mockMatchPublisher.EXPECT().PublishMatchEvent(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, event *pb.MatchEventPayload) error {
+			matches = append(matches, event)
+			return nil
+		}).AnyTimes()
+
+	// The store moves carol to another tier
+	tierStore := feev1_mock.NewMockTierStore(fixture.ctrl)
+	tierStore.EXPECT().LoadUserTiers(gomock.Any()).Return(map[string]string{"carol": "vip"}, nil)
+
+	options := DefaultEngineOptions()
+	options.FeeTiers = tierStore
+	engine, err := OpenEngine(fixture.orderbook, fixture.stopBook, fixture.mockOrderReader, fixture.mockSnapshotStore,
+		fixture.mockMatchPublisher, fixture.mockOrderPublisher, fixture.logger, fixture.config, options)
+	require.NoError(t, err)
+	engine.ctx = context.Background()
+
+	requests := []orderbookv1.PlaceOrderRequest{
+		createTestOrderRequest("alice", orderbookv1.OrderTypeLimit, false, 10, 10_000, 1),
+		createTestOrderRequest("bob", orderbookv1.OrderTypeLimit, true, 10, 10_000, 2),
+		createTestOrderRequest("dave", orderbookv1.OrderTypeLimit, true, 10, 10_000, 3),
+		createTestOrderRequest("carol", orderbookv1.OrderTypeLimit, false, 10, 10_000, 4),
+	}
+	for i := range requests {
+		require.NoError(t, engine.applyOrder(&requests[i]))
+	}
+	require.Len(t, matches, 2)
+
+	// Notional of 100000: alice makes at the pair's 1 bps rebate, bob takes at its 5 bps
+	assert.Equal(t, &pb.TradeFee{Amount: -10, AmountUnits: -10, Rate: -0.0001, Asset: "USD"}, matches[0].MakerFee)
+	assert.Equal(t, &pb.TradeFee{Amount: 50, AmountUnits: 50, Rate: 0.0005, Asset: "USD"}, matches[0].TakerFee)
+
+	// dave makes at the pro tier's 2 bps rebate, carol takes at the vip tier's 3 bps
+	assert.Equal(t, "dave", matches[1].MakerUserID)
+	assert.Equal(t, &pb.TradeFee{Amount: -20, AmountUnits: -20, Rate: -0.0002, Asset: "USD"}, matches[1].MakerFee)
+	assert.Equal(t, &pb.TradeFee{Amount: 30, AmountUnits: 30, Rate: 0.0003, Asset: "USD"}, matches[1].TakerFee)
+}
+
+func TestEngine_FeeScheduleErrors(t *testing.T) {
+	testCases := []struct {
+		name      string
+		users     []string
+		tiers     map[string]string
+		storeErr  error
+		expectErr error
+	}{
+		{
+			name:      "configured user in an unknown tier",
+			users:     []string{"alice:gold"},
+			expectErr: feev1.ErrUnknownTier,
+		},
+		{
+			name:      "stored user in an unknown tier",
+			tiers:     map[string]string{"alice": "gold"},
+			expectErr: feev1.ErrUnknownTier,
+		},
+		{
+			name:     "store failure",
+			storeErr: errors.New("connection refused"),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			fixture := setupTestFixture(t)
+			defer fixture.teardown()
+			fixture.config.FeeConfig.Tiers = []string{"vip:0:3"}
+			fixture.config.FeeConfig.Users = testCase.users
+
+			tierStore := feev1_mock.NewMockTierStore(fixture.ctrl)
+			tierStore.EXPECT().LoadUserTiers(gomock.Any()).Return(testCase.tiers, testCase.storeErr)
+
+			options := DefaultEngineOptions()
+			options.FeeTiers = tierStore
+			_, err := OpenEngine(fixture.orderbook, fixture.stopBook, fixture.mockOrderReader, fixture.mockSnapshotStore,
+				fixture.mockMatchPublisher, fixture.mockOrderPublisher, fixture.logger, fixture.config, options)
+			require.Error(t, err)
+			if testCase.expectErr != nil {
+				assert.ErrorIs(t, err, testCase.expectErr)
+			} else {
+				assert.ErrorIs(t, err, testCase.storeErr)
+			}
+		})
+	}
+}
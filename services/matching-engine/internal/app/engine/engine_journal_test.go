@@ -0,0 +1,297 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
+	journalv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/journal/v1"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+	snapshotv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/snapshot/v1"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/journal"
+)
+
+// journalTestEngine is an engine writing to a journal, with the events it published
+type journalTestEngine struct {
+	*Engine
+	matches     []*pb.MatchEventPayload
+	orderEvents []*pb.OrderEventPayload
+}
+
+// newJournalTestEngine creates an engine with a fresh book on the journal at path,
+// starting from snapshot if it is set
+func newJournalTestEngine(t *testing.T, path string, snapshot *snapshotv1.Snapshot) *journalTestEngine {
+	fixture := setupTestFixture(t)
+	t.Cleanup(fixture.teardown)
+
+	j, err := journal.NewJournal(path, false, fixture.logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { j.Close() })
+
+	te := &journalTestEngine{}
+	fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(snapshot, nil)
+	fixture.mockMatchPublisher.EXPECT().PublishMatchEvent(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, event *pb.MatchEventPayload) error {
+			te.matches = append(te.matches, event)
+			return nil
+		}).AnyTimes()
+	fixture.mockOrderPublisher.EXPECT().PublishOrderEvent(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, event *pb.OrderEventPayload) error {
+			te.orderEvents = append(te.orderEvents, event)
+			return nil
+		}).AnyTimes()
+
+	options := DefaultEngineOptions()
+	options.Journal = j
+	te.Engine = NewEngineWithOptions(fixture.orderbook, fixture.stopBook, fixture.mockOrderReader, fixture.mockSnapshotStore,
+		fixture.mockMatchPublisher, fixture.mockOrderPublisher, fixture.logger, fixture.config, options)
+	te.ctx = context.Background()
+	return te
+}
+
+// journalTestRequests exercises resting, matching, iceberg refresh, stops, replaces and failures
+func journalTestRequests() []orderbookv1.PlaceOrderRequest {
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).UnixNano()
+	requests := []orderbookv1.PlaceOrderRequest{
+		{OrderID: "ask-1", UserID: "maker1", Type: orderbookv1.OrderTypeLimit, Size: 10, Price: 100},
+		{OrderID: "ask-2", UserID: "maker2", Type: orderbookv1.OrderTypeLimit, Size: 9, Price: 101, DisplaySize: 2},
+		{OrderID: "bid-1", UserID: "maker3", Type: orderbookv1.OrderTypeLimit, Bid: true, Size: 4, Price: 99},
+		{OrderID: "stop-1", UserID: "user4", Type: orderbookv1.OrderTypeStop, Bid: true, Size: 3, StopPrice: 101},
+		{OrderID: "mkt-1", UserID: "taker5", Type: orderbookv1.OrderTypeMarket, Bid: true, Size: 12},
+		{OrderID: "bid-1", UserID: "maker3", Type: orderbookv1.OrderTypeReplace, Bid: true, Size: 6, Price: 98},
+		{OrderID: "missing", UserID: "user6", Type: orderbookv1.OrderTypeCancel},
+		{OrderID: "ask-3", UserID: "maker7", Type: orderbookv1.OrderTypeLimit, Size: 3, Price: 98},
+		{OrderID: "gtd-1", UserID: "maker8", Type: orderbookv1.OrderTypeLimit, Size: 1, Price: 110,
+			TimeInForce: orderbookv1.TimeInForceGTD, ExpireAt: base + int64(20*time.Second)},
+	}
+	for i := range requests {
+		requests[i].Offset = int64(i)
+		requests[i].Timestamp = base + int64(i)*int64(time.Second)
+	}
+	return requests
+}
+
+func marshalState(t *testing.T, e *Engine) []byte {
+	state, err := json.Marshal(e.Snapshot())
+	require.NoError(t, err)
+	return state
+}
+
+func marshalMatches(t *testing.T, matches []*pb.MatchEventPayload) []byte {
+	data, err := json.Marshal(matches)
+	require.NoError(t, err)
+	return data
+}
+
+func replayedMatches(events []*journalv1.Entry) []*pb.MatchEventPayload {
+	var matches []*pb.MatchEventPayload
+	for _, event := range events {
+		if event.Type == journalv1.EntryTypeMatch {
+			matches = append(matches, event.Match)
+		}
+	}
+	return matches
+}
+
+func replayedOrderEvents(events []*journalv1.Entry) []*pb.OrderEventPayload {
+	var orderEvents []*pb.OrderEventPayload
+	for _, event := range events {
+		if event.Type == journalv1.EntryTypeOrderEvent {
+			orderEvents = append(orderEvents, event.OrderEvent)
+		}
+	}
+	return orderEvents
+}
+
+func TestEngine_JournalReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.journal")
+	requests := journalTestRequests()
+
+	live := newJournalTestEngine(t, path, nil)
+	for i := range requests {
+		request := requests[i]
+		live.applyOrder(&request)
+	}
+	live.expireOrders(time.Unix(0, requests[len(requests)-1].ExpireAt))
+
+	require.NotEmpty(t, live.matches)
+	require.NotEmpty(t, live.orderEvents)
+	assert.Equal(t, int64(len(requests)-1), live.GetOrderOffset())
+	liveState := marshalState(t, live.Engine)
+
+	t.Run("replay from an empty book rebuilds the live state and matches", func(t *testing.T) {
+		first := newJournalTestEngine(t, path, nil)
+		firstEvents, err := first.Replay(context.Background())
+		require.NoError(t, err)
+
+		second := newJournalTestEngine(t, path, nil)
+		secondEvents, err := second.Replay(context.Background())
+		require.NoError(t, err)
+
+		assert.Equal(t, liveState, marshalState(t, first.Engine))
+		assert.Equal(t, marshalMatches(t, live.matches), marshalMatches(t, replayedMatches(firstEvents)))
+
+		// Lifecycle events come out the same, the expiry cancel included
+		liveOrderEvents, err := json.Marshal(live.orderEvents)
+		require.NoError(t, err)
+		replayedOrderEventsJSON, err := json.Marshal(replayedOrderEvents(firstEvents))
+		require.NoError(t, err)
+		assert.Equal(t, liveOrderEvents, replayedOrderEventsJSON)
+
+		// Two replays of the same journal are byte-identical
+		firstJSON, err := json.Marshal(firstEvents)
+		require.NoError(t, err)
+		secondJSON, err := json.Marshal(secondEvents)
+		require.NoError(t, err)
+		assert.Equal(t, firstJSON, secondJSON)
+		assert.Equal(t, marshalState(t, first.Engine), marshalState(t, second.Engine))
+
+		// Nothing is published or journaled again
+		assert.Empty(t, first.matches)
+		assert.Empty(t, first.orderEvents)
+		assert.Equal(t, live.journal.LastSequence(), first.journal.LastSequence())
+		assert.Equal(t, live.GetOrderOffset(), first.GetOrderOffset())
+	})
+
+	t.Run("replay from a snapshot applies only later entries", func(t *testing.T) {
+		dir := t.TempDir()
+		partialPath := filepath.Join(dir, "orders.journal")
+
+		partial := newJournalTestEngine(t, partialPath, nil)
+		for i := range requests[:5] {
+			request := requests[i]
+			partial.applyOrder(&request)
+		}
+		snapshot := partial.Snapshot()
+		assert.Equal(t, partial.journal.LastSequence(), snapshot.OrderBookSnapshot.LogSequence)
+		for i := range requests[5:] {
+			request := requests[5+i]
+			partial.applyOrder(&request)
+		}
+		partial.expireOrders(time.Unix(0, requests[len(requests)-1].ExpireAt))
+
+		restored := newJournalTestEngine(t, partialPath, snapshot)
+		events, err := restored.Replay(context.Background())
+		require.NoError(t, err)
+
+		assert.Equal(t, liveState, marshalState(t, restored.Engine))
+		assert.Equal(t, marshalMatches(t, partial.matches[len(partial.matches)-len(replayedMatches(events)):]),
+			marshalMatches(t, replayedMatches(events)))
+	})
+
+	t.Run("replay runs once", func(t *testing.T) {
+		e := newJournalTestEngine(t, path, nil)
+		_, err := e.Replay(context.Background())
+		require.NoError(t, err)
+
+		events, err := e.Replay(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, events)
+		assert.Equal(t, liveState, marshalState(t, e.Engine))
+	})
+}
+
+func TestEngine_JournalBehindSnapshot(t *testing.T) {
+	snapshot := &snapshotv1.Snapshot{
+		Version:           snapshotv1.CurrentVersion,
+		OrderOffset:       10,
+		OrderBookSnapshot: snapshotv1.OrderBookSnapshot{LogSequence: 5},
+	}
+	e := newJournalTestEngine(t, filepath.Join(t.TempDir(), "orders.journal"), snapshot)
+
+	_, err := e.Replay(context.Background())
+	assert.ErrorIs(t, err, journalv1.ErrBehindSnapshot)
+}
+
+func TestEngine_JournalConsumesFailedOrders(t *testing.T) {
+	e := newJournalTestEngine(t, filepath.Join(t.TempDir(), "orders.journal"), nil)
+
+	// A failed request is in the journal, so replay handles it and its offset must not be read again
+	request := orderbookv1.PlaceOrderRequest{Type: orderbookv1.OrderTypeMarketState, MarketState: "sleeping", Offset: 7}
+	assert.Error(t, e.applyOrder(&request))
+
+	assert.Equal(t, int64(7), e.GetOrderOffset())
+	assert.Equal(t, int64(1), e.journal.LastSequence())
+	assert.NotZero(t, request.Timestamp)
+}
+
+func TestEngine_JournalTopicOffset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.journal")
+	e := newJournalTestEngine(t, path, nil)
+
+	// The offset in the payload is the producer's, the topic decides where the request is
+	first := createTestOrderPayload("maker", orderbookv1.OrderTypeLimit, false, 1, 100, 42)
+	require.NoError(t, e.processMessage(kafka.Message{Offset: 3}, first))
+	second := createTestOrderPayload("maker", orderbookv1.OrderTypeLimit, false, 1, 101, 42)
+	second.OrderID = "maker-43"
+	require.NoError(t, e.processMessage(kafka.Message{Offset: 4}, second))
+	assert.Equal(t, int64(4), e.GetOrderOffset())
+
+	var offsets []int64
+	require.NoError(t, e.journal.Read(0, func(entry *journalv1.Entry) error {
+		if entry.Type == journalv1.EntryTypeOrder {
+			offsets = append(offsets, entry.Offset)
+		}
+		return nil
+	}))
+	assert.Equal(t, []int64{3, 4}, offsets)
+
+	// Replay keeps both requests, though the producer gave them the same offset
+	replayed := newJournalTestEngine(t, path, nil)
+	_, err := replayed.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), replayed.GetOrderOffset())
+	assert.Equal(t, marshalState(t, e.Engine), marshalState(t, replayed.Engine))
+}
+
+func TestEngine_JournalExpiryBeforeCancels(t *testing.T) {
+	e := newJournalTestEngine(t, filepath.Join(t.TempDir(), "orders.journal"), nil)
+
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).UnixNano()
+	gtd := func(orderID string, offset int64) orderbookv1.PlaceOrderRequest {
+		return orderbookv1.PlaceOrderRequest{
+			OrderID: orderID, UserID: "maker", Type: orderbookv1.OrderTypeLimit, Size: 1, Price: 100, Offset: offset,
+			TimeInForce: orderbookv1.TimeInForceGTD, ExpireAt: base + int64(time.Minute), Timestamp: base,
+		}
+	}
+	first, second := gtd("first", 0), gtd("second", 1)
+	require.NoError(t, e.applyOrder(&first))
+
+	// A sweep with nothing to expire leaves no trace
+	e.expireOrders(time.Unix(0, base))
+	sequence := e.journal.LastSequence()
+
+	e.expireOrders(time.Unix(0, first.ExpireAt))
+
+	var entries []*journalv1.Entry
+	require.NoError(t, e.journal.Read(sequence, func(entry *journalv1.Entry) error {
+		entries = append(entries, entry)
+		return nil
+	}))
+	require.Len(t, entries, 2)
+	assert.Equal(t, journalv1.EntryTypeExpiry, entries[0].Type)
+	assert.Equal(t, journalv1.EntryTypeOrderEvent, entries[1].Type)
+	assert.Equal(t, "first", entries[1].OrderEvent.OrderID)
+
+	// Nothing expires when the sweep cannot be journaled before the engine stops
+	require.NoError(t, e.applyOrder(&second))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	e.ctx = ctx
+	require.NoError(t, e.journal.Close())
+	published := len(e.orderEvents)
+
+	e.expireOrders(time.Unix(0, second.ExpireAt))
+
+	_, err := e.orderbook.GetOrder("second")
+	assert.NoError(t, err)
+	assert.Len(t, e.orderEvents, published)
+}
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
+	orderpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-publisher/v1"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+	snapshotv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/snapshot/v1"
+)
+
+func TestEngine_MarketState(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+	fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(nil, nil)
+	orderEvents := fixture.recordOrderEvents()
+	engine := createTestEngine(fixture)
+	assert.Equal(t, orderbookv1.MarketStateContinuous, engine.MarketState())
+
+	resting := createTestOrderRequest("alice", orderbookv1.OrderTypeLimit, true, 5, 99, 1)
+	require.NoError(t, engine.applyOrder(&resting))
+
+	// A control message on the order topic moves the pair to cancel-only
+	control := &pb.PlaceOrderPayload{Type: string(orderbookv1.OrderTypeMarketState), MarketState: "cancel_only", Offset: 2}
+	require.NoError(t, engine.processMessage(kafka.Message{Offset: 2}, control))
+	assert.Equal(t, orderbookv1.MarketStateCancelOnly, engine.MarketState())
+	assert.Equal(t, int64(2), engine.GetOrderOffset())
+
+	changes := orderEvents.ofType(orderpublisherv1.EventTypeMarketStateChanged)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "BTC-USD", changes[0].Symbol)
+	assert.Equal(t, "cancel_only", changes[0].MarketState)
+	assert.Equal(t, "continuous", changes[0].PreviousMarketState)
+	assert.Empty(t, changes[0].OrderID)
+
+	// New orders are refused, cancels go through
+	crossing := createTestOrderRequest("bob", orderbookv1.OrderTypeLimit, false, 5, 99, 3)
+	require.NoError(t, engine.applyOrder(&crossing))
+	rejected := orderEvents.ofOrder(crossing.OrderID)
+	require.Len(t, rejected, 1)
+	assert.Equal(t, string(orderpublisherv1.EventTypeRejected), rejected[0].EventType)
+	assert.Equal(t, string(orderbookv1.RejectCodeMarketState), rejected[0].ReasonCode)
+	assert.Equal(t, int64(0), engine.GetTotalMatches())
+
+	cancel := createTestOrderRequest("alice", orderbookv1.OrderTypeCancel, true, 0, 0, 4)
+	cancel.OrderID = resting.OrderID
+	require.NoError(t, engine.applyOrder(&cancel))
+	assert.NotContains(t, fixture.orderbook.Orders, resting.OrderID)
+
+	// A halted pair refuses cancels too
+	require.NoError(t, engine.SetMarketState(orderbookv1.MarketStateHalted))
+	stop := createTestOrderRequest("carol", orderbookv1.OrderTypeStop, true, 1, 0, 5)
+	stop.StopPrice = 120
+	require.NoError(t, engine.applyOrder(&stop))
+	assert.False(t, fixture.stopBook.HasStopOrder(stop.OrderID))
+	_, err := engine.CancelUserOrders("alice")
+	assert.ErrorIs(t, err, orderbookv1.ErrMarketState)
+
+	// Moving to the current state publishes nothing, an unknown state is an error
+	require.NoError(t, engine.SetMarketState(orderbookv1.MarketStateHalted))
+	assert.Len(t, orderEvents.ofType(orderpublisherv1.EventTypeMarketStateChanged), 2)
+	assert.ErrorIs(t, engine.SetMarketState("suspended"), orderbookv1.ErrInvalidMarketState)
+
+	require.NoError(t, engine.SetMarketState(orderbookv1.MarketStateContinuous))
+	again := createTestOrderRequest("bob", orderbookv1.OrderTypeLimit, false, 5, 99, 6)
+	require.NoError(t, engine.applyOrder(&again))
+	assert.Contains(t, fixture.orderbook.Orders, again.OrderID)
+}
+
+func TestEngine_MarketStateReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.journal")
+	engine := newJournalTestEngine(t, path, nil)
+
+	first := createTestOrderRequest("alice", orderbookv1.OrderTypeLimit, true, 5, 99, 1)
+	require.NoError(t, engine.applyOrder(&first))
+	require.NoError(t, engine.SetMarketState(orderbookv1.MarketStateClosed))
+	second := createTestOrderRequest("bob", orderbookv1.OrderTypeLimit, false, 5, 99, 2)
+	require.NoError(t, engine.applyOrder(&second))
+
+	// The operator's change consumes no order offset
+	assert.Equal(t, int64(2), engine.GetOrderOffset())
+	assert.Equal(t, int64(0), engine.GetTotalMatches())
+
+	replayed := newJournalTestEngine(t, path, nil)
+	events, err := replayed.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, orderbookv1.MarketStateClosed, replayed.MarketState())
+	assert.Equal(t, marshalState(t, engine.Engine), marshalState(t, replayed.Engine))
+	assert.Equal(t, engine.orderEvents, replayedOrderEvents(events))
+}
+
+func TestEngine_MarketStateSnapshot(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+	fixture.config.MarketState = string(orderbookv1.MarketStatePreOpen)
+
+	// Without a snapshot the pair starts in the configured state
+	fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(nil, nil)
+	engine := createTestEngine(fixture)
+	assert.Equal(t, orderbookv1.MarketStatePreOpen, engine.MarketState())
+	assert.Equal(t, "pre_open", engine.Snapshot().OrderBookSnapshot.MarketState)
+
+	// A snapshot restores the state it recorded
+	snapshot := &snapshotv1.Snapshot{
+		PriceDecimals: 2,
+		SizeDecimals:  8,
+		OrderBookSnapshot: snapshotv1.OrderBookSnapshot{
+			MarketState: string(orderbookv1.MarketStateHalted),
+		},
+	}
+	fixture.config.PriceDecimals, fixture.config.SizeDecimals = 2, 8
+	fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(snapshot, nil)
+	restored := createTestEngine(fixture)
+	assert.Equal(t, orderbookv1.MarketStateHalted, restored.MarketState())
+
+	fixture.config.MarketState = "suspended"
+	_, err := OpenEngine(fixture.orderbook, fixture.stopBook, fixture.mockOrderReader, fixture.mockSnapshotStore,
+		fixture.mockMatchPublisher, fixture.mockOrderPublisher, fixture.logger, fixture.config, DefaultEngineOptions())
+	assert.ErrorIs(t, err, orderbookv1.ErrInvalidMarketState)
+}
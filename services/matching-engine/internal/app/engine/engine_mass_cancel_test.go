@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	orderpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-publisher/v1"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+)
+
+func TestEngine_MassCancel(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+	fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(nil, nil)
+	orderEvents := fixture.recordOrderEvents()
+	engine := createTestEngine(fixture)
+
+	requests := []orderbookv1.PlaceOrderRequest{
+		createTestOrderRequest("maker", orderbookv1.OrderTypeLimit, true, 5, 99, 1),
+		createTestOrderRequest("maker", orderbookv1.OrderTypeLimit, true, 3, 97, 2),
+		createTestOrderRequest("maker", orderbookv1.OrderTypeLimit, false, 4, 101, 3),
+		createTestOrderRequest("other", orderbookv1.OrderTypeLimit, true, 2, 99, 4),
+	}
+	for i := range requests {
+		require.NoError(t, engine.applyOrder(&requests[i]))
+	}
+
+	// Only the maker's bids from 98 up are pulled
+	massCancel := createTestOrderRequest("maker", orderbookv1.OrderTypeMassCancel, false, 0, 0, 5)
+	massCancel.Side = orderbookv1.SideBuy
+	massCancel.MinPrice = 98
+	require.NoError(t, engine.applyOrder(&massCancel))
+
+	cancelled := orderEvents.ofType(orderpublisherv1.EventTypeCancelled)
+	require.Len(t, cancelled, 1)
+	assert.Equal(t, requests[0].OrderID, cancelled[0].OrderID)
+	assert.Equal(t, float64(99), cancelled[0].Price)
+	assert.Equal(t, float64(5), cancelled[0].Size)
+	assert.Equal(t, orderbookv1.ErrMassCancelled.Error(), cancelled[0].Reason)
+
+	// Cancel-only still lets the maker pull the rest, one event per order
+	require.NoError(t, engine.SetMarketState(orderbookv1.MarketStateCancelOnly))
+	pullAll := createTestOrderRequest("maker", orderbookv1.OrderTypeMassCancel, false, 0, 0, 6)
+	require.NoError(t, engine.applyOrder(&pullAll))
+
+	cancelled = orderEvents.ofType(orderpublisherv1.EventTypeCancelled)
+	require.Len(t, cancelled, 3)
+	assert.Equal(t, requests[1].OrderID, cancelled[1].OrderID)
+	assert.Equal(t, requests[2].OrderID, cancelled[2].OrderID)
+	assert.Equal(t, []string{requests[3].OrderID}, orderIDs(fixture.orderbook.Orders))
+
+	// An invalid filter is rejected without cancelling anything
+	invalid := createTestOrderRequest("maker", orderbookv1.OrderTypeMassCancel, false, 0, 0, 7)
+	invalid.Side = "both"
+	require.NoError(t, engine.applyOrder(&invalid))
+
+	rejected := orderEvents.ofOrder(invalid.OrderID)
+	require.Len(t, rejected, 1)
+	assert.Equal(t, string(orderpublisherv1.EventTypeRejected), rejected[0].EventType)
+	assert.Len(t, orderEvents.ofType(orderpublisherv1.EventTypeCancelled), 3)
+}
+
+// orderIDs returns the IDs of the orders in an order map
+func orderIDs(orders map[string]*orderbookv1.Order) []string {
+	var ids []string
+	for id := range orders {
+		ids = append(ids, id)
+	}
+	return ids
+}
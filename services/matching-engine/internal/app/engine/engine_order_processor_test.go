@@ -3,6 +3,7 @@ package engine
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -12,18 +13,19 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
 	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
 )
 
 // Test helper to capture what happens in runOrderProcessor
 type orderProcessorTestHelper struct {
 	messages []kafka.Message
-	orders   []orderbookv1.PlaceOrderRequest
+	orders   []*pb.PlaceOrderPayload
 	errors   []error
 	mu       sync.Mutex
 }
 
-func (h *orderProcessorTestHelper) addMessage(msg kafka.Message, order orderbookv1.PlaceOrderRequest, err error) {
+func (h *orderProcessorTestHelper) addMessage(msg kafka.Message, order *pb.PlaceOrderPayload, err error) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	h.messages = append(h.messages, msg)
@@ -37,6 +39,19 @@ func (h *orderProcessorTestHelper) getCount() int {
 	return len(h.messages)
 }
 
+// createTestOrderPayload builds the Kafka payload an OrderReader would return
+func createTestOrderPayload(userID string, orderType orderbookv1.OrderType, bid bool, size, price float64, offset int64) *pb.PlaceOrderPayload {
+	return &pb.PlaceOrderPayload{
+		OrderID: fmt.Sprintf("%s-%d", userID, offset),
+		UserID:  userID,
+		Type:    string(orderType),
+		Bid:     bid,
+		Size:    size,
+		Price:   price,
+		Offset:  offset,
+	}
+}
+
 func TestEngine_RunOrderProcessor_Basic(t *testing.T) {
 	testCases := []struct {
 		name             string
@@ -63,14 +78,15 @@ func TestEngine_RunOrderProcessor_Basic(t *testing.T) {
 					SetOffset(int64(-1)).
 					Return(nil).
 					Times(1)
+				f.recordOrderEvents()
 
 				// One successful message
 				msg := kafka.Message{Offset: 1}
-				order := createTestOrderRequest("user1", orderbookv1.OrderTypeLimit, false, 10.0, 50000.0, 1)
+				order := createTestOrderPayload("user1", orderbookv1.OrderTypeLimit, false, 10.0, 50000.0, 1)
 
 				f.mockOrderReader.EXPECT().
 					ReadMessage(gomock.Any()).
-					DoAndReturn(func(ctx context.Context) (kafka.Message, orderbookv1.PlaceOrderRequest, error) {
+					DoAndReturn(func(ctx context.Context) (kafka.Message, *pb.PlaceOrderPayload, error) {
 						helper.addMessage(msg, order, nil)
 						return msg, order, nil
 					}).
@@ -84,9 +100,9 @@ func TestEngine_RunOrderProcessor_Basic(t *testing.T) {
 				// Second call will be cancelled
 				f.mockOrderReader.EXPECT().
 					ReadMessage(gomock.Any()).
-					DoAndReturn(func(ctx context.Context) (kafka.Message, orderbookv1.PlaceOrderRequest, error) {
+					DoAndReturn(func(ctx context.Context) (kafka.Message, *pb.PlaceOrderPayload, error) {
 						<-ctx.Done()
-						return kafka.Message{}, orderbookv1.PlaceOrderRequest{}, ctx.Err()
+						return kafka.Message{}, nil, ctx.Err()
 					}).
 					Times(1)
 
@@ -119,19 +135,20 @@ func TestEngine_RunOrderProcessor_Basic(t *testing.T) {
 					SetOffset(int64(-1)).
 					Return(nil).
 					Times(1)
+				f.recordOrderEvents()
 
 				// First message - limit order
 				msg1 := kafka.Message{Offset: 1}
-				order1 := createTestOrderRequest("seller", orderbookv1.OrderTypeLimit, false, 10.0, 50000.0, 1)
+				order1 := createTestOrderPayload("seller", orderbookv1.OrderTypeLimit, false, 10.0, 50000.0, 1)
 
 				// Second message - market order
 				msg2 := kafka.Message{Offset: 2}
-				order2 := createTestOrderRequest("buyer", orderbookv1.OrderTypeMarket, true, 5.0, 0.0, 2)
+				order2 := createTestOrderPayload("buyer", orderbookv1.OrderTypeMarket, true, 5.0, 0.0, 2)
 
 				callCount := 0
 				f.mockOrderReader.EXPECT().
 					ReadMessage(gomock.Any()).
-					DoAndReturn(func(ctx context.Context) (kafka.Message, orderbookv1.PlaceOrderRequest, error) {
+					DoAndReturn(func(ctx context.Context) (kafka.Message, *pb.PlaceOrderPayload, error) {
 						callCount++
 						if callCount == 1 {
 							helper.addMessage(msg1, order1, nil)
@@ -141,7 +158,7 @@ func TestEngine_RunOrderProcessor_Basic(t *testing.T) {
 							return msg2, order2, nil
 						} else {
 							<-ctx.Done()
-							return kafka.Message{}, orderbookv1.PlaceOrderRequest{}, ctx.Err()
+							return kafka.Message{}, nil, ctx.Err()
 						}
 					}).
 					Times(3)
@@ -184,19 +201,20 @@ func TestEngine_RunOrderProcessor_Basic(t *testing.T) {
 					SetOffset(int64(-1)).
 					Return(nil).
 					Times(1)
+				f.recordOrderEvents()
 
 				callCount := 0
 				f.mockOrderReader.EXPECT().
 					ReadMessage(gomock.Any()).
-					DoAndReturn(func(ctx context.Context) (kafka.Message, orderbookv1.PlaceOrderRequest, error) {
+					DoAndReturn(func(ctx context.Context) (kafka.Message, *pb.PlaceOrderPayload, error) {
 						callCount++
 						if callCount == 1 {
 							// First call returns error
-							helper.addMessage(kafka.Message{}, orderbookv1.PlaceOrderRequest{}, errors.New("kafka error"))
-							return kafka.Message{}, orderbookv1.PlaceOrderRequest{}, errors.New("kafka error")
+							helper.addMessage(kafka.Message{}, nil, errors.New("kafka error"))
+							return kafka.Message{}, nil, errors.New("kafka error")
 						} else {
 							<-ctx.Done()
-							return kafka.Message{}, orderbookv1.PlaceOrderRequest{}, ctx.Err()
+							return kafka.Message{}, nil, ctx.Err()
 						}
 					}).
 					Times(2)
@@ -275,9 +293,10 @@ func TestEngine_RunOrderProcessor_ErrorHandling(t *testing.T) {
 					SetOffset(int64(-1)).
 					Return(nil).
 					Times(1)
+				f.recordOrderEvents()
 
 				msg := kafka.Message{Offset: 1}
-				order := createTestOrderRequest("user1", orderbookv1.OrderTypeLimit, false, 10.0, 50000.0, 1)
+				order := createTestOrderPayload("user1", orderbookv1.OrderTypeLimit, false, 10.0, 50000.0, 1)
 
 				f.mockOrderReader.EXPECT().
 					ReadMessage(gomock.Any()).
@@ -293,9 +312,9 @@ func TestEngine_RunOrderProcessor_ErrorHandling(t *testing.T) {
 				// Should continue reading
 				f.mockOrderReader.EXPECT().
 					ReadMessage(gomock.Any()).
-					DoAndReturn(func(ctx context.Context) (kafka.Message, orderbookv1.PlaceOrderRequest, error) {
+					DoAndReturn(func(ctx context.Context) (kafka.Message, *pb.PlaceOrderPayload, error) {
 						<-ctx.Done()
-						return kafka.Message{}, orderbookv1.PlaceOrderRequest{}, ctx.Err()
+						return kafka.Message{}, nil, ctx.Err()
 					}).
 					Times(1)
 
@@ -322,10 +341,11 @@ func TestEngine_RunOrderProcessor_ErrorHandling(t *testing.T) {
 					SetOffset(int64(-1)).
 					Return(nil).
 					Times(1)
+				f.recordOrderEvents()
 
 				// Invalid order (negative price)
 				msg := kafka.Message{Offset: 1}
-				order := createTestOrderRequest("user1", orderbookv1.OrderTypeLimit, false, 10.0, -1.0, 1)
+				order := createTestOrderPayload("user1", orderbookv1.OrderTypeLimit, false, 10.0, -1.0, 1)
 
 				f.mockOrderReader.EXPECT().
 					ReadMessage(gomock.Any()).
@@ -339,9 +359,9 @@ func TestEngine_RunOrderProcessor_ErrorHandling(t *testing.T) {
 
 				f.mockOrderReader.EXPECT().
 					ReadMessage(gomock.Any()).
-					DoAndReturn(func(ctx context.Context) (kafka.Message, orderbookv1.PlaceOrderRequest, error) {
+					DoAndReturn(func(ctx context.Context) (kafka.Message, *pb.PlaceOrderPayload, error) {
 						<-ctx.Done()
-						return kafka.Message{}, orderbookv1.PlaceOrderRequest{}, ctx.Err()
+						return kafka.Message{}, nil, ctx.Err()
 					}).
 					Times(1)
 
@@ -391,17 +411,60 @@ func TestEngine_RunOrderProcessor_ErrorHandling(t *testing.T) {
 	}
 }
 
-// Test that we can't easily unit test the SetOffset fatal error
-// because it calls log.Fatal which exits the process
-func TestEngine_RunOrderProcessor_SetOffsetError_Documentation(t *testing.T) {
-	// This test documents that SetOffset errors cause Fatal logs
-	// In a real application, you might want to:
-	// 1. Return the error instead of calling Fatal
-	// 2. Use dependency injection for the logger to make it testable
-	// 3. Have a separate initialization phase that can be tested
-
-	t.Log("SetOffset errors currently call log.Fatal which cannot be easily unit tested")
-	t.Log("Consider refactoring to return errors instead of calling Fatal for better testability")
+// SetOffset errors halt the pair instead of exiting the process, so the other pairs of
+// a multi-pair process keep running
+func TestEngine_RunOrderProcessor_SetOffsetError(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+
+	fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(nil, nil)
+	fixture.mockOrderReader.EXPECT().SetOffset(int64(-1)).Return(errors.New("broker unavailable"))
+	fixture.mockOrderReader.EXPECT().Close().Times(1)
+
+	engine := createTestEngine(fixture)
+	require.NoError(t, engine.Start(context.Background()))
+
+	select {
+	case <-engine.Done():
+	case <-time.After(time.Second):
+		t.Fatal("order processor did not stop")
+	}
+	assert.ErrorContains(t, engine.HaltError(), "broker unavailable")
+
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), time.Second)
+	defer stopCancel()
+	assert.NoError(t, engine.Stop(stopCtx))
+}
+
+// A panic while processing halts the pair and stops its engine instead of crashing the process
+func TestEngine_RunOrderProcessor_PanicHaltsPair(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+
+	fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(nil, nil)
+	fixture.mockOrderReader.EXPECT().SetOffset(int64(-1)).Return(nil)
+	fixture.mockOrderReader.EXPECT().
+		ReadMessage(gomock.Any()).
+		DoAndReturn(func(ctx context.Context) (kafka.Message, *pb.PlaceOrderPayload, error) {
+			panic("corrupted book")
+		})
+	fixture.mockOrderReader.EXPECT().Close().Times(1)
+
+	engine := createTestEngine(fixture)
+	require.NoError(t, engine.Start(context.Background()))
+
+	select {
+	case <-engine.Done():
+	case <-time.After(time.Second):
+		t.Fatal("order processor did not stop")
+	}
+	assert.ErrorIs(t, engine.HaltError(), ErrPanicked)
+	assert.ErrorContains(t, engine.HaltError(), "corrupted book")
+
+	// The panic also stopped the engine's other goroutines
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), time.Second)
+	defer stopCancel()
+	assert.NoError(t, engine.Stop(stopCtx))
 }
 
 // Integration test with realistic message flow
@@ -419,30 +482,31 @@ func TestEngine_RunOrderProcessor_Integration(t *testing.T) {
 		SetOffset(int64(-1)).
 		Return(nil).
 		Times(1)
+	fixture.recordOrderEvents()
 
 	// Create a realistic sequence of messages
 	messages := []struct {
 		msg   kafka.Message
-		order orderbookv1.PlaceOrderRequest
+		order *pb.PlaceOrderPayload
 	}{
 		{
 			msg:   kafka.Message{Offset: 1},
-			order: createTestOrderRequest("seller1", orderbookv1.OrderTypeLimit, false, 10.0, 50000.0, 1),
+			order: createTestOrderPayload("seller1", orderbookv1.OrderTypeLimit, false, 10.0, 50000.0, 1),
 		},
 		{
 			msg:   kafka.Message{Offset: 2},
-			order: createTestOrderRequest("buyer1", orderbookv1.OrderTypeLimit, true, 8.0, 49900.0, 2),
+			order: createTestOrderPayload("buyer1", orderbookv1.OrderTypeLimit, true, 8.0, 49900.0, 2),
 		},
 		{
 			msg:   kafka.Message{Offset: 3},
-			order: createTestOrderRequest("buyer2", orderbookv1.OrderTypeMarket, true, 5.0, 0.0, 3),
+			order: createTestOrderPayload("buyer2", orderbookv1.OrderTypeMarket, true, 5.0, 0.0, 3),
 		},
 	}
 
 	messageIndex := 0
 	fixture.mockOrderReader.EXPECT().
 		ReadMessage(gomock.Any()).
-		DoAndReturn(func(ctx context.Context) (kafka.Message, orderbookv1.PlaceOrderRequest, error) {
+		DoAndReturn(func(ctx context.Context) (kafka.Message, *pb.PlaceOrderPayload, error) {
 			if messageIndex < len(messages) {
 				msg := messages[messageIndex]
 				messageIndex++
@@ -450,7 +514,7 @@ func TestEngine_RunOrderProcessor_Integration(t *testing.T) {
 			}
 			// Block until cancelled after all messages
 			<-ctx.Done()
-			return kafka.Message{}, orderbookv1.PlaceOrderRequest{}, ctx.Err()
+			return kafka.Message{}, nil, ctx.Err()
 		}).
 		Times(len(messages) + 1) // +1 for the final cancelled call
 
@@ -0,0 +1,180 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
+	journalv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/journal/v1"
+	matchpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/match-publisher/v1"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/journal"
+)
+
+var errPublisherDown = errors.New("publisher down")
+
+// flakyMatchPublisher fails all but every succeedEvery-th attempt, or every attempt while
+// down, and keeps the events it confirmed
+type flakyMatchPublisher struct {
+	mu           sync.Mutex
+	succeedEvery int
+	down         bool
+	attempts     int
+	attempted    []string
+	published    []*pb.MatchEventPayload
+}
+
+func (p *flakyMatchPublisher) PublishMatchEvent(_ context.Context, matchEvent *pb.MatchEventPayload) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.attempts++
+	p.attempted = append(p.attempted, matchEvent.MatchID)
+	if p.down || p.attempts%p.succeedEvery != 0 {
+		return errPublisherDown
+	}
+	p.published = append(p.published, matchEvent)
+	return nil
+}
+
+func (p *flakyMatchPublisher) setDown(down bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.down = down
+}
+
+func (p *flakyMatchPublisher) attemptCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.attempts
+}
+
+// newOutboxTestEngine creates an engine publishing matches to publisher, journaling to
+// j if it is set
+func newOutboxTestEngine(t *testing.T, publisher matchpublisherv1.MatchPublisher, j journalv1.Journal) *Engine {
+	fixture := setupTestFixture(t)
+	t.Cleanup(fixture.teardown)
+
+	fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(nil, nil)
+	fixture.recordOrderEvents()
+
+	options := DefaultEngineOptions()
+	options.PublishRetryInterval = time.Millisecond
+	options.Journal = j
+
+	engine := NewEngineWithOptions(fixture.orderbook, fixture.stopBook, fixture.mockOrderReader, fixture.mockSnapshotStore,
+		publisher, fixture.mockOrderPublisher, fixture.logger, fixture.config, options)
+	engine.ctx, engine.cancel = context.WithCancel(context.Background())
+	t.Cleanup(engine.cancel)
+	return engine
+}
+
+// outboxTestSweep rests three asks and returns a market buy sweeping them
+func outboxTestSweep(t *testing.T, e *Engine, round int) *orderbookv1.PlaceOrderRequest {
+	base := int64(round * 10)
+	for i := int64(0); i < 3; i++ {
+		ask := createTestOrderRequest(fmt.Sprintf("seller%d", i), orderbookv1.OrderTypeLimit, false, 1, 100+i, base+i)
+		require.NoError(t, e.applyOrder(&ask))
+	}
+	sweep := createTestOrderRequest("buyer", orderbookv1.OrderTypeMarket, true, 3, 0, base+3)
+	return &sweep
+}
+
+func TestEngine_OutboxRetriesUntilConfirmed(t *testing.T) {
+	publisher := &flakyMatchPublisher{succeedEvery: 3}
+	e := newOutboxTestEngine(t, publisher, nil)
+
+	for round := 0; round < 4; round++ {
+		sweep := outboxTestSweep(t, e, round)
+		require.NoError(t, e.applyOrder(sweep))
+		assert.Equal(t, sweep.Offset, e.GetOrderOffset())
+	}
+
+	// Every trade arrives once, in sequence, although two of three attempts failed
+	require.Len(t, publisher.published, 12)
+	for i, matchEvent := range publisher.published {
+		assert.Equal(t, int64(i+1), matchEvent.TradeSequence)
+		assert.Equal(t, matchpublisherv1.TradeID("BTC-USD", int64(i+1)), matchEvent.MatchID)
+	}
+	assert.Equal(t, 36, publisher.attemptCount())
+	assert.Empty(t, e.outbox)
+}
+
+func TestEngine_OutboxHoldsOffsetWhileUnconfirmed(t *testing.T) {
+	publisher := &flakyMatchPublisher{succeedEvery: 1}
+	e := newOutboxTestEngine(t, publisher, nil)
+
+	sweep := outboxTestSweep(t, e, 0)
+	previousOffset := e.GetOrderOffset()
+	publisher.setDown(true)
+
+	done := make(chan error, 1)
+	go func() { done <- e.applyOrder(sweep) }()
+
+	require.Eventually(t, func() bool { return publisher.attemptCount() >= 5 }, time.Second, time.Millisecond)
+	e.cancel()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(time.Second):
+		t.Fatal("apply did not return after the engine stopped")
+	}
+	assert.ErrorIs(t, err, matchpublisherv1.ErrMatchesPending)
+
+	// The order is not consumed and no snapshot may record its trades in the book
+	assert.Equal(t, previousOffset, e.GetOrderOffset())
+	assert.Len(t, e.outbox, 3)
+	assert.Empty(t, publisher.published)
+	e.createAndStoreSnapshot()
+	assert.Equal(t, int64(0), e.GetLastSnapshotOffset())
+}
+
+func TestEngine_OutboxRepublishesAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.journal")
+	fixture := setupTestFixture(t)
+
+	openJournal := func() journalv1.Journal {
+		j, err := journal.NewJournal(path, false, fixture.logger)
+		require.NoError(t, err)
+		t.Cleanup(func() { j.Close() })
+		return j
+	}
+
+	// The engine stops while the publisher is down, after the sweep was journaled
+	down := &flakyMatchPublisher{succeedEvery: 1}
+	stopped := newOutboxTestEngine(t, down, openJournal())
+	sweep := outboxTestSweep(t, stopped, 0)
+	down.setDown(true)
+
+	done := make(chan error, 1)
+	go func() { done <- stopped.applyOrder(sweep) }()
+	require.Eventually(t, func() bool { return down.attemptCount() >= 3 }, time.Second, time.Millisecond)
+	stopped.cancel()
+	require.ErrorIs(t, <-done, matchpublisherv1.ErrMatchesPending)
+
+	// The restarted engine replays the journal and publishes the same trades again
+	publisher := &flakyMatchPublisher{succeedEvery: 2}
+	restarted := newOutboxTestEngine(t, publisher, openJournal())
+	events, err := restarted.Replay(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, restarted.republish(events))
+
+	require.Len(t, publisher.published, 3)
+	assert.Equal(t, down.attempted[0], publisher.published[0].MatchID)
+	for i, matchEvent := range publisher.published {
+		assert.Equal(t, matchpublisherv1.TradeID("BTC-USD", int64(i+1)), matchEvent.MatchID)
+		assert.Equal(t, int64(i+1), matchEvent.TradeSequence)
+	}
+	assert.Equal(t, sweep.Offset, restarted.GetOrderOffset())
+	assert.Equal(t, int64(3), restarted.GetTradeSequence())
+}
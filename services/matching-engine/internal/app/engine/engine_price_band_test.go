@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	orderpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-publisher/v1"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/pkg/config"
+)
+
+func TestEngine_PriceBand(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+	fixture.config.PriceBandConfig.Percent = 5
+	fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(nil, nil)
+	orderEvents := fixture.recordOrderEvents()
+	engine := createTestEngine(fixture)
+
+	// Before the first trade there is no reference, so any price is accepted
+	far := createTestOrderRequest("alice", orderbookv1.OrderTypeLimit, false, 2, 108, 1)
+	require.NoError(t, engine.applyOrder(&far))
+	ask := createTestOrderRequest("alice", orderbookv1.OrderTypeLimit, false, 1, 100, 2)
+	require.NoError(t, engine.applyOrder(&ask))
+	bid := createTestOrderRequest("bob", orderbookv1.OrderTypeLimit, true, 1, 100, 3)
+	require.NoError(t, engine.applyOrder(&bid))
+	require.Equal(t, int64(100), engine.GetLastTradePrice())
+
+	// Limit orders outside the band around the last trade are rejected
+	outside := createTestOrderRequest("carol", orderbookv1.OrderTypeLimit, true, 1, 94, 4)
+	require.NoError(t, engine.applyOrder(&outside))
+	rejected := orderEvents.ofOrder(outside.OrderID)
+	require.Len(t, rejected, 1)
+	assert.Equal(t, string(orderpublisherv1.EventTypeRejected), rejected[0].EventType)
+	assert.Equal(t, string(orderbookv1.RejectCodePriceBand), rejected[0].ReasonCode)
+	assert.NotContains(t, fixture.orderbook.Orders, outside.OrderID)
+
+	inside := createTestOrderRequest("carol", orderbookv1.OrderTypeLimit, false, 1, 104, 5)
+	require.NoError(t, engine.applyOrder(&inside))
+	assert.Contains(t, fixture.orderbook.Orders, inside.OrderID)
+
+	// So are replaces to a price outside it
+	replace := createTestOrderRequest("carol", orderbookv1.OrderTypeReplace, false, 1, 106, 6)
+	replace.OrderID = inside.OrderID
+	require.NoError(t, engine.applyOrder(&replace))
+	assert.Equal(t, string(orderbookv1.RejectCodePriceBand), orderEvents.ofType(orderpublisherv1.EventTypeRejected)[1].ReasonCode)
+	assert.Equal(t, int64(104), fixture.orderbook.Orders[inside.OrderID].Limit.Price)
+
+	// A market buy fills up to the top of the band, the rest is cancelled
+	market := createTestOrderRequest("dave", orderbookv1.OrderTypeMarket, true, 3, 0, 7)
+	require.NoError(t, engine.applyOrder(&market))
+	assert.Equal(t, int64(104), engine.GetLastTradePrice())
+	assert.Equal(t, int64(2), fixture.orderbook.AskTotalVolume())
+
+	cancelled := orderEvents.ofType(orderpublisherv1.EventTypeCancelled)
+	require.Len(t, cancelled, 1)
+	assert.Equal(t, market.OrderID, cancelled[0].OrderID)
+	assert.Equal(t, orderbookv1.ErrPriceProtection.Error(), cancelled[0].Reason)
+}
+
+func TestEngine_PriceBandMovingAverage(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+	fixture.config.PriceBandConfig = config.PriceBandConfig{Percent: 10, Reference: "moving_average", AverageTrades: 2}
+	fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(nil, nil)
+	fixture.recordOrderEvents()
+	engine := createTestEngine(fixture)
+
+	offset := int64(0)
+	trade := func(price int64) {
+		offset++
+		ask := createTestOrderRequest("alice", orderbookv1.OrderTypeLimit, false, 1, price, offset)
+		require.NoError(t, engine.applyOrder(&ask))
+		offset++
+		bid := createTestOrderRequest("bob", orderbookv1.OrderTypeLimit, true, 1, price, offset)
+		require.NoError(t, engine.applyOrder(&bid))
+	}
+	trade(100)
+	trade(108)
+	trade(112)
+
+	// The band is centred on the average of the last two trades, 110
+	engine.applyMu.Lock()
+	defer engine.applyMu.Unlock()
+	assert.Equal(t, int64(110), engine.referencePrice())
+	assert.NoError(t, engine.checkPriceBand("price", 101))
+	assert.Error(t, engine.checkPriceBand("price", 122))
+	assert.Equal(t, []int64{108, 112}, engine.snapshot().OrderBookSnapshot.AveragePrices)
+}
+
+func TestEngine_CircuitBreaker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.journal")
+	breaker := orderbookv1.CircuitBreaker{MoveBps: 1_000, Window: time.Minute, CoolDown: 5 * time.Minute}
+	engine := newJournalTestEngine(t, path, nil)
+	engine.circuitBreaker = breaker
+
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	requests := []orderbookv1.PlaceOrderRequest{
+		createTestOrderRequest("alice", orderbookv1.OrderTypeLimit, false, 1, 100, 1),
+		createTestOrderRequest("bob", orderbookv1.OrderTypeLimit, true, 1, 100, 2),
+		createTestOrderRequest("alice", orderbookv1.OrderTypeLimit, false, 1, 111, 3),
+		createTestOrderRequest("carol", orderbookv1.OrderTypeStop, true, 1, 0, 4),
+		createTestOrderRequest("bob", orderbookv1.OrderTypeLimit, true, 1, 111, 5),
+		createTestOrderRequest("dave", orderbookv1.OrderTypeLimit, true, 1, 110, 6),
+	}
+	requests[3].StopPrice = 105
+	for i := range requests {
+		requests[i].Timestamp = base.Add(time.Duration(i) * time.Second).UnixNano()
+		require.NoError(t, engine.applyOrder(&requests[i]))
+	}
+
+	// A move of 11% within the window halts the pair; the triggered stop waits
+	assert.Equal(t, orderbookv1.MarketStateHalted, engine.MarketState())
+	assert.Equal(t, int64(2), engine.GetTotalMatches())
+	assert.True(t, engine.stopBook.HasStopOrder(requests[3].OrderID))
+	assert.Equal(t, string(orderbookv1.RejectCodeMarketState), engine.orderEvents[len(engine.orderEvents)-1].ReasonCode)
+
+	// Trading resumes once the cool-down is over
+	halted := base.Add(4 * time.Second)
+	engine.endCoolDown(halted.Add(time.Minute))
+	assert.Equal(t, orderbookv1.MarketStateHalted, engine.MarketState())
+	engine.endCoolDown(halted.Add(5 * time.Minute))
+	assert.Equal(t, orderbookv1.MarketStateContinuous, engine.MarketState())
+	assert.Zero(t, engine.haltedUntil)
+
+	var states []string
+	for _, event := range engine.orderEvents {
+		if event.EventType == string(orderpublisherv1.EventTypeMarketStateChanged) {
+			states = append(states, event.MarketState)
+		}
+	}
+	assert.Equal(t, []string{"halted", "continuous"}, states)
+
+	// Replay halts and resumes the pair at the same points
+	replayed := newJournalTestEngine(t, path, nil)
+	replayed.circuitBreaker = breaker
+	events, err := replayed.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, marshalState(t, engine.Engine), marshalState(t, replayed.Engine))
+	assert.Equal(t, engine.orderEvents, replayedOrderEvents(events))
+}
+
+func TestEngine_CircuitBreakerReleasesStops(t *testing.T) {
+	engine := newJournalTestEngine(t, filepath.Join(t.TempDir(), "orders.journal"), nil)
+	engine.circuitBreaker = orderbookv1.CircuitBreaker{MoveBps: 1_000, Window: time.Minute, CoolDown: 5 * time.Minute}
+
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	requests := []orderbookv1.PlaceOrderRequest{
+		createTestOrderRequest("alice", orderbookv1.OrderTypeLimit, false, 1, 100, 1),
+		createTestOrderRequest("bob", orderbookv1.OrderTypeLimit, true, 1, 100, 2),
+		createTestOrderRequest("alice", orderbookv1.OrderTypeLimit, false, 2, 111, 3),
+		createTestOrderRequest("carol", orderbookv1.OrderTypeStop, true, 1, 0, 4),
+		createTestOrderRequest("bob", orderbookv1.OrderTypeLimit, true, 1, 111, 5),
+	}
+	requests[3].StopPrice = 105
+	for i := range requests {
+		requests[i].Timestamp = base.Add(time.Duration(i) * time.Second).UnixNano()
+		require.NoError(t, engine.applyOrder(&requests[i]))
+	}
+	require.Equal(t, orderbookv1.MarketStateHalted, engine.MarketState())
+	require.Len(t, engine.matches, 2)
+
+	// The stop released when the cool-down ends trades and is published without another input
+	engine.endCoolDown(base.Add(4*time.Second + 5*time.Minute))
+	assert.Equal(t, orderbookv1.MarketStateContinuous, engine.MarketState())
+	require.Len(t, engine.matches, 3)
+	assert.Equal(t, "carol", engine.matches[2].TakerUserID)
+	assert.Empty(t, engine.outbox)
+	assert.Equal(t, int64(-1), engine.inputOffset)
+}
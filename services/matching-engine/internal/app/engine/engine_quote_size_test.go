@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	orderpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-publisher/v1"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+)
+
+func TestEngine_QuoteSizeMarketOrder(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+	fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(nil, nil)
+	orderEvents := fixture.recordOrderEvents()
+	engine := createTestEngine(fixture)
+
+	for _, request := range []orderbookv1.PlaceOrderRequest{
+		createTestOrderRequest("alice", orderbookv1.OrderTypeLimit, false, 2, 30, 1),
+		createTestOrderRequest("bob", orderbookv1.OrderTypeLimit, false, 10, 40, 2),
+	} {
+		require.NoError(t, engine.applyOrder(&request))
+	}
+
+	// 150 buys 2 at 30 and 2 at 40; the 10 left cannot buy another at 40 and is reported
+	// on the last fill
+	spend := createTestOrderRequest("carol", orderbookv1.OrderTypeMarket, true, 0, 0, 3)
+	spend.QuoteSize = 150
+	require.NoError(t, engine.applyOrder(&spend))
+
+	events := orderEvents.ofOrder(spend.OrderID)
+	require.Len(t, events, 3)
+	assert.Equal(t, string(orderpublisherv1.EventTypeAccepted), events[0].EventType)
+	assert.Equal(t, float64(150), events[0].QuoteSize)
+	assert.Equal(t, string(orderpublisherv1.EventTypePartiallyFilled), events[1].EventType)
+	assert.Equal(t, float64(2), events[1].Size)
+	assert.Equal(t, float64(2), events[1].RemainingSize)
+	assert.Equal(t, float64(90), events[1].RemainingQuoteSize)
+	assert.Equal(t, string(orderpublisherv1.EventTypeFilled), events[2].EventType)
+	assert.Equal(t, float64(40), events[2].Price)
+	assert.Equal(t, float64(0), events[2].RemainingSize)
+	assert.Equal(t, float64(10), events[2].RemainingQuoteSize)
+
+	// The book runs out with budget that could still buy more, which is cancelled
+	exhaust := createTestOrderRequest("dave", orderbookv1.OrderTypeMarket, true, 0, 0, 4)
+	exhaust.QuoteSize = 1_000
+	require.NoError(t, engine.applyOrder(&exhaust))
+
+	events = orderEvents.ofOrder(exhaust.OrderID)
+	require.Len(t, events, 3)
+	assert.Equal(t, string(orderpublisherv1.EventTypeFilled), events[1].EventType)
+	assert.Equal(t, float64(680), events[1].RemainingQuoteSize)
+	cancelled := events[2]
+	assert.Equal(t, string(orderpublisherv1.EventTypeCancelled), cancelled.EventType)
+	assert.Equal(t, float64(680), cancelled.QuoteSize)
+	assert.Equal(t, float64(0), cancelled.Size)
+	assert.Equal(t, orderbookv1.ErrUnfilledRemainder.Error(), cancelled.Reason)
+	assert.Equal(t, int64(0), fixture.orderbook.AskTotalVolume())
+
+	// A budget is given instead of a size, not with one
+	invalid := createTestOrderRequest("erin", orderbookv1.OrderTypeMarket, true, 1, 0, 5)
+	invalid.QuoteSize = 100
+	require.NoError(t, engine.applyOrder(&invalid))
+
+	rejected := orderEvents.ofOrder(invalid.OrderID)
+	require.Len(t, rejected, 1)
+	assert.Equal(t, string(orderpublisherv1.EventTypeRejected), rejected[0].EventType)
+	assert.Equal(t, string(orderbookv1.RejectCodeInvalidOrder), rejected[0].ReasonCode)
+}
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	orderpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-publisher/v1"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+)
+
+func TestEngine_MarketOrderSlippageLimit(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+	fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(nil, nil)
+	orderEvents := fixture.recordOrderEvents()
+	engine := createTestEngine(fixture)
+
+	for _, request := range []orderbookv1.PlaceOrderRequest{
+		createTestOrderRequest("alice", orderbookv1.OrderTypeLimit, false, 2, 100, 1),
+		createTestOrderRequest("bob", orderbookv1.OrderTypeLimit, false, 2, 101, 2),
+		createTestOrderRequest("carol", orderbookv1.OrderTypeLimit, false, 5, 104, 3),
+	} {
+		require.NoError(t, engine.applyOrder(&request))
+	}
+
+	// 2% from the best ask of 100 reaches 102, the rest is cancelled
+	slippage := createTestOrderRequest("dave", orderbookv1.OrderTypeMarket, true, 6, 0, 4)
+	slippage.MaxSlippageBps = 200
+	require.NoError(t, engine.applyOrder(&slippage))
+
+	events := orderEvents.ofOrder(slippage.OrderID)
+	require.Len(t, events, 4)
+	assert.Equal(t, string(orderpublisherv1.EventTypeCancelled), events[3].EventType)
+	assert.Equal(t, float64(2), events[3].Size)
+	assert.Equal(t, float64(0), events[3].RemainingSize)
+	assert.Equal(t, orderbookv1.ErrSlippageLimit.Error(), events[3].Reason)
+	assert.Equal(t, int64(101), engine.GetLastTradePrice())
+
+	// Nothing is left at or below the worst price, so the whole order is cancelled
+	worst := createTestOrderRequest("erin", orderbookv1.OrderTypeMarket, true, 3, 0, 5)
+	worst.WorstPrice = 103
+	require.NoError(t, engine.applyOrder(&worst))
+
+	events = orderEvents.ofOrder(worst.OrderID)
+	require.Len(t, events, 2)
+	assert.Equal(t, string(orderpublisherv1.EventTypeCancelled), events[1].EventType)
+	assert.Equal(t, float64(3), events[1].Size)
+	assert.Equal(t, orderbookv1.ErrSlippageLimit.Error(), events[1].Reason)
+	assert.Equal(t, int64(5), fixture.orderbook.AskTotalVolume())
+
+	// An order that runs out of book is not stopped by its limit
+	exhaust := createTestOrderRequest("frank", orderbookv1.OrderTypeMarket, true, 7, 0, 6)
+	exhaust.WorstPrice = 110
+	require.NoError(t, engine.applyOrder(&exhaust))
+
+	events = orderEvents.ofOrder(exhaust.OrderID)
+	require.Len(t, events, 3)
+	assert.Equal(t, float64(2), events[2].Size)
+	assert.Equal(t, orderbookv1.ErrUnfilledRemainder.Error(), events[2].Reason)
+
+	// Limits only apply to market and stop orders
+	limit := createTestOrderRequest("grace", orderbookv1.OrderTypeLimit, true, 1, 100, 7)
+	limit.WorstPrice = 101
+	require.NoError(t, engine.applyOrder(&limit))
+
+	rejected := orderEvents.ofOrder(limit.OrderID)
+	require.Len(t, rejected, 1)
+	assert.Equal(t, string(orderpublisherv1.EventTypeRejected), rejected[0].EventType)
+	assert.Equal(t, orderbookv1.ErrInvalidSlippage.Error(), rejected[0].Reason)
+}
@@ -3,19 +3,24 @@ package engine
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/muhammadchandra19/exchange/pkg/logger"
+	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
 	matchpublisherv1_mock "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/match-publisher/v1/mock"
+	orderpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-publisher/v1"
+	orderpublisherv1_mock "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-publisher/v1/mock"
 	orderreaderv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-reader/v1"
 	orderreadermock "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-reader/v1/mock"
 	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
 	snapshotv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/snapshot/v1"
 	snapshotmock "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/snapshot/v1/mock"
 	"github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/orderbook"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/internal/usecase/stopbook"
 	"github.com/muhammadchandra19/exchange/services/matching-engine/pkg/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -27,7 +32,9 @@ type testFixture struct {
 	mockOrderReader    *orderreadermock.MockOrderReader
 	mockSnapshotStore  *snapshotmock.MockStore
 	mockMatchPublisher *matchpublisherv1_mock.MockMatchPublisher
+	mockOrderPublisher *orderpublisherv1_mock.MockOrderPublisher
 	orderbook          *orderbook.Orderbook
+	stopBook           *stopbook.StopBook
 	logger             *logger.Logger
 	config             *config.Config
 }
@@ -43,7 +50,9 @@ func setupTestFixture(t *testing.T) *testFixture {
 		mockOrderReader:    orderreadermock.NewMockOrderReader(ctrl),
 		mockSnapshotStore:  snapshotmock.NewMockStore(ctrl),
 		mockMatchPublisher: matchpublisherv1_mock.NewMockMatchPublisher(ctrl),
+		mockOrderPublisher: orderpublisherv1_mock.NewMockOrderPublisher(ctrl),
 		orderbook:          orderbook.NewOrderbook(),
+		stopBook:           stopbook.NewStopBook(),
 		logger:             log,
 		config: &config.Config{
 			Pair: "BTC-USD",
@@ -64,14 +73,15 @@ func (f *testFixture) teardown() {
 	f.ctrl.Finish()
 }
 
-func createTestOrderRequest(userID string, orderType orderbookv1.OrderType, bid bool, size, price float64, offset int64) orderbookv1.PlaceOrderRequest {
+func createTestOrderRequest(userID string, orderType orderbookv1.OrderType, bid bool, size, price int64, offset int64) orderbookv1.PlaceOrderRequest {
 	return orderbookv1.PlaceOrderRequest{
-		UserID: userID,
-		Type:   orderType,
-		Bid:    bid,
-		Size:   size,
-		Price:  price,
-		Offset: offset,
+		OrderID: fmt.Sprintf("%s-%d", userID, offset),
+		UserID:  userID,
+		Type:    orderType,
+		Bid:     bid,
+		Size:    size,
+		Price:   price,
+		Offset:  offset,
 	}
 }
 
@@ -85,9 +95,11 @@ func createTestEngine(fixture *testFixture) *Engine {
 
 	engine := NewEngine(
 		fixture.orderbook,
+		fixture.stopBook,
 		fixture.mockOrderReader,
 		fixture.mockSnapshotStore,
 		fixture.mockMatchPublisher,
+		fixture.mockOrderPublisher,
 		fixture.logger,
 		fixture.config,
 	)
@@ -98,6 +110,67 @@ func createTestEngine(fixture *testFixture) *Engine {
 	return engine
 }
 
+// orderEventRecorder keeps the order events an engine published, in order
+type orderEventRecorder struct {
+	mu     sync.Mutex
+	events []*pb.OrderEventPayload
+}
+
+// recordOrderEvents accepts any number of order events and records them
+func (f *testFixture) recordOrderEvents() *orderEventRecorder {
+	recorder := &orderEventRecorder{}
+	f.mockOrderPublisher.EXPECT().
+		PublishOrderEvent(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, orderEvent *pb.OrderEventPayload) error {
+			recorder.mu.Lock()
+			defer recorder.mu.Unlock()
+			recorder.events = append(recorder.events, orderEvent)
+			return nil
+		}).
+		AnyTimes()
+	return recorder
+}
+
+// types returns the type of every recorded event
+func (r *orderEventRecorder) types() []orderpublisherv1.EventType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var types []orderpublisherv1.EventType
+	for _, event := range r.events {
+		types = append(types, orderpublisherv1.EventType(event.EventType))
+	}
+	return types
+}
+
+// ofOrder returns the recorded events of one order
+func (r *orderEventRecorder) ofOrder(orderID string) []*pb.OrderEventPayload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []*pb.OrderEventPayload
+	for _, event := range r.events {
+		if event.OrderID == orderID {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// ofType returns the recorded events of one type
+func (r *orderEventRecorder) ofType(eventType orderpublisherv1.EventType) []*pb.OrderEventPayload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []*pb.OrderEventPayload
+	for _, event := range r.events {
+		if event.EventType == string(eventType) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
 func TestNewEngine(t *testing.T) {
 	testCases := []struct {
 		name                string
@@ -144,9 +217,11 @@ func TestNewEngine(t *testing.T) {
 
 			engine := NewEngine(
 				fixture.orderbook,
+				fixture.stopBook,
 				fixture.mockOrderReader,
 				fixture.mockSnapshotStore,
 				fixture.mockMatchPublisher,
+				fixture.mockOrderPublisher,
 				fixture.logger,
 				fixture.config,
 			)
@@ -206,9 +281,11 @@ func TestNewEngineWithOptions(t *testing.T) {
 
 			engine := NewEngineWithOptions(
 				fixture.orderbook,
+				fixture.stopBook,
 				fixture.mockOrderReader,
 				fixture.mockSnapshotStore,
 				fixture.mockMatchPublisher,
+				fixture.mockOrderPublisher,
 				fixture.logger,
 				fixture.config,
 				tc.options,
@@ -231,16 +308,18 @@ func TestEngine_ProcessOrder(t *testing.T) {
 		expectedOrders int
 		expectedLimits int
 		expectMatch    bool
+		expectedEvents []orderpublisherv1.EventType
 	}{
 		{
 			name: "process valid limit order",
 			orderRequest: &orderbookv1.PlaceOrderRequest{
-				UserID: "user1",
-				Type:   orderbookv1.OrderTypeLimit,
-				Bid:    false,
-				Size:   10.0,
-				Price:  50000.0,
-				Offset: 1,
+				OrderID: "order-1",
+				UserID:  "user1",
+				Type:    orderbookv1.OrderTypeLimit,
+				Bid:     false,
+				Size:    10,
+				Price:   50000,
+				Offset:  1,
 			},
 			setupMocks:     func(f *testFixture) {},
 			setupOrderbook: func(ob *orderbook.Orderbook) {},
@@ -248,61 +327,75 @@ func TestEngine_ProcessOrder(t *testing.T) {
 			expectedOrders: 1,
 			expectedLimits: 1,
 			expectMatch:    false,
+			expectedEvents: []orderpublisherv1.EventType{
+				orderpublisherv1.EventTypeAccepted,
+				orderpublisherv1.EventTypeRested,
+			},
 		},
 		{
 			name: "process market order with existing limit order",
 			orderRequest: &orderbookv1.PlaceOrderRequest{
-				UserID: "buyer",
-				Type:   orderbookv1.OrderTypeMarket,
-				Bid:    true,
-				Size:   5.0,
-				Price:  0.0,
-				Offset: 2,
+				OrderID: "order-2",
+				UserID:  "buyer",
+				Type:    orderbookv1.OrderTypeMarket,
+				Bid:     true,
+				Size:    5,
+				Price:   0,
+				Offset:  2,
 			},
 			setupMocks: func(f *testFixture) {},
 			setupOrderbook: func(ob *orderbook.Orderbook) {
 				// Add a sell limit order first
-				sellOrder := orderbookv1.NewOrder("seller", 10.0, false, "sell1")
-				ob.PlaceLimitOrder(49000.0, sellOrder)
+				sellOrder := orderbookv1.NewOrder("seller", 10, false, "sell1")
+				ob.PlaceLimitOrder(49000, sellOrder)
 			},
 			expectedError:  false,
 			expectedOrders: 1, // Original sell order remains (partially filled)
 			expectedLimits: 1,
 			expectMatch:    true,
+			expectedEvents: []orderpublisherv1.EventType{
+				orderpublisherv1.EventTypeAccepted,
+				orderpublisherv1.EventTypeFilled,
+				orderpublisherv1.EventTypePartiallyFilled,
+			},
 		},
 		{
 			name: "process invalid limit order - negative price",
 			orderRequest: &orderbookv1.PlaceOrderRequest{
-				UserID: "user1",
-				Type:   orderbookv1.OrderTypeLimit,
-				Bid:    false,
-				Size:   10.0,
-				Price:  -1.0,
-				Offset: 3,
+				OrderID: "order-3",
+				UserID:  "user1",
+				Type:    orderbookv1.OrderTypeLimit,
+				Bid:     false,
+				Size:    10,
+				Price:   -1,
+				Offset:  3,
 			},
 			setupMocks:     func(f *testFixture) {},
 			setupOrderbook: func(ob *orderbook.Orderbook) {},
-			expectedError:  true,
+			expectedError:  false,
 			expectedOrders: 0,
 			expectedLimits: 0,
 			expectMatch:    false,
+			expectedEvents: []orderpublisherv1.EventType{orderpublisherv1.EventTypeRejected},
 		},
 		{
 			name: "process invalid order - zero size",
 			orderRequest: &orderbookv1.PlaceOrderRequest{
-				UserID: "user1",
-				Type:   orderbookv1.OrderTypeLimit,
-				Bid:    false,
-				Size:   0.0,
-				Price:  50000.0,
-				Offset: 4,
+				OrderID: "order-4",
+				UserID:  "user1",
+				Type:    orderbookv1.OrderTypeLimit,
+				Bid:     false,
+				Size:    0,
+				Price:   50000,
+				Offset:  4,
 			},
 			setupMocks:     func(f *testFixture) {},
 			setupOrderbook: func(ob *orderbook.Orderbook) {},
-			expectedError:  true,
+			expectedError:  false,
 			expectedOrders: 0,
 			expectedLimits: 0,
 			expectMatch:    false,
+			expectedEvents: []orderpublisherv1.EventType{orderpublisherv1.EventTypeRejected},
 		},
 	}
 
@@ -312,6 +405,7 @@ func TestEngine_ProcessOrder(t *testing.T) {
 			defer fixture.teardown()
 
 			tc.setupMocks(fixture)
+			orderEvents := fixture.recordOrderEvents()
 
 			// Setup snapshot loading
 			fixture.mockSnapshotStore.EXPECT().
@@ -350,6 +444,8 @@ func TestEngine_ProcessOrder(t *testing.T) {
 				finalMatches := engine.GetTotalMatches()
 				assert.Equal(t, initialMatches, finalMatches, "Expected no matches to be generated")
 			}
+
+			assert.Equal(t, tc.expectedEvents, orderEvents.types())
 		})
 	}
 }
@@ -438,9 +534,11 @@ func TestEngine_SnapshotManagement(t *testing.T) {
 
 			engine := NewEngineWithOptions(
 				fixture.orderbook,
+				fixture.stopBook,
 				fixture.mockOrderReader,
 				fixture.mockSnapshotStore,
 				fixture.mockMatchPublisher,
+				fixture.mockOrderPublisher,
 				fixture.logger,
 				fixture.config,
 				options,
@@ -513,17 +611,18 @@ func TestEngine_ConcurrentAccess(t *testing.T) {
 					LoadStore(gomock.Any()).
 					Return(nil, nil).
 					Times(1)
+				f.recordOrderEvents()
 			},
 			testOperation: func(engine *Engine, goroutineID, operationID int) {
 				orderRequest := createTestOrderRequest(
 					"user",
 					orderbookv1.OrderTypeLimit,
 					goroutineID%2 == 0, // Alternate bid/ask
-					10.0,
-					50000.0+float64(goroutineID*100+operationID),
+					10,
+					50000+int64(goroutineID*100+operationID),
 					int64(goroutineID*1000+operationID),
 				)
-				_ = engine.processOrder(&orderRequest)
+				_ = engine.applyOrder(&orderRequest)
 			},
 		},
 	}
@@ -574,6 +673,7 @@ func TestEngine_GetTotalMatches(t *testing.T) {
 		LoadStore(gomock.Any()).
 		Return(nil, nil).
 		Times(1)
+	fixture.recordOrderEvents()
 
 	engine := createTestEngine(fixture)
 
@@ -581,17 +681,954 @@ func TestEngine_GetTotalMatches(t *testing.T) {
 	assert.Equal(t, int64(0), engine.GetTotalMatches())
 
 	// Add a sell order
-	sellOrder := orderbookv1.NewOrder("seller", 10.0, false, "sell1")
-	fixture.orderbook.PlaceLimitOrder(50000.0, sellOrder)
+	sellOrder := orderbookv1.NewOrder("seller", 10, false, "sell1")
+	fixture.orderbook.PlaceLimitOrder(50000, sellOrder)
 
 	// Process a market buy order that should create a match
-	marketOrder := createTestOrderRequest("buyer", orderbookv1.OrderTypeMarket, true, 5.0, 0.0, 1)
+	marketOrder := createTestOrderRequest("buyer", orderbookv1.OrderTypeMarket, true, 5, 0, 1)
 	err := engine.processOrder(&marketOrder)
 
 	assert.NoError(t, err)
 	assert.Equal(t, int64(1), engine.GetTotalMatches())
 }
 
+// Test that a crossing limit order is matched and its trades are published
+func TestEngine_ProcessCrossingLimitOrder(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+
+	fixture.mockSnapshotStore.EXPECT().
+		LoadStore(gomock.Any()).
+		Return(nil, nil).
+		Times(1)
+
+	fixture.mockMatchPublisher.EXPECT().
+		PublishMatchEvent(gomock.Any(), gomock.Any()).
+		Return(nil).
+		Times(2)
+	orderEvents := fixture.recordOrderEvents()
+
+	engine := NewEngine(
+		fixture.orderbook,
+		fixture.stopBook,
+		fixture.mockOrderReader,
+		fixture.mockSnapshotStore,
+		fixture.mockMatchPublisher,
+		fixture.mockOrderPublisher,
+		fixture.logger,
+		fixture.config,
+	)
+	engine.ctx = context.Background()
+
+	fixture.orderbook.PlaceLimitOrder(50000, orderbookv1.NewOrder("seller1", 3, false, "sell1"))
+	fixture.orderbook.PlaceLimitOrder(50100, orderbookv1.NewOrder("seller2", 3, false, "sell2"))
+
+	buyOrder := createTestOrderRequest("buyer", orderbookv1.OrderTypeLimit, true, 8, 50100, 1)
+	err := engine.applyOrder(&buyOrder)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), engine.GetTotalMatches())
+	assert.Empty(t, fixture.orderbook.AskLimits)
+	require.Contains(t, fixture.orderbook.BidLimits, int64(50100))
+	assert.Equal(t, int64(2), fixture.orderbook.BidLimits[50100].GetTotalVolume())
+
+	// The buyer fills twice against the two sellers, then rests the remainder
+	buyerEvents := orderEvents.ofOrder(buyOrder.OrderID)
+	require.Len(t, buyerEvents, 4)
+	assert.Equal(t, string(orderpublisherv1.EventTypeAccepted), buyerEvents[0].EventType)
+	assert.Equal(t, string(orderbookv1.OrderTypeLimit), buyerEvents[0].OrderType)
+	for i, price := range []float64{50000, 50100} {
+		fill := buyerEvents[i+1]
+		assert.Equal(t, string(orderpublisherv1.EventTypePartiallyFilled), fill.EventType)
+		assert.Equal(t, string(orderpublisherv1.LiquidityTaker), fill.Liquidity)
+		assert.Equal(t, price, fill.Price)
+		assert.Equal(t, 3.0, fill.Size)
+		assert.Equal(t, float64(5-3*i), fill.RemainingSize)
+	}
+	assert.Equal(t, string(orderpublisherv1.EventTypeRested), buyerEvents[3].EventType)
+	assert.Equal(t, 50100.0, buyerEvents[3].Price)
+	assert.Equal(t, 2.0, buyerEvents[3].Size)
+
+	for _, sellerID := range []string{"sell1", "sell2"} {
+		sellerEvents := orderEvents.ofOrder(sellerID)
+		require.Len(t, sellerEvents, 1)
+		assert.Equal(t, string(orderpublisherv1.EventTypeFilled), sellerEvents[0].EventType)
+		assert.Equal(t, string(orderpublisherv1.LiquidityMaker), sellerEvents[0].Liquidity)
+		assert.Zero(t, sellerEvents[0].RemainingSize)
+	}
+}
+
+// Test that the expiry sweep removes expired GTD orders only
+func TestEngine_ExpireOrders(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+
+	fixture.mockSnapshotStore.EXPECT().
+		LoadStore(gomock.Any()).
+		Return(nil, nil).
+		Times(1)
+	orderEvents := fixture.recordOrderEvents()
+
+	engine := createTestEngine(fixture)
+
+	expireAt := time.Now().Add(time.Minute)
+
+	gtdOrder := createTestOrderRequest("user1", orderbookv1.OrderTypeLimit, true, 5, 49000, 1)
+	gtdOrder.TimeInForce = orderbookv1.TimeInForceGTD
+	gtdOrder.ExpireAt = expireAt.UnixNano()
+	require.NoError(t, engine.processOrder(&gtdOrder))
+
+	gtcOrder := createTestOrderRequest("user2", orderbookv1.OrderTypeLimit, true, 5, 48000, 2)
+	require.NoError(t, engine.processOrder(&gtcOrder))
+
+	expiredOrder := createTestOrderRequest("user3", orderbookv1.OrderTypeLimit, true, 5, 48000, 3)
+	expiredOrder.TimeInForce = orderbookv1.TimeInForceGTD
+	expiredOrder.ExpireAt = time.Now().Add(-time.Minute).UnixNano()
+	require.NoError(t, engine.processOrder(&expiredOrder))
+	rejected := orderEvents.ofOrder(expiredOrder.OrderID)
+	require.Len(t, rejected, 1)
+	assert.Equal(t, string(orderpublisherv1.EventTypeRejected), rejected[0].EventType)
+	assert.Equal(t, orderbookv1.ErrInvalidExpireAt.Error(), rejected[0].Reason)
+
+	engine.expireOrders(expireAt.Add(-time.Second))
+	assert.Equal(t, 2, len(fixture.orderbook.Orders))
+
+	engine.expireOrders(expireAt)
+	assert.Equal(t, 1, len(fixture.orderbook.Orders))
+	assert.Contains(t, fixture.orderbook.Orders, gtcOrder.OrderID)
+
+	cancelled := orderEvents.ofType(orderpublisherv1.EventTypeCancelled)
+	require.Len(t, cancelled, 1)
+	assert.Equal(t, gtdOrder.OrderID, cancelled[0].OrderID)
+	assert.Equal(t, orderbookv1.ErrOrderExpired.Error(), cancelled[0].Reason)
+	assert.Equal(t, 49000.0, cancelled[0].Price)
+	assert.Equal(t, 5.0, cancelled[0].Size)
+	assert.Equal(t, expireAt.UnixNano(), cancelled[0].Timestamp.AsTime().UnixNano())
+}
+
+// Test that the part of an order that does not trade is published as rested or cancelled
+func TestEngine_OrderRemainderEvents(t *testing.T) {
+	testCases := []struct {
+		name           string
+		requests       func() []orderbookv1.PlaceOrderRequest
+		orderID        string
+		expectedEvents []orderpublisherv1.EventType
+		expectedReason error
+		expectedSize   float64
+	}{
+		{
+			name: "ioc remainder is cancelled",
+			requests: func() []orderbookv1.PlaceOrderRequest {
+				order := createTestOrderRequest("taker", orderbookv1.OrderTypeLimit, true, 5, 50000, 1)
+				order.TimeInForce = orderbookv1.TimeInForceIOC
+				return []orderbookv1.PlaceOrderRequest{order}
+			},
+			orderID: "taker-1",
+			expectedEvents: []orderpublisherv1.EventType{
+				orderpublisherv1.EventTypeAccepted,
+				orderpublisherv1.EventTypePartiallyFilled,
+				orderpublisherv1.EventTypeCancelled,
+			},
+			expectedReason: orderbookv1.ErrUnfilledRemainder,
+			expectedSize:   2,
+		},
+		{
+			name: "market remainder is cancelled",
+			requests: func() []orderbookv1.PlaceOrderRequest {
+				return []orderbookv1.PlaceOrderRequest{
+					createTestOrderRequest("taker", orderbookv1.OrderTypeMarket, true, 4, 0, 1),
+				}
+			},
+			orderID: "taker-1",
+			expectedEvents: []orderpublisherv1.EventType{
+				orderpublisherv1.EventTypeAccepted,
+				orderpublisherv1.EventTypePartiallyFilled,
+				orderpublisherv1.EventTypeCancelled,
+			},
+			expectedReason: orderbookv1.ErrUnfilledRemainder,
+			expectedSize:   1,
+		},
+		{
+			name: "fok that cannot fill is cancelled whole",
+			requests: func() []orderbookv1.PlaceOrderRequest {
+				order := createTestOrderRequest("taker", orderbookv1.OrderTypeLimit, true, 5, 50000, 1)
+				order.TimeInForce = orderbookv1.TimeInForceFOK
+				return []orderbookv1.PlaceOrderRequest{order}
+			},
+			orderID: "taker-1",
+			expectedEvents: []orderpublisherv1.EventType{
+				orderpublisherv1.EventTypeAccepted,
+				orderpublisherv1.EventTypeCancelled,
+			},
+			expectedReason: orderbookv1.ErrUnfilledRemainder,
+			expectedSize:   5,
+		},
+		{
+			name: "resting order is cancelled on request",
+			requests: func() []orderbookv1.PlaceOrderRequest {
+				order := createTestOrderRequest("maker", orderbookv1.OrderTypeLimit, true, 5, 49000, 1)
+				cancel := createTestOrderRequest("maker", orderbookv1.OrderTypeCancel, true, 0, 0, 2)
+				cancel.OrderID = order.OrderID
+				return []orderbookv1.PlaceOrderRequest{order, cancel}
+			},
+			orderID: "maker-1",
+			expectedEvents: []orderpublisherv1.EventType{
+				orderpublisherv1.EventTypeAccepted,
+				orderpublisherv1.EventTypeRested,
+				orderpublisherv1.EventTypeCancelled,
+			},
+			expectedReason: orderbookv1.ErrCancelRequested,
+			expectedSize:   5,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fixture := setupTestFixture(t)
+			defer fixture.teardown()
+
+			fixture.mockSnapshotStore.EXPECT().
+				LoadStore(gomock.Any()).
+				Return(nil, nil).
+				Times(1)
+			orderEvents := fixture.recordOrderEvents()
+
+			engine := createTestEngine(fixture)
+
+			fixture.orderbook.PlaceLimitOrder(50000, orderbookv1.NewOrder("seller", 3, false, "sell1"))
+
+			for _, request := range tc.requests() {
+				require.NoError(t, engine.processOrder(&request))
+			}
+
+			events := orderEvents.ofOrder(tc.orderID)
+			var types []orderpublisherv1.EventType
+			for _, event := range events {
+				types = append(types, orderpublisherv1.EventType(event.EventType))
+			}
+			require.Equal(t, tc.expectedEvents, types)
+
+			cancelled := events[len(events)-1]
+			assert.Equal(t, tc.expectedReason.Error(), cancelled.Reason)
+			assert.Equal(t, tc.expectedSize, cancelled.Size)
+			assert.Zero(t, cancelled.RemainingSize)
+			assert.NotContains(t, fixture.orderbook.Orders, tc.orderID)
+		})
+	}
+}
+
+// Test that a crossing post-only order is rejected through an order event
+func TestEngine_ProcessPostOnlyRejection(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+
+	fixture.mockSnapshotStore.EXPECT().
+		LoadStore(gomock.Any()).
+		Return(nil, nil).
+		Times(1)
+
+	engine := createTestEngine(fixture)
+
+	fixture.orderbook.PlaceLimitOrder(50000, orderbookv1.NewOrder("seller", 3, false, "sell1"))
+
+	orderRequest := createTestOrderRequest("maker", orderbookv1.OrderTypeLimit, true, 1, 50000, 1)
+	orderRequest.PostOnly = true
+
+	fixture.mockOrderPublisher.EXPECT().
+		PublishOrderEvent(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, orderEvent *pb.OrderEventPayload) error {
+			assert.Equal(t, string(orderpublisherv1.EventTypeRejected), orderEvent.EventType)
+			assert.Equal(t, orderRequest.OrderID, orderEvent.OrderID)
+			assert.Equal(t, "maker", orderEvent.UserID)
+			assert.Equal(t, fixture.config.Pair, orderEvent.Symbol)
+			assert.Equal(t, orderbookv1.ErrPostOnlyWouldCross.Error(), orderEvent.Reason)
+			return nil
+		}).
+		Times(1)
+
+	err := engine.processOrder(&orderRequest)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), engine.GetTotalMatches())
+	assert.NotContains(t, fixture.orderbook.Orders, orderRequest.OrderID)
+}
+
+// Test that requests the book or the stop book refuses are rejected through an order event
+func TestEngine_RejectsInvalidRequests(t *testing.T) {
+	resting := createTestOrderRequest("maker", orderbookv1.OrderTypeLimit, false, 5, 50000, 1)
+
+	badTimeInForce := createTestOrderRequest("taker", orderbookv1.OrderTypeLimit, true, 1, 49000, 2)
+	badTimeInForce.TimeInForce = "day"
+
+	badSelfTrade := createTestOrderRequest("taker", orderbookv1.OrderTypeMarket, true, 1, 0, 2)
+	badSelfTrade.SelfTradePrevention = "cancel_all"
+
+	stop := createTestOrderRequest("taker", orderbookv1.OrderTypeStop, true, 1, 0, 2)
+	stop.StopPrice = 51000
+
+	postOnlyIOC := createTestOrderRequest("taker", orderbookv1.OrderTypeLimit, true, 1, 49000, 2)
+	postOnlyIOC.PostOnly = true
+	postOnlyIOC.TimeInForce = orderbookv1.TimeInForceIOC
+
+	postOnlyMarket := createTestOrderRequest("taker", orderbookv1.OrderTypeMarket, true, 1, 0, 2)
+	postOnlyMarket.PostOnly = true
+
+	badPostOnlyMode := createTestOrderRequest("taker", orderbookv1.OrderTypeLimit, true, 1, 49000, 2)
+	badPostOnlyMode.PostOnly = true
+	badPostOnlyMode.PostOnlyMode = "bounce"
+
+	testCases := []struct {
+		name         string
+		setup        []orderbookv1.PlaceOrderRequest
+		request      orderbookv1.PlaceOrderRequest
+		expectedCode orderbookv1.RejectCode
+	}{
+		{name: "duplicate order ID", request: resting, expectedCode: orderbookv1.RejectCodeInvalidOrder},
+		{name: "invalid time in force", request: badTimeInForce, expectedCode: orderbookv1.RejectCodeInvalidOrder},
+		{name: "invalid self-trade prevention", request: badSelfTrade, expectedCode: orderbookv1.RejectCodeInvalidOrder},
+		{name: "duplicate stop order ID", setup: []orderbookv1.PlaceOrderRequest{stop}, request: stop, expectedCode: orderbookv1.RejectCodeInvalidOrder},
+		{name: "post-only IOC order", request: postOnlyIOC, expectedCode: orderbookv1.RejectCodePostOnlyNotAllowed},
+		{name: "post-only market order", request: postOnlyMarket, expectedCode: orderbookv1.RejectCodePostOnlyNotAllowed},
+		{name: "invalid post-only mode", request: badPostOnlyMode, expectedCode: orderbookv1.RejectCodePostOnlyMode},
+		{
+			name:         "cancel of an unknown order",
+			request:      orderbookv1.PlaceOrderRequest{OrderID: "missing", UserID: "taker", Type: orderbookv1.OrderTypeCancel, Offset: 2},
+			expectedCode: orderbookv1.RejectCodeOrderNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fixture := setupTestFixture(t)
+			defer fixture.teardown()
+			fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(nil, nil)
+			orderEvents := fixture.recordOrderEvents()
+			engine := createTestEngine(fixture)
+
+			for _, request := range append([]orderbookv1.PlaceOrderRequest{resting}, tc.setup...) {
+				require.NoError(t, engine.applyOrder(&request))
+			}
+			request := tc.request
+			require.NoError(t, engine.applyOrder(&request))
+
+			rejected := orderEvents.ofType(orderpublisherv1.EventTypeRejected)
+			require.Len(t, rejected, 1)
+			assert.Equal(t, request.OrderID, rejected[0].OrderID)
+			assert.Equal(t, string(tc.expectedCode), rejected[0].ReasonCode)
+			assert.Contains(t, fixture.orderbook.Orders, resting.OrderID)
+		})
+	}
+}
+
+// Test that the events of a retried order ID are told apart by the input they result from
+func TestEngine_RetriedOrderEventIDs(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+	fixture.mockSnapshotStore.EXPECT().LoadStore(gomock.Any()).Return(nil, nil)
+	orderEvents := fixture.recordOrderEvents()
+	engine := createTestEngine(fixture)
+
+	// The order is retried while it rests, cancelled, then retried once more
+	requests := make([]orderbookv1.PlaceOrderRequest, 0, 5)
+	for offset := int64(1); offset <= 5; offset++ {
+		request := createTestOrderRequest("alice", orderbookv1.OrderTypeLimit, true, 1, 100, offset)
+		if offset == 4 {
+			request.Type = orderbookv1.OrderTypeCancel
+		}
+		request.OrderID = "alice-1"
+		requests = append(requests, request)
+	}
+	for i := range requests {
+		require.NoError(t, engine.applyOrder(&requests[i]))
+	}
+
+	events := orderEvents.ofOrder("alice-1")
+	assert.Equal(t, []orderpublisherv1.EventType{
+		orderpublisherv1.EventTypeAccepted,
+		orderpublisherv1.EventTypeRested,
+		orderpublisherv1.EventTypeRejected,
+		orderpublisherv1.EventTypeRejected,
+		orderpublisherv1.EventTypeCancelled,
+		orderpublisherv1.EventTypeAccepted,
+		orderpublisherv1.EventTypeRested,
+	}, orderEvents.types())
+
+	eventIDs := make(map[string]bool, len(events))
+	for _, event := range events {
+		assert.False(t, eventIDs[event.EventID], "event ID %s is repeated", event.EventID)
+		eventIDs[event.EventID] = true
+	}
+	assert.Equal(t, "alice-1-order_rejected-3", events[3].EventID)
+}
+
+// Test that trades trigger resting stops and that released stops cascade
+func TestEngine_ProcessStopOrders(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+
+	fixture.mockSnapshotStore.EXPECT().
+		LoadStore(gomock.Any()).
+		Return(nil, nil).
+		Times(1)
+	orderEvents := fixture.recordOrderEvents()
+
+	engine := createTestEngine(fixture)
+
+	// Resting bids below the market
+	fixture.orderbook.PlaceLimitOrder(99, orderbookv1.NewOrder("bidder1", 1, true, "bid1"))
+	fixture.orderbook.PlaceLimitOrder(97, orderbookv1.NewOrder("bidder2", 1, true, "bid2"))
+	fixture.orderbook.PlaceLimitOrder(95, orderbookv1.NewOrder("bidder3", 5, true, "bid3"))
+
+	// Sell stop at 99 sells into the 97 bid, which triggers the sell stop-limit at 97
+	stop := createTestOrderRequest("stopper1", orderbookv1.OrderTypeStop, false, 1, 0, 1)
+	stop.StopPrice = 99
+	require.NoError(t, engine.processOrder(&stop))
+
+	stopLimit := createTestOrderRequest("stopper2", orderbookv1.OrderTypeStopLimit, false, 2, 96, 2)
+	stopLimit.StopPrice = 97
+	require.NoError(t, engine.processOrder(&stopLimit))
+
+	// A far away stop that must stay pending, then cancelled
+	farStop := createTestOrderRequest("stopper3", orderbookv1.OrderTypeStop, false, 1, 0, 3)
+	farStop.StopPrice = 50
+	require.NoError(t, engine.processOrder(&farStop))
+
+	assert.Equal(t, int64(0), engine.GetTotalMatches())
+	assert.True(t, fixture.stopBook.HasStopOrder(stop.OrderID))
+
+	// Market sell hits the 99 bid and sets the chain off
+	marketSell := createTestOrderRequest("seller", orderbookv1.OrderTypeMarket, false, 1, 0, 4)
+	require.NoError(t, engine.processOrder(&marketSell))
+
+	assert.Equal(t, int64(2), engine.GetTotalMatches())
+	assert.Equal(t, int64(97), engine.GetLastTradePrice())
+	assert.False(t, fixture.stopBook.HasStopOrder(stop.OrderID))
+	assert.False(t, fixture.stopBook.HasStopOrder(stopLimit.OrderID))
+	assert.True(t, fixture.stopBook.HasStopOrder(farStop.OrderID))
+
+	// The stop-limit could not trade below 96 and rests at its limit price
+	require.Contains(t, fixture.orderbook.AskLimits, int64(96))
+	assert.Equal(t, int64(2), fixture.orderbook.AskLimits[96].GetTotalVolume())
+
+	cancel := createTestOrderRequest("stopper3", orderbookv1.OrderTypeCancel, false, 0, 0, 5)
+	cancel.OrderID = farStop.OrderID
+	require.NoError(t, engine.processOrder(&cancel))
+	assert.False(t, fixture.stopBook.HasStopOrder(farStop.OrderID))
+
+	// Stops are accepted once, when they enter the stop book
+	assert.Len(t, orderEvents.ofType(orderpublisherv1.EventTypeAccepted), 4)
+
+	farStopEvents := orderEvents.ofOrder(farStop.OrderID)
+	require.Len(t, farStopEvents, 2)
+	assert.Equal(t, string(orderbookv1.OrderTypeStop), farStopEvents[0].OrderType)
+	assert.Equal(t, string(orderpublisherv1.EventTypeCancelled), farStopEvents[1].EventType)
+	assert.Equal(t, orderbookv1.ErrCancelRequested.Error(), farStopEvents[1].Reason)
+	assert.Equal(t, 1.0, farStopEvents[1].Size)
+
+	stopLimitEvents := orderEvents.ofOrder(stopLimit.OrderID)
+	require.Len(t, stopLimitEvents, 2)
+	assert.Equal(t, string(orderpublisherv1.EventTypeRested), stopLimitEvents[1].EventType)
+	assert.Equal(t, 96.0, stopLimitEvents[1].Price)
+}
+
+// Test that a triggered stop whose order the book refuses is cancelled, not dropped
+func TestEngine_TriggeredStopRefused(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+
+	fixture.mockSnapshotStore.EXPECT().
+		LoadStore(gomock.Any()).
+		Return(nil, nil).
+		Times(1)
+	orderEvents := fixture.recordOrderEvents()
+
+	engine := createTestEngine(fixture)
+
+	fixture.orderbook.PlaceLimitOrder(99, orderbookv1.NewOrder("bidder1", 1, true, "bid1"))
+	fixture.orderbook.PlaceLimitOrder(97, orderbookv1.NewOrder("bidder2", 5, true, "bid2"))
+
+	// Once triggered, the post-only sell at 97 would take the 97 bid
+	stopLimit := createTestOrderRequest("stopper", orderbookv1.OrderTypeStopLimit, false, 2, 97, 1)
+	stopLimit.StopPrice = 99
+	stopLimit.PostOnly = true
+	require.NoError(t, engine.processOrder(&stopLimit))
+
+	marketSell := createTestOrderRequest("seller", orderbookv1.OrderTypeMarket, false, 1, 0, 2)
+	require.NoError(t, engine.processOrder(&marketSell))
+
+	assert.False(t, fixture.stopBook.HasStopOrder(stopLimit.OrderID))
+	assert.NotContains(t, fixture.orderbook.Orders, stopLimit.OrderID)
+
+	events := orderEvents.ofOrder(stopLimit.OrderID)
+	require.Len(t, events, 2)
+	assert.Equal(t, string(orderpublisherv1.EventTypeAccepted), events[0].EventType)
+	cancelled := events[1]
+	assert.Equal(t, string(orderpublisherv1.EventTypeCancelled), cancelled.EventType)
+	assert.Equal(t, orderbookv1.ErrPostOnlyWouldCross.Error(), cancelled.Reason)
+	assert.Equal(t, 97.0, cancelled.Price)
+	assert.Equal(t, 2.0, cancelled.Size)
+	assert.Equal(t, 0.0, cancelled.RemainingSize)
+}
+
+// Test that the expiry sweep removes expired GTD stop orders only
+func TestEngine_ExpireStopOrders(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+
+	fixture.mockSnapshotStore.EXPECT().
+		LoadStore(gomock.Any()).
+		Return(nil, nil).
+		Times(1)
+	orderEvents := fixture.recordOrderEvents()
+
+	engine := createTestEngine(fixture)
+
+	expireAt := time.Now().Add(time.Minute)
+
+	gtdStop := createTestOrderRequest("user1", orderbookv1.OrderTypeStopLimit, true, 3, 101, 1)
+	gtdStop.StopPrice = 100
+	gtdStop.TimeInForce = orderbookv1.TimeInForceGTD
+	gtdStop.ExpireAt = expireAt.UnixNano()
+	require.NoError(t, engine.processOrder(&gtdStop))
+
+	gtcStop := createTestOrderRequest("user2", orderbookv1.OrderTypeStop, true, 1, 0, 2)
+	gtcStop.StopPrice = 100
+	require.NoError(t, engine.processOrder(&gtcStop))
+
+	engine.expireOrders(expireAt.Add(-time.Second))
+	assert.True(t, fixture.stopBook.HasStopOrder(gtdStop.OrderID))
+
+	engine.expireOrders(expireAt)
+	assert.False(t, fixture.stopBook.HasStopOrder(gtdStop.OrderID))
+	assert.True(t, fixture.stopBook.HasStopOrder(gtcStop.OrderID))
+
+	cancelled := orderEvents.ofType(orderpublisherv1.EventTypeCancelled)
+	require.Len(t, cancelled, 1)
+	assert.Equal(t, gtdStop.OrderID, cancelled[0].OrderID)
+	assert.Equal(t, orderbookv1.ErrOrderExpired.Error(), cancelled[0].Reason)
+	assert.Equal(t, 101.0, cancelled[0].Price)
+	assert.Equal(t, 3.0, cancelled[0].Size)
+	assert.Equal(t, 0.0, cancelled[0].RemainingSize)
+}
+
+// Test that a replace order amends a resting order and publishes the result
+func TestEngine_ProcessReplaceOrder(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+
+	fixture.mockSnapshotStore.EXPECT().
+		LoadStore(gomock.Any()).
+		Return(nil, nil).
+		Times(1)
+
+	engine := createTestEngine(fixture)
+
+	fixture.orderbook.PlaceLimitOrder(50000, orderbookv1.NewOrder("maker", 3, true, "bid1"))
+
+	testCases := []struct {
+		name          string
+		userID        string
+		orderID       string
+		price         int64
+		size          int64
+		expectedEvent orderpublisherv1.EventType
+	}{
+		{
+			name:          "amend resting order",
+			userID:        "maker",
+			orderID:       "bid1",
+			price:         50100,
+			size:          2,
+			expectedEvent: orderpublisherv1.EventTypeReplaced,
+		},
+		{
+			name:          "amend unknown order",
+			userID:        "maker",
+			orderID:       "missing",
+			price:         50100,
+			size:          2,
+			expectedEvent: orderpublisherv1.EventTypeRejected,
+		},
+		{
+			name:          "amend order of another user",
+			userID:        "intruder",
+			orderID:       "bid1",
+			price:         49000,
+			size:          1,
+			expectedEvent: orderpublisherv1.EventTypeRejected,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			orderRequest := createTestOrderRequest(tc.userID, orderbookv1.OrderTypeReplace, true, tc.size, tc.price, int64(i+1))
+			orderRequest.OrderID = tc.orderID
+
+			fixture.mockOrderPublisher.EXPECT().
+				PublishOrderEvent(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, orderEvent *pb.OrderEventPayload) error {
+					assert.Equal(t, string(tc.expectedEvent), orderEvent.EventType)
+					assert.Equal(t, tc.orderID, orderEvent.OrderID)
+					assert.Equal(t, float64(tc.price), orderEvent.Price)
+					assert.Equal(t, float64(tc.size), orderEvent.Size)
+					if tc.expectedEvent == orderpublisherv1.EventTypeRejected {
+						assert.Equal(t, string(orderbookv1.RejectCodeOrderNotFound), orderEvent.ReasonCode)
+					}
+					return nil
+				}).
+				Times(1)
+
+			assert.NoError(t, engine.processOrder(&orderRequest))
+		})
+	}
+
+	assert.NotContains(t, fixture.orderbook.BidLimits, int64(50000))
+	require.Contains(t, fixture.orderbook.BidLimits, int64(50100))
+	assert.Equal(t, int64(2), fixture.orderbook.BidLimits[50100].GetTotalVolume())
+}
+
+// Test that orders cancelled by self-trade prevention are published as order events
+func TestEngine_ProcessSelfTradePrevention(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+
+	fixture.mockSnapshotStore.EXPECT().
+		LoadStore(gomock.Any()).
+		Return(nil, nil).
+		Times(1)
+
+	engine := createTestEngine(fixture)
+
+	fixture.orderbook.PlaceLimitOrder(50000, orderbookv1.NewOrder("trader", 2, false, "resting"))
+
+	orderRequest := createTestOrderRequest("trader", orderbookv1.OrderTypeLimit, true, 1, 50000, 1)
+	orderRequest.SelfTradePrevention = orderbookv1.SelfTradePreventionCancelBoth
+
+	orderEvents := fixture.recordOrderEvents()
+
+	require.NoError(t, engine.processOrder(&orderRequest))
+
+	assert.Equal(t, int64(0), engine.GetTotalMatches())
+	assert.Equal(t, []orderpublisherv1.EventType{
+		orderpublisherv1.EventTypeAccepted,
+		orderpublisherv1.EventTypeCancelled,
+		orderpublisherv1.EventTypeCancelled,
+	}, orderEvents.types())
+
+	events := orderEvents.ofType(orderpublisherv1.EventTypeCancelled)
+	require.Len(t, events, 2)
+	for _, event := range events {
+		assert.Equal(t, string(orderpublisherv1.EventTypeCancelled), event.EventType)
+		assert.Equal(t, orderbookv1.ErrSelfTradePrevented.Error(), event.Reason)
+		assert.Equal(t, fixture.config.Pair, event.Symbol)
+	}
+	assert.Equal(t, "resting", events[0].OrderID)
+	assert.Equal(t, 2.0, events[0].Size)
+	assert.Equal(t, orderRequest.OrderID, events[1].OrderID)
+	assert.Equal(t, 1.0, events[1].Size)
+	assert.Empty(t, fixture.orderbook.Orders)
+}
+
+// Test that an order decrement_and_cancel only reduces gets a partial cancel
+func TestEngine_ProcessSelfTradeDecrement(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+
+	fixture.mockSnapshotStore.EXPECT().
+		LoadStore(gomock.Any()).
+		Return(nil, nil).
+		Times(1)
+
+	engine := createTestEngine(fixture)
+	orderEvents := fixture.recordOrderEvents()
+
+	resting := createTestOrderRequest("trader", orderbookv1.OrderTypeLimit, false, 5, 50000, 1)
+	require.NoError(t, engine.processOrder(&resting))
+
+	incoming := createTestOrderRequest("trader", orderbookv1.OrderTypeLimit, true, 2, 50000, 2)
+	incoming.SelfTradePrevention = orderbookv1.SelfTradePreventionDecrementAndCancel
+	require.NoError(t, engine.processOrder(&incoming))
+
+	// The resting order keeps 3 after giving up 2
+	events := orderEvents.ofOrder(resting.OrderID)
+	require.Len(t, events, 3)
+	reduced := events[2]
+	assert.Equal(t, string(orderpublisherv1.EventTypeCancelled), reduced.EventType)
+	assert.Equal(t, orderbookv1.ErrSelfTradePrevented.Error(), reduced.Reason)
+	assert.Equal(t, 2.0, reduced.Size)
+	assert.Equal(t, 3.0, reduced.RemainingSize)
+	assert.Equal(t, 50000.0, reduced.Price)
+
+	events = orderEvents.ofOrder(incoming.OrderID)
+	require.Len(t, events, 2)
+	assert.Equal(t, string(orderpublisherv1.EventTypeCancelled), events[1].EventType)
+	assert.Equal(t, 2.0, events[1].Size)
+	assert.Equal(t, 0.0, events[1].RemainingSize)
+
+	assert.Equal(t, int64(0), engine.GetTotalMatches())
+	assert.Equal(t, int64(3), fixture.orderbook.AskTotalVolume())
+}
+
+// Test that orders breaking the instrument specification are rejected with structured reasons
+func TestEngine_ProcessInstrumentSpecRejection(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+
+	fixture.config.PriceDecimals = 2
+	fixture.config.SizeDecimals = 8
+	fixture.config.TickSize = 0.5
+	fixture.config.LotSize = 0.001
+	fixture.config.MinNotional = 10
+
+	fixture.mockSnapshotStore.EXPECT().
+		LoadStore(gomock.Any()).
+		Return(nil, nil).
+		Times(1)
+
+	engine := createTestEngine(fixture)
+
+	testCases := []struct {
+		name        string
+		price       int64
+		size        int64
+		reasonCode  orderbookv1.RejectCode
+		reasonField string
+		reasonLimit string
+	}{
+		{
+			name:        "price off the tick grid",
+			price:       5000001,
+			size:        100000,
+			reasonCode:  orderbookv1.RejectCodeTickSize,
+			reasonField: "price",
+			reasonLimit: "0.50",
+		},
+		{
+			name:        "size off the lot grid",
+			price:       5000000,
+			size:        100001,
+			reasonCode:  orderbookv1.RejectCodeLotSize,
+			reasonField: "size",
+			reasonLimit: "0.00100000",
+		},
+		{
+			name:        "notional below minimum",
+			price:       500000,
+			size:        100000,
+			reasonCode:  orderbookv1.RejectCodeMinNotional,
+			reasonField: "notional",
+			reasonLimit: "10.00",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			orderRequest := createTestOrderRequest("maker", orderbookv1.OrderTypeLimit, true, tc.size, tc.price, int64(i+1))
+
+			fixture.mockOrderPublisher.EXPECT().
+				PublishOrderEvent(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, orderEvent *pb.OrderEventPayload) error {
+					assert.Equal(t, string(orderpublisherv1.EventTypeRejected), orderEvent.EventType)
+					assert.Equal(t, orderRequest.OrderID, orderEvent.OrderID)
+					assert.Equal(t, "maker", orderEvent.UserID)
+					assert.Equal(t, string(tc.reasonCode), orderEvent.ReasonCode)
+					assert.Equal(t, tc.reasonField, orderEvent.ReasonField)
+					assert.Equal(t, tc.reasonLimit, orderEvent.ReasonLimit)
+					assert.NotEmpty(t, orderEvent.Reason)
+					return nil
+				}).
+				Times(1)
+
+			assert.NoError(t, engine.processOrder(&orderRequest))
+			assert.NotContains(t, fixture.orderbook.Orders, orderRequest.OrderID)
+		})
+	}
+
+	// A valid order passes the checks and rests
+	orderEvents := fixture.recordOrderEvents()
+	orderRequest := createTestOrderRequest("maker", orderbookv1.OrderTypeLimit, true, 100000000, 5000000, 10)
+	require.NoError(t, engine.processOrder(&orderRequest))
+	assert.Contains(t, fixture.orderbook.Orders, orderRequest.OrderID)
+	assert.Equal(t, []orderpublisherv1.EventType{
+		orderpublisherv1.EventTypeAccepted,
+		orderpublisherv1.EventTypeRested,
+	}, orderEvents.types())
+}
+
+// Test that match events carry exact units at the pair precision
+func TestEngine_MatchEventUnits(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+
+	fixture.config.PriceDecimals = 2
+	fixture.config.SizeDecimals = 8
+
+	fixture.mockSnapshotStore.EXPECT().
+		LoadStore(gomock.Any()).
+		Return(nil, nil).
+		Times(1)
+
+	var published *pb.MatchEventPayload
+	fixture.mockMatchPublisher.EXPECT().
+		PublishMatchEvent(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, matchEvent *pb.MatchEventPayload) error {
+			published = matchEvent
+			return nil
+		}).
+		Times(1)
+	fixture.recordOrderEvents()
+
+	engine := NewEngine(
+		fixture.orderbook,
+		fixture.stopBook,
+		fixture.mockOrderReader,
+		fixture.mockSnapshotStore,
+		fixture.mockMatchPublisher,
+		fixture.mockOrderPublisher,
+		fixture.logger,
+		fixture.config,
+	)
+	engine.ctx = context.Background()
+
+	// 0.1 BTC at 50000.01
+	fixture.orderbook.PlaceLimitOrder(5000001, orderbookv1.NewOrder("seller", 10000000, false, "sell1"))
+
+	buyOrder := createTestOrderRequest("buyer", orderbookv1.OrderTypeMarket, true, 10000000, 0, 1)
+	require.NoError(t, engine.applyOrder(&buyOrder))
+
+	require.NotNil(t, published)
+	assert.Equal(t, int64(5000001), published.PriceUnits)
+	assert.Equal(t, int64(10000000), published.VolumeUnits)
+	assert.Equal(t, int32(2), published.PriceDecimals)
+	assert.Equal(t, int32(8), published.VolumeDecimals)
+	assert.Equal(t, 50000.01, published.Price)
+	assert.Equal(t, 0.1, published.Volume)
+	assert.Equal(t, int64(5000001), engine.GetLastTradePrice())
+}
+
+// Test that every trade of a sweep gets its own ID and the next sequence, and that the
+// sequence carries over a snapshot
+func TestEngine_TradeSequence(t *testing.T) {
+	newEngine := func(t *testing.T, snapshot *snapshotv1.Snapshot) (*Engine, *testFixture, *[]*pb.MatchEventPayload) {
+		fixture := setupTestFixture(t)
+		t.Cleanup(fixture.teardown)
+
+		fixture.mockSnapshotStore.EXPECT().
+			LoadStore(gomock.Any()).
+			Return(snapshot, nil).
+			Times(1)
+		fixture.recordOrderEvents()
+
+		var published []*pb.MatchEventPayload
+		fixture.mockMatchPublisher.EXPECT().
+			PublishMatchEvent(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, matchEvent *pb.MatchEventPayload) error {
+				published = append(published, matchEvent)
+				return nil
+			}).
+			AnyTimes()
+
+		engine := NewEngine(fixture.orderbook, fixture.stopBook, fixture.mockOrderReader, fixture.mockSnapshotStore,
+			fixture.mockMatchPublisher, fixture.mockOrderPublisher, fixture.logger, fixture.config)
+		engine.ctx = context.Background()
+		return engine, fixture, &published
+	}
+
+	engine, fixture, published := newEngine(t, nil)
+
+	fixture.orderbook.PlaceLimitOrder(50000, orderbookv1.NewOrder("seller1", 1, false, "sell1"))
+	fixture.orderbook.PlaceLimitOrder(50100, orderbookv1.NewOrder("seller2", 1, false, "sell2"))
+	fixture.orderbook.PlaceLimitOrder(50200, orderbookv1.NewOrder("seller3", 1, false, "sell3"))
+
+	sweep := createTestOrderRequest("buyer", orderbookv1.OrderTypeMarket, true, 3, 0, 1)
+	sweep.Timestamp = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).UnixNano()
+	require.NoError(t, engine.applyOrder(&sweep))
+
+	require.Len(t, *published, 3)
+	for i, matchEvent := range *published {
+		sequence := int64(i + 1)
+		assert.Equal(t, sequence, matchEvent.TradeSequence)
+		assert.Equal(t, fmt.Sprintf("BTC-USD-%d", sequence), matchEvent.MatchID)
+		assert.Equal(t, "buyer", matchEvent.TakerUserID)
+		assert.Equal(t, fmt.Sprintf("seller%d", i+1), matchEvent.MakerUserID)
+		assert.Equal(t, sweep.Timestamp, matchEvent.Timestamp.AsTime().UnixNano())
+	}
+	assert.Equal(t, int64(3), engine.GetTradeSequence())
+
+	snapshot := engine.Snapshot()
+	assert.Equal(t, int64(3), snapshot.OrderBookSnapshot.TradeSequence)
+
+	// A restored engine continues the sequence where the snapshot left it
+	restored, fixture, published := newEngine(t, snapshot)
+	assert.Equal(t, int64(3), restored.GetTradeSequence())
+
+	fixture.orderbook.PlaceLimitOrder(50000, orderbookv1.NewOrder("buyer2", 1, true, "bid1"))
+	sell := createTestOrderRequest("seller4", orderbookv1.OrderTypeMarket, false, 1, 0, 2)
+	require.NoError(t, restored.applyOrder(&sell))
+
+	require.Len(t, *published, 1)
+	assert.Equal(t, int64(4), (*published)[0].TradeSequence)
+	assert.Equal(t, "BTC-USD-4", (*published)[0].MatchID)
+	assert.Equal(t, "buyer2", (*published)[0].MakerUserID)
+	assert.Equal(t, "seller4", (*published)[0].TakerUserID)
+}
+
+// Test that a snapshot written at another precision is not restored
+func TestEngine_LoadSnapshotScaleMismatch(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+
+	fixture.mockSnapshotStore.EXPECT().
+		LoadStore(gomock.Any()).
+		Return(nil, nil).
+		Times(1)
+
+	engine := createTestEngine(fixture)
+
+	fixture.mockSnapshotStore.EXPECT().
+		LoadStore(gomock.Any()).
+		Return(&snapshotv1.Snapshot{
+			Version:       snapshotv1.CurrentVersion,
+			PriceDecimals: 2,
+			SizeDecimals:  8,
+		}, nil).
+		Times(1)
+
+	err := engine.loadSnapshot(context.Background())
+	assert.ErrorIs(t, err, orderbookv1.ErrInvalidScale)
+}
+
+// Test that a snapshot the book cannot restore fails the load
+func TestEngine_LoadSnapshotRestoreFailure(t *testing.T) {
+	fixture := setupTestFixture(t)
+	defer fixture.teardown()
+
+	fixture.mockSnapshotStore.EXPECT().
+		LoadStore(gomock.Any()).
+		Return(nil, nil).
+		Times(1)
+
+	engine := createTestEngine(fixture)
+	offset := engine.GetOrderOffset()
+
+	fixture.mockSnapshotStore.EXPECT().
+		LoadStore(gomock.Any()).
+		Return(&snapshotv1.Snapshot{
+			Version:       snapshotv1.CurrentVersion,
+			PriceDecimals: fixture.config.PriceDecimals,
+			SizeDecimals:  fixture.config.SizeDecimals,
+			OrderOffset:   7,
+			OrderBookSnapshot: snapshotv1.OrderBookSnapshot{
+				Orders: []snapshotv1.BookOrder{{OrderID: "alice-1", UserID: "alice", Price: 100, Size: 0, Bid: true}},
+			},
+		}, nil).
+		Times(1)
+
+	err := engine.loadSnapshot(context.Background())
+	assert.ErrorIs(t, err, orderbookv1.ErrInvalidSize)
+	assert.Equal(t, offset, engine.GetOrderOffset())
+}
+
 func TestEngine_Start(t *testing.T) {
 	type fields struct {
 		orderbook           orderbookv1.Orderbook
@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/muhammadchandra19/exchange/pkg/logger"
+	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
+	deadletterv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/dead-letter/v1"
+	matchpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/match-publisher/v1"
+	"github.com/segmentio/kafka-go"
+)
+
+// errNotApplied marks the failure of an input that could not be journaled, so it did not
+// change the book and may be applied again.
+var errNotApplied = errors.New("input not applied")
+
+// publishFailure is the failure of an input the book took while the publisher refused
+// some of its order events. Only those events may be retried.
+type publishFailure struct {
+	events []*pb.OrderEventPayload // Refused events, oldest first
+	err    error
+}
+
+// Error implements the error interface.
+func (f *publishFailure) Error() string {
+	return f.err.Error()
+}
+
+// Unwrap returns the error the input failed with.
+func (f *publishFailure) Unwrap() error {
+	return f.err
+}
+
+// publishAgain publishes the order events of a failed input that were refused, oldest
+// first, keeping those that are refused again for the next attempt. They were already
+// journaled with the input.
+func (e *Engine) publishAgain(failure *publishFailure) error {
+	for len(failure.events) > 0 {
+		if err := e.orderPublisher.PublishOrderEvent(e.ctx, failure.events[0]); err != nil {
+			return err
+		}
+		failure.events = failure.events[1:]
+	}
+	return nil
+}
+
+// handleFailure applies the error policy of the class to a message that failed with err.
+// retry redoes what failed and is nil when nothing can be redone, in which case a retry
+// policy dead-letters the message at once. A nil result lets the message be committed;
+// ErrHalted, ErrMatchesPending or a stopped engine leave it unconsumed.
+func (e *Engine) handleFailure(class deadletterv1.ErrorClass, msg kafka.Message, err error, retry func() error) error {
+	attempts := 1
+	action := e.errorPolicy.Action(class)
+
+	e.logger.ErrorContext(e.ctx, err,
+		logger.Field{Key: "action", Value: "handle_" + string(class) + "_failure"},
+		logger.Field{Key: "policy", Value: action},
+		logger.Field{Key: "offset", Value: msg.Offset},
+	)
+
+	if action == deadletterv1.ActionRetry {
+		backoff := e.errorPolicy.RetryBackoff
+		for retry != nil && attempts <= e.errorPolicy.MaxRetries {
+			select {
+			case <-e.ctx.Done():
+				return e.ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+
+			attempts++
+			err = retry()
+			if err == nil || errors.Is(err, matchpublisherv1.ErrMatchesPending) {
+				return err
+			}
+			e.logger.ErrorContext(e.ctx, err,
+				logger.Field{Key: "action", Value: "retry_order_message"},
+				logger.Field{Key: "attempt", Value: attempts},
+				logger.Field{Key: "offset", Value: msg.Offset},
+			)
+		}
+		action = deadletterv1.ActionDeadLetter
+	}
+
+	switch action {
+	case deadletterv1.ActionDeadLetter:
+		return e.deadLetter(deadletterv1.NewMessage(e.config.Pair, class, msg, err, attempts, time.Now().UnixNano()))
+	case deadletterv1.ActionHalt:
+		return fmt.Errorf("%w: %s failure at offset %d: %v", deadletterv1.ErrHalted, class, msg.Offset, err)
+	}
+	return nil
+}
+
+// deadLetter publishes the message to the dead-letter topic, retrying until the publisher
+// confirms it so the failed order is never consumed without a trace.
+func (e *Engine) deadLetter(message *deadletterv1.Message) error {
+	if e.deadLetters == nil {
+		e.logger.Warn("No dead-letter publisher, skipping failed order message",
+			logger.Field{Key: "offset", Value: message.Offset},
+			logger.Field{Key: "error", Value: message.Error},
+		)
+		return nil
+	}
+
+	for {
+		err := e.deadLetters.PublishDeadLetter(e.ctx, message)
+		if err == nil {
+			return nil
+		}
+		e.logger.ErrorContext(e.ctx, err,
+			logger.Field{Key: "action", Value: "publish_dead_letter"},
+			logger.Field{Key: "offset", Value: message.Offset},
+		)
+
+		select {
+		case <-e.ctx.Done():
+			return e.ctx.Err()
+		case <-time.After(e.publishRetryInterval):
+		}
+	}
+}
+
+// ErrPanicked is the halt error of a pair whose processing panicked.
+var ErrPanicked = errors.New("pair processing panicked")
+
+// halt records the failure that stopped the pair from consuming orders. The first
+// failure is kept.
+func (e *Engine) halt(err error) {
+	e.mu.Lock()
+	if e.haltErr == nil {
+		e.haltErr = err
+	}
+	e.mu.Unlock()
+
+	e.logger.ErrorContext(e.ctx, err,
+		logger.Field{Key: "action", Value: "halt_pair"},
+		logger.Field{Key: "pair", Value: e.config.Pair},
+	)
+}
+
+// recoverPanic turns a panic of one of the engine's goroutines into a halt of the pair
+// and stops the engine, so the panic does not take down the other pairs of the process.
+// It must be deferred directly by the goroutine.
+func (e *Engine) recoverPanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	e.halt(fmt.Errorf("%w: %v", ErrPanicked, r))
+	e.cancel()
+}
+
+// Done returns a channel that is closed when the engine stops consuming orders, because
+// it was stopped or the pair halted. HaltError tells the two apart.
+func (e *Engine) Done() <-chan struct{} {
+	return e.done
+}
+
+// HaltError returns the failure that halted the pair, nil while it consumes orders.
+func (e *Engine) HaltError() error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.haltErr
+}
@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"fmt"
+
+	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
+	feev1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/fee/v1"
+	matchpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/match-publisher/v1"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/pkg/config"
+)
+
+// NewFeeSchedule creates the fee schedule of the configured pair. storedTiers are the user
+// tiers of the tier store, if any, and take precedence over the configured ones.
+func NewFeeSchedule(config *config.Config, storedTiers map[string]string) (*feev1.Schedule, error) {
+	pairRates, err := config.FeeConfig.PairRates(config.Pair)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := feev1.NewRates(pairRates.MakerBps, pairRates.TakerBps)
+	if err != nil {
+		return nil, fmt.Errorf("pair %s: %w", config.Pair, err)
+	}
+
+	tierRates, err := config.FeeConfig.TierRates()
+	if err != nil {
+		return nil, err
+	}
+	tiers := make(map[string]feev1.Rates, len(tierRates))
+	for name, r := range tierRates {
+		if tiers[name], err = feev1.NewRates(r.MakerBps, r.TakerBps); err != nil {
+			return nil, fmt.Errorf("tier %s: %w", name, err)
+		}
+	}
+
+	userTiers, err := config.FeeConfig.UserTiers()
+	if err != nil {
+		return nil, err
+	}
+	for userID, tier := range storedTiers {
+		userTiers[userID] = tier
+	}
+
+	return feev1.NewSchedule(config.Pair, rates, tiers, userTiers)
+}
+
+// stampFees sets the fees the maker and the taker of a trade pay on its match event.
+func (e *Engine) stampFees(matchEvent *pb.MatchEventPayload) {
+	makerFee, takerFee := e.fees.Fees(matchEvent.PriceUnits, matchEvent.VolumeUnits, e.scale.SizeDecimals, matchEvent.MakerUserID, matchEvent.TakerUserID)
+	matchEvent.MakerFee = matchpublisherv1.CreateTradeFee(makerFee, e.scale)
+	matchEvent.TakerFee = matchpublisherv1.CreateTradeFee(takerFee, e.scale)
+}
@@ -1,17 +1,42 @@
 package engine
 
-import "time"
+import (
+	"time"
+
+	deadletterv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/dead-letter/v1"
+	feev1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/fee/v1"
+	journalv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/journal/v1"
+)
 
 // Options represents configuration options for the Engine.
 type Options struct {
-	SnapshotInterval    time.Duration
-	SnapshotOffsetDelta int64
+	SnapshotInterval     time.Duration
+	SnapshotOffsetDelta  int64
+	ExpirySweepInterval  time.Duration // How often expired GTD orders are removed from the book
+	PublishRetryInterval time.Duration // Wait before publishing a match event the publisher did not confirm again
+
+	// Journal records every accepted order and the events it produced, and is replayed
+	// on start. Nil disables journaling.
+	Journal journalv1.Journal
+
+	// ErrorPolicy decides what happens to order messages that cannot be decoded or
+	// processed. DeadLetters receives the messages it dead-letters; with no publisher
+	// they are logged and skipped.
+	ErrorPolicy deadletterv1.Policy
+	DeadLetters deadletterv1.Publisher
+
+	// FeeTiers assigns users to the fee tiers of the configuration when the engine opens,
+	// overriding the configured users. Nil uses the configured users only.
+	FeeTiers feev1.TierStore
 }
 
 // DefaultEngineOptions returns the default engine options.
 func DefaultEngineOptions() *Options {
 	return &Options{
-		SnapshotInterval:    30 * time.Second,
-		SnapshotOffsetDelta: 1000,
+		SnapshotInterval:     30 * time.Second,
+		SnapshotOffsetDelta:  1000,
+		ExpirySweepInterval:  1 * time.Second,
+		PublishRetryInterval: 100 * time.Millisecond,
+		ErrorPolicy:          deadletterv1.DefaultPolicy(),
 	}
 }
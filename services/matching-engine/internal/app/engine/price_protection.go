@@ -0,0 +1,179 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/muhammadchandra19/exchange/pkg/logger"
+	journalv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/journal/v1"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+	snapshotv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/snapshot/v1"
+)
+
+// referencePrice returns the price the price band is centred on, zero before the pair's
+// first trade. Caller must hold the apply lock.
+func (e *Engine) referencePrice() int64 {
+	if e.priceBand.Reference != orderbookv1.PriceReferenceMovingAverage {
+		return e.getLastTradePrice()
+	}
+	if len(e.averagePrices) == 0 {
+		return 0
+	}
+
+	sum := int64(0)
+	for _, price := range e.averagePrices {
+		sum += price
+	}
+	return sum / int64(len(e.averagePrices))
+}
+
+// checkPriceBand returns a violation if the price of a request field is outside the price
+// band. Caller must hold the apply lock.
+func (e *Engine) checkPriceBand(field string, price int64) error {
+	if !e.priceBand.IsEnabled() {
+		return nil
+	}
+	return e.priceBand.Check(field, price, e.referencePrice(), e.scale)
+}
+
+// protectionPrice returns the worst price a market order on the given side may fill at,
+// zero for none. Caller must hold the apply lock.
+func (e *Engine) protectionPrice(bid bool) int64 {
+	if !e.priceBand.IsEnabled() {
+		return 0
+	}
+	return e.priceBand.ProtectionPrice(bid, e.referencePrice())
+}
+
+// recordTrade adds a trade made at timestamp to the moving average and to the circuit
+// breaker's window, and halts the pair for the cool-down if the price moved more than the
+// breaker allows within the window. Caller must hold the apply lock.
+func (e *Engine) recordTrade(price, timestamp int64) {
+	if e.priceBand.Reference == orderbookv1.PriceReferenceMovingAverage {
+		e.averagePrices = append(e.averagePrices, price)
+		if extra := len(e.averagePrices) - e.priceBand.AverageTrades; extra > 0 {
+			e.averagePrices = e.averagePrices[extra:]
+		}
+	}
+
+	if !e.circuitBreaker.IsEnabled() || e.getMarketState() != orderbookv1.MarketStateContinuous {
+		return
+	}
+
+	// The window keeps the falling highs and rising lows of its trades, so its first high
+	// and low are the highest and lowest price traded within it
+	print := orderbookv1.TradePrint{Price: price, Timestamp: timestamp}
+	cutoff := timestamp - int64(e.circuitBreaker.Window)
+	e.breakerHighs = pushTradePrint(e.breakerHighs, print, cutoff, func(last orderbookv1.TradePrint) bool { return last.Price <= price })
+	e.breakerLows = pushTradePrint(e.breakerLows, print, cutoff, func(last orderbookv1.TradePrint) bool { return last.Price >= price })
+
+	// The move is measured from whichever extreme came first
+	high, low := e.breakerHighs[0], e.breakerLows[0]
+	from, to := low, high
+	if high.Timestamp < low.Timestamp {
+		from, to = high, low
+	}
+	if !e.circuitBreaker.Exceeds(from.Price, to.Price) {
+		return
+	}
+
+	e.logger.Warn("Circuit breaker tripped",
+		logger.Field{Key: "pair", Value: e.config.Pair},
+		logger.Field{Key: "from", Value: from.Price},
+		logger.Field{Key: "to", Value: to.Price},
+		logger.Field{Key: "coolDown", Value: e.circuitBreaker.CoolDown.String()},
+	)
+	if err := e.changeMarketState(orderbookv1.MarketStateHalted, timestamp); err != nil {
+		e.logger.ErrorContext(e.ctx, err, logger.Field{
+			Key:   "action",
+			Value: "halt_on_circuit_breaker",
+		})
+		return
+	}
+	e.haltedUntil = timestamp + int64(e.circuitBreaker.CoolDown)
+
+	// Trading resumes with an empty window
+	e.breakerHighs, e.breakerLows = nil, nil
+}
+
+// pushTradePrint drops the prints older than cutoff from the front of window and the
+// prints the new one supersedes from its back, then appends the new print.
+func pushTradePrint(window []orderbookv1.TradePrint, print orderbookv1.TradePrint, cutoff int64, supersedes func(orderbookv1.TradePrint) bool) []orderbookv1.TradePrint {
+	for len(window) > 0 && window[0].Timestamp < cutoff {
+		window = window[1:]
+	}
+	for len(window) > 0 && supersedes(window[len(window)-1]) {
+		window = window[:len(window)-1]
+	}
+	return append(window, print)
+}
+
+// endCoolDown moves a pair the circuit breaker halted back to continuous trading once its
+// cool-down is over at now. The change is journaled like a market state message from the
+// order topic, without an order offset, so replay resumes the pair at the same point.
+func (e *Engine) endCoolDown(now time.Time) {
+	e.applyMu.Lock()
+	defer e.applyMu.Unlock()
+
+	if e.haltedUntil == 0 || now.UnixNano() < e.haltedUntil {
+		return
+	}
+
+	request := &orderbookv1.PlaceOrderRequest{
+		Type:        orderbookv1.OrderTypeMarketState,
+		MarketState: orderbookv1.MarketStateContinuous,
+		Offset:      -1,
+		Timestamp:   now.UnixNano(),
+	}
+	input := &journalv1.Entry{
+		Type:      journalv1.EntryTypeOrder,
+		Offset:    -1,
+		Timestamp: request.Timestamp,
+		Order:     request,
+	}
+	// Stops released by the resumption trade at once, so their matches are published too
+	if err := e.applyLocked(-1, input, func() error { return e.processOrder(request) }); err != nil {
+		e.logger.ErrorContext(e.ctx, err, logger.Field{
+			Key:   "action",
+			Value: "end_cool_down",
+		})
+	}
+}
+
+// snapshotPriceProtection copies the price protection state into a snapshot. Caller must
+// hold the apply lock.
+func (e *Engine) snapshotPriceProtection(snapshot *snapshotv1.OrderBookSnapshot) {
+	snapshot.AveragePrices = append([]int64(nil), e.averagePrices...)
+	snapshot.BreakerHighs = toSnapshotPrints(e.breakerHighs)
+	snapshot.BreakerLows = toSnapshotPrints(e.breakerLows)
+	snapshot.HaltedUntil = e.haltedUntil
+}
+
+// restorePriceProtection restores the price protection state of a snapshot.
+func (e *Engine) restorePriceProtection(snapshot *snapshotv1.OrderBookSnapshot) {
+	e.averagePrices = append([]int64(nil), snapshot.AveragePrices...)
+	e.breakerHighs = fromSnapshotPrints(snapshot.BreakerHighs)
+	e.breakerLows = fromSnapshotPrints(snapshot.BreakerLows)
+	e.haltedUntil = snapshot.HaltedUntil
+}
+
+func toSnapshotPrints(prints []orderbookv1.TradePrint) []snapshotv1.TradePrint {
+	if len(prints) == 0 {
+		return nil
+	}
+	result := make([]snapshotv1.TradePrint, len(prints))
+	for i, print := range prints {
+		result[i] = snapshotv1.TradePrint{Price: print.Price, Timestamp: print.Timestamp}
+	}
+	return result
+}
+
+func fromSnapshotPrints(prints []snapshotv1.TradePrint) []orderbookv1.TradePrint {
+	if len(prints) == 0 {
+		return nil
+	}
+	result := make([]orderbookv1.TradePrint, len(prints))
+	for i, print := range prints {
+		result[i] = orderbookv1.TradePrint{Price: print.Price, Timestamp: print.Timestamp}
+	}
+	return result
+}
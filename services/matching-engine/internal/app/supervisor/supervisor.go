@@ -0,0 +1,378 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/muhammadchandra19/exchange/pkg/logger"
+	deadletterv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/dead-letter/v1"
+)
+
+// State represents the lifecycle state of a supervised pair.
+type State string
+
+const (
+	// StateRunning is a pair consuming orders.
+	StateRunning State = "running"
+	// StateHalted is a pair that stopped consuming orders after a failure.
+	StateHalted State = "halted"
+	// StateStopped is a pair stopped on request.
+	StateStopped State = "stopped"
+)
+
+var (
+	ErrNotStarted  = errors.New("supervisor is not started")
+	ErrUnknownPair = errors.New("pair is not supervised")
+	ErrPairRunning = errors.New("pair is already running")
+	ErrPairStopped = errors.New("pair is not running")
+)
+
+// Engine is the matching engine of a single pair.
+type Engine interface {
+	// Start starts consuming orders, until ctx is cancelled or the engine is stopped
+	Start(ctx context.Context) error
+	// Stop stops the engine and waits for it to finish
+	Stop(ctx context.Context) error
+	// Done is closed when the engine stops consuming orders
+	Done() <-chan struct{}
+	// HaltError returns the failure that stopped the engine, nil if it was stopped
+	HaltError() error
+}
+
+// Factory creates a new engine for the pair, with its own order book and reader. It is
+// called again for every restart, so the pair starts over from its snapshot and journal.
+type Factory func(pair string) (Engine, error)
+
+// Options represents configuration options for the Supervisor.
+type Options struct {
+	RestartBackoff    time.Duration // Wait before restarting a failed pair, doubled for every failed restart
+	MaxRestartBackoff time.Duration // Longest wait between restarts
+	StopTimeout       time.Duration // Time a pair's engine gets to stop
+}
+
+// DefaultOptions returns the default supervisor options.
+func DefaultOptions() *Options {
+	return &Options{
+		RestartBackoff:    1 * time.Second,
+		MaxRestartBackoff: 1 * time.Minute,
+		StopTimeout:       30 * time.Second,
+	}
+}
+
+// PairStatus describes a supervised pair.
+type PairStatus struct {
+	Pair     string
+	State    State
+	Restarts int   // Automatic restarts since the pair was last started on request
+	Err      error // Failure that halted the pair, nil while it runs
+}
+
+// pair is the supervision state of a single pair. mu serializes starting and stopping
+// the pair, so a slow pair never blocks the others.
+type pair struct {
+	mu         sync.Mutex
+	name       string
+	engine     Engine
+	generation int // Incremented whenever the pair's engine is replaced or stopped
+	state      State
+	restarts   int
+	err        error
+}
+
+// Supervisor runs the engines of many pairs in one process. Each pair has its own engine
+// and goroutines; starting, stopping or restarting one pair leaves the others running.
+// A pair that fails is restarted with backoff, except when its error policy halted it,
+// which waits for an operator to restart it.
+type Supervisor struct {
+	factory Factory
+	logger  *logger.Logger
+	options *Options
+
+	ctx   context.Context
+	mu    sync.RWMutex
+	pairs map[string]*pair
+	wg    sync.WaitGroup
+}
+
+// NewSupervisor creates a new supervisor creating the engines of its pairs with factory.
+func NewSupervisor(factory Factory, logger *logger.Logger, options *Options) *Supervisor {
+	defaults := DefaultOptions()
+	if options == nil {
+		options = defaults
+	}
+	if options.RestartBackoff <= 0 {
+		options.RestartBackoff = defaults.RestartBackoff
+	}
+	if options.MaxRestartBackoff < options.RestartBackoff {
+		options.MaxRestartBackoff = options.RestartBackoff
+	}
+	if options.StopTimeout <= 0 {
+		options.StopTimeout = defaults.StopTimeout
+	}
+
+	return &Supervisor{
+		factory: factory,
+		logger:  logger,
+		options: options,
+		pairs:   make(map[string]*pair),
+	}
+}
+
+// Start starts the given pairs. The pairs run until ctx is cancelled or Stop is called.
+// A pair that fails to start is reported and retried in the background; the other pairs
+// start regardless.
+func (s *Supervisor) Start(ctx context.Context, pairs ...string) error {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+
+	var errs []error
+	for _, name := range pairs {
+		if err := s.StartPair(name); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StartPair starts a pair that is not running. A pair that fails to start is retried
+// in the background with backoff.
+func (s *Supervisor) StartPair(name string) error {
+	s.mu.Lock()
+	if s.ctx == nil {
+		s.mu.Unlock()
+		return ErrNotStarted
+	}
+	p, ok := s.pairs[name]
+	if !ok {
+		p = &pair{name: name}
+		s.pairs[name] = p
+	}
+	s.mu.Unlock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.state == StateRunning {
+		return ErrPairRunning
+	}
+	p.restarts = 0
+	err := s.launch(p)
+	if err != nil {
+		s.wg.Add(1)
+		go s.restartLoop(p, p.generation)
+	}
+	return err
+}
+
+// StopPair stops a pair. The pair keeps its place in the supervisor and can be started again.
+func (s *Supervisor) StopPair(name string) error {
+	p, err := s.pair(name)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.generation++
+	p.state = StateStopped
+	p.err = nil
+	return s.stopEngine(p)
+}
+
+// RestartPair stops a pair if it runs and starts it with a new engine.
+func (s *Supervisor) RestartPair(name string) error {
+	if err := s.StopPair(name); err != nil {
+		return err
+	}
+	return s.StartPair(name)
+}
+
+// Stop stops every pair and waits for the supervisor's goroutines until ctx is done.
+func (s *Supervisor) Stop(ctx context.Context) error {
+	var errs []error
+	for _, status := range s.Status() {
+		if err := s.StopPair(status.Pair); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", status.Pair, err))
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, ctx.Err())
+	}
+	return errors.Join(errs...)
+}
+
+// Status returns the state of every supervised pair, ordered by pair.
+func (s *Supervisor) Status() []PairStatus {
+	s.mu.RLock()
+	pairs := make([]*pair, 0, len(s.pairs))
+	for _, p := range s.pairs {
+		pairs = append(pairs, p)
+	}
+	s.mu.RUnlock()
+
+	statuses := make([]PairStatus, 0, len(pairs))
+	for _, p := range pairs {
+		p.mu.Lock()
+		statuses = append(statuses, PairStatus{Pair: p.name, State: p.state, Restarts: p.restarts, Err: p.err})
+		p.mu.Unlock()
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Pair < statuses[j].Pair })
+	return statuses
+}
+
+// Engine returns the engine the pair currently runs.
+func (s *Supervisor) Engine(name string) (Engine, error) {
+	p, err := s.pair(name)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.engine == nil {
+		return nil, fmt.Errorf("%w: %s is %s", ErrPairStopped, name, p.state)
+	}
+	return p.engine, nil
+}
+
+func (s *Supervisor) pair(name string) (*pair, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.pairs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPair, name)
+	}
+	return p, nil
+}
+
+// launch creates and starts a new engine for the pair and watches it. The caller holds p.mu.
+func (s *Supervisor) launch(p *pair) error {
+	p.generation++
+
+	engine, err := s.factory(p.name)
+	if err == nil {
+		if err = engine.Start(s.ctx); err != nil {
+			// Release what the engine opened
+			p.engine = engine
+			s.stopEngine(p)
+		}
+	}
+	if err != nil {
+		p.state = StateHalted
+		p.err = err
+		s.logger.Error(err,
+			logger.Field{Key: "action", Value: "start_pair"},
+			logger.Field{Key: "pair", Value: p.name},
+		)
+		return err
+	}
+
+	p.engine = engine
+	p.state = StateRunning
+	p.err = nil
+	s.logger.Info("Pair started", logger.Field{Key: "pair", Value: p.name})
+
+	s.wg.Add(1)
+	go s.watch(p, engine, p.generation)
+	return nil
+}
+
+// stopEngine stops the pair's engine, if it has one. The caller holds p.mu.
+func (s *Supervisor) stopEngine(p *pair) error {
+	if p.engine == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.options.StopTimeout)
+	defer cancel()
+
+	err := p.engine.Stop(ctx)
+	p.engine = nil
+	return err
+}
+
+// watch waits for the pair's engine to stop consuming orders and restarts it if it failed.
+func (s *Supervisor) watch(p *pair, engine Engine, generation int) {
+	defer s.wg.Done()
+
+	<-engine.Done()
+	err := engine.HaltError()
+	if err == nil {
+		return
+	}
+
+	p.mu.Lock()
+	if p.generation != generation {
+		// The pair was stopped or restarted on request meanwhile
+		p.mu.Unlock()
+		return
+	}
+	p.state = StateHalted
+	p.err = err
+	s.stopEngine(p)
+	p.mu.Unlock()
+
+	s.logger.Error(err,
+		logger.Field{Key: "action", Value: "pair_halted"},
+		logger.Field{Key: "pair", Value: p.name},
+	)
+
+	// A halt by the error policy needs an operator to look at the failed message
+	if errors.Is(err, deadletterv1.ErrHalted) {
+		return
+	}
+	s.wg.Add(1)
+	go s.restartLoop(p, generation)
+}
+
+// restartLoop starts a halted pair again with backoff, until it runs or the pair is
+// started, stopped or restarted on request.
+func (s *Supervisor) restartLoop(p *pair, generation int) {
+	defer s.wg.Done()
+
+	backoff := s.options.RestartBackoff
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		p.mu.Lock()
+		if p.generation != generation {
+			p.mu.Unlock()
+			return
+		}
+		err := s.launch(p)
+		if err == nil {
+			p.restarts++
+		}
+		generation = p.generation
+		p.mu.Unlock()
+
+		if err == nil {
+			return
+		}
+		backoff *= 2
+		if backoff > s.options.MaxRestartBackoff {
+			backoff = s.options.MaxRestartBackoff
+		}
+	}
+}
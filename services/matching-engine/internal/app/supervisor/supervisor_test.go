@@ -0,0 +1,225 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/muhammadchandra19/exchange/pkg/logger"
+	deadletterv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/dead-letter/v1"
+)
+
+// fakeEngine stands in for a pair's engine; fail halts it with the given error
+type fakeEngine struct {
+	pair    string
+	mu      sync.Mutex
+	done    chan struct{}
+	haltErr error
+	started bool
+	stopped bool
+}
+
+func (e *fakeEngine) Start(_ context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.started = true
+	return nil
+}
+
+func (e *fakeEngine) Stop(_ context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.stopped {
+		e.stopped = true
+		if e.haltErr == nil {
+			close(e.done)
+		}
+	}
+	return nil
+}
+
+func (e *fakeEngine) Done() <-chan struct{} { return e.done }
+
+func (e *fakeEngine) HaltError() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.haltErr
+}
+
+func (e *fakeEngine) fail(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.haltErr = err
+	close(e.done)
+}
+
+func (e *fakeEngine) isStopped() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.stopped
+}
+
+// fakeFactory creates fake engines and keeps every engine it created per pair
+type fakeFactory struct {
+	mu      sync.Mutex
+	engines map[string][]*fakeEngine
+	failing map[string]int // Number of upcoming creations to fail per pair
+}
+
+func newFakeFactory() *fakeFactory {
+	return &fakeFactory{engines: make(map[string][]*fakeEngine), failing: make(map[string]int)}
+}
+
+func (f *fakeFactory) create(pair string) (Engine, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failing[pair] > 0 {
+		f.failing[pair]--
+		return nil, fmt.Errorf("cannot open %s", pair)
+	}
+	engine := &fakeEngine{pair: pair, done: make(chan struct{})}
+	f.engines[pair] = append(f.engines[pair], engine)
+	return engine, nil
+}
+
+func (f *fakeFactory) created(pair string) []*fakeEngine {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*fakeEngine(nil), f.engines[pair]...)
+}
+
+func newTestSupervisor(t *testing.T, factory *fakeFactory) *Supervisor {
+	log, err := logger.NewLogger()
+	require.NoError(t, err)
+
+	s := NewSupervisor(factory.create, log, &Options{
+		RestartBackoff:    time.Millisecond,
+		MaxRestartBackoff: 4 * time.Millisecond,
+		StopTimeout:       time.Second,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		s.Stop(context.Background())
+	})
+	require.NoError(t, s.Start(ctx, "BTC-USD", "ETH-USD", "SOL-USD"))
+	return s
+}
+
+func stateOf(s *Supervisor, pair string) PairStatus {
+	for _, status := range s.Status() {
+		if status.Pair == pair {
+			return status
+		}
+	}
+	return PairStatus{}
+}
+
+func TestSupervisor_StartsEveryPair(t *testing.T) {
+	factory := newFakeFactory()
+	s := newTestSupervisor(t, factory)
+
+	statuses := s.Status()
+	require.Len(t, statuses, 3)
+	for i, pair := range []string{"BTC-USD", "ETH-USD", "SOL-USD"} {
+		assert.Equal(t, pair, statuses[i].Pair)
+		assert.Equal(t, StateRunning, statuses[i].State)
+		require.Len(t, factory.created(pair), 1)
+		assert.True(t, factory.created(pair)[0].started)
+	}
+
+	assert.ErrorIs(t, s.StartPair("BTC-USD"), ErrPairRunning)
+	assert.ErrorIs(t, s.StopPair("DOGE-USD"), ErrUnknownPair)
+}
+
+func TestSupervisor_StopAndRestartOnePair(t *testing.T) {
+	factory := newFakeFactory()
+	s := newTestSupervisor(t, factory)
+
+	require.NoError(t, s.StopPair("ETH-USD"))
+	assert.Equal(t, StateStopped, stateOf(s, "ETH-USD").State)
+	assert.True(t, factory.created("ETH-USD")[0].isStopped())
+	_, err := s.Engine("ETH-USD")
+	assert.ErrorIs(t, err, ErrPairStopped)
+
+	require.NoError(t, s.RestartPair("ETH-USD"))
+	assert.Equal(t, StateRunning, stateOf(s, "ETH-USD").State)
+	require.Len(t, factory.created("ETH-USD"), 2)
+	engine, err := s.Engine("ETH-USD")
+	require.NoError(t, err)
+	assert.Same(t, factory.created("ETH-USD")[1], engine)
+
+	// The other pairs keep their engines
+	for _, pair := range []string{"BTC-USD", "SOL-USD"} {
+		assert.Len(t, factory.created(pair), 1)
+		assert.False(t, factory.created(pair)[0].isStopped())
+		assert.Equal(t, StateRunning, stateOf(s, pair).State)
+	}
+}
+
+func TestSupervisor_RestartsFailedPair(t *testing.T) {
+	factory := newFakeFactory()
+	s := newTestSupervisor(t, factory)
+
+	// The pair panics and its first restart cannot open the engine
+	factory.mu.Lock()
+	factory.failing["BTC-USD"] = 1
+	factory.mu.Unlock()
+	failed := factory.created("BTC-USD")[0]
+	failed.fail(errors.New("pair processing panicked"))
+
+	require.Eventually(t, func() bool { return stateOf(s, "BTC-USD").State == StateRunning }, time.Second, time.Millisecond)
+	assert.True(t, failed.isStopped())
+	assert.Len(t, factory.created("BTC-USD"), 2)
+	assert.Equal(t, 1, stateOf(s, "BTC-USD").Restarts)
+
+	for _, pair := range []string{"ETH-USD", "SOL-USD"} {
+		assert.Len(t, factory.created(pair), 1)
+		assert.Equal(t, StateRunning, stateOf(s, pair).State)
+	}
+}
+
+func TestSupervisor_PolicyHaltWaitsForOperator(t *testing.T) {
+	factory := newFakeFactory()
+	s := newTestSupervisor(t, factory)
+
+	haltErr := fmt.Errorf("%w: process failure at offset 3", deadletterv1.ErrHalted)
+	factory.created("SOL-USD")[0].fail(haltErr)
+
+	require.Eventually(t, func() bool { return stateOf(s, "SOL-USD").State == StateHalted }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(t, factory.created("SOL-USD"), 1, "a policy halt is not restarted automatically")
+	assert.ErrorIs(t, stateOf(s, "SOL-USD").Err, deadletterv1.ErrHalted)
+
+	require.NoError(t, s.StartPair("SOL-USD"))
+	assert.Equal(t, StateRunning, stateOf(s, "SOL-USD").State)
+	assert.Nil(t, stateOf(s, "SOL-USD").Err)
+}
+
+func TestSupervisor_StopPairCancelsRestart(t *testing.T) {
+	factory := newFakeFactory()
+	s := newTestSupervisor(t, factory)
+
+	// Every restart fails until the pair is stopped on request
+	factory.mu.Lock()
+	factory.failing["ETH-USD"] = 1 << 30
+	factory.mu.Unlock()
+	factory.created("ETH-USD")[0].fail(errors.New("reader lost"))
+
+	require.Eventually(t, func() bool { return stateOf(s, "ETH-USD").State == StateHalted }, time.Second, time.Millisecond)
+	require.NoError(t, s.StopPair("ETH-USD"))
+	assert.Equal(t, StateStopped, stateOf(s, "ETH-USD").State)
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, StateStopped, stateOf(s, "ETH-USD").State)
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, s.Stop(stopCtx))
+}
@@ -0,0 +1,144 @@
+package deadletterv1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ErrorClass represents the kind of failure an order message ran into.
+type ErrorClass string
+
+const (
+	// ErrorClassDecode is a message that cannot be decoded as an order.
+	ErrorClassDecode ErrorClass = "decode"
+	// ErrorClassProcess is an order the engine failed to process.
+	ErrorClassProcess ErrorClass = "process"
+)
+
+// Action represents what the engine does with a message that failed.
+type Action string
+
+const (
+	// ActionSkip logs the failure and consumes the message.
+	ActionSkip Action = "skip"
+	// ActionRetry processes the message again with backoff, and dead-letters it once the
+	// retries are used up.
+	ActionRetry Action = "retry"
+	// ActionDeadLetter publishes the message to the dead-letter topic and consumes it.
+	ActionDeadLetter Action = "dlq"
+	// ActionHalt stops consuming orders of the pair without consuming the message.
+	ActionHalt Action = "halt"
+)
+
+var (
+	// ErrInvalidAction is returned for an action that is not known.
+	ErrInvalidAction = errors.New("invalid error policy action")
+	// ErrRetryNotAllowed is returned when decode failures are set to retry; decoding the same bytes again always fails.
+	ErrRetryNotAllowed = errors.New("decode failures cannot be retried")
+	// ErrHalted is returned when a failure halted the pair.
+	ErrHalted = errors.New("pair halted by error policy")
+)
+
+// Validate checks that the action is a known value.
+func (a Action) Validate() error {
+	switch a {
+	case ActionSkip, ActionRetry, ActionDeadLetter, ActionHalt:
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidAction, a)
+}
+
+// Policy decides what happens to a failed order message, per error class.
+type Policy struct {
+	Decode       Action        // Action for messages that cannot be decoded
+	Process      Action        // Action for orders the engine failed to process
+	MaxRetries   int           // Attempts after the first one before a retried message is dead-lettered
+	RetryBackoff time.Duration // Wait before the first retry, doubled for every following one
+}
+
+// DefaultPolicy returns a policy that logs and skips every failure.
+func DefaultPolicy() Policy {
+	return Policy{
+		Decode:       ActionSkip,
+		Process:      ActionSkip,
+		MaxRetries:   3,
+		RetryBackoff: 100 * time.Millisecond,
+	}
+}
+
+// Validate checks the actions of the policy.
+func (p Policy) Validate() error {
+	if err := p.Decode.Validate(); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+	if p.Decode == ActionRetry {
+		return ErrRetryNotAllowed
+	}
+	if err := p.Process.Validate(); err != nil {
+		return fmt.Errorf("process: %w", err)
+	}
+	return nil
+}
+
+// Action returns the action for the error class.
+func (p Policy) Action(class ErrorClass) Action {
+	if class == ErrorClassDecode {
+		return p.Decode
+	}
+	return p.Process
+}
+
+// Message is an order message moved to the dead-letter topic. It keeps the original
+// payload untouched so the message can be re-driven to the order topic as it was.
+type Message struct {
+	Pair      string     `json:"pair"`
+	Class     ErrorClass `json:"class"`
+	Error     string     `json:"error"`
+	Attempts  int        `json:"attempts"`  // Times the message was processed before it was dead-lettered
+	Topic     string     `json:"topic"`     // Topic the message was read from
+	Partition int        `json:"partition"` // Partition the message was read from
+	Offset    int64      `json:"offset"`    // Offset of the message in the partition
+	Key       []byte     `json:"key,omitempty"`
+	Payload   []byte     `json:"payload"`   // Original message value
+	Timestamp int64      `json:"timestamp"` // Time the message was dead-lettered, in Unix nanoseconds
+}
+
+// NewMessage creates the dead-letter message of an order message that failed with err.
+func NewMessage(pair string, class ErrorClass, msg kafka.Message, err error, attempts int, timestamp int64) *Message {
+	return &Message{
+		Pair:      pair,
+		Class:     class,
+		Error:     err.Error(),
+		Attempts:  attempts,
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       msg.Key,
+		Payload:   msg.Value,
+		Timestamp: timestamp,
+	}
+}
+
+// ToBytes converts the dead-letter message to a byte array.
+func ToBytes(message *Message) []byte {
+	json, err := json.Marshal(message)
+	if err != nil {
+		return nil
+	}
+
+	return json
+}
+
+// FromBytes converts a byte array to a dead-letter message.
+func FromBytes(data []byte) *Message {
+	var message Message
+	err := json.Unmarshal(data, &message)
+	if err != nil {
+		return nil
+	}
+	return &message
+}
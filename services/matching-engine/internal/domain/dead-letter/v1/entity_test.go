@@ -0,0 +1,46 @@
+package deadletterv1
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_Validate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		policy      Policy
+		expectedErr error
+	}{
+		{name: "default policy", policy: DefaultPolicy()},
+		{name: "dead-letter and halt", policy: Policy{Decode: ActionDeadLetter, Process: ActionHalt}},
+		{name: "retry processing", policy: Policy{Decode: ActionSkip, Process: ActionRetry}},
+		{name: "retry decoding", policy: Policy{Decode: ActionRetry, Process: ActionSkip}, expectedErr: ErrRetryNotAllowed},
+		{name: "unknown decode action", policy: Policy{Decode: "drop", Process: ActionSkip}, expectedErr: ErrInvalidAction},
+		{name: "empty process action", policy: Policy{Decode: ActionSkip}, expectedErr: ErrInvalidAction},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.policy.Validate()
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMessage_RoundTrip(t *testing.T) {
+	msg := kafka.Message{Topic: "orders", Partition: 2, Offset: 42, Key: []byte("order-1"), Value: []byte(`{"orderID":"order-1"`)}
+	message := NewMessage("BTC-USD", ErrorClassDecode, msg, errors.New("unexpected end of JSON input"), 1, 1000)
+
+	decoded := FromBytes(ToBytes(message))
+	require.NotNil(t, decoded)
+	assert.Equal(t, message, decoded)
+	assert.Equal(t, msg.Value, decoded.Payload)
+}
@@ -0,0 +1,13 @@
+package deadletterv1
+
+import (
+	"context"
+)
+
+// Publisher defines the interface for publishing order messages to the dead-letter topic.
+//
+//go:generate mockgen -source interface.go -destination=mock/interface_mock.go -package=deadletterv1_mock
+type Publisher interface {
+	// PublishDeadLetter publishes the message, a nil error confirms it is stored
+	PublishDeadLetter(ctx context.Context, message *Message) error
+}
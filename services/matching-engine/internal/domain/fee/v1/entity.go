@@ -0,0 +1,168 @@
+package feev1
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// RateUnit is the denominator of fee rates: rates are in millionths of the notional, so
+// a rate of 1000 is 0.1%, 10 basis points.
+const RateUnit = 1_000_000
+
+// rateUnitsPerBps is the number of rate units in a basis point.
+const rateUnitsPerBps = RateUnit / 10_000
+
+var (
+	// ErrInvalidRate is returned for rates that are out of range or too precise.
+	ErrInvalidRate = errors.New("invalid fee rate")
+	// ErrUnknownTier is returned when a user is assigned a tier the schedule does not define.
+	ErrUnknownTier = errors.New("unknown fee tier")
+)
+
+// Rates are the maker and taker fee rates of a pair or a tier in millionths of the
+// notional. A negative maker rate is a rebate paid to the maker.
+type Rates struct {
+	Maker int64
+	Taker int64
+}
+
+// NewRates creates rates from basis points, which must be whole multiples of a hundredth
+// of a basis point.
+func NewRates(makerBps, takerBps float64) (Rates, error) {
+	maker, err := bpsToRate(makerBps)
+	if err != nil {
+		return Rates{}, fmt.Errorf("%w: maker: %w", ErrInvalidRate, err)
+	}
+	taker, err := bpsToRate(takerBps)
+	if err != nil {
+		return Rates{}, fmt.Errorf("%w: taker: %w", ErrInvalidRate, err)
+	}
+
+	rates := Rates{Maker: maker, Taker: taker}
+	if err := rates.Validate(); err != nil {
+		return Rates{}, err
+	}
+	return rates, nil
+}
+
+// Validate checks that the taker pays a fee below 100% and that a maker rebate does not
+// exceed the taker fee, so the exchange never pays out more on a trade than it charges.
+func (r Rates) Validate() error {
+	if r.Taker < 0 || r.Taker >= RateUnit {
+		return fmt.Errorf("%w: taker rate %d must be within [0, %d)", ErrInvalidRate, r.Taker, RateUnit)
+	}
+	if r.Maker >= RateUnit {
+		return fmt.Errorf("%w: maker rate %d must be below %d", ErrInvalidRate, r.Maker, RateUnit)
+	}
+	if r.Maker < -r.Taker {
+		return fmt.Errorf("%w: maker rebate %d exceeds taker fee %d", ErrInvalidRate, -r.Maker, r.Taker)
+	}
+	return nil
+}
+
+// Fee is what one side of a trade pays, in integer units of the fee asset at the pair's
+// price precision. A negative amount is a rebate the side receives.
+type Fee struct {
+	Amount int64
+	Rate   int64 // Rate the amount was charged at, in millionths of the notional
+	Asset  string
+}
+
+// Schedule holds the fee rates of a pair: every user pays the pair's rates unless they
+// are assigned a tier with rates of its own. Fees are charged in the pair's quote asset.
+type Schedule struct {
+	Rates     Rates             // Rates of users without a tier
+	Tiers     map[string]Rates  // Rates of each tier by name
+	UserTiers map[string]string // Tier of each user by user ID
+	Asset     string            // Asset every fee is charged in
+}
+
+// NewSchedule creates the fee schedule of the pair. Every user must be assigned a tier
+// that is defined.
+func NewSchedule(pair string, rates Rates, tiers map[string]Rates, userTiers map[string]string) (*Schedule, error) {
+	schedule := &Schedule{
+		Rates:     rates,
+		Tiers:     tiers,
+		UserTiers: userTiers,
+		Asset:     QuoteAsset(pair),
+	}
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// Validate checks the rates of the pair and of every tier, and that every user's tier exists.
+func (s *Schedule) Validate() error {
+	if err := s.Rates.Validate(); err != nil {
+		return err
+	}
+	for name, rates := range s.Tiers {
+		if err := rates.Validate(); err != nil {
+			return fmt.Errorf("tier %s: %w", name, err)
+		}
+	}
+	for userID, tier := range s.UserTiers {
+		if _, ok := s.Tiers[tier]; !ok {
+			return fmt.Errorf("%w: %q of user %s", ErrUnknownTier, tier, userID)
+		}
+	}
+	return nil
+}
+
+// RatesOf returns the rates the user pays.
+func (s *Schedule) RatesOf(userID string) Rates {
+	if tier, ok := s.UserTiers[userID]; ok {
+		return s.Tiers[tier]
+	}
+	return s.Rates
+}
+
+// Fees returns the fees the maker and the taker of a trade of size at price pay. Sizes
+// have sizeDecimals decimals, and fees are in price units. Fees round up and rebates
+// round towards zero, so rounding never favours the users over the exchange.
+func (s *Schedule) Fees(price, size int64, sizeDecimals int32, makerID, takerID string) (maker Fee, taker Fee) {
+	makerRate := s.RatesOf(makerID).Maker
+	takerRate := s.RatesOf(takerID).Taker
+	return s.fee(price, size, sizeDecimals, makerRate), s.fee(price, size, sizeDecimals, takerRate)
+}
+
+// fee returns the fee at rate of a trade of size at price.
+func (s *Schedule) fee(price, size int64, sizeDecimals int32, rate int64) Fee {
+	// price * size * rate / (10^sizeDecimals * RateUnit), rounded up
+	numerator := new(big.Int).Mul(big.NewInt(price), big.NewInt(size))
+	numerator.Mul(numerator, big.NewInt(rate))
+	denominator := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(sizeDecimals)), nil)
+	denominator.Mul(denominator, big.NewInt(RateUnit))
+
+	quotient, remainder := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+	if remainder.Sign() > 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+
+	return Fee{Amount: quotient.Int64(), Rate: rate, Asset: s.Asset}
+}
+
+// QuoteAsset returns the quote asset of a pair named BASE-QUOTE or BASE/QUOTE, or an
+// empty string when the name has no separator.
+func QuoteAsset(pair string) string {
+	i := strings.LastIndexAny(pair, "-/")
+	if i < 0 {
+		return ""
+	}
+	return pair[i+1:]
+}
+
+// bpsToRate converts basis points to rate units, rejecting values that are not a whole
+// number of them.
+func bpsToRate(bps float64) (int64, error) {
+	units := bps * rateUnitsPerBps
+	rounded := math.Round(units)
+	if math.IsNaN(units) || math.IsInf(units, 0) || math.Abs(units-rounded) > 1e-6 {
+		return 0, fmt.Errorf("%v bps is not a multiple of 0.01 bps", bps)
+	}
+	return int64(rounded), nil
+}
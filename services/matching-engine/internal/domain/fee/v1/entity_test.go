@@ -0,0 +1,129 @@
+package feev1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRates(t *testing.T) {
+	testCases := []struct {
+		name     string
+		maker    float64
+		taker    float64
+		expected Rates
+		err      bool
+	}{
+		{name: "fees", maker: 2, taker: 5, expected: Rates{Maker: 200, Taker: 500}},
+		{name: "fractional bps", maker: 0.25, taker: 2.5, expected: Rates{Maker: 25, Taker: 250}},
+		{name: "maker rebate", maker: -1, taker: 4, expected: Rates{Maker: -100, Taker: 400}},
+		{name: "no fees", expected: Rates{}},
+		{name: "too precise", maker: 0.001, taker: 1, err: true},
+		{name: "negative taker", maker: 1, taker: -1, err: true},
+		{name: "rebate above taker fee", maker: -5, taker: 4, err: true},
+		{name: "taker fee of 100%", taker: 10_000, err: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			rates, err := NewRates(testCase.maker, testCase.taker)
+			if testCase.err {
+				assert.ErrorIs(t, err, ErrInvalidRate)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, rates)
+		})
+	}
+}
+
+func TestNewSchedule(t *testing.T) {
+	tiers := map[string]Rates{"vip": {Maker: -50, Taker: 300}}
+
+	schedule, err := NewSchedule("BTC-USD", Rates{Maker: 200, Taker: 500}, tiers, map[string]string{"alice": "vip"})
+	require.NoError(t, err)
+	assert.Equal(t, "USD", schedule.Asset)
+	assert.Equal(t, tiers["vip"], schedule.RatesOf("alice"))
+	assert.Equal(t, Rates{Maker: 200, Taker: 500}, schedule.RatesOf("bob"))
+
+	_, err = NewSchedule("BTC-USD", Rates{}, tiers, map[string]string{"bob": "gold"})
+	assert.ErrorIs(t, err, ErrUnknownTier)
+
+	_, err = NewSchedule("BTC-USD", Rates{}, map[string]Rates{"vip": {Maker: -10}}, nil)
+	assert.ErrorIs(t, err, ErrInvalidRate)
+}
+
+func TestSchedule_Fees(t *testing.T) {
+	schedule, err := NewSchedule("ETH/USDT", Rates{Maker: 200, Taker: 500}, map[string]Rates{
+		"vip": {Maker: -100, Taker: 300},
+	}, map[string]string{"vip": "vip"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		price        int64
+		size         int64
+		sizeDecimals int32
+		maker        string
+		taker        string
+		makerFee     Fee
+		takerFee     Fee
+	}{
+		{
+			// 100.00 * 2 = 200.00, maker 0.02% = 0.04, taker 0.05% = 0.10
+			name:     "pair rates",
+			price:    10_000,
+			size:     2,
+			maker:    "alice",
+			taker:    "bob",
+			makerFee: Fee{Amount: 4, Rate: 200, Asset: "USDT"},
+			takerFee: Fee{Amount: 10, Rate: 500, Asset: "USDT"},
+		},
+		{
+			// 200.00 notional, maker rebate 0.01% = -0.02, taker 0.03% = 0.06
+			name:     "tier rates with a rebate",
+			price:    10_000,
+			size:     2,
+			maker:    "vip",
+			taker:    "vip",
+			makerFee: Fee{Amount: -2, Rate: -100, Asset: "USDT"},
+			takerFee: Fee{Amount: 6, Rate: 300, Asset: "USDT"},
+		},
+		{
+			// 100.00 * 0.015 = 1.50, the rebate of -0.00015 rounds to 0.00 and the fee of 0.00075 to 0.01
+			name:         "fees round up, rebates towards zero",
+			price:        10_000,
+			size:         15,
+			sizeDecimals: 3,
+			maker:        "vip",
+			taker:        "alice",
+			makerFee:     Fee{Amount: 0, Rate: -100, Asset: "USDT"},
+			takerFee:     Fee{Amount: 1, Rate: 500, Asset: "USDT"},
+		},
+		{
+			name:         "notional beyond int64 before scaling",
+			price:        9_000_000_000,
+			size:         9_000_000_000,
+			sizeDecimals: 8,
+			maker:        "alice",
+			taker:        "bob",
+			makerFee:     Fee{Amount: 162_000_000, Rate: 200, Asset: "USDT"},
+			takerFee:     Fee{Amount: 405_000_000, Rate: 500, Asset: "USDT"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			makerFee, takerFee := schedule.Fees(testCase.price, testCase.size, testCase.sizeDecimals, testCase.maker, testCase.taker)
+			assert.Equal(t, testCase.makerFee, makerFee)
+			assert.Equal(t, testCase.takerFee, takerFee)
+		})
+	}
+}
+
+func TestQuoteAsset(t *testing.T) {
+	assert.Equal(t, "USD", QuoteAsset("BTC-USD"))
+	assert.Equal(t, "USDT", QuoteAsset("ETH/USDT"))
+	assert.Equal(t, "", QuoteAsset("BTCUSD"))
+}
@@ -0,0 +1,11 @@
+package feev1
+
+import "context"
+
+// TierStore defines the interface for loading the fee tiers users are assigned to.
+//
+//go:generate mockgen -source interface.go -destination=mock/interface_mock.go -package=feev1_mock
+type TierStore interface {
+	// LoadUserTiers returns the tier of each user by user ID.
+	LoadUserTiers(ctx context.Context) (map[string]string, error)
+}
@@ -0,0 +1,49 @@
+package journalv1
+
+import (
+	"errors"
+
+	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+)
+
+// EntryType represents the kind of a journal entry.
+type EntryType string
+
+const (
+	// EntryTypeOrder records an accepted order request, written before the request is processed.
+	EntryTypeOrder EntryType = "order"
+	// EntryTypeExpiry records an expiry sweep that removed orders from the book.
+	EntryTypeExpiry EntryType = "expiry"
+	// EntryTypeMatch records a match event produced by the entry before it.
+	EntryTypeMatch EntryType = "match"
+	// EntryTypeOrderEvent records an order event produced by the entry before it.
+	EntryTypeOrderEvent EntryType = "order_event"
+)
+
+var (
+	// ErrCorruptEntry is returned when an entry in the middle of the journal fails its checksum.
+	ErrCorruptEntry = errors.New("journal entry is corrupt")
+	// ErrSequenceGap is returned when entry sequences in the journal are not consecutive.
+	ErrSequenceGap = errors.New("journal sequence gap")
+	// ErrBehindSnapshot is returned when the journal ends before the entries a snapshot already reflects.
+	ErrBehindSnapshot = errors.New("journal ends before the snapshot")
+)
+
+// Entry is a single record of the journal. Order and expiry entries change the book and
+// are replayed; match and order event entries record what those changes produced.
+type Entry struct {
+	Sequence  int64     `json:"sequence"`  // Position in the journal, assigned on append and starting at 1
+	Type      EntryType `json:"type"`      // Kind of the entry
+	Offset    int64     `json:"offset"`    // Kafka offset of the message the entry came from, -1 for none
+	Timestamp int64     `json:"timestamp"` // Time the engine accepted the input, in Unix nanoseconds
+
+	Order      *orderbookv1.PlaceOrderRequest `json:"order,omitempty"`
+	Match      *pb.MatchEventPayload          `json:"match,omitempty"`
+	OrderEvent *pb.OrderEventPayload          `json:"orderEvent,omitempty"`
+}
+
+// IsInput reports whether the entry changes the book when replayed.
+func (e *Entry) IsInput() bool {
+	return e.Type == EntryTypeOrder || e.Type == EntryTypeExpiry
+}
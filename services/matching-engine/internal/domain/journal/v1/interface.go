@@ -0,0 +1,15 @@
+package journalv1
+
+// Journal defines the interface for an append-only log of the engine's inputs and the events they produced.
+//
+//go:generate mockgen -source interface.go -destination=mock/interface_mock.go -package=journalv1_mock
+type Journal interface {
+	// Append durably writes the entries in order, assigning their sequence numbers
+	Append(entries ...*Entry) error
+	// Read calls fn for every entry with a sequence greater than after, in order
+	Read(after int64, fn func(*Entry) error) error
+	// LastSequence returns the sequence of the last entry written, zero for an empty journal
+	LastSequence() int64
+	// Close closes the journal
+	Close() error
+}
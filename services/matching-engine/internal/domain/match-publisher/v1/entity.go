@@ -2,37 +2,68 @@ package matchpublisherv1
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 
 	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
+	feev1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/fee/v1"
 	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-// CreateFromMatch creates a match event from a match and an order.
-func CreateFromMatch(match *orderbookv1.Match, order *orderbookv1.Order) *pb.MatchEventPayload {
+// TradeID returns the ID of the trade with the given sequence in the symbol's trade stream.
+func TradeID(symbol string, sequence int64) string {
+	return fmt.Sprintf("%s-%d", symbol, sequence)
+}
+
+// CreateFromMatch creates the match event of the trade with the given sequence in the
+// symbol's trade stream, between the taker order and the resting order of the match. It
+// is timestamped with the taker order's time, the time the engine processed the request
+// that traded. The exact price and volume are published as integer units at the pair's
+// scale, alongside their float values.
+func CreateFromMatch(match *orderbookv1.Match, order *orderbookv1.Order, symbol string, sequence int64, scale orderbookv1.Scale) *pb.MatchEventPayload {
 	matchEvent := &pb.MatchEventPayload{
-		MatchID:   order.ID,
-		Timestamp: timestamppb.New(time.Unix(order.Timestamp, 0)),
+		MatchID:       TradeID(symbol, sequence),
+		TradeSequence: sequence,
+		Timestamp:     timestamppb.New(time.Unix(0, order.Timestamp)),
+		Symbol:        symbol,
+		TakerUserID:   order.UserID,
 	}
 
 	if order.Bid {
 		matchEvent.BuyOrderID = order.ID
 		matchEvent.SellOrderID = match.Ask.ID
+		matchEvent.MakerUserID = match.Ask.UserID
 		matchEvent.TakerSide = "buy"
 	} else {
 		matchEvent.BuyOrderID = match.Bid.ID
 		matchEvent.SellOrderID = order.ID
+		matchEvent.MakerUserID = match.Bid.UserID
 		matchEvent.TakerSide = "sell"
 	}
 
-	matchEvent.Volume = match.SizeFilled
-	matchEvent.Price = match.Price
-	matchEvent.Timestamp = timestamppb.Now()
+	matchEvent.PriceUnits = match.Price
+	matchEvent.VolumeUnits = match.SizeFilled
+	matchEvent.PriceDecimals = scale.PriceDecimals
+	matchEvent.VolumeDecimals = scale.SizeDecimals
+	matchEvent.Volume = scale.FromSize(match.SizeFilled)
+	matchEvent.Price = scale.FromPrice(match.Price)
 
 	return matchEvent
 }
 
+// CreateTradeFee creates the fee of one side of a trade. Fees are in price units, so the
+// exact amount is published at the pair's price decimals, alongside its float value.
+func CreateTradeFee(fee feev1.Fee, scale orderbookv1.Scale) *pb.TradeFee {
+	return &pb.TradeFee{
+		Amount:      scale.FromPrice(fee.Amount),
+		AmountUnits: fee.Amount,
+		Decimals:    scale.PriceDecimals,
+		Rate:        float64(fee.Rate) / feev1.RateUnit,
+		Asset:       fee.Asset,
+	}
+}
+
 // ToBytes converts the match event to a byte array.
 func ToBytes(matchEvent *pb.MatchEventPayload) []byte {
 	json, err := json.Marshal(matchEvent)
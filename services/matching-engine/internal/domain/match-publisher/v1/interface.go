@@ -2,10 +2,15 @@ package matchpublisherv1
 
 import (
 	"context"
+	"errors"
 
 	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
 )
 
+// ErrMatchesPending is returned when the engine stops before the matches of an order
+// were confirmed by the publisher. The order's offset is not consumed.
+var ErrMatchesPending = errors.New("match events not confirmed by the publisher")
+
 // MatchPublisher defines the interface for publishing match events.
 //
 //go:generate mockgen -source interface.go -destination=mock/interface_mock.go -package=matchpublisherv1_mock
@@ -0,0 +1,210 @@
+package orderpublisherv1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// EventType represents the type of order event.
+type EventType string
+
+const (
+	// EventTypeAccepted is published when the engine takes an order in, before it is matched.
+	EventTypeAccepted EventType = "order_accepted"
+	// EventTypeRested is published when the unfilled part of an order is added to the book.
+	EventTypeRested EventType = "order_rested"
+	// EventTypePartiallyFilled is published for a trade that leaves the order with size remaining.
+	EventTypePartiallyFilled EventType = "order_partially_filled"
+	// EventTypeFilled is published for the trade that fills the order completely.
+	EventTypeFilled EventType = "order_filled"
+	// EventTypeRejected is published when the engine refuses an order.
+	EventTypeRejected EventType = "order_rejected"
+	// EventTypeReplaced is published when a resting order's price or size is amended.
+	EventTypeReplaced EventType = "order_replaced"
+	// EventTypeCancelled is published when an order, or part of it, is removed without trading:
+	// at the owner's request, on expiry, for an unfilled IOC, FOK or market remainder, or by
+	// self-trade prevention.
+	EventTypeCancelled EventType = "order_cancelled"
+	// EventTypeMarketStateChanged is published when the market state of the pair changes.
+	// It carries no order.
+	EventTypeMarketStateChanged EventType = "market_state_changed"
+	// EventTypeAuctionIndicative is published during an auction when the price or volume
+	// the pair would uncross at changes. It carries no order.
+	EventTypeAuctionIndicative EventType = "auction_indicative"
+)
+
+// Liquidity tells whether a filled order was resting in the book or took liquidity from it.
+type Liquidity string
+
+const (
+	// LiquidityMaker marks the fill of the resting order.
+	LiquidityMaker Liquidity = "maker"
+	// LiquidityTaker marks the fill of the incoming order.
+	LiquidityTaker Liquidity = "taker"
+)
+
+// CreateAcceptedEvent creates an order accepted event for an order of the given type at its
+// limit price, zero for market and stop orders. offset is the order offset of the input the
+// event results from, -1 when it has none.
+func CreateAcceptedEvent(order *orderbookv1.Order, orderType orderbookv1.OrderType, price, offset int64, scale orderbookv1.Scale) *pb.OrderEventPayload {
+	orderEvent := createEvent(EventTypeAccepted, order, price, order.TotalSize(), scale)
+	orderEvent.EventID = inputEventID(EventTypeAccepted, order, offset)
+	orderEvent.OrderType = string(orderType)
+	orderEvent.RemainingSize = scale.FromSize(order.TotalSize())
+	orderEvent.QuoteSize = scale.FromPrice(order.QuoteSize)
+
+	return orderEvent
+}
+
+// CreateRestedEvent creates an order rested event for the size the order added to the book
+// at the price it rests at. offset is the order offset of the input the event results from.
+func CreateRestedEvent(order *orderbookv1.Order, offset int64, scale orderbookv1.Scale) *pb.OrderEventPayload {
+	orderEvent := createEvent(EventTypeRested, order, order.Price, order.TotalSize(), scale)
+	orderEvent.EventID = inputEventID(EventTypeRested, order, offset)
+	orderEvent.RemainingSize = scale.FromSize(order.TotalSize())
+
+	return orderEvent
+}
+
+// CreateFillEvent creates the fill event of order, one of the two sides of match, at the
+// trade's price and size. It is a filled event if the match left the order with nothing and
+// a partially filled event otherwise. timestamp is the time of the trade. The fills of a
+// quote-size order also carry the budget it had left.
+func CreateFillEvent(order *orderbookv1.Order, match *orderbookv1.Match, liquidity Liquidity, timestamp int64, scale orderbookv1.Scale) *pb.OrderEventPayload {
+	eventType := EventTypePartiallyFilled
+	remaining := match.Remaining(order)
+	if remaining == 0 {
+		eventType = EventTypeFilled
+	}
+
+	orderEvent := createEvent(eventType, order, match.Price, match.SizeFilled, scale)
+	orderEvent.EventID = fmt.Sprintf("%s-%s-%d-%d", order.ID, eventType, timestamp, remaining)
+	orderEvent.Timestamp = timestamppb.New(time.Unix(0, timestamp))
+	orderEvent.RemainingSize = scale.FromSize(remaining)
+	orderEvent.Liquidity = string(liquidity)
+	if order.IsQuote() {
+		orderEvent.RemainingQuoteSize = scale.FromPrice(match.QuoteRemaining)
+	}
+
+	return orderEvent
+}
+
+// CreateRejectedEvent creates an order rejected event for the order's owner. The reason is
+// published as text and as a reject code; instrument rule violations also carry the
+// offending field and the limit it broke. offset is the order offset of the refused input.
+func CreateRejectedEvent(order *orderbookv1.Order, price int64, reason error, offset int64, scale orderbookv1.Scale) *pb.OrderEventPayload {
+	orderEvent := createEvent(EventTypeRejected, order, price, order.Size, scale)
+	orderEvent.EventID = inputEventID(EventTypeRejected, order, offset)
+	orderEvent.Reason = reason.Error()
+	orderEvent.ReasonCode = string(orderbookv1.RejectCodeOf(reason))
+
+	var violation *orderbookv1.SpecViolation
+	if errors.As(reason, &violation) {
+		orderEvent.ReasonField = violation.Field
+		orderEvent.ReasonLimit = violation.Limit
+	}
+
+	return orderEvent
+}
+
+// CreateReplacedEvent creates an order replaced event with the amended price and remaining size.
+func CreateReplacedEvent(order *orderbookv1.Order, price int64, scale orderbookv1.Scale) *pb.OrderEventPayload {
+	orderEvent := createEvent(EventTypeReplaced, order, price, order.TotalSize(), scale)
+	orderEvent.EventID = fmt.Sprintf("%s-%s-%d", order.ID, EventTypeReplaced, order.Timestamp)
+	orderEvent.RemainingSize = scale.FromSize(order.TotalSize())
+
+	return orderEvent
+}
+
+// CreateCancelledEvent creates an order cancelled event for the cancelled size of the order.
+// timestamp is the time of the cancel; the order may keep size after a partial cancel, which
+// is published as its remaining size. A quote-size order gives up the budget it had left.
+func CreateCancelledEvent(order *orderbookv1.Order, price, size int64, reason error, timestamp int64, scale orderbookv1.Scale) *pb.OrderEventPayload {
+	orderEvent := createEvent(EventTypeCancelled, order, price, size, scale)
+	orderEvent.EventID = fmt.Sprintf("%s-%s-%d-%d", order.ID, EventTypeCancelled, timestamp, order.TotalSize())
+	orderEvent.Timestamp = timestamppb.New(time.Unix(0, timestamp))
+	orderEvent.Reason = reason.Error()
+	orderEvent.RemainingSize = scale.FromSize(order.TotalSize())
+	orderEvent.QuoteSize = scale.FromPrice(order.QuoteRemaining)
+
+	return orderEvent
+}
+
+// CreateMarketStateEvent creates a market state changed event for pair moving from the
+// previous state to state at timestamp.
+func CreateMarketStateEvent(pair string, previous, state orderbookv1.MarketState, timestamp int64) *pb.OrderEventPayload {
+	return &pb.OrderEventPayload{
+		EventID:             fmt.Sprintf("%s-%s-%s-%d", pair, EventTypeMarketStateChanged, state, timestamp),
+		Timestamp:           timestamppb.New(time.Unix(0, timestamp)),
+		EventType:           string(EventTypeMarketStateChanged),
+		Symbol:              pair,
+		MarketState:         string(state),
+		PreviousMarketState: string(previous),
+	}
+}
+
+// CreateIndicativeEvent creates an auction indicative event with the price and volume pair
+// would uncross at, as of timestamp. A zero volume means the book does not cross.
+func CreateIndicativeEvent(pair string, uncross orderbookv1.Uncross, timestamp int64, scale orderbookv1.Scale) *pb.OrderEventPayload {
+	return &pb.OrderEventPayload{
+		EventID:     fmt.Sprintf("%s-%s-%d", pair, EventTypeAuctionIndicative, timestamp),
+		Timestamp:   timestamppb.New(time.Unix(0, timestamp)),
+		EventType:   string(EventTypeAuctionIndicative),
+		Symbol:      pair,
+		Price:       scale.FromPrice(uncross.Price),
+		Size:        scale.FromSize(uncross.Volume),
+		MarketState: string(orderbookv1.MarketStateAuction),
+	}
+}
+
+// createEvent fills the fields shared by every order event, converting price and size
+// from units at the pair's scale.
+func createEvent(eventType EventType, order *orderbookv1.Order, price, size int64, scale orderbookv1.Scale) *pb.OrderEventPayload {
+	side := "sell"
+	if order.Bid {
+		side = "buy"
+	}
+
+	return &pb.OrderEventPayload{
+		EventID:   fmt.Sprintf("%s-%s", order.ID, eventType),
+		Timestamp: timestamppb.New(time.Unix(0, order.Timestamp)),
+		EventType: string(eventType),
+		OrderID:   order.ID,
+		UserID:    order.UserID,
+		Side:      side,
+		Price:     scale.FromPrice(price),
+		Size:      scale.FromSize(size),
+	}
+}
+
+// inputEventID identifies an event by the input it results from, as retried requests
+// may carry an order ID that was seen before.
+func inputEventID(eventType EventType, order *orderbookv1.Order, offset int64) string {
+	return fmt.Sprintf("%s-%s-%d", order.ID, eventType, offset)
+}
+
+// ToBytes converts the order event to a byte array.
+func ToBytes(orderEvent *pb.OrderEventPayload) []byte {
+	json, err := json.Marshal(orderEvent)
+	if err != nil {
+		return nil
+	}
+
+	return json
+}
+
+// FromBytes converts a byte array to an order event.
+func FromBytes(data []byte) *pb.OrderEventPayload {
+	var orderEvent pb.OrderEventPayload
+	err := json.Unmarshal(data, &orderEvent)
+	if err != nil {
+		return nil
+	}
+	return &orderEvent
+}
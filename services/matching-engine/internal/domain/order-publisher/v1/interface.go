@@ -0,0 +1,15 @@
+package orderpublisherv1
+
+import (
+	"context"
+
+	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
+)
+
+// OrderPublisher defines the interface for publishing order events.
+//
+//go:generate mockgen -source interface.go -destination=mock/interface_mock.go -package=orderpublisherv1_mock
+type OrderPublisher interface {
+	// PublishOrderEvent publishes an order event to the Kafka topic.
+	PublishOrderEvent(ctx context.Context, orderEvent *pb.OrderEventPayload) error
+}
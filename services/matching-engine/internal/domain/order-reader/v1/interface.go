@@ -2,19 +2,26 @@ package orderreaderv1
 
 import (
 	"context"
+	"errors"
 
 	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
 	"github.com/segmentio/kafka-go"
 )
 
+// ErrInvalidMessage is returned with the raw message when a message cannot be decoded as an order.
+var ErrInvalidMessage = errors.New("invalid order message")
+
 // OrderReader defines the interface for reading orders from a source.
 //
 //go:generate mockgen -source interface.go -destination=mock/interface_mock.go -package=orderreaderv1_mock
 type OrderReader interface {
-	// ReadMessage reads a message and returns the offset and parsed order
+	// ReadMessage reads a message and returns the offset and parsed order. A message that
+	// cannot be decoded is returned with a nil order and ErrInvalidMessage
 	ReadMessage(ctx context.Context) (kafka.Message, *pb.PlaceOrderPayload, error)
 	// SetOffset sets the offset for the reader
 	SetOffset(offset int64) error
+	// Lag returns the number of messages after the reader's offset that were not read yet
+	Lag(ctx context.Context) (int64, error)
 	// Close closes the reader
 	Close() error
 
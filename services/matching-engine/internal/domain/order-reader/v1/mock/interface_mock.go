@@ -69,6 +69,21 @@ func (mr *MockOrderReaderMockRecorder) CommitMessages(ctx interface{}, msgs ...i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommitMessages", reflect.TypeOf((*MockOrderReader)(nil).CommitMessages), varargs...)
 }
 
+// Lag mocks base method.
+func (m *MockOrderReader) Lag(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Lag", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Lag indicates an expected call of Lag.
+func (mr *MockOrderReaderMockRecorder) Lag(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lag", reflect.TypeOf((*MockOrderReader)(nil).Lag), ctx)
+}
+
 // ReadMessage mocks base method.
 func (m *MockOrderReader) ReadMessage(ctx context.Context) (kafka.Message, *kafkav1.PlaceOrderPayload, error) {
 	m.ctrl.T.Helper()
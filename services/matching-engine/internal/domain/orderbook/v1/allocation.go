@@ -0,0 +1,174 @@
+package orderbookv1
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+)
+
+// AllocationStrategy is how the size of an incoming order is split between the resting
+// orders of a price level.
+type AllocationStrategy string
+
+const (
+	// AllocationFIFO fills resting orders in time priority. This is the default.
+	AllocationFIFO AllocationStrategy = "fifo"
+	// AllocationProRata fills every resting order in proportion to its size.
+	AllocationProRata AllocationStrategy = "pro_rata"
+	// AllocationHybrid fills the order at the head of the queue first, then the others pro-rata.
+	AllocationHybrid AllocationStrategy = "hybrid"
+)
+
+// RemainderRule is who gets the size left over once pro-rata shares are rounded down.
+type RemainderRule string
+
+const (
+	// RemainderFIFO gives the remainder to the resting orders in time priority. This is the default.
+	RemainderFIFO RemainderRule = "fifo"
+	// RemainderLargest gives the remainder to the largest resting orders first, the
+	// oldest first among orders of the same size.
+	RemainderLargest RemainderRule = "largest"
+)
+
+var ErrInvalidAllocation = errors.New("invalid allocation")
+
+// Allocation splits the size of an incoming order between the resting orders of a limit.
+type Allocation interface {
+	// Allocate returns the size each of orders, given in queue order, fills against an
+	// incoming order of size. No fill exceeds the visible size of its order, and the
+	// fills add up to size, or to the orders' total visible size when that is smaller.
+	Allocate(orders []*Order, size int64) []int64
+}
+
+// NewAllocation creates the allocation of a pair with the given instrument specification.
+// Pro-rata shares are rounded down to the pair's lot size, and shares smaller than
+// minAllocation, a decimal size, are dropped in favour of the remainder rule.
+func NewAllocation(spec InstrumentSpec, strategy AllocationStrategy, minAllocation float64, remainder RemainderRule) (Allocation, error) {
+	minSize, err := spec.Scale.ToSize(minAllocation)
+	if err != nil {
+		return nil, fmt.Errorf("%w: minimum allocation: %w", ErrInvalidAllocation, err)
+	}
+	if minSize < 0 {
+		return nil, fmt.Errorf("%w: minimum allocation must not be negative", ErrInvalidAllocation)
+	}
+	if remainder == "" {
+		remainder = RemainderFIFO
+	}
+	if remainder != RemainderFIFO && remainder != RemainderLargest {
+		return nil, fmt.Errorf("%w: unknown remainder rule %q", ErrInvalidAllocation, remainder)
+	}
+
+	proRata := ProRataAllocation{MinSize: minSize, LotSize: spec.LotSize, Remainder: remainder}
+	switch strategy {
+	case AllocationFIFO, "":
+		return FIFOAllocation{}, nil
+	case AllocationProRata:
+		return proRata, nil
+	case AllocationHybrid:
+		return HybridAllocation{ProRata: proRata}, nil
+	}
+	return nil, fmt.Errorf("%w: unknown strategy %q", ErrInvalidAllocation, strategy)
+}
+
+// FIFOAllocation fills resting orders in time priority, each one completely before the next.
+type FIFOAllocation struct{}
+
+// Allocate fills the orders in queue order.
+func (FIFOAllocation) Allocate(orders []*Order, size int64) []int64 {
+	fills := make([]int64, len(orders))
+	allocateInOrder(orders, fills, queueOrder(len(orders)), size)
+	return fills
+}
+
+// ProRataAllocation fills every resting order in proportion to its visible size.
+type ProRataAllocation struct {
+	MinSize   int64 // Shares below it are dropped and go to the remainder instead
+	LotSize   int64 // Shares are rounded down to a multiple of it, zero for the size unit
+	Remainder RemainderRule
+}
+
+// Allocate gives each order its share of size rounded down to the lot size, and hands
+// what rounding left over out by the remainder rule.
+func (p ProRataAllocation) Allocate(orders []*Order, size int64) []int64 {
+	fills := make([]int64, len(orders))
+
+	total := int64(0)
+	for _, order := range orders {
+		total += order.Size
+	}
+	if size >= total {
+		for i, order := range orders {
+			fills[i] = order.Size
+		}
+		return fills
+	}
+
+	left := size
+	for i, order := range orders {
+		share := proRataShare(size, order.Size, total)
+		if p.LotSize > 1 {
+			share -= share % p.LotSize
+		}
+		if share < p.MinSize {
+			share = 0
+		}
+		fills[i] = share
+		left -= share
+	}
+
+	priority := queueOrder(len(orders))
+	if p.Remainder == RemainderLargest {
+		sort.SliceStable(priority, func(a, b int) bool {
+			return orders[priority[a]].Size > orders[priority[b]].Size
+		})
+	}
+	allocateInOrder(orders, fills, priority, left)
+	return fills
+}
+
+// HybridAllocation fills the order at the head of the queue first, and splits what is
+// left between the other orders pro-rata.
+type HybridAllocation struct {
+	ProRata ProRataAllocation
+}
+
+// Allocate fills the first order in queue order, then the rest pro-rata.
+func (h HybridAllocation) Allocate(orders []*Order, size int64) []int64 {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	top := min(size, orders[0].Size)
+	return append([]int64{top}, h.ProRata.Allocate(orders[1:], size-top)...)
+}
+
+// allocateInOrder adds size to fills, filling each order up to its visible size in the
+// order given by priority.
+func allocateInOrder(orders []*Order, fills []int64, priority []int, size int64) {
+	for _, i := range priority {
+		if size <= 0 {
+			return
+		}
+		fill := min(size, orders[i].Size-fills[i])
+		fills[i] += fill
+		size -= fill
+	}
+}
+
+// queueOrder returns the indexes of n orders in queue order.
+func queueOrder(n int) []int {
+	priority := make([]int, n)
+	for i := range priority {
+		priority[i] = i
+	}
+	return priority
+}
+
+// proRataShare returns size * part / total rounded down, without overflowing. The
+// division cannot overflow as size is below total and part at most total.
+func proRataShare(size, part, total int64) int64 {
+	hi, lo := bits.Mul64(uint64(size), uint64(part))
+	quotient, _ := bits.Div64(hi, lo, uint64(total))
+	return int64(quotient)
+}
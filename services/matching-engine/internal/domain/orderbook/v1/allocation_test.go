@@ -0,0 +1,170 @@
+package orderbookv1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAllocation(t *testing.T) {
+	spec := InstrumentSpec{Scale: Scale{SizeDecimals: 2}, LotSize: 5}
+
+	allocation, err := NewAllocation(spec, AllocationProRata, 0.1, "")
+	require.NoError(t, err)
+	assert.Equal(t, ProRataAllocation{MinSize: 10, LotSize: 5, Remainder: RemainderFIFO}, allocation)
+
+	allocation, err = NewAllocation(spec, AllocationHybrid, 0, RemainderLargest)
+	require.NoError(t, err)
+	assert.Equal(t, HybridAllocation{ProRata: ProRataAllocation{LotSize: 5, Remainder: RemainderLargest}}, allocation)
+
+	allocation, err = NewAllocation(spec, "", 0, "")
+	require.NoError(t, err)
+	assert.Equal(t, FIFOAllocation{}, allocation)
+
+	for name, invalid := range map[string]func() (Allocation, error){
+		"unknown strategy":       func() (Allocation, error) { return NewAllocation(spec, "random", 0, "") },
+		"unknown remainder rule": func() (Allocation, error) { return NewAllocation(spec, AllocationProRata, 0, "smallest") },
+		"too precise minimum":    func() (Allocation, error) { return NewAllocation(spec, AllocationProRata, 0.001, "") },
+		"negative minimum":       func() (Allocation, error) { return NewAllocation(spec, AllocationProRata, -1, "") },
+	} {
+		_, err := invalid()
+		assert.ErrorIs(t, err, ErrInvalidAllocation, name)
+	}
+}
+
+func TestAllocation_Allocate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		allocation Allocation
+		sizes      []int64
+		size       int64
+		expected   []int64
+	}{
+		{
+			name:       "fifo",
+			allocation: FIFOAllocation{},
+			sizes:      []int64{10, 30, 60},
+			size:       50,
+			expected:   []int64{10, 30, 10},
+		},
+		{
+			name:       "pro-rata",
+			allocation: ProRataAllocation{},
+			sizes:      []int64{10, 30, 60},
+			size:       50,
+			expected:   []int64{5, 15, 30},
+		},
+		{
+			name:       "pro-rata fills everything it can",
+			allocation: ProRataAllocation{},
+			sizes:      []int64{10, 30},
+			size:       50,
+			expected:   []int64{10, 30},
+		},
+		{
+			name:       "pro-rata shares round down to lots, the remainder goes first in, first out",
+			allocation: ProRataAllocation{LotSize: 2, Remainder: RemainderFIFO},
+			sizes:      []int64{10, 30, 60},
+			size:       26,
+			expected:   []int64{6, 6, 14},
+		},
+		{
+			name:       "the remainder goes to the largest order",
+			allocation: ProRataAllocation{LotSize: 2, Remainder: RemainderLargest},
+			sizes:      []int64{10, 30, 60},
+			size:       26,
+			expected:   []int64{2, 6, 18},
+		},
+		{
+			name:       "shares below the minimum allocation are dropped",
+			allocation: ProRataAllocation{MinSize: 3, Remainder: RemainderLargest},
+			sizes:      []int64{10, 30, 60},
+			size:       20,
+			expected:   []int64{0, 6, 14},
+		},
+		{
+			name:       "hybrid fills the top order, then pro-rata",
+			allocation: HybridAllocation{ProRata: ProRataAllocation{Remainder: RemainderFIFO}},
+			sizes:      []int64{10, 30, 60},
+			size:       50,
+			expected:   []int64{10, 14, 26},
+		},
+		{
+			name:       "hybrid with a partially filled top order",
+			allocation: HybridAllocation{},
+			sizes:      []int64{10, 30, 60},
+			size:       4,
+			expected:   []int64{4, 0, 0},
+		},
+		{
+			name:       "pro-rata shares of sizes whose product overflows",
+			allocation: ProRataAllocation{},
+			sizes:      []int64{4_000_000_000_000_000_000, 4_000_000_000_000_000_000},
+			size:       3_000_000_000_000_000_000,
+			expected:   []int64{1_500_000_000_000_000_000, 1_500_000_000_000_000_000},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			orders := make([]*Order, len(testCase.sizes))
+			for i, size := range testCase.sizes {
+				orders[i] = createOrderWithTimestamp("maker", size, false, int64(i+1), 0)
+			}
+			assert.Equal(t, testCase.expected, testCase.allocation.Allocate(orders, testCase.size))
+		})
+	}
+}
+
+func TestLimit_Fill_ProRata(t *testing.T) {
+	setup := func() (*Limit, []*Order) {
+		limit := NewLimit(100)
+		limit.SetAllocation(ProRataAllocation{Remainder: RemainderFIFO})
+
+		orders := []*Order{
+			createOrderWithTimestamp("alice", 5, false, 1000, 0),
+			createOrderWithTimestamp("bob", 15, false, 2000, 0),
+			createOrderWithTimestamp("carol", 30, false, 3000, 0),
+		}
+		for _, order := range orders {
+			require.NoError(t, limit.AddOrder(order))
+		}
+		return limit, orders
+	}
+
+	t.Run("every order fills in proportion to its size", func(t *testing.T) {
+		limit, orders := setup()
+
+		matches := limit.Fill(createTestOrder("dave", 10, true))
+
+		require.Len(t, matches, 3)
+		for i, expected := range []int64{1, 3, 6} {
+			assert.Equal(t, orders[i], matches[i].Ask)
+			assert.Equal(t, expected, matches[i].SizeFilled)
+		}
+		assert.Equal(t, int64(40), limit.GetTotalVolume())
+		assert.Equal(t, 3, limit.OrderCount())
+		assert.NoError(t, limit.Validate())
+	})
+
+	t.Run("size left after self-trade prevention is allocated again", func(t *testing.T) {
+		limit, orders := setup()
+
+		incoming := createTestOrder("bob", 20, true)
+		incoming.SelfTradePrevention = SelfTradePreventionCancelOldest
+		matches := limit.Fill(incoming)
+
+		// alice fills her share of 2 before bob's order is reached and cancelled, then the
+		// 18 left are split between alice's 3 and carol's 30
+		require.Len(t, matches, 3)
+		assert.Equal(t, []*Order{orders[0], orders[0], orders[2]}, []*Order{matches[0].Ask, matches[1].Ask, matches[2].Ask})
+		assert.Equal(t, []int64{2, 2, 16}, []int64{matches[0].SizeFilled, matches[1].SizeFilled, matches[2].SizeFilled})
+		require.Len(t, incoming.SelfTradeCancels, 1)
+		assert.Equal(t, orders[1], incoming.SelfTradeCancels[0].Order)
+		assert.True(t, incoming.IsFilled())
+		assert.Equal(t, []*Order{orders[0], orders[2]}, limit.GetOrders())
+		assert.Equal(t, int64(15), limit.GetTotalVolume())
+		assert.NoError(t, limit.Validate())
+	})
+}
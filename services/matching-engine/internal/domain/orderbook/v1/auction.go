@@ -0,0 +1,118 @@
+package orderbookv1
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrAuctionOrder is returned for orders that cannot take part in a call auction.
+var ErrAuctionOrder = errors.New("immediate and post-only orders are not accepted during an auction")
+
+// AuctionLevel is the size of one side of the book at a price, hidden reserves included.
+type AuctionLevel struct {
+	Price int64
+	Size  int64
+}
+
+// Uncross is where a call auction uncrosses: every bid at or above Price trades with
+// every ask at or below it, Volume in total, all at Price. Surplus is the bid size less
+// the ask size that can trade at Price; a positive surplus is left on the buy side. A
+// zero Volume means the book does not cross.
+type Uncross struct {
+	Price   int64
+	Volume  int64
+	Surplus int64
+}
+
+// FindUncross returns the equilibrium price of a book with the given bid levels, highest
+// price first, and ask levels, lowest price first. Of the prices in the book it picks
+// the one that executes the most volume, then the one leaving the smallest surplus. If
+// several remain, the highest wins when they all leave buyers over, the lowest when they
+// all leave sellers over, and otherwise the one closest to reference, the lower price on
+// a tie or without a reference.
+func FindUncross(bids, asks []AuctionLevel, reference int64) Uncross {
+	if len(bids) == 0 || len(asks) == 0 || bids[0].Price < asks[0].Price {
+		return Uncross{}
+	}
+
+	// Only prices between the best ask and the best bid can execute anything
+	low, high := asks[0].Price, bids[0].Price
+	var prices []int64
+	for _, levels := range [][]AuctionLevel{bids, asks} {
+		for _, level := range levels {
+			if level.Price >= low && level.Price <= high {
+				prices = append(prices, level.Price)
+			}
+		}
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i] < prices[j] })
+
+	bidSize := int64(0)
+	for _, level := range bids {
+		bidSize += level.Size
+	}
+
+	// Walking up the prices adds the asks at or below each price and drops the bids below it
+	var (
+		candidates []Uncross
+		askSize    int64
+		nextAsk    int
+		nextBid    = len(bids) - 1
+	)
+	for i, price := range prices {
+		if i > 0 && price == prices[i-1] {
+			continue
+		}
+		for ; nextAsk < len(asks) && asks[nextAsk].Price <= price; nextAsk++ {
+			askSize += asks[nextAsk].Size
+		}
+		for ; nextBid >= 0 && bids[nextBid].Price < price; nextBid-- {
+			bidSize -= bids[nextBid].Size
+		}
+
+		candidate := Uncross{Price: price, Volume: min(bidSize, askSize), Surplus: bidSize - askSize}
+		switch {
+		case candidate.Volume == 0:
+			continue
+		case len(candidates) == 0 || candidate.Volume > candidates[0].Volume:
+			candidates = []Uncross{candidate}
+		case candidate.Volume < candidates[0].Volume:
+			continue
+		case abs(candidate.Surplus) < abs(candidates[0].Surplus):
+			candidates = []Uncross{candidate}
+		case abs(candidate.Surplus) == abs(candidates[0].Surplus):
+			candidates = append(candidates, candidate)
+		}
+	}
+	if len(candidates) == 0 {
+		return Uncross{}
+	}
+
+	buyers, sellers := true, true
+	for _, candidate := range candidates {
+		buyers = buyers && candidate.Surplus > 0
+		sellers = sellers && candidate.Surplus < 0
+	}
+	switch {
+	case buyers:
+		return candidates[len(candidates)-1]
+	case sellers:
+		return candidates[0]
+	}
+
+	// Candidates are in ascending price order, so the first closest one is the lower
+	best := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if abs(candidate.Price-reference) < abs(best.Price-reference) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+func abs(value int64) int64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
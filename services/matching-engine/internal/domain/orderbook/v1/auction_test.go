@@ -0,0 +1,71 @@
+package orderbookv1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindUncross(t *testing.T) {
+	testCases := []struct {
+		name      string
+		bids      []AuctionLevel
+		asks      []AuctionLevel
+		reference int64
+		expected  Uncross
+	}{
+		{
+			name:     "most executable volume",
+			bids:     []AuctionLevel{{Price: 101, Size: 4}},
+			asks:     []AuctionLevel{{Price: 100, Size: 2}, {Price: 101, Size: 3}},
+			expected: Uncross{Price: 101, Volume: 4, Surplus: -1},
+		},
+		{
+			name:     "smallest surplus, sellers left over take the lowest price",
+			bids:     []AuctionLevel{{Price: 102, Size: 5}, {Price: 100, Size: 2}},
+			asks:     []AuctionLevel{{Price: 99, Size: 5}, {Price: 101, Size: 1}},
+			expected: Uncross{Price: 101, Volume: 5, Surplus: -1},
+		},
+		{
+			name:     "buyers left over take the highest price",
+			bids:     []AuctionLevel{{Price: 102, Size: 5}, {Price: 101, Size: 5}},
+			asks:     []AuctionLevel{{Price: 99, Size: 3}, {Price: 100, Size: 4}, {Price: 103, Size: 10}},
+			expected: Uncross{Price: 101, Volume: 7, Surplus: 3},
+		},
+		{
+			name:      "balanced book takes the price closest to the reference",
+			bids:      []AuctionLevel{{Price: 101, Size: 5}},
+			asks:      []AuctionLevel{{Price: 99, Size: 5}},
+			reference: 101,
+			expected:  Uncross{Price: 101, Volume: 5},
+		},
+		{
+			name:      "equally close prices take the lower",
+			bids:      []AuctionLevel{{Price: 101, Size: 5}},
+			asks:      []AuctionLevel{{Price: 99, Size: 5}},
+			reference: 100,
+			expected:  Uncross{Price: 99, Volume: 5},
+		},
+		{
+			name:     "without a reference the lower",
+			bids:     []AuctionLevel{{Price: 101, Size: 5}},
+			asks:     []AuctionLevel{{Price: 99, Size: 5}},
+			expected: Uncross{Price: 99, Volume: 5},
+		},
+		{
+			name: "book does not cross",
+			bids: []AuctionLevel{{Price: 99, Size: 5}},
+			asks: []AuctionLevel{{Price: 100, Size: 5}},
+		},
+		{
+			name: "one side empty",
+			bids: []AuctionLevel{{Price: 99, Size: 5}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, FindUncross(testCase.bids, testCase.asks, testCase.reference))
+		})
+	}
+}
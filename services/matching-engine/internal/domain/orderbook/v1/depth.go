@@ -0,0 +1,39 @@
+package orderbookv1
+
+// Level is a copy of a price level of the book, safe to read while the book changes.
+// Sizes are in size units of the pair's Scale.
+type Level struct {
+	Price      int64
+	Size       int64 // Visible size of the level
+	HiddenSize int64 // Size held in the hidden reserve of iceberg orders
+	OrderCount int
+	Orders     []*Order // Copies of the orders in queue order, only set when requested
+}
+
+// NewLevel copies the state of limit, and its orders when withOrders is set.
+func NewLevel(limit *Limit, withOrders bool) Level {
+	limit.mu.RLock()
+	defer limit.mu.RUnlock()
+
+	level := Level{
+		Price:      limit.Price,
+		Size:       limit.TotalVolume,
+		OrderCount: limit.count,
+	}
+	for order := limit.head; order != nil; order = order.next {
+		level.HiddenSize += order.HiddenSize
+		if withOrders {
+			level.Orders = append(level.Orders, order.Clone())
+		}
+	}
+	return level
+}
+
+// Clone returns a copy of the order detached from its limit's queue.
+func (o *Order) Clone() *Order {
+	clone := *o
+	clone.Limit = nil
+	clone.prev, clone.next = nil, nil
+	clone.SelfTradeCancels = nil
+	return &clone
+}
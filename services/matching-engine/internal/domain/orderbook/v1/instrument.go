@@ -0,0 +1,317 @@
+package orderbookv1
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// RejectCode is the machine readable reason an order was refused.
+type RejectCode string
+
+const (
+	// RejectCodeTickSize means a price is not a multiple of the tick size.
+	RejectCodeTickSize RejectCode = "tick_size"
+	// RejectCodeLotSize means a size is not a multiple of the lot size.
+	RejectCodeLotSize RejectCode = "lot_size"
+	// RejectCodeMinSize means the size is below the minimum order size.
+	RejectCodeMinSize RejectCode = "min_size"
+	// RejectCodeMinNotional means price times size is below the minimum notional.
+	RejectCodeMinNotional RejectCode = "min_notional"
+	// RejectCodeMaxNotional means price times size is above the maximum notional.
+	RejectCodeMaxNotional RejectCode = "max_notional"
+	// RejectCodePrecision means a value has more decimals than the pair precision.
+	RejectCodePrecision RejectCode = "precision"
+	// RejectCodePostOnly means a post-only order would have crossed the book.
+	RejectCodePostOnly RejectCode = "post_only_would_cross"
+	// RejectCodePostOnlyNotAllowed means post-only was set on a market, IOC or FOK order.
+	RejectCodePostOnlyNotAllowed RejectCode = "post_only_not_allowed"
+	// RejectCodePostOnlyMode means the post-only mode is not one the engine knows.
+	RejectCodePostOnlyMode RejectCode = "invalid_post_only_mode"
+	// RejectCodeOrderNotFound means the order to amend or cancel is not in the book, or the
+	// order to amend is not the requester's.
+	RejectCodeOrderNotFound RejectCode = "order_not_found"
+	// RejectCodePriceBand means the price is outside the pair's price band.
+	RejectCodePriceBand RejectCode = "price_band"
+	// RejectCodeMarketState means the pair's market state does not accept the request.
+	RejectCodeMarketState RejectCode = "market_state"
+	// RejectCodeInvalidOrder is used for every other rejection.
+	RejectCodeInvalidOrder RejectCode = "invalid_order"
+)
+
+var (
+	ErrInvalidInstrument = errors.New("invalid instrument specification")
+	ErrInstrumentSpec    = errors.New("order violates the instrument specification")
+)
+
+// RejectCodeOf returns the reject code for an error returned while placing an order.
+func RejectCodeOf(err error) RejectCode {
+	var violation *SpecViolation
+	switch {
+	case errors.As(err, &violation):
+		return violation.Code
+	case errors.Is(err, ErrPrecisionLoss):
+		return RejectCodePrecision
+	case errors.Is(err, ErrPostOnlyWouldCross):
+		return RejectCodePostOnly
+	case errors.Is(err, ErrPostOnlyNotAllowed):
+		return RejectCodePostOnlyNotAllowed
+	case errors.Is(err, ErrInvalidPostOnlyMode):
+		return RejectCodePostOnlyMode
+	case errors.Is(err, ErrUnknownOrder):
+		return RejectCodeOrderNotFound
+	case errors.Is(err, ErrMarketState):
+		return RejectCodeMarketState
+	}
+	return RejectCodeInvalidOrder
+}
+
+// SpecViolation describes which rule of the instrument specification an order broke.
+// Value and Limit are exact decimal strings at the pair precision.
+type SpecViolation struct {
+	Code  RejectCode
+	Field string // Request field that broke the rule: price, stop_price, worst_price, size, display_size, quote_size or notional
+	Value string
+	Limit string
+}
+
+// Error implements the error interface.
+func (v *SpecViolation) Error() string {
+	switch v.Code {
+	case RejectCodeTickSize:
+		return fmt.Sprintf("%s %s is not a multiple of the tick size %s", v.Field, v.Value, v.Limit)
+	case RejectCodeLotSize:
+		return fmt.Sprintf("%s %s is not a multiple of the lot size %s", v.Field, v.Value, v.Limit)
+	case RejectCodeMinSize:
+		return fmt.Sprintf("%s %s is below the minimum size %s", v.Field, v.Value, v.Limit)
+	case RejectCodeMinNotional:
+		return fmt.Sprintf("%s %s is below the minimum notional %s", v.Field, v.Value, v.Limit)
+	case RejectCodeMaxNotional:
+		return fmt.Sprintf("%s %s is above the maximum notional %s", v.Field, v.Value, v.Limit)
+	case RejectCodePriceBand:
+		return fmt.Sprintf("%s %s is outside the price band, which ends at %s", v.Field, v.Value, v.Limit)
+	}
+	return fmt.Sprintf("%s %s violates %s %s", v.Field, v.Value, v.Code, v.Limit)
+}
+
+// Is makes every violation match ErrInstrumentSpec.
+func (v *SpecViolation) Is(target error) bool {
+	return target == ErrInstrumentSpec
+}
+
+// InstrumentSpec holds the trading rules of a pair. Prices and notionals are in price
+// units, sizes in size units of Scale. A zero rule is not enforced.
+type InstrumentSpec struct {
+	Scale       Scale
+	TickSize    int64 // Prices must be a multiple of the tick size
+	LotSize     int64 // Sizes must be a multiple of the lot size
+	MinSize     int64 // Smallest order size
+	MinNotional int64 // Smallest price times size of orders with a price
+	MaxNotional int64 // Largest price times size of orders with a price
+}
+
+// NewInstrumentSpec creates a specification from decimal values, converting them to units
+// of the scale. Each value must be exactly representable at the scale's precision.
+func NewInstrumentSpec(scale Scale, tickSize, lotSize, minSize, minNotional, maxNotional float64) (InstrumentSpec, error) {
+	spec := InstrumentSpec{Scale: scale}
+
+	values := []struct {
+		name    string
+		value   float64
+		convert func(float64) (int64, error)
+		target  *int64
+	}{
+		{"tick size", tickSize, scale.ToPrice, &spec.TickSize},
+		{"lot size", lotSize, scale.ToSize, &spec.LotSize},
+		{"min size", minSize, scale.ToSize, &spec.MinSize},
+		{"min notional", minNotional, scale.ToPrice, &spec.MinNotional},
+		{"max notional", maxNotional, scale.ToPrice, &spec.MaxNotional},
+	}
+	for _, v := range values {
+		units, err := v.convert(v.value)
+		if err != nil {
+			return InstrumentSpec{}, fmt.Errorf("%w: %s: %w", ErrInvalidInstrument, v.name, err)
+		}
+		*v.target = units
+	}
+
+	if err := spec.Validate(); err != nil {
+		return InstrumentSpec{}, err
+	}
+	return spec, nil
+}
+
+// Validate checks that the rules are consistent.
+func (s InstrumentSpec) Validate() error {
+	if err := s.Scale.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidInstrument, err)
+	}
+	if s.TickSize < 0 || s.LotSize < 0 || s.MinSize < 0 || s.MinNotional < 0 || s.MaxNotional < 0 {
+		return fmt.Errorf("%w: rules must not be negative", ErrInvalidInstrument)
+	}
+	if s.MaxNotional > 0 && s.MinNotional > s.MaxNotional {
+		return fmt.Errorf("%w: min notional is above max notional", ErrInvalidInstrument)
+	}
+	return nil
+}
+
+// Check validates a place order request against the specification. Cancels are not
+// checked, and market and stop orders have no notional check because their execution
+// price is not known up front, except for the budget of a quote-size market order.
+func (s InstrumentSpec) Check(r *PlaceOrderRequest) error {
+	if r.Type == OrderTypeCancel || r.Type == OrderTypeMassCancel || r.Type == OrderTypeMarketState {
+		return nil
+	}
+	if r.WorstPrice != 0 || r.MaxSlippageBps != 0 {
+		if err := s.checkSlippage(r); err != nil {
+			return err
+		}
+	}
+	if r.QuoteSize != 0 {
+		return s.checkQuoteSize(r)
+	}
+
+	// Without a minimum size the lot and size rules let an empty order through
+	if r.Size <= 0 {
+		return fmt.Errorf("%w: got %d", ErrInvalidSize, r.Size)
+	}
+	if err := s.checkSize("size", r.Size); err != nil {
+		return err
+	}
+	if r.DisplaySize > 0 && s.LotSize > 0 && r.DisplaySize%s.LotSize != 0 {
+		return s.sizeViolation(RejectCodeLotSize, "display_size", r.DisplaySize, s.LotSize)
+	}
+
+	if r.Type == OrderTypeStop || r.Type == OrderTypeStopLimit {
+		if err := s.checkTick("stop_price", r.StopPrice); err != nil {
+			return err
+		}
+	}
+
+	switch r.Type {
+	case OrderTypeLimit, OrderTypeStopLimit, OrderTypeReplace:
+		if err := s.checkTick("price", r.Price); err != nil {
+			return err
+		}
+		return s.checkNotional(r.Price, r.Size)
+	}
+	return nil
+}
+
+// checkQuoteSize checks a quote-size market order. Its budget is already a notional, so
+// it is held to the notional limits; the size it fills is whole lots by construction.
+func (s InstrumentSpec) checkQuoteSize(r *PlaceOrderRequest) error {
+	if r.Type != OrderTypeMarket || r.Size != 0 || r.QuoteSize < 0 || r.TimeInForce == TimeInForceFOK {
+		return ErrInvalidQuoteSize
+	}
+	if s.MinNotional > 0 && r.QuoteSize < s.MinNotional {
+		return s.quoteViolation(RejectCodeMinNotional, r.QuoteSize, s.MinNotional)
+	}
+	if s.MaxNotional > 0 && r.QuoteSize > s.MaxNotional {
+		return s.quoteViolation(RejectCodeMaxNotional, r.QuoteSize, s.MaxNotional)
+	}
+	return nil
+}
+
+// checkSlippage checks the owner's limits on a market or stop order.
+func (s InstrumentSpec) checkSlippage(r *PlaceOrderRequest) error {
+	if r.Type != OrderTypeMarket && r.Type != OrderTypeStop {
+		return ErrInvalidSlippage
+	}
+	if r.WorstPrice < 0 || r.MaxSlippageBps < 0 || r.MaxSlippageBps > bpsPerUnit {
+		return ErrInvalidSlippage
+	}
+	return s.checkTick("worst_price", r.WorstPrice)
+}
+
+// checkSize checks the lot size and minimum size rules.
+func (s InstrumentSpec) checkSize(field string, size int64) error {
+	if s.LotSize > 0 && size%s.LotSize != 0 {
+		return s.sizeViolation(RejectCodeLotSize, field, size, s.LotSize)
+	}
+	if size < s.MinSize {
+		return s.sizeViolation(RejectCodeMinSize, field, size, s.MinSize)
+	}
+	return nil
+}
+
+// checkTick checks that a price is on the tick grid.
+func (s InstrumentSpec) checkTick(field string, price int64) error {
+	if s.TickSize > 0 && price%s.TickSize != 0 {
+		return &SpecViolation{
+			Code:  RejectCodeTickSize,
+			Field: field,
+			Value: s.Scale.FormatPrice(price),
+			Limit: s.Scale.FormatPrice(s.TickSize),
+		}
+	}
+	return nil
+}
+
+// checkNotional checks price times size against the notional limits. The product is
+// compared in 128 bits, so large orders cannot overflow past the maximum.
+func (s InstrumentSpec) checkNotional(price, size int64) error {
+	if price <= 0 || size <= 0 {
+		return nil
+	}
+
+	if s.MinNotional > 0 && s.compareNotional(price, size, s.MinNotional) < 0 {
+		return s.notionalViolation(RejectCodeMinNotional, price, size, s.MinNotional)
+	}
+	if s.MaxNotional > 0 && s.compareNotional(price, size, s.MaxNotional) > 0 {
+		return s.notionalViolation(RejectCodeMaxNotional, price, size, s.MaxNotional)
+	}
+	return nil
+}
+
+// compareNotional compares price times size with a notional in price units. Size units
+// carry SizeDecimals extra decimals, so the notional is scaled up by 10^SizeDecimals.
+func (s InstrumentSpec) compareNotional(price, size, notional int64) int {
+	hi, lo := bits.Mul64(uint64(price), uint64(size))
+	limitHi, limitLo := bits.Mul64(uint64(notional), pow10(s.Scale.SizeDecimals))
+
+	if hi != limitHi {
+		return cmp.Compare(hi, limitHi)
+	}
+	return cmp.Compare(lo, limitLo)
+}
+
+// sizeViolation builds a violation of a size rule.
+func (s InstrumentSpec) sizeViolation(code RejectCode, field string, size, limit int64) error {
+	return &SpecViolation{
+		Code:  code,
+		Field: field,
+		Value: s.Scale.FormatSize(size),
+		Limit: s.Scale.FormatSize(limit),
+	}
+}
+
+// notionalViolation builds a violation of a notional rule.
+func (s InstrumentSpec) notionalViolation(code RejectCode, price, size, limit int64) error {
+	return &SpecViolation{
+		Code:  code,
+		Field: "notional",
+		Value: fmt.Sprintf("%s x %s", s.Scale.FormatPrice(price), s.Scale.FormatSize(size)),
+		Limit: s.Scale.FormatPrice(limit),
+	}
+}
+
+// quoteViolation builds a violation of a notional rule by a quote size.
+func (s InstrumentSpec) quoteViolation(code RejectCode, quoteSize, limit int64) error {
+	return &SpecViolation{
+		Code:  code,
+		Field: "quote_size",
+		Value: s.Scale.FormatPrice(quoteSize),
+		Limit: s.Scale.FormatPrice(limit),
+	}
+}
+
+// pow10 returns 10^n for n up to MaxDecimals.
+func pow10(n int32) uint64 {
+	result := uint64(1)
+	for i := int32(0); i < n; i++ {
+		result *= 10
+	}
+	return result
+}
@@ -0,0 +1,234 @@
+package orderbookv1
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewInstrumentSpec(t *testing.T) {
+	scale := Scale{PriceDecimals: 2, SizeDecimals: 8}
+
+	t.Run("converts rules to units", func(t *testing.T) {
+		spec, err := NewInstrumentSpec(scale, 0.05, 0.001, 0.01, 10, 1_000_000)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), spec.TickSize)
+		assert.Equal(t, int64(100_000), spec.LotSize)
+		assert.Equal(t, int64(1_000_000), spec.MinSize)
+		assert.Equal(t, int64(1_000), spec.MinNotional)
+		assert.Equal(t, int64(100_000_000), spec.MaxNotional)
+	})
+
+	t.Run("rule finer than the pair precision", func(t *testing.T) {
+		_, err := NewInstrumentSpec(scale, 0.001, 0, 0, 0, 0)
+		assert.ErrorIs(t, err, ErrInvalidInstrument)
+		assert.ErrorIs(t, err, ErrPrecisionLoss)
+	})
+
+	t.Run("negative rule", func(t *testing.T) {
+		_, err := NewInstrumentSpec(scale, 0.01, -1, 0, 0, 0)
+		assert.ErrorIs(t, err, ErrInvalidInstrument)
+	})
+
+	t.Run("min notional above max notional", func(t *testing.T) {
+		_, err := NewInstrumentSpec(scale, 0.01, 0, 0, 100, 10)
+		assert.ErrorIs(t, err, ErrInvalidInstrument)
+	})
+}
+
+func TestInstrumentSpec_Check(t *testing.T) {
+	// Tick 0.05, lot 0.001, min size 0.01, notional between 10 and 1,000,000
+	spec, err := NewInstrumentSpec(Scale{PriceDecimals: 2, SizeDecimals: 8}, 0.05, 0.001, 0.01, 10, 1_000_000)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		request   PlaceOrderRequest
+		wantCode  RejectCode
+		wantField string
+		wantLimit string
+	}{
+		{
+			name:    "valid limit order",
+			request: PlaceOrderRequest{Type: OrderTypeLimit, Price: 5_000_000, Size: 1_000_000},
+		},
+		{
+			name:      "price off the tick grid",
+			request:   PlaceOrderRequest{Type: OrderTypeLimit, Price: 5_000_001, Size: 1_000_000},
+			wantCode:  RejectCodeTickSize,
+			wantField: "price",
+			wantLimit: "0.05",
+		},
+		{
+			name:      "size off the lot grid",
+			request:   PlaceOrderRequest{Type: OrderTypeLimit, Price: 5_000_000, Size: 1_050_000},
+			wantCode:  RejectCodeLotSize,
+			wantField: "size",
+			wantLimit: "0.00100000",
+		},
+		{
+			name:      "display size off the lot grid",
+			request:   PlaceOrderRequest{Type: OrderTypeLimit, Price: 5_000_000, Size: 2_000_000, DisplaySize: 50},
+			wantCode:  RejectCodeLotSize,
+			wantField: "display_size",
+			wantLimit: "0.00100000",
+		},
+		{
+			name:    "valid quote-size market order",
+			request: PlaceOrderRequest{Type: OrderTypeMarket, QuoteSize: 10_000},
+		},
+		{
+			name:      "quote size below minimum notional",
+			request:   PlaceOrderRequest{Type: OrderTypeMarket, QuoteSize: 999},
+			wantCode:  RejectCodeMinNotional,
+			wantField: "quote_size",
+			wantLimit: "10.00",
+		},
+		{
+			name:      "quote size above maximum notional",
+			request:   PlaceOrderRequest{Type: OrderTypeMarket, QuoteSize: 100_000_001},
+			wantCode:  RejectCodeMaxNotional,
+			wantField: "quote_size",
+			wantLimit: "1000000.00",
+		},
+		{
+			name:    "market order with a worst price and slippage limit",
+			request: PlaceOrderRequest{Type: OrderTypeMarket, Size: 1_000_000, WorstPrice: 5_000_000, MaxSlippageBps: 50},
+		},
+		{
+			name:      "worst price off the tick grid",
+			request:   PlaceOrderRequest{Type: OrderTypeStop, Size: 1_000_000, StopPrice: 5_000_000, WorstPrice: 5_000_001},
+			wantCode:  RejectCodeTickSize,
+			wantField: "worst_price",
+			wantLimit: "0.05",
+		},
+		{
+			name:      "size below minimum",
+			request:   PlaceOrderRequest{Type: OrderTypeMarket, Size: 100_000},
+			wantCode:  RejectCodeMinSize,
+			wantField: "size",
+			wantLimit: "0.01000000",
+		},
+		{
+			name:      "notional below minimum",
+			request:   PlaceOrderRequest{Type: OrderTypeLimit, Price: 500, Size: 1_000_000},
+			wantCode:  RejectCodeMinNotional,
+			wantField: "notional",
+			wantLimit: "10.00",
+		},
+		{
+			name:    "notional exactly at minimum",
+			request: PlaceOrderRequest{Type: OrderTypeLimit, Price: 100_000, Size: 1_000_000},
+		},
+		{
+			name:      "notional above maximum",
+			request:   PlaceOrderRequest{Type: OrderTypeLimit, Price: 5_000_000, Size: 2_100_000_000},
+			wantCode:  RejectCodeMaxNotional,
+			wantField: "notional",
+			wantLimit: "1000000.00",
+		},
+		{
+			name:      "notional that overflows int64",
+			request:   PlaceOrderRequest{Type: OrderTypeReplace, Price: 1_000_000_000_000, Size: 1_000_000_000_000},
+			wantCode:  RejectCodeMaxNotional,
+			wantField: "notional",
+			wantLimit: "1000000.00",
+		},
+		{
+			name:    "market order has no notional check",
+			request: PlaceOrderRequest{Type: OrderTypeMarket, Size: 100_000_000_000},
+		},
+		{
+			name:      "stop price off the tick grid",
+			request:   PlaceOrderRequest{Type: OrderTypeStop, StopPrice: 4_900_001, Size: 1_000_000},
+			wantCode:  RejectCodeTickSize,
+			wantField: "stop_price",
+			wantLimit: "0.05",
+		},
+		{
+			name:      "stop-limit price off the tick grid",
+			request:   PlaceOrderRequest{Type: OrderTypeStopLimit, StopPrice: 4_900_000, Price: 4_899_999, Size: 1_000_000},
+			wantCode:  RejectCodeTickSize,
+			wantField: "price",
+			wantLimit: "0.05",
+		},
+		{
+			name:    "cancel is not checked",
+			request: PlaceOrderRequest{Type: OrderTypeCancel},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := spec.Check(&tt.request)
+			if tt.wantCode == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, ErrInstrumentSpec)
+			var violation *SpecViolation
+			require.ErrorAs(t, err, &violation)
+			assert.Equal(t, tt.wantCode, violation.Code)
+			assert.Equal(t, tt.wantField, violation.Field)
+			assert.Equal(t, tt.wantLimit, violation.Limit)
+			assert.Equal(t, tt.wantCode, RejectCodeOf(err))
+		})
+	}
+}
+
+func TestInstrumentSpec_CheckQuoteSize(t *testing.T) {
+	spec := InstrumentSpec{Scale: Scale{PriceDecimals: 2, SizeDecimals: 8}}
+
+	for name, request := range map[string]PlaceOrderRequest{
+		"limit order":     {Type: OrderTypeLimit, Price: 5_000_000, QuoteSize: 10_000},
+		"stop order":      {Type: OrderTypeStop, StopPrice: 5_000_000, QuoteSize: 10_000},
+		"with a size":     {Type: OrderTypeMarket, Size: 1_000_000, QuoteSize: 10_000},
+		"fill-or-kill":    {Type: OrderTypeMarket, QuoteSize: 10_000, TimeInForce: TimeInForceFOK},
+		"negative budget": {Type: OrderTypeMarket, QuoteSize: -10_000},
+	} {
+		err := spec.Check(&request)
+		assert.ErrorIs(t, err, ErrInvalidQuoteSize, name)
+		assert.Equal(t, RejectCodeInvalidOrder, RejectCodeOf(err), name)
+	}
+}
+
+func TestInstrumentSpec_CheckSize(t *testing.T) {
+	// No minimum size, so only the size itself keeps empty orders out
+	spec := InstrumentSpec{Scale: Scale{PriceDecimals: 2, SizeDecimals: 8}}
+
+	for name, request := range map[string]PlaceOrderRequest{
+		"empty market order":    {Type: OrderTypeMarket},
+		"negative market order": {Type: OrderTypeMarket, Size: -1},
+		"empty limit order":     {Type: OrderTypeLimit, Price: 5_000_000},
+		"empty stop order":      {Type: OrderTypeStop, StopPrice: 5_000_000},
+	} {
+		err := spec.Check(&request)
+		assert.ErrorIs(t, err, ErrInvalidSize, name)
+		assert.Equal(t, RejectCodeInvalidOrder, RejectCodeOf(err), name)
+	}
+}
+
+func TestInstrumentSpec_CheckSlippage(t *testing.T) {
+	spec := InstrumentSpec{Scale: Scale{PriceDecimals: 2, SizeDecimals: 8}}
+
+	for name, request := range map[string]PlaceOrderRequest{
+		"limit order":          {Type: OrderTypeLimit, Price: 5_000_000, Size: 1, WorstPrice: 5_100_000},
+		"stop-limit order":     {Type: OrderTypeStopLimit, Price: 5_000_000, Size: 1, MaxSlippageBps: 50},
+		"negative worst price": {Type: OrderTypeMarket, Size: 1, WorstPrice: -1},
+		"more than 100%":       {Type: OrderTypeMarket, Size: 1, MaxSlippageBps: 10_001},
+	} {
+		assert.ErrorIs(t, spec.Check(&request), ErrInvalidSlippage, name)
+	}
+}
+
+func TestRejectCodeOf(t *testing.T) {
+	assert.Equal(t, RejectCodePostOnly, RejectCodeOf(ErrPostOnlyWouldCross))
+	assert.Equal(t, RejectCodePostOnlyNotAllowed, RejectCodeOf(ErrPostOnlyNotAllowed))
+	assert.Equal(t, RejectCodePostOnlyMode, RejectCodeOf(fmt.Errorf("%w: reject", ErrInvalidPostOnlyMode)))
+	assert.Equal(t, RejectCodePrecision, RejectCodeOf(fmt.Errorf("price: %w", ErrPrecisionLoss)))
+	assert.Equal(t, RejectCodeOrderNotFound, RejectCodeOf(fmt.Errorf("%w: missing", ErrUnknownOrder)))
+	assert.Equal(t, RejectCodeInvalidOrder, RejectCodeOf(ErrInvalidTimeInForce))
+}
@@ -4,13 +4,21 @@ import snapshotv1 "github.com/muhammadchandra19/exchange/services/matching-engin
 
 // Orderbook defines the interface for an order book in a matching service.
 type Orderbook interface {
-	AskTotalVolume() float64
+	AmendOrder(orderID, userID string, price, size, timestamp int64) (*Order, []Match, error)
+	AskTotalVolume() int64
 	Asks() []*Limit
-	BidTotalVolume() float64
+	BidTotalVolume() int64
 	Bids() []*Limit
-	CancelOrder(orderID string) error
-	PlaceLimitOrder(price float64, o *Order) error
+	CancelOrder(orderID string) (*Order, error)
+	MassCancel(filter MassCancelFilter) ([]*Order, error)
+	HasExpiredOrders(now int64) bool
+	ExpireOrders(now int64) []*Order
+	GetOrder(orderID string) (*Order, error)
+	PlaceLimitOrder(price int64, o *Order) ([]Match, error)
 	PlaceMarketOrder(o *Order) ([]Match, error)
 	CreateSnapshot() *snapshotv1.Snapshot
 	RestoreOrderbook(*snapshotv1.Snapshot) error
+	SetAuction(auction bool)
+	IndicativeUncross(reference int64) Uncross
+	Uncross(reference, timestamp int64) (Uncross, []Match, []SelfTradeCancel)
 }
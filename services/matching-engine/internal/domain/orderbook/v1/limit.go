@@ -3,7 +3,6 @@ package orderbookv1
 import (
 	"errors"
 	"fmt"
-	"sort"
 	"sync"
 )
 
@@ -15,19 +14,27 @@ var (
 )
 
 // Limit represents a price level in the order book with associated orders.
+// Price and TotalVolume are in units of the pair's Scale; TotalVolume only counts
+// the visible size of iceberg orders.
+// Orders are kept in an intrusive doubly linked queue in time priority (timestamp,
+// then sequence), so the next order to fill is always at the head and removing an
+// order is O(1).
 type Limit struct {
-	Price       float64  `json:"price"`
-	Orders      []*Order `json:"orders"`
-	TotalVolume float64  `json:"totalVolume"`
+	Price       int64 `json:"price"`
+	TotalVolume int64 `json:"totalVolume"`
 	mu          sync.RWMutex
+
+	head, tail *Order
+	count      int
+
+	allocation Allocation // Splits incoming orders between the queued orders, nil for FIFO
 }
 
 // NewLimit creates a new Limit with the specified price.
-func NewLimit(price float64) *Limit {
+func NewLimit(price int64) *Limit {
 	return &Limit{
 		Price:       price,
-		Orders:      make([]*Order, 0),
-		TotalVolume: 0.0,
+		TotalVolume: 0,
 	}
 }
 
@@ -37,14 +44,15 @@ func (l *Limit) AddOrder(order *Order) error {
 		return ErrNilOrder
 	}
 	if order.Size <= 0 {
-		return fmt.Errorf("%w: got %f", ErrInvalidSize, order.Size)
+		return fmt.Errorf("%w: got %d", ErrInvalidSize, order.Size)
 	}
 
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	order.Limit = l
-	l.Orders = append(l.Orders, order)
+	order.Price = l.Price
+	l.linkUnsafe(order)
 	l.TotalVolume += order.Size
 
 	return nil
@@ -59,20 +67,63 @@ func (l *Limit) RemoveOrder(order *Order) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	for i, o := range l.Orders {
-		if o == order {
-			// Remove order from slice
-			l.Orders = append(l.Orders[:i], l.Orders[i+1:]...)
-			l.TotalVolume -= order.Size
-			order.Limit = nil
-			return nil
-		}
+	if order.Limit != l {
+		return ErrOrderNotFound
 	}
 
-	return ErrOrderNotFound
+	l.TotalVolume -= order.Size
+	l.removeOrderUnsafe(order)
+	return nil
 }
 
-// Fill matches the limit with an incoming order and returns matches.
+// ReduceOrder lowers the remaining size of an order without changing its place in the queue.
+// The hidden reserve of an iceberg order is reduced before its visible slice.
+func (l *Limit) ReduceOrder(order *Order, size int64) error {
+	if order == nil {
+		return ErrNilOrder
+	}
+	if size <= 0 || size > order.TotalSize() {
+		return fmt.Errorf("%w: cannot reduce %d to %d", ErrInvalidSize, order.TotalSize(), size)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if order.Limit != l {
+		return ErrOrderNotFound
+	}
+
+	l.reduceUnsafe(order, order.TotalSize()-size)
+	return nil
+}
+
+// reduceUnsafe takes reduction off an order, hidden reserve first, without locking (internal use)
+func (l *Limit) reduceUnsafe(order *Order, reduction int64) {
+	fromHidden := reduction
+	if fromHidden > order.HiddenSize {
+		fromHidden = order.HiddenSize
+	}
+
+	order.HiddenSize -= fromHidden
+	order.Size -= reduction - fromHidden
+	l.TotalVolume -= reduction - fromHidden
+}
+
+// SetAllocation sets how incoming orders are split between the orders of the limit.
+// Nil fills them in time priority.
+func (l *Limit) SetAllocation(allocation Allocation) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.allocation = allocation
+}
+
+// Fill matches the limit with an incoming order and returns matches. The limit's
+// allocation decides how much each resting order fills.
+// When the incoming order meets a resting order of the same user, its self-trade
+// prevention mode decides which orders are cancelled instead of matched; every
+// cancellation is recorded in incomingOrder.SelfTradeCancels. What is left of the
+// incoming order afterwards is allocated again.
 func (l *Limit) Fill(incomingOrder *Order) []Match {
 	if incomingOrder == nil {
 		return nil
@@ -81,51 +132,126 @@ func (l *Limit) Fill(incomingOrder *Order) []Match {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	allocation := l.allocation
+	if allocation == nil {
+		allocation = FIFOAllocation{}
+	}
+
 	var matches []Match
 
-	// Sort orders by timestamp (FIFO), then by sequence for ties
-	ordersToProcess := make([]*Order, len(l.Orders))
-	copy(ordersToProcess, l.Orders)
-	sort.Slice(ordersToProcess, func(i, j int) bool {
-		if ordersToProcess[i].Timestamp == ordersToProcess[j].Timestamp {
-			return ordersToProcess[i].Sequence < ordersToProcess[j].Sequence
+	// Every round allocates the incoming size over the queue as it is; a new round starts
+	// once self-trade prevention changed the sizes, or refreshed iceberg slices have
+	// joined the back of the queue
+	for incomingOrder.Size > 0 && l.head != nil {
+		orders := make([]*Order, 0, l.count)
+		for order := l.head; order != nil; order = order.next {
+			orders = append(orders, order)
+		}
+		fills := allocation.Allocate(orders, incomingOrder.Size)
+
+		filled := false
+		for i, existingOrder := range orders {
+			if incomingOrder.Size <= 0 {
+				break
+			}
+			if fills[i] <= 0 {
+				continue
+			}
+
+			if existingOrder.UserID == incomingOrder.UserID && incomingOrder.SelfTradePrevention.IsEnabled() {
+				if l.preventSelfTrade(incomingOrder, existingOrder) {
+					l.removeOrderUnsafe(existingOrder)
+				}
+				filled = true
+				break
+			}
+
+			// Create a match
+			match := l.createMatch(incomingOrder, existingOrder, min(fills[i], incomingOrder.Size))
+			matches = append(matches, match)
+			filled = true
+
+			// Update total volume
+			l.TotalVolume -= match.SizeFilled
+
+			if existingOrder.Size > 0 {
+				continue
+			}
+
+			// Refresh an exhausted iceberg slice from its reserve; the new slice goes to
+			// the back of the queue like an order placed at the time of the fill
+			if refill := existingOrder.Refresh(); refill > 0 {
+				l.TotalVolume += refill
+				existingOrder.Timestamp = incomingOrder.Timestamp
+				existingOrder.NextSequence()
+				l.requeueUnsafe(existingOrder)
+				continue
+			}
+
+			// Filled orders leave the queue
+			l.removeOrderUnsafe(existingOrder)
 		}
-		return ordersToProcess[i].Timestamp < ordersToProcess[j].Timestamp
-	})
-
-	var ordersToRemove []*Order
 
-	// Process orders in FIFO order
-	for _, existingOrder := range ordersToProcess {
-		if incomingOrder.Size <= 0 {
+		if !filled {
 			break
 		}
+	}
 
-		// Create a match
-		match := l.createMatch(incomingOrder, existingOrder)
-		matches = append(matches, match)
+	return matches
+}
 
-		// Update total volume
-		l.TotalVolume -= match.SizeFilled
+// preventSelfTrade applies the incoming order's self-trade prevention mode against a
+// resting order of the same user and reports whether the resting order was cancelled.
+// Every size taken off either order is recorded, including the decrement of an order
+// that survives decrement_and_cancel.
+func (l *Limit) preventSelfTrade(incomingOrder, existingOrder *Order) bool {
+	cancelIncoming := func(size int64) {
+		incomingOrder.SelfTradeCancels = append(incomingOrder.SelfTradeCancels, SelfTradeCancel{
+			Order:   incomingOrder,
+			Size:    size,
+			Partial: size < incomingOrder.Size,
+		})
+		incomingOrder.Size -= size
+	}
+	cancelExisting := func(size int64) {
+		incomingOrder.SelfTradeCancels = append(incomingOrder.SelfTradeCancels, SelfTradeCancel{
+			Order:   existingOrder,
+			Price:   l.Price,
+			Size:    size,
+			Partial: size < existingOrder.TotalSize(),
+		})
+		l.reduceUnsafe(existingOrder, size)
+	}
 
-		// Mark filled orders for removal
-		if existingOrder.Size <= 0 {
-			ordersToRemove = append(ordersToRemove, existingOrder)
+	switch incomingOrder.SelfTradePrevention {
+	case SelfTradePreventionCancelNewest:
+		cancelIncoming(incomingOrder.Size)
+		return false
+	case SelfTradePreventionCancelOldest:
+		cancelExisting(existingOrder.TotalSize())
+		return true
+	case SelfTradePreventionCancelBoth:
+		cancelExisting(existingOrder.TotalSize())
+		cancelIncoming(incomingOrder.Size)
+		return true
+	case SelfTradePreventionDecrementAndCancel:
+		decrement := incomingOrder.Size
+		if existingOrder.TotalSize() < decrement {
+			decrement = existingOrder.TotalSize()
 		}
-	}
 
-	// Remove filled orders
-	for _, orderToRemove := range ordersToRemove {
-		l.removeOrderUnsafe(orderToRemove)
+		cancelled := existingOrder.TotalSize() <= decrement
+		cancelExisting(decrement)
+		cancelIncoming(decrement)
+		return cancelled
 	}
 
-	return matches
+	return false
 }
 
-// createMatch creates a match between incoming and existing order
-func (l *Limit) createMatch(incomingOrder, existingOrder *Order) Match {
+// createMatch creates a match of sizeFilled between incoming and existing order
+func (l *Limit) createMatch(incomingOrder, existingOrder *Order, sizeFilled int64) Match {
 	var bid, ask *Order
-	var sizeFilled float64
 
 	// Determine which is bid and which is ask
 	if incomingOrder.IsBid() {
@@ -136,125 +262,162 @@ func (l *Limit) createMatch(incomingOrder, existingOrder *Order) Match {
 		ask = incomingOrder
 	}
 
-	// Calculate size filled
-	if incomingOrder.Size >= existingOrder.Size {
-		sizeFilled = existingOrder.Size
-		incomingOrder.Size -= existingOrder.Size
-		existingOrder.Size = 0
-	} else {
-		sizeFilled = incomingOrder.Size
-		existingOrder.Size -= incomingOrder.Size
-		incomingOrder.Size = 0
-	}
+	incomingOrder.Size -= sizeFilled
+	existingOrder.Size -= sizeFilled
 
 	return Match{
-		Ask:        ask,
-		Bid:        bid,
-		SizeFilled: sizeFilled,
-		Price:      l.Price,
+		Ask:          ask,
+		Bid:          bid,
+		SizeFilled:   sizeFilled,
+		Price:        l.Price,
+		AskRemaining: ask.TotalSize(),
+		BidRemaining: bid.TotalSize(),
 	}
 }
 
 // removeOrderUnsafe removes order without locking (internal use)
 func (l *Limit) removeOrderUnsafe(order *Order) {
-	for i, o := range l.Orders {
-		if o == order {
-			l.Orders = append(l.Orders[:i], l.Orders[i+1:]...)
-			order.Limit = nil
-			break
-		}
+	l.unlinkUnsafe(order)
+	order.Limit = nil
+}
+
+// requeueUnsafe moves order to its place by time priority without locking (internal use)
+func (l *Limit) requeueUnsafe(order *Order) {
+	l.unlinkUnsafe(order)
+	l.linkUnsafe(order)
+}
+
+// linkUnsafe inserts order into the queue by time priority. Orders almost always arrive
+// in time order, so the search from the back usually stops at the tail.
+func (l *Limit) linkUnsafe(order *Order) {
+	after := l.tail
+	for after != nil && order.hasPriorityOver(after) {
+		after = after.prev
+	}
+
+	order.prev = after
+	if after == nil {
+		order.next = l.head
+		l.head = order
+	} else {
+		order.next = after.next
+		after.next = order
+	}
+	if order.next == nil {
+		l.tail = order
+	} else {
+		order.next.prev = order
+	}
+	l.count++
+}
+
+// unlinkUnsafe takes order out of the queue
+func (l *Limit) unlinkUnsafe(order *Order) {
+	if order.prev == nil {
+		l.head = order.next
+	} else {
+		order.prev.next = order.next
+	}
+	if order.next == nil {
+		l.tail = order.prev
+	} else {
+		order.next.prev = order.prev
 	}
+	order.prev, order.next = nil, nil
+	l.count--
 }
 
 // IsEmpty checks if the limit has no orders
 func (l *Limit) IsEmpty() bool {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
-	return len(l.Orders) == 0
+	return l.count == 0
 }
 
 // OrderCount returns the number of orders at this limit
 func (l *Limit) OrderCount() int {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
-	return len(l.Orders)
+	return l.count
 }
 
 // GetPrice returns the price of this limit
-func (l *Limit) GetPrice() float64 {
+func (l *Limit) GetPrice() int64 {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 	return l.Price
 }
 
 // GetTotalVolume returns the total volume at this limit
-func (l *Limit) GetTotalVolume() float64 {
+func (l *Limit) GetTotalVolume() int64 {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 	return l.TotalVolume
 }
 
-// GetOrders returns a copy of the orders slice
-func (l *Limit) GetOrders() []*Order {
+// GetHiddenVolume returns the size held in the hidden reserve of iceberg orders at this limit
+func (l *Limit) GetHiddenVolume() int64 {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
-	orders := make([]*Order, len(l.Orders))
-	copy(orders, l.Orders)
-	return orders
+	hidden := int64(0)
+	for order := l.head; order != nil; order = order.next {
+		hidden += order.HiddenSize
+	}
+	return hidden
 }
 
-// GetOrdersByPriority returns orders sorted by timestamp then sequence
-func (l *Limit) GetOrdersByPriority() []*Order {
+// GetOrders returns the orders of the limit in queue order
+func (l *Limit) GetOrders() []*Order {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
-	orders := make([]*Order, len(l.Orders))
-	copy(orders, l.Orders)
-
-	sort.Slice(orders, func(i, j int) bool {
-		if orders[i].Timestamp == orders[j].Timestamp {
-			return orders[i].Sequence < orders[j].Sequence
-		}
-		return orders[i].Timestamp < orders[j].Timestamp
-	})
-
+	orders := make([]*Order, 0, l.count)
+	for order := l.head; order != nil; order = order.next {
+		orders = append(orders, order)
+	}
 	return orders
 }
 
+// GetOrdersByPriority returns orders sorted by timestamp then sequence, which is the
+// queue order
+func (l *Limit) GetOrdersByPriority() []*Order {
+	return l.GetOrders()
+}
+
 // Validate performs basic validation of the limit's state
 func (l *Limit) Validate() error {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
 	if l.Price <= 0 {
-		return fmt.Errorf("%w: limit price %f", ErrInvalidPrice, l.Price)
+		return fmt.Errorf("%w: limit price %d", ErrInvalidPrice, l.Price)
 	}
 
-	calculatedVolume := 0.0
-	for _, order := range l.Orders {
-		if order == nil {
-			return fmt.Errorf("nil order found in limit")
+	calculatedVolume := int64(0)
+	count := 0
+	for order := l.head; order != nil; order = order.next {
+		if order.next != nil && order.next.prev != order {
+			return fmt.Errorf("broken order queue at order %s", order.ID)
 		}
+		if order.next != nil && order.next.hasPriorityOver(order) {
+			return fmt.Errorf("order %s is queued ahead of an older order", order.ID)
+		}
+		count++
 		if order.Size < 0 {
-			return fmt.Errorf("%w: order has size %f", ErrInvalidSize, order.Size)
+			return fmt.Errorf("%w: order has size %d", ErrInvalidSize, order.Size)
 		}
 		calculatedVolume += order.Size
 	}
 
-	// Check volume consistency (with small tolerance for floating point)
-	const epsilon = 1e-9
-	if abs(calculatedVolume-l.TotalVolume) > epsilon {
-		return fmt.Errorf("volume mismatch: calculated %f, stored %f", calculatedVolume, l.TotalVolume)
+	if count != l.count {
+		return fmt.Errorf("order count mismatch: queued %d, stored %d", count, l.count)
 	}
 
-	return nil
-}
-
-// Helper function for absolute value
-func abs(x float64) float64 {
-	if x < 0 {
-		return -x
+	// Volumes are integers, so they must match exactly
+	if calculatedVolume != l.TotalVolume {
+		return fmt.Errorf("volume mismatch: calculated %d, stored %d", calculatedVolume, l.TotalVolume)
 	}
-	return x
+
+	return nil
 }
@@ -9,15 +9,15 @@ import (
 )
 
 // Helper function to create a test order
-func createTestOrder(userID string, size float64, bid bool) *Order {
-	order := NewOrder(userID, size, bid)
+func createTestOrder(userID string, size int64, bid bool) *Order {
+	order := NewOrder(userID, size, bid, "test-id")
 	order.Timestamp = time.Now().UnixNano()
 	order.Sequence = 1
 	return order
 }
 
 // Helper function to create an order with specific timestamp and sequence
-func createOrderWithTimestamp(userID string, size float64, bid bool, timestamp int64, sequence int64) *Order {
+func createOrderWithTimestamp(userID string, size int64, bid bool, timestamp int64, sequence int64) *Order {
 	order := &Order{
 		ID:        "test-id",
 		UserID:    userID,
@@ -30,25 +30,25 @@ func createOrderWithTimestamp(userID string, size float64, bid bool, timestamp i
 }
 
 func TestNewLimit(t *testing.T) {
-	limit := NewLimit(100.0)
+	limit := NewLimit(100)
 
 	assert.NotNil(t, limit)
-	assert.Equal(t, 100.0, limit.Price)
-	assert.Equal(t, 0.0, limit.TotalVolume)
-	assert.Empty(t, limit.Orders)
+	assert.Equal(t, int64(100), limit.Price)
+	assert.Equal(t, int64(0), limit.TotalVolume)
+	assert.Zero(t, limit.OrderCount())
 	assert.True(t, limit.IsEmpty())
 }
 
 func TestLimit_AddOrder(t *testing.T) {
-	limit := NewLimit(100.0)
+	limit := NewLimit(100)
 
 	t.Run("Add valid order", func(t *testing.T) {
-		order := createTestOrder("user1", 10.0, true)
+		order := createTestOrder("user1", 10, true)
 		err := limit.AddOrder(order)
 
 		require.NoError(t, err)
-		assert.Equal(t, 1, len(limit.Orders))
-		assert.Equal(t, 10.0, limit.TotalVolume)
+		assert.Equal(t, 1, limit.OrderCount())
+		assert.Equal(t, int64(10), limit.TotalVolume)
 		assert.Equal(t, limit, order.Limit)
 		assert.False(t, limit.IsEmpty())
 	})
@@ -59,29 +59,29 @@ func TestLimit_AddOrder(t *testing.T) {
 	})
 
 	t.Run("Add order with zero size", func(t *testing.T) {
-		order := createTestOrder("user1", 0.0, true)
+		order := createTestOrder("user1", 0, true)
 		err := limit.AddOrder(order)
 		assert.ErrorIs(t, err, ErrInvalidSize)
 	})
 
 	t.Run("Add multiple orders", func(t *testing.T) {
-		limit := NewLimit(100.0)
-		order1 := createTestOrder("user1", 10.0, true)
-		order2 := createTestOrder("user2", 20.0, false)
+		limit := NewLimit(100)
+		order1 := createTestOrder("user1", 10, true)
+		order2 := createTestOrder("user2", 20, false)
 
 		err1 := limit.AddOrder(order1)
 		err2 := limit.AddOrder(order2)
 
 		require.NoError(t, err1)
 		require.NoError(t, err2)
-		assert.Equal(t, 2, len(limit.Orders))
-		assert.Equal(t, 30.0, limit.TotalVolume)
+		assert.Equal(t, 2, limit.OrderCount())
+		assert.Equal(t, int64(30), limit.TotalVolume)
 	})
 }
 
 func TestLimit_RemoveOrder(t *testing.T) {
-	limit := NewLimit(100.0)
-	order := createTestOrder("user1", 10.0, true)
+	limit := NewLimit(100)
+	order := createTestOrder("user1", 10, true)
 
 	// Add order first
 	require.NoError(t, limit.AddOrder(order))
@@ -90,8 +90,8 @@ func TestLimit_RemoveOrder(t *testing.T) {
 		err := limit.RemoveOrder(order)
 
 		require.NoError(t, err)
-		assert.Equal(t, 0, len(limit.Orders))
-		assert.Equal(t, 0.0, limit.TotalVolume)
+		assert.Equal(t, 0, limit.OrderCount())
+		assert.Equal(t, int64(0), limit.TotalVolume)
 		assert.Nil(t, order.Limit)
 		assert.True(t, limit.IsEmpty())
 	})
@@ -104,15 +104,15 @@ func TestLimit_RemoveOrder(t *testing.T) {
 
 func TestLimit_Fill_Simple(t *testing.T) {
 	t.Run("Simple partial fill", func(t *testing.T) {
-		limit := NewLimit(100.0)
+		limit := NewLimit(100)
 
 		// Add a sell order
-		sellOrder := createTestOrder("seller", 10.0, false)
+		sellOrder := createTestOrder("seller", 10, false)
 		err := limit.AddOrder(sellOrder)
 		require.NoError(t, err)
 
 		// Create incoming buy order (smaller)
-		buyOrder := createTestOrder("buyer", 5.0, true)
+		buyOrder := createTestOrder("buyer", 5, true)
 
 		// Fill
 		matches := limit.Fill(buyOrder)
@@ -120,31 +120,31 @@ func TestLimit_Fill_Simple(t *testing.T) {
 		require.Equal(t, 1, len(matches))
 
 		match := matches[0]
-		assert.Equal(t, 5.0, match.SizeFilled)
-		assert.Equal(t, 100.0, match.Price)
+		assert.Equal(t, int64(5), match.SizeFilled)
+		assert.Equal(t, int64(100), match.Price)
 		assert.Equal(t, buyOrder, match.Bid)
 		assert.Equal(t, sellOrder, match.Ask)
 
 		// Check remaining sizes
-		assert.Equal(t, 0.0, buyOrder.Size)  // Fully filled
-		assert.Equal(t, 5.0, sellOrder.Size) // Partially filled
+		assert.Equal(t, int64(0), buyOrder.Size)  // Fully filled
+		assert.Equal(t, int64(5), sellOrder.Size) // Partially filled
 
 		// Check limit state
-		assert.Equal(t, 1, len(limit.Orders))   // Sell order still there
-		assert.Equal(t, 5.0, limit.TotalVolume) // Volume updated
+		assert.Equal(t, 1, limit.OrderCount())       // Sell order still there
+		assert.Equal(t, int64(5), limit.TotalVolume) // Volume updated
 		assert.False(t, limit.IsEmpty())
 	})
 
 	t.Run("Exact match", func(t *testing.T) {
-		limit := NewLimit(100.0)
+		limit := NewLimit(100)
 
 		// Add a sell order
-		sellOrder := createTestOrder("seller", 10.0, false)
+		sellOrder := createTestOrder("seller", 10, false)
 		err := limit.AddOrder(sellOrder)
 		require.NoError(t, err)
 
 		// Create incoming buy order of same size
-		buyOrder := createTestOrder("buyer", 10.0, true)
+		buyOrder := createTestOrder("buyer", 10, true)
 
 		// Fill
 		matches := limit.Fill(buyOrder)
@@ -152,33 +152,33 @@ func TestLimit_Fill_Simple(t *testing.T) {
 		require.Equal(t, 1, len(matches))
 
 		match := matches[0]
-		assert.Equal(t, 10.0, match.SizeFilled)
+		assert.Equal(t, int64(10), match.SizeFilled)
 
 		// Both orders should be fully filled
-		assert.Equal(t, 0.0, buyOrder.Size)
-		assert.Equal(t, 0.0, sellOrder.Size)
+		assert.Equal(t, int64(0), buyOrder.Size)
+		assert.Equal(t, int64(0), sellOrder.Size)
 
 		// Limit should be empty
 		assert.True(t, limit.IsEmpty())
-		assert.Equal(t, 0.0, limit.TotalVolume)
+		assert.Equal(t, int64(0), limit.TotalVolume)
 	})
 }
 
 func TestLimit_Fill_FIFO(t *testing.T) {
 	t.Run("FIFO ordering by timestamp", func(t *testing.T) {
-		limit := NewLimit(100.0)
+		limit := NewLimit(100)
 
 		// Create orders with different timestamps
-		order1 := createOrderWithTimestamp("user1", 10.0, false, 1000, 1) // Ask, earliest
-		order2 := createOrderWithTimestamp("user2", 15.0, false, 2000, 2) // Ask, latest
-		order3 := createOrderWithTimestamp("user3", 8.0, false, 1500, 3)  // Ask, middle
+		order1 := createOrderWithTimestamp("user1", 10, false, 1000, 1) // Ask, earliest
+		order2 := createOrderWithTimestamp("user2", 15, false, 2000, 2) // Ask, latest
+		order3 := createOrderWithTimestamp("user3", 8, false, 1500, 3)  // Ask, middle
 
 		require.NoError(t, limit.AddOrder(order1))
 		require.NoError(t, limit.AddOrder(order2))
 		require.NoError(t, limit.AddOrder(order3))
 
 		// Create incoming bid order
-		incomingOrder := createTestOrder("buyer", 25.0, true)
+		incomingOrder := createTestOrder("buyer", 25, true)
 
 		matches := limit.Fill(incomingOrder)
 
@@ -187,19 +187,239 @@ func TestLimit_Fill_FIFO(t *testing.T) {
 
 		// First match: order1 (timestamp 1000)
 		assert.Equal(t, order1, matches[0].Ask)
-		assert.Equal(t, 10.0, matches[0].SizeFilled)
+		assert.Equal(t, int64(10), matches[0].SizeFilled)
 
 		// Second match: order3 (timestamp 1500)
 		assert.Equal(t, order3, matches[1].Ask)
-		assert.Equal(t, 8.0, matches[1].SizeFilled)
+		assert.Equal(t, int64(8), matches[1].SizeFilled)
 
 		// Third match: order2 (timestamp 2000)
 		assert.Equal(t, order2, matches[2].Ask)
-		assert.Equal(t, 7.0, matches[2].SizeFilled) // Remaining 7 from 25 - 10 - 8
+		assert.Equal(t, int64(7), matches[2].SizeFilled) // Remaining 7 from 25 - 10 - 8
 
 		// Check final state
-		assert.Equal(t, 1, len(limit.Orders))   // Only order2 remains (partially filled)
-		assert.Equal(t, 8.0, limit.TotalVolume) // 15 - 7 = 8 remaining
+		assert.Equal(t, 1, limit.OrderCount())       // Only order2 remains (partially filled)
+		assert.Equal(t, int64(8), limit.TotalVolume) // 15 - 7 = 8 remaining
 		assert.True(t, incomingOrder.IsFilled())
 	})
 }
+
+func TestLimit_Queue(t *testing.T) {
+	t.Run("orders are queued by timestamp then sequence", func(t *testing.T) {
+		limit := NewLimit(100)
+
+		late := createOrderWithTimestamp("late", 1, false, 3000, 1)
+		early := createOrderWithTimestamp("early", 1, false, 1000, 1)
+		tieSecond := createOrderWithTimestamp("tie-second", 1, false, 2000, 2)
+		tieFirst := createOrderWithTimestamp("tie-first", 1, false, 2000, 1)
+
+		for _, order := range []*Order{late, early, tieSecond, tieFirst} {
+			require.NoError(t, limit.AddOrder(order))
+		}
+
+		assert.Equal(t, []*Order{early, tieFirst, tieSecond, late}, limit.GetOrders())
+		assert.NoError(t, limit.Validate())
+	})
+
+	t.Run("removing from the middle keeps the queue intact", func(t *testing.T) {
+		limit := NewLimit(100)
+
+		first := createOrderWithTimestamp("first", 2, false, 1000, 0)
+		middle := createOrderWithTimestamp("middle", 3, false, 2000, 0)
+		last := createOrderWithTimestamp("last", 4, false, 3000, 0)
+		for _, order := range []*Order{first, middle, last} {
+			require.NoError(t, limit.AddOrder(order))
+		}
+
+		require.NoError(t, limit.RemoveOrder(middle))
+		assert.ErrorIs(t, limit.RemoveOrder(middle), ErrOrderNotFound)
+
+		assert.Equal(t, []*Order{first, last}, limit.GetOrders())
+		assert.Equal(t, 2, limit.OrderCount())
+		assert.Equal(t, int64(6), limit.GetTotalVolume())
+		assert.NoError(t, limit.Validate())
+	})
+}
+
+func TestLimit_Fill_Iceberg(t *testing.T) {
+	t.Run("refreshed slice loses time priority", func(t *testing.T) {
+		limit := NewLimit(100)
+
+		iceberg := createOrderWithTimestamp("iceberg", 15, false, 1000, 0)
+		iceberg.DisplaySize = 5
+		iceberg.Hide()
+		other := createOrderWithTimestamp("other", 4, false, 2000, 0)
+
+		require.NoError(t, limit.AddOrder(iceberg))
+		require.NoError(t, limit.AddOrder(other))
+
+		// Only the visible slice counts towards the limit volume
+		assert.Equal(t, int64(9), limit.TotalVolume)
+		assert.Equal(t, int64(10), limit.GetHiddenVolume())
+
+		matches := limit.Fill(createTestOrder("buyer", 11, true))
+
+		// Visible slice, then the order that was behind it, then the refreshed slice
+		require.Equal(t, 3, len(matches))
+		assert.Equal(t, iceberg, matches[0].Ask)
+		assert.Equal(t, int64(5), matches[0].SizeFilled)
+		assert.Equal(t, other, matches[1].Ask)
+		assert.Equal(t, int64(4), matches[1].SizeFilled)
+		assert.Equal(t, iceberg, matches[2].Ask)
+		assert.Equal(t, int64(2), matches[2].SizeFilled)
+
+		assert.Equal(t, int64(3), iceberg.Size)
+		assert.Equal(t, int64(5), iceberg.HiddenSize)
+		assert.Greater(t, iceberg.Timestamp, int64(2000))
+		assert.Equal(t, int64(3), limit.TotalVolume)
+		assert.Equal(t, []*Order{iceberg}, limit.GetOrders())
+		assert.NoError(t, limit.Validate())
+	})
+
+	t.Run("iceberg is removed once the reserve is exhausted", func(t *testing.T) {
+		limit := NewLimit(100)
+
+		iceberg := createOrderWithTimestamp("iceberg", 7, false, 1000, 0)
+		iceberg.DisplaySize = 3
+		iceberg.Hide()
+		require.NoError(t, limit.AddOrder(iceberg))
+
+		incoming := createTestOrder("buyer", 10, true)
+		matches := limit.Fill(incoming)
+
+		require.Equal(t, 3, len(matches))
+		assert.Equal(t, []int64{3, 3, 1}, []int64{matches[0].SizeFilled, matches[1].SizeFilled, matches[2].SizeFilled})
+		assert.True(t, iceberg.IsFilled())
+		assert.True(t, limit.IsEmpty())
+		assert.Equal(t, int64(0), limit.TotalVolume)
+		assert.Equal(t, int64(3), incoming.Size)
+	})
+}
+
+func TestLimit_Fill_SelfTradePrevention(t *testing.T) {
+	testCases := []struct {
+		name             string
+		mode             SelfTradePrevention
+		incomingSize     int64
+		expectedMatches  int
+		expectedIncoming int64
+		expectedOwnSize  int64
+		expectedCancels  []int64 // cancelled sizes: own resting order first, then incoming
+		expectedPartial  []bool  // whether each cancel left the order live, when any is
+		expectOwnRemoved bool
+	}{
+		{
+			name:             "no prevention matches own order",
+			mode:             SelfTradePreventionNone,
+			incomingSize:     6,
+			expectedMatches:  2,
+			expectedIncoming: 0,
+			expectedOwnSize:  0,
+			expectOwnRemoved: true,
+		},
+		{
+			name:             "cancel newest stops the incoming order",
+			mode:             SelfTradePreventionCancelNewest,
+			incomingSize:     6,
+			expectedMatches:  1,
+			expectedIncoming: 0,
+			expectedOwnSize:  4,
+			expectedCancels:  []int64{4},
+		},
+		{
+			name:             "cancel oldest removes the resting order and keeps matching",
+			mode:             SelfTradePreventionCancelOldest,
+			incomingSize:     6,
+			expectedMatches:  2,
+			expectedIncoming: 0,
+			expectedOwnSize:  0,
+			expectedCancels:  []int64{4},
+			expectOwnRemoved: true,
+		},
+		{
+			name:             "cancel both",
+			mode:             SelfTradePreventionCancelBoth,
+			incomingSize:     6,
+			expectedMatches:  1,
+			expectedIncoming: 0,
+			expectedOwnSize:  0,
+			expectedCancels:  []int64{4, 4},
+			expectOwnRemoved: true,
+		},
+		{
+			name:             "decrement and cancel the smaller resting order",
+			mode:             SelfTradePreventionDecrementAndCancel,
+			incomingSize:     8,
+			expectedMatches:  2,
+			expectedIncoming: 0,
+			expectedOwnSize:  0,
+			expectedCancels:  []int64{4, 4},
+			expectedPartial:  []bool{false, true},
+			expectOwnRemoved: true,
+		},
+		{
+			name:             "decrement and cancel the smaller incoming order",
+			mode:             SelfTradePreventionDecrementAndCancel,
+			incomingSize:     3,
+			expectedMatches:  1,
+			expectedIncoming: 0,
+			expectedOwnSize:  3,
+			expectedCancels:  []int64{1, 1},
+			expectedPartial:  []bool{true, false},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			limit := NewLimit(100)
+
+			other := createOrderWithTimestamp("other", 2, false, 1000, 0)
+			own := createOrderWithTimestamp("trader", 4, false, 2000, 0)
+			after := createOrderWithTimestamp("after", 10, false, 3000, 0)
+			require.NoError(t, limit.AddOrder(other))
+			require.NoError(t, limit.AddOrder(own))
+			require.NoError(t, limit.AddOrder(after))
+
+			incoming := createTestOrder("trader", tc.incomingSize, true)
+			incoming.SelfTradePrevention = tc.mode
+
+			matches := limit.Fill(incoming)
+
+			assert.Equal(t, tc.expectedMatches, len(matches))
+			if tc.mode.IsEnabled() {
+				for _, match := range matches {
+					assert.NotEqual(t, "trader", match.Ask.UserID)
+				}
+			}
+			assert.Equal(t, tc.expectedIncoming, incoming.Size)
+			assert.Equal(t, tc.expectedOwnSize, own.Size)
+
+			cancelled := make([]int64, 0, len(incoming.SelfTradeCancels))
+			partial := make([]bool, 0, len(incoming.SelfTradeCancels))
+			for _, cancel := range incoming.SelfTradeCancels {
+				cancelled = append(cancelled, cancel.Size)
+				partial = append(partial, cancel.Partial)
+			}
+			if tc.expectedCancels == nil {
+				assert.Empty(t, cancelled)
+			} else {
+				assert.Equal(t, tc.expectedCancels, cancelled)
+			}
+			if tc.expectedPartial != nil {
+				assert.Equal(t, tc.expectedPartial, partial)
+			}
+
+			assert.Equal(t, tc.expectOwnRemoved, !containsOrder(limit.GetOrders(), own))
+			assert.NoError(t, limit.Validate())
+		})
+	}
+}
+
+func containsOrder(orders []*Order, order *Order) bool {
+	for _, o := range orders {
+		if o == order {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package orderbookv1
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MarketState is the trading phase of a pair, which decides the requests the engine accepts.
+type MarketState string
+
+const (
+	// MarketStatePreOpen is the phase before the pair opens. Resting and stop orders can be
+	// cancelled, new orders are refused.
+	MarketStatePreOpen MarketState = "pre_open"
+	// MarketStateContinuous matches orders as they arrive. This is the default.
+	MarketStateContinuous MarketState = "continuous"
+	// MarketStateAuction is the call period of an opening or closing auction. Limit orders
+	// rest without matching, even if they cross, until the pair leaves the state and the
+	// book uncrosses at a single price.
+	MarketStateAuction MarketState = "auction"
+	// MarketStateCancelOnly only accepts cancels, so users can leave a pair that is winding down.
+	MarketStateCancelOnly MarketState = "cancel_only"
+	// MarketStateHalted suspends trading: no request is accepted and the book stays as it is.
+	MarketStateHalted MarketState = "halted"
+	// MarketStateClosed is the phase after the pair closed. Resting and stop orders can be
+	// cancelled, new orders are refused.
+	MarketStateClosed MarketState = "closed"
+)
+
+var (
+	ErrInvalidMarketState = errors.New("invalid market state")
+	ErrMarketState        = errors.New("request not accepted in the current market state")
+)
+
+// Validate checks that the market state is a known value.
+func (s MarketState) Validate() error {
+	switch s {
+	case MarketStatePreOpen, MarketStateContinuous, MarketStateAuction, MarketStateCancelOnly, MarketStateHalted, MarketStateClosed:
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidMarketState, s)
+}
+
+// Accepts reports whether the engine accepts a request of the given type in the state.
+// Market state changes are not requests of the pair's users and are always accepted.
+func (s MarketState) Accepts(orderType OrderType) bool {
+	if orderType == OrderTypeMarketState {
+		return true
+	}
+
+	switch s {
+	case MarketStateContinuous:
+		return true
+	case MarketStateAuction:
+		return orderType != OrderTypeMarket
+	case MarketStatePreOpen, MarketStateCancelOnly, MarketStateClosed:
+		return orderType == OrderTypeCancel || orderType == OrderTypeMassCancel
+	}
+	return false
+}
+
+// Refuse returns the reason a request of the given type is refused in the state.
+func (s MarketState) Refuse(orderType OrderType) error {
+	return fmt.Errorf("%w: %s requests are not accepted while the pair is %s", ErrMarketState, orderType, s)
+}
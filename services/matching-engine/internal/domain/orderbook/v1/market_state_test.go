@@ -0,0 +1,48 @@
+package orderbookv1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarketState_Accepts(t *testing.T) {
+	orderTypes := []OrderType{
+		OrderTypeLimit, OrderTypeMarket, OrderTypeStop, OrderTypeStopLimit, OrderTypeReplace, OrderTypeCancel,
+		OrderTypeMassCancel, OrderTypeMarketState,
+	}
+	testCases := []struct {
+		state    MarketState
+		accepted []OrderType
+	}{
+		{state: MarketStateContinuous, accepted: orderTypes},
+		{state: MarketStateAuction, accepted: []OrderType{
+			OrderTypeLimit, OrderTypeStop, OrderTypeStopLimit, OrderTypeReplace, OrderTypeCancel, OrderTypeMassCancel, OrderTypeMarketState,
+		}},
+		{state: MarketStatePreOpen, accepted: []OrderType{OrderTypeCancel, OrderTypeMassCancel, OrderTypeMarketState}},
+		{state: MarketStateCancelOnly, accepted: []OrderType{OrderTypeCancel, OrderTypeMassCancel, OrderTypeMarketState}},
+		{state: MarketStateClosed, accepted: []OrderType{OrderTypeCancel, OrderTypeMassCancel, OrderTypeMarketState}},
+		{state: MarketStateHalted, accepted: []OrderType{OrderTypeMarketState}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(string(testCase.state), func(t *testing.T) {
+			assert.NoError(t, testCase.state.Validate())
+
+			var accepted []OrderType
+			for _, orderType := range orderTypes {
+				if testCase.state.Accepts(orderType) {
+					accepted = append(accepted, orderType)
+				}
+			}
+			assert.Equal(t, testCase.accepted, accepted)
+		})
+	}
+
+	assert.ErrorIs(t, MarketState("").Validate(), ErrInvalidMarketState)
+	assert.ErrorIs(t, MarketState("suspended").Validate(), ErrInvalidMarketState)
+
+	err := MarketStateHalted.Refuse(OrderTypeCancel)
+	assert.ErrorIs(t, err, ErrMarketState)
+	assert.Equal(t, RejectCodeMarketState, RejectCodeOf(err))
+}
@@ -0,0 +1,63 @@
+package orderbookv1
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Side is the side of the book a mass cancel applies to.
+type Side string
+
+const (
+	// SideBuy selects bids.
+	SideBuy Side = "buy"
+	// SideSell selects asks.
+	SideSell Side = "sell"
+)
+
+var (
+	ErrInvalidMassCancel = errors.New("invalid mass cancel")
+	ErrMassCancelled     = errors.New("cancelled by a mass cancel at the owner's request")
+)
+
+// MassCancelFilter selects the resting orders of one user a mass cancel removes. Prices
+// are in price units of the pair's Scale; a zero filter value matches every order.
+type MassCancelFilter struct {
+	UserID   string
+	Side     Side  // buy or sell, empty for both
+	MinPrice int64 // Lowest price cancelled
+	MaxPrice int64 // Highest price cancelled
+}
+
+// Validate checks that the filter names a user, a known side and a price range that is
+// not empty.
+func (f MassCancelFilter) Validate() error {
+	if f.UserID == "" {
+		return fmt.Errorf("%w: user ID cannot be empty", ErrInvalidMassCancel)
+	}
+	if f.Side != "" && f.Side != SideBuy && f.Side != SideSell {
+		return fmt.Errorf("%w: unknown side %q", ErrInvalidMassCancel, f.Side)
+	}
+	if f.MinPrice < 0 || f.MaxPrice < 0 {
+		return fmt.Errorf("%w: prices must not be negative", ErrInvalidMassCancel)
+	}
+	if f.MaxPrice > 0 && f.MinPrice > f.MaxPrice {
+		return fmt.Errorf("%w: min price is above max price", ErrInvalidMassCancel)
+	}
+	return nil
+}
+
+// Matches reports whether a resting order is selected by the filter.
+func (f MassCancelFilter) Matches(order *Order) bool {
+	switch {
+	case order.UserID != f.UserID:
+		return false
+	case f.Side == SideBuy && !order.Bid, f.Side == SideSell && order.Bid:
+		return false
+	case f.MinPrice > 0 && order.Price < f.MinPrice:
+		return false
+	case f.MaxPrice > 0 && order.Price > f.MaxPrice:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+package orderbookv1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMassCancelFilter_Validate(t *testing.T) {
+	assert.NoError(t, MassCancelFilter{UserID: "alice"}.Validate())
+	assert.NoError(t, MassCancelFilter{UserID: "alice", Side: SideSell, MinPrice: 100, MaxPrice: 100}.Validate())
+
+	for name, filter := range map[string]MassCancelFilter{
+		"no user":        {Side: SideBuy},
+		"unknown side":   {UserID: "alice", Side: "both"},
+		"negative price": {UserID: "alice", MinPrice: -1},
+		"empty range":    {UserID: "alice", MinPrice: 101, MaxPrice: 100},
+	} {
+		assert.ErrorIs(t, filter.Validate(), ErrInvalidMassCancel, name)
+	}
+}
+
+func TestMassCancelFilter_Matches(t *testing.T) {
+	bid := &Order{UserID: "alice", Bid: true, Price: 100}
+	ask := &Order{UserID: "alice", Price: 110}
+
+	testCases := []struct {
+		name   string
+		filter MassCancelFilter
+		bid    bool
+		ask    bool
+	}{
+		{name: "every order of the user", filter: MassCancelFilter{UserID: "alice"}, bid: true, ask: true},
+		{name: "another user", filter: MassCancelFilter{UserID: "bob"}},
+		{name: "buy side", filter: MassCancelFilter{UserID: "alice", Side: SideBuy}, bid: true},
+		{name: "sell side", filter: MassCancelFilter{UserID: "alice", Side: SideSell}, ask: true},
+		{name: "from a price", filter: MassCancelFilter{UserID: "alice", MinPrice: 105}, ask: true},
+		{name: "up to a price", filter: MassCancelFilter{UserID: "alice", MaxPrice: 105}, bid: true},
+		{name: "bounds are inclusive", filter: MassCancelFilter{UserID: "alice", MinPrice: 100, MaxPrice: 110}, bid: true, ask: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.bid, testCase.filter.Matches(bid))
+			assert.Equal(t, testCase.ask, testCase.filter.Matches(ask))
+		})
+	}
+}
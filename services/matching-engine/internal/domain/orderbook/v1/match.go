@@ -1,11 +1,26 @@
 package orderbookv1
 
-// Match represents a match between an ask and a bid order.
+// Match represents a match between an ask and a bid order, in units of the pair's Scale.
+// The orders keep changing after the match, so the size each had left right after it,
+// hidden reserve included, is recorded with the match.
 type Match struct {
-	Ask        *Order  `json:"ask"`
-	Bid        *Order  `json:"bid"`
-	SizeFilled float64 `json:"sizeFilled"`
-	Price      float64 `json:"price"`
+	Ask          *Order `json:"ask"`
+	Bid          *Order `json:"bid"`
+	SizeFilled   int64  `json:"sizeFilled"`
+	Price        int64  `json:"price"`
+	AskRemaining int64  `json:"askRemaining"`
+	BidRemaining int64  `json:"bidRemaining"`
+
+	// Budget a quote-size taker had left right after the match, in price units
+	QuoteRemaining int64 `json:"quoteRemaining"`
+}
+
+// Remaining returns the size order, one of the two sides of the match, had left right after it.
+func (m *Match) Remaining(order *Order) int64 {
+	if order == m.Ask {
+		return m.AskRemaining
+	}
+	return m.BidRemaining
 }
 
 // AskIsFilled checks if the ask order is filled.
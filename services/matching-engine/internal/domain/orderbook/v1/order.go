@@ -1,6 +1,9 @@
 package orderbookv1
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"time"
 
 	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
@@ -16,45 +19,288 @@ const (
 	OrderTypeLimit OrderType = "limit"
 	// OrderTypeCancel represents a cancel order.
 	OrderTypeCancel OrderType = "cancel"
+	// OrderTypeReplace represents an amend of a resting order's price and size.
+	OrderTypeReplace OrderType = "replace"
+	// OrderTypeStop represents a stop order, sent as a market order once triggered.
+	OrderTypeStop OrderType = "stop"
+	// OrderTypeStopLimit represents a stop-limit order, sent as a limit order once triggered.
+	OrderTypeStopLimit OrderType = "stop_limit"
+	// OrderTypeMarketState is a control message that moves the pair to MarketState.
+	OrderTypeMarketState OrderType = "market_state"
+	// OrderTypeMassCancel represents a cancel of every resting order of a user that
+	// matches a side and price range.
+	OrderTypeMassCancel OrderType = "mass_cancel"
 )
 
+// TimeInForce represents how long an order stays active in the order book.
+type TimeInForce string
+
+const (
+	// TimeInForceGTC rests the unfilled part of the order until it is cancelled.
+	TimeInForceGTC TimeInForce = "gtc"
+	// TimeInForceIOC fills what it can immediately and cancels the rest.
+	TimeInForceIOC TimeInForce = "ioc"
+	// TimeInForceFOK fills the whole order immediately or cancels it entirely.
+	TimeInForceFOK TimeInForce = "fok"
+	// TimeInForceGTD rests the unfilled part of the order until ExpireAt.
+	TimeInForceGTD TimeInForce = "gtd"
+)
+
+// PostOnlyMode decides what happens to a post-only order that would cross the book.
+type PostOnlyMode string
+
+const (
+	// PostOnlyModeReject rejects the order. This is the default.
+	PostOnlyModeReject PostOnlyMode = "reject"
+	// PostOnlyModeReprice moves the order one tick behind the best opposite price.
+	PostOnlyModeReprice PostOnlyMode = "reprice"
+)
+
+// SelfTradePrevention decides what happens when an order would match a resting order
+// of the same user. The mode of the incoming order applies.
+type SelfTradePrevention string
+
+const (
+	// SelfTradePreventionNone lets orders of the same user match. This is the default.
+	SelfTradePreventionNone SelfTradePrevention = "none"
+	// SelfTradePreventionCancelNewest cancels the remainder of the incoming order.
+	SelfTradePreventionCancelNewest SelfTradePrevention = "cancel_newest"
+	// SelfTradePreventionCancelOldest cancels the resting order and keeps matching.
+	SelfTradePreventionCancelOldest SelfTradePrevention = "cancel_oldest"
+	// SelfTradePreventionCancelBoth cancels the resting order and the remainder of the incoming order.
+	SelfTradePreventionCancelBoth SelfTradePrevention = "cancel_both"
+	// SelfTradePreventionDecrementAndCancel reduces both orders by the smaller size and cancels
+	// whichever order is left with nothing.
+	SelfTradePreventionDecrementAndCancel SelfTradePrevention = "decrement_and_cancel"
+)
+
+var (
+	ErrInvalidTimeInForce  = errors.New("invalid time in force")
+	ErrInvalidExpireAt     = errors.New("good-till-date order requires a future expiry")
+	ErrInvalidPostOnlyMode = errors.New("invalid post-only mode")
+	ErrPostOnlyNotAllowed  = errors.New("post-only is only supported for resting limit orders")
+	ErrPostOnlyWouldCross  = errors.New("post-only order would cross the book")
+	ErrInvalidDisplaySize  = errors.New("display size must not be negative")
+	ErrInvalidSelfTrade    = errors.New("invalid self-trade prevention mode")
+	ErrSelfTradePrevented  = errors.New("cancelled by self-trade prevention")
+	ErrUnknownOrder        = errors.New("order does not exist")
+	ErrCancelRequested     = errors.New("cancelled at the owner's request")
+	ErrOrderExpired        = errors.New("good-till-date order expired")
+	ErrUnfilledRemainder   = errors.New("unfilled remainder cancelled")
+	ErrInvalidQuoteSize    = errors.New("quote size is only supported for market orders without a size that are not fill-or-kill")
+	ErrInvalidSlippage     = errors.New("worst price and max slippage are only supported for market and stop orders, and max slippage must be between 0 and 10000 bps")
+	ErrSlippageLimit       = errors.New("unfilled remainder cancelled at the worst acceptable price")
+)
+
+// Validate checks that the time in force is a known value. An empty value is treated as GTC.
+func (t TimeInForce) Validate() error {
+	switch t {
+	case "", TimeInForceGTC, TimeInForceIOC, TimeInForceFOK, TimeInForceGTD:
+		return nil
+	}
+	return ErrInvalidTimeInForce
+}
+
+// Validate checks that the post-only mode is a known value. An empty value is treated as reject.
+func (m PostOnlyMode) Validate() error {
+	switch m {
+	case "", PostOnlyModeReject, PostOnlyModeReprice:
+		return nil
+	}
+	return ErrInvalidPostOnlyMode
+}
+
+// Validate checks that the self-trade prevention mode is a known value. An empty value
+// falls back to the pair's mode.
+func (s SelfTradePrevention) Validate() error {
+	switch s {
+	case "", SelfTradePreventionNone, SelfTradePreventionCancelNewest, SelfTradePreventionCancelOldest,
+		SelfTradePreventionCancelBoth, SelfTradePreventionDecrementAndCancel:
+		return nil
+	}
+	return ErrInvalidSelfTrade
+}
+
+// IsEnabled reports whether the mode stops orders of the same user from matching.
+func (s SelfTradePrevention) IsEnabled() bool {
+	return s != "" && s != SelfTradePreventionNone
+}
+
+// IsImmediate reports whether an order with this time in force must never rest in the book.
+func (t TimeInForce) IsImmediate() bool {
+	return t == TimeInForceIOC || t == TimeInForceFOK
+}
+
 // Order represents a single order in the order book.
+// Sizes are in size units of the pair's Scale.
 type Order struct {
-	ID        string  `json:"id"`
-	UserID    string  `json:"userID"`
-	Size      float64 `json:"size"`
-	Bid       bool    `json:"bid"`
-	Limit     *Limit  `json:"-"`
-	Timestamp int64   `json:"timestamp"`
-	Sequence  int64   `json:"sequence"` // Sequence number for the order
+	ID          string      `json:"id"`
+	UserID      string      `json:"userID"`
+	Size        int64       `json:"size"`
+	Bid         bool        `json:"bid"`
+	Price       int64       `json:"price"` // Price of the limit the order rests or last rested at, set by the limit
+	Limit       *Limit      `json:"-"`
+	prev, next  *Order      // Neighbours in the limit's queue, maintained by the limit
+	Timestamp   int64       `json:"timestamp"`
+	Sequence    int64       `json:"sequence"` // Sequence number for the order
+	TimeInForce TimeInForce `json:"timeInForce"`
+	ExpireAt    int64       `json:"expireAt"` // Expiry in Unix nanoseconds, only used by GTD orders
+
+	PostOnly     bool         `json:"postOnly"`
+	PostOnlyMode PostOnlyMode `json:"postOnlyMode"`
+
+	// Iceberg orders only show DisplaySize in the book; Size is the visible slice
+	// and HiddenSize the reserve it is refreshed from. HiddenSize is never serialized.
+	DisplaySize int64 `json:"displaySize"`
+	HiddenSize  int64 `json:"-"`
+
+	SelfTradePrevention SelfTradePrevention `json:"selfTradePrevention"`
+	// Orders cancelled by self-trade prevention while this order was matched, set by the order book
+	SelfTradeCancels []SelfTradeCancel `json:"-"`
+
+	// Worst price a market order may fill at, zero for none. Protected is set by the order
+	// book when the order stopped filling there with size left.
+	ProtectionPrice int64 `json:"-"`
+	Protected       bool  `json:"-"`
+
+	// The owner's own limits on a market order, zero for none: the worst price it may fill
+	// at and how far in basis points it may fill from the best opposite price it meets.
+	// SlippageLimited is set by the order book when the order stopped filling at them.
+	WorstPrice      int64 `json:"-"`
+	MaxSlippageBps  int64 `json:"-"`
+	SlippageLimited bool  `json:"-"`
+
+	// Budget of a quote-size market order in price units, zero for orders with a size. The
+	// order book sets QuoteRemaining to the budget left after matching, and QuoteDust when
+	// that is too little to buy a whole lot at the best price.
+	QuoteSize      int64 `json:"-"`
+	QuoteRemaining int64 `json:"-"`
+	QuoteDust      bool  `json:"-"`
+}
+
+// SelfTradeCancel records an order cancelled by self-trade prevention, or only reduced
+// by decrement_and_cancel, in which case Partial is set and the order stays live.
+type SelfTradeCancel struct {
+	Order   *Order
+	Price   int64 // Limit price of a cancelled resting order, zero for the incoming order
+	Size    int64 // Size that was cancelled
+	Partial bool
 }
 
 // PlaceOrderRequest represents a request to place an order in the order book.
+// Prices and sizes are in units of the pair's Scale.
 type PlaceOrderRequest struct {
-	OrderID string    `json:"orderID"`
-	UserID  string    `json:"userID"`
-	Type    OrderType `json:"type"`
-	Bid     bool      `json:"bid"`
-	Size    float64   `json:"size"`
-	Price   float64   `json:"price"`
-	Offset  int64     // Offset for the order in the stream
+	OrderID     string      `json:"orderID"`
+	UserID      string      `json:"userID"`
+	Type        OrderType   `json:"type"`
+	Bid         bool        `json:"bid"`
+	Size        int64       `json:"size"`
+	QuoteSize   int64       `json:"quoteSize"` // Budget of a market order in price units, given instead of a size
+	Price       int64       `json:"price"`
+	StopPrice   int64       `json:"stopPrice"` // Trigger price of stop and stop-limit orders
+	TimeInForce TimeInForce `json:"timeInForce"`
+	ExpireAt    int64       `json:"expireAt"`
+
+	// Market and stop orders only: the worst price the order may fill at, and how far in
+	// basis points it may fill from the best opposite price it meets. Zero is no limit
+	WorstPrice     int64 `json:"worstPrice"`
+	MaxSlippageBps int64 `json:"maxSlippageBps"`
+
+	PostOnly     bool         `json:"postOnly"`
+	PostOnlyMode PostOnlyMode `json:"postOnlyMode"`
+
+	DisplaySize int64 `json:"displaySize"` // Visible quantity of an iceberg order, zero shows the full size
+
+	SelfTradePrevention SelfTradePrevention `json:"selfTradePrevention"`
+
+	MarketState MarketState `json:"marketState"` // State a market state control message moves the pair to
+
+	// Filters of a mass cancel besides UserID: the side, empty for both, and the price
+	// range, zero for no bound
+	Side     Side  `json:"side"`
+	MinPrice int64 `json:"minPrice"`
+	MaxPrice int64 `json:"maxPrice"`
+
+	Offset    int64 `json:"offset"`    // Offset of the order message on the topic, -1 for requests the engine makes
+	Timestamp int64 `json:"timestamp"` // Time the engine accepted the request in Unix nanoseconds, stamped on the orders it places
 }
 
-// FromKafkaPayload converts a Kafka payload to a PlaceOrderRequest.
-func (r *PlaceOrderRequest) FromKafkaPayload(payload *pb.PlaceOrderPayload) *PlaceOrderRequest {
-	return &PlaceOrderRequest{
-		OrderID: payload.OrderID,
-		UserID:  payload.UserID,
-		Type:    OrderType(payload.Type),
-		Bid:     payload.Bid,
-		Size:    payload.Size,
-		Price:   payload.Price,
-		Offset:  payload.Offset,
+// FromKafkaPayload converts a Kafka payload to a PlaceOrderRequest, moving prices and
+// sizes to units of the pair's scale. Values that need more decimals than the pair
+// allows are rejected with ErrPrecisionLoss rather than rounded.
+func (r *PlaceOrderRequest) FromKafkaPayload(payload *pb.PlaceOrderPayload, scale Scale) (*PlaceOrderRequest, error) {
+	size, err := scale.ToSize(payload.Size)
+	if err != nil {
+		return nil, fmt.Errorf("size: %w", err)
 	}
+	price, err := scale.ToPrice(payload.Price)
+	if err != nil {
+		return nil, fmt.Errorf("price: %w", err)
+	}
+	stopPrice, err := scale.ToPrice(payload.StopPrice)
+	if err != nil {
+		return nil, fmt.Errorf("stop price: %w", err)
+	}
+	displaySize, err := scale.ToSize(payload.DisplaySize)
+	if err != nil {
+		return nil, fmt.Errorf("display size: %w", err)
+	}
+	quoteSize, err := scale.ToPrice(payload.QuoteSize)
+	if err != nil {
+		return nil, fmt.Errorf("quote size: %w", err)
+	}
+	worstPrice, err := scale.ToPrice(payload.WorstPrice)
+	if err != nil {
+		return nil, fmt.Errorf("worst price: %w", err)
+	}
+	minPrice, err := scale.ToPrice(payload.MinPrice)
+	if err != nil {
+		return nil, fmt.Errorf("min price: %w", err)
+	}
+	maxPrice, err := scale.ToPrice(payload.MaxPrice)
+	if err != nil {
+		return nil, fmt.Errorf("max price: %w", err)
+	}
+
+	return &PlaceOrderRequest{
+		OrderID:     payload.OrderID,
+		UserID:      payload.UserID,
+		Type:        OrderType(payload.Type),
+		Bid:         payload.Bid,
+		Size:        size,
+		QuoteSize:   quoteSize,
+		Price:       price,
+		StopPrice:   stopPrice,
+		TimeInForce: TimeInForce(payload.TimeInForce),
+		ExpireAt:    payload.ExpireAt,
+
+		WorstPrice:     worstPrice,
+		MaxSlippageBps: payload.MaxSlippageBps,
+
+		PostOnly:     payload.PostOnly,
+		PostOnlyMode: PostOnlyMode(payload.PostOnlyMode),
+
+		DisplaySize: displaySize,
+
+		SelfTradePrevention: SelfTradePrevention(payload.SelfTradePrevention),
+
+		MarketState: MarketState(payload.MarketState),
+
+		Side:     Side(payload.Side),
+		MinPrice: minPrice,
+		MaxPrice: maxPrice,
+
+		Offset: payload.Offset,
+	}, nil
+}
+
+// MassCancelFilter returns the orders a mass cancel request selects.
+func (r *PlaceOrderRequest) MassCancelFilter() MassCancelFilter {
+	return MassCancelFilter{UserID: r.UserID, Side: r.Side, MinPrice: r.MinPrice, MaxPrice: r.MaxPrice}
 }
 
 // NewOrder creates a new order with the given parameters.
-func NewOrder(userID string, size float64, bid bool, id string) *Order {
+func NewOrder(userID string, size int64, bid bool, id string) *Order {
 	return &Order{
 		ID:        id,
 		UserID:    userID,
@@ -74,9 +320,85 @@ func (o *Order) IsAsk() bool {
 	return !o.Bid
 }
 
-// IsFilled checks if the order is filled (size is zero).
+// IsExpired checks if a GTD order has expired at the given Unix nanosecond time.
+func (o *Order) IsExpired(now int64) bool {
+	return o.TimeInForce == TimeInForceGTD && o.ExpireAt <= now
+}
+
+// IsFilled checks if the order is filled (visible and hidden size are zero).
 func (o *Order) IsFilled() bool {
-	return o.Size == 0.0
+	return o.Size == 0 && o.HiddenSize == 0
+}
+
+// IsQuote checks if the order is a market order that spends a quote budget instead of filling a size.
+func (o *Order) IsQuote() bool {
+	return o.QuoteSize > 0
+}
+
+// WorstAcceptablePrice returns the worst price the owner's limits let a market order fill
+// at when the best opposite price is best: the tighter of WorstPrice and MaxSlippageBps
+// away from best. Zero means the order has no limit.
+func (o *Order) WorstAcceptablePrice(best int64) int64 {
+	if o.MaxSlippageBps <= 0 || best <= 0 {
+		return o.WorstPrice
+	}
+
+	width := applyBps(best, o.MaxSlippageBps)
+	if o.Bid {
+		if width > math.MaxInt64-best {
+			return o.WorstPrice
+		}
+		if o.WorstPrice > 0 {
+			return min(o.WorstPrice, best+width)
+		}
+		return best + width
+	}
+	// A sell may not fill at zero, however much slippage it accepts
+	return max(o.WorstPrice, best-width, 1)
+}
+
+// IsIceberg checks if the order only shows part of its size in the book.
+func (o *Order) IsIceberg() bool {
+	return o.DisplaySize > 0
+}
+
+// TotalSize returns the visible and hidden size of the order.
+func (o *Order) TotalSize() int64 {
+	return o.Size + o.HiddenSize
+}
+
+// Hide moves everything above the display size of an iceberg order into its hidden reserve.
+func (o *Order) Hide() {
+	if !o.IsIceberg() || o.Size <= o.DisplaySize {
+		return
+	}
+	o.HiddenSize += o.Size - o.DisplaySize
+	o.Size = o.DisplaySize
+}
+
+// Refresh tops the visible size of an iceberg order back up from its hidden reserve
+// and returns the size that became visible.
+func (o *Order) Refresh() int64 {
+	if !o.IsIceberg() || o.HiddenSize <= 0 || o.Size >= o.DisplaySize {
+		return 0
+	}
+
+	refill := o.DisplaySize - o.Size
+	if refill > o.HiddenSize {
+		refill = o.HiddenSize
+	}
+	o.Size += refill
+	o.HiddenSize -= refill
+	return refill
+}
+
+// hasPriorityOver reports whether o is ahead of other in a limit's queue: the earlier
+// timestamp first, then the lower sequence.
+func (o *Order) hasPriorityOver(other *Order) bool {
+	if o.Timestamp == other.Timestamp {
+		return o.Sequence < other.Sequence
+	}
+	return o.Timestamp < other.Timestamp
 }
 
 // NextSequence increments the order's sequence number and returns the new value.
@@ -0,0 +1,36 @@
+package orderbookv1
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrder_WorstAcceptablePrice(t *testing.T) {
+	tests := []struct {
+		name       string
+		bid        bool
+		worstPrice int64
+		bps        int64
+		best       int64
+		want       int64
+	}{
+		{name: "no limit", bid: true, best: 10_000, want: 0},
+		{name: "worst price only", bid: true, worstPrice: 10_500, best: 10_000, want: 10_500},
+		{name: "buy slippage", bid: true, bps: 150, best: 10_000, want: 10_150},
+		{name: "sell slippage", bps: 150, best: 10_000, want: 9_850},
+		{name: "buy takes the tighter limit", bid: true, worstPrice: 10_100, bps: 150, best: 10_000, want: 10_100},
+		{name: "sell takes the tighter limit", worstPrice: 9_800, bps: 150, best: 10_000, want: 9_850},
+		{name: "sell never reaches zero", bps: 10_000, best: 10_000, want: 1},
+		{name: "empty book leaves the worst price", bid: true, worstPrice: 10_500, bps: 150, want: 10_500},
+		{name: "buy limit beyond any price", bid: true, bps: 10_000, best: math.MaxInt64 - 1, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := &Order{Bid: tt.bid, WorstPrice: tt.worstPrice, MaxSlippageBps: tt.bps}
+			assert.Equal(t, tt.want, order.WorstAcceptablePrice(tt.best))
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package orderbookv1
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// PriceReference is the price a pair's price band is centred on.
+type PriceReference string
+
+const (
+	// PriceReferenceLastTrade centres the band on the last trade price. This is the default.
+	PriceReferenceLastTrade PriceReference = "last_trade"
+	// PriceReferenceMovingAverage centres the band on the average price of the last trades.
+	PriceReferenceMovingAverage PriceReference = "moving_average"
+)
+
+// bpsPerUnit is the number of basis points in a whole, 100%.
+const bpsPerUnit = 10_000
+
+var (
+	ErrInvalidPriceBand      = errors.New("invalid price band")
+	ErrInvalidCircuitBreaker = errors.New("invalid circuit breaker")
+	ErrPriceProtection       = errors.New("unfilled remainder cancelled at the protection price")
+)
+
+// TradePrint is the price of a trade and the time it was made in Unix nanoseconds.
+type TradePrint struct {
+	Price     int64
+	Timestamp int64
+}
+
+// PriceBand limits the prices orders are accepted at to a width either side of a reference
+// price. Limit orders outside the band are rejected and market orders only fill inside it.
+type PriceBand struct {
+	Bps           int64 // Width either side of the reference in basis points, zero disables the band
+	Reference     PriceReference
+	AverageTrades int // Trades averaged by the moving average reference
+}
+
+// NewPriceBand creates a band percent wide either side of the reference price. The width
+// must be a whole number of basis points.
+func NewPriceBand(percent float64, reference PriceReference, averageTrades int) (PriceBand, error) {
+	bps, err := percentToBps(percent)
+	if err != nil {
+		return PriceBand{}, fmt.Errorf("%w: %w", ErrInvalidPriceBand, err)
+	}
+	if reference == "" {
+		reference = PriceReferenceLastTrade
+	}
+
+	band := PriceBand{Bps: bps, Reference: reference, AverageTrades: averageTrades}
+	if err := band.Validate(); err != nil {
+		return PriceBand{}, err
+	}
+	return band, nil
+}
+
+// Validate checks that the band is at most 100% wide and has a known reference.
+func (b PriceBand) Validate() error {
+	if b.Bps < 0 || b.Bps > bpsPerUnit {
+		return fmt.Errorf("%w: width must be between 0 and 100%%", ErrInvalidPriceBand)
+	}
+	switch b.Reference {
+	case PriceReferenceLastTrade:
+	case PriceReferenceMovingAverage:
+		if b.AverageTrades <= 0 {
+			return fmt.Errorf("%w: moving average needs at least one trade", ErrInvalidPriceBand)
+		}
+	default:
+		return fmt.Errorf("%w: unknown reference %q", ErrInvalidPriceBand, b.Reference)
+	}
+	return nil
+}
+
+// IsEnabled reports whether the band limits prices.
+func (b PriceBand) IsEnabled() bool {
+	return b.Bps > 0
+}
+
+// Limits returns the lowest and highest price of the band around reference.
+func (b PriceBand) Limits(reference int64) (int64, int64) {
+	width := applyBps(reference, b.Bps)
+	return reference - width, reference + width
+}
+
+// Check returns a violation of the price band if price is outside the band around
+// reference. Before the pair's first trade there is no reference, and every price passes.
+func (b PriceBand) Check(field string, price, reference int64, scale Scale) error {
+	if !b.IsEnabled() || reference <= 0 {
+		return nil
+	}
+
+	low, high := b.Limits(reference)
+	limit := low
+	if price > high {
+		limit = high
+	} else if price >= low {
+		return nil
+	}
+
+	return &SpecViolation{
+		Code:  RejectCodePriceBand,
+		Field: field,
+		Value: scale.FormatPrice(price),
+		Limit: scale.FormatPrice(limit),
+	}
+}
+
+// ProtectionPrice returns the worst price a market order may fill at: the top of the band
+// around reference for buys, the bottom for sells. Zero means the order is not limited.
+func (b PriceBand) ProtectionPrice(bid bool, reference int64) int64 {
+	if !b.IsEnabled() || reference <= 0 {
+		return 0
+	}
+
+	low, high := b.Limits(reference)
+	if bid {
+		return high
+	}
+	// A sell may not fill at zero, however wide the band
+	return max(low, 1)
+}
+
+// CircuitBreaker halts a pair for CoolDown when its price moves more than MoveBps within Window.
+type CircuitBreaker struct {
+	MoveBps  int64 // Largest move in basis points, zero disables the breaker
+	Window   time.Duration
+	CoolDown time.Duration
+}
+
+// NewCircuitBreaker creates a breaker that trips on a move of more than percent within
+// window and halts the pair for coolDown.
+func NewCircuitBreaker(percent float64, window, coolDown time.Duration) (CircuitBreaker, error) {
+	bps, err := percentToBps(percent)
+	if err != nil {
+		return CircuitBreaker{}, fmt.Errorf("%w: %w", ErrInvalidCircuitBreaker, err)
+	}
+
+	breaker := CircuitBreaker{MoveBps: bps, Window: window, CoolDown: coolDown}
+	if err := breaker.Validate(); err != nil {
+		return CircuitBreaker{}, err
+	}
+	return breaker, nil
+}
+
+// Validate checks that an enabled breaker has a window and a cool-down.
+func (c CircuitBreaker) Validate() error {
+	if c.MoveBps < 0 {
+		return fmt.Errorf("%w: move must not be negative", ErrInvalidCircuitBreaker)
+	}
+	if c.IsEnabled() && (c.Window <= 0 || c.CoolDown <= 0) {
+		return fmt.Errorf("%w: window and cool-down must be positive", ErrInvalidCircuitBreaker)
+	}
+	return nil
+}
+
+// IsEnabled reports whether the breaker can halt the pair.
+func (c CircuitBreaker) IsEnabled() bool {
+	return c.MoveBps > 0
+}
+
+// Exceeds reports whether moving from one price to another is more than the breaker allows.
+func (c CircuitBreaker) Exceeds(from, to int64) bool {
+	move := to - from
+	if move < 0 {
+		move = -move
+	}
+	return move > applyBps(from, c.MoveBps)
+}
+
+// percentToBps converts a percentage to whole basis points.
+func percentToBps(percent float64) (int64, error) {
+	bps := math.Round(percent * 100)
+	if math.Abs(percent*100-bps) > 1e-6 {
+		return 0, fmt.Errorf("%v%% is not a whole number of basis points", percent)
+	}
+	return int64(bps), nil
+}
+
+// applyBps returns bps basis points of value, rounded down, without overflowing.
+func applyBps(value, bps int64) int64 {
+	return value/bpsPerUnit*bps + value%bpsPerUnit*bps/bpsPerUnit
+}
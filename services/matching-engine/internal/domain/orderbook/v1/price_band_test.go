@@ -0,0 +1,94 @@
+package orderbookv1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceBand_Check(t *testing.T) {
+	scale := Scale{PriceDecimals: 2}
+	band, err := NewPriceBand(5, "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), band.Bps)
+	assert.Equal(t, PriceReferenceLastTrade, band.Reference)
+
+	testCases := []struct {
+		name      string
+		price     int64
+		reference int64
+		limit     string
+	}{
+		{name: "inside", price: 10_300, reference: 10_000},
+		{name: "at the top", price: 10_500, reference: 10_000},
+		{name: "at the bottom", price: 9_500, reference: 10_000},
+		{name: "above", price: 10_501, reference: 10_000, limit: "105.00"},
+		{name: "below", price: 9_499, reference: 10_000, limit: "95.00"},
+		{name: "no reference", price: 1, reference: 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := band.Check("price", testCase.price, testCase.reference, scale)
+			if testCase.limit == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var violation *SpecViolation
+			require.ErrorAs(t, err, &violation)
+			assert.Equal(t, RejectCodePriceBand, violation.Code)
+			assert.Equal(t, "price", violation.Field)
+			assert.Equal(t, testCase.limit, violation.Limit)
+			assert.Equal(t, RejectCodePriceBand, RejectCodeOf(err))
+		})
+	}
+
+	assert.NoError(t, PriceBand{Reference: PriceReferenceLastTrade}.Check("price", 1, 10_000, scale))
+}
+
+func TestPriceBand_ProtectionPrice(t *testing.T) {
+	band := PriceBand{Bps: 250, Reference: PriceReferenceLastTrade}
+	assert.Equal(t, int64(10_250), band.ProtectionPrice(true, 10_000))
+	assert.Equal(t, int64(9_750), band.ProtectionPrice(false, 10_000))
+	assert.Zero(t, band.ProtectionPrice(true, 0))
+
+	wide := PriceBand{Bps: bpsPerUnit, Reference: PriceReferenceLastTrade}
+	assert.Equal(t, int64(1), wide.ProtectionPrice(false, 10_000))
+	assert.Zero(t, PriceBand{}.ProtectionPrice(true, 10_000))
+}
+
+func TestPriceBand_Validate(t *testing.T) {
+	_, err := NewPriceBand(101, PriceReferenceLastTrade, 0)
+	assert.ErrorIs(t, err, ErrInvalidPriceBand)
+	_, err = NewPriceBand(0.001, PriceReferenceLastTrade, 0)
+	assert.ErrorIs(t, err, ErrInvalidPriceBand)
+	_, err = NewPriceBand(5, "vwap", 0)
+	assert.ErrorIs(t, err, ErrInvalidPriceBand)
+	_, err = NewPriceBand(5, PriceReferenceMovingAverage, 0)
+	assert.ErrorIs(t, err, ErrInvalidPriceBand)
+
+	band, err := NewPriceBand(0.25, PriceReferenceMovingAverage, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(25), band.Bps)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	breaker, err := NewCircuitBreaker(10, time.Minute, 5*time.Minute)
+	require.NoError(t, err)
+	assert.True(t, breaker.IsEnabled())
+	assert.False(t, breaker.Exceeds(10_000, 11_000))
+	assert.True(t, breaker.Exceeds(10_000, 11_001))
+	assert.True(t, breaker.Exceeds(10_000, 8_999))
+
+	disabled, err := NewCircuitBreaker(0, 0, 0)
+	require.NoError(t, err)
+	assert.False(t, disabled.IsEnabled())
+
+	_, err = NewCircuitBreaker(10, 0, time.Minute)
+	assert.ErrorIs(t, err, ErrInvalidCircuitBreaker)
+	_, err = NewCircuitBreaker(-1, time.Minute, time.Minute)
+	assert.ErrorIs(t, err, ErrInvalidCircuitBreaker)
+}
@@ -0,0 +1,166 @@
+package orderbookv1
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// MaxDecimals is the largest precision a pair can use; 10^18 still fits in an int64.
+const MaxDecimals = 18
+
+var (
+	ErrInvalidScale  = errors.New("decimals must be between 0 and 18")
+	ErrInvalidNumber = errors.New("value must be a finite number")
+	ErrPrecisionLoss = errors.New("value has more decimals than the pair precision")
+	ErrValueTooLarge = errors.New("value does not fit the pair precision")
+)
+
+// Scale holds the per-pair precision of prices and sizes. Inside the engine every price
+// and size is an int64 count of the smallest unit, e.g. a price of 123.45 with
+// PriceDecimals 2 is stored as 12345. Conversions are exact: a value that cannot be
+// represented at the pair precision is rejected instead of rounded.
+type Scale struct {
+	PriceDecimals int32 `json:"priceDecimals"`
+	SizeDecimals  int32 `json:"sizeDecimals"`
+}
+
+// NewScale creates a scale with the given number of price and size decimals.
+func NewScale(priceDecimals, sizeDecimals int32) (Scale, error) {
+	scale := Scale{PriceDecimals: priceDecimals, SizeDecimals: sizeDecimals}
+	if err := scale.Validate(); err != nil {
+		return Scale{}, err
+	}
+	return scale, nil
+}
+
+// Validate checks that both precisions are within range.
+func (s Scale) Validate() error {
+	if s.PriceDecimals < 0 || s.PriceDecimals > MaxDecimals || s.SizeDecimals < 0 || s.SizeDecimals > MaxDecimals {
+		return fmt.Errorf("%w: price %d, size %d", ErrInvalidScale, s.PriceDecimals, s.SizeDecimals)
+	}
+	return nil
+}
+
+// ToPrice converts a decimal price to price units.
+func (s Scale) ToPrice(price float64) (int64, error) {
+	return toUnits(price, s.PriceDecimals)
+}
+
+// ToSize converts a decimal size to size units.
+func (s Scale) ToSize(size float64) (int64, error) {
+	return toUnits(size, s.SizeDecimals)
+}
+
+// FromPrice converts price units back to a decimal price.
+func (s Scale) FromPrice(price int64) float64 {
+	return fromUnits(price, s.PriceDecimals)
+}
+
+// FromSize converts size units back to a decimal size.
+func (s Scale) FromSize(size int64) float64 {
+	return fromUnits(size, s.SizeDecimals)
+}
+
+// FormatPrice formats price units as an exact decimal string.
+func (s Scale) FormatPrice(price int64) string {
+	return formatUnits(price, s.PriceDecimals)
+}
+
+// FormatSize formats size units as an exact decimal string.
+func (s Scale) FormatSize(size int64) string {
+	return formatUnits(size, s.SizeDecimals)
+}
+
+// SizeFor returns the largest size, in size units, that quote price units buy at price,
+// rounded down to a multiple of lotSize. A zero lotSize rounds to the size unit.
+func (s Scale) SizeFor(quote, price, lotSize int64) int64 {
+	if quote <= 0 || price <= 0 {
+		return 0
+	}
+
+	size := int64(math.MaxInt64)
+	hi, lo := bits.Mul64(uint64(quote), pow10(s.SizeDecimals))
+	if hi < uint64(price) {
+		if quotient, _ := bits.Div64(hi, lo, uint64(price)); quotient <= math.MaxInt64 {
+			size = int64(quotient)
+		}
+	}
+	if lotSize > 1 {
+		size -= size % lotSize
+	}
+	return size
+}
+
+// Notional returns price times size in price units, rounded up to the next price unit so
+// a budget is never overspent.
+func (s Scale) Notional(price, size int64) int64 {
+	hi, lo := bits.Mul64(uint64(price), uint64(size))
+	unit := pow10(s.SizeDecimals)
+	if hi >= unit {
+		return math.MaxInt64
+	}
+	quotient, remainder := bits.Div64(hi, lo, unit)
+	if remainder > 0 {
+		quotient++
+	}
+	if quotient > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(quotient)
+}
+
+// toUnits converts a float to units without rounding. The float is read through its
+// shortest decimal representation, which is the value the producer wrote.
+func toUnits(value float64, decimals int32) (int64, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, ErrInvalidNumber
+	}
+
+	digits := strconv.FormatFloat(value, 'f', -1, 64)
+	negative := strings.HasPrefix(digits, "-")
+	digits = strings.TrimPrefix(digits, "-")
+
+	whole, fraction, _ := strings.Cut(digits, ".")
+	if len(fraction) > int(decimals) {
+		return 0, fmt.Errorf("%w: %s has more than %d decimals", ErrPrecisionLoss, digits, decimals)
+	}
+	fraction += strings.Repeat("0", int(decimals)-len(fraction))
+
+	units, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrValueTooLarge, digits)
+	}
+	if negative {
+		units = -units
+	}
+	return units, nil
+}
+
+// fromUnits converts units to the float closest to their exact decimal value.
+func fromUnits(units int64, decimals int32) float64 {
+	value, _ := strconv.ParseFloat(formatUnits(units, decimals), 64)
+	return value
+}
+
+// formatUnits formats units as a decimal string with exactly decimals fraction digits.
+func formatUnits(units int64, decimals int32) string {
+	sign := ""
+	digits := strconv.FormatUint(uint64(units), 10)
+	if units < 0 {
+		sign = "-"
+		digits = strconv.FormatUint(uint64(-units), 10)
+	}
+	if decimals == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= int(decimals) {
+		digits = strings.Repeat("0", int(decimals)-len(digits)+1) + digits
+	}
+	split := len(digits) - int(decimals)
+	return sign + digits[:split] + "." + digits[split:]
+}
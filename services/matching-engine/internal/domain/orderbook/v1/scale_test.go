@@ -0,0 +1,131 @@
+package orderbookv1
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScale_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		scale   Scale
+		wantErr bool
+	}{
+		{name: "zero decimals", scale: Scale{}},
+		{name: "typical pair", scale: Scale{PriceDecimals: 2, SizeDecimals: 8}},
+		{name: "max decimals", scale: Scale{PriceDecimals: MaxDecimals, SizeDecimals: MaxDecimals}},
+		{name: "negative price decimals", scale: Scale{PriceDecimals: -1}, wantErr: true},
+		{name: "too many size decimals", scale: Scale{SizeDecimals: MaxDecimals + 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewScale(tt.scale.PriceDecimals, tt.scale.SizeDecimals)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidScale)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestScale_ToUnits(t *testing.T) {
+	scale := Scale{PriceDecimals: 2, SizeDecimals: 8}
+
+	tests := []struct {
+		name    string
+		convert func(float64) (int64, error)
+		value   float64
+		want    int64
+		wantErr error
+	}{
+		{name: "whole price", convert: scale.ToPrice, value: 100, want: 10000},
+		{name: "price at precision", convert: scale.ToPrice, value: 123.45, want: 12345},
+		{name: "float error is not rounded", convert: scale.ToPrice, value: 0.30000000000000004, wantErr: ErrPrecisionLoss},
+		{name: "price below precision", convert: scale.ToPrice, value: 0.001, wantErr: ErrPrecisionLoss},
+		{name: "negative price", convert: scale.ToPrice, value: -1.5, want: -150},
+		{name: "zero", convert: scale.ToPrice, value: 0, want: 0},
+		{name: "satoshi size", convert: scale.ToSize, value: 0.00000001, want: 1},
+		{name: "size below precision", convert: scale.ToSize, value: 0.000000001, wantErr: ErrPrecisionLoss},
+		{name: "size too large", convert: scale.ToSize, value: 1e12, wantErr: ErrValueTooLarge},
+		{name: "not a number", convert: scale.ToSize, value: math.NaN(), wantErr: ErrInvalidNumber},
+		{name: "infinity", convert: scale.ToPrice, value: math.Inf(1), wantErr: ErrInvalidNumber},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.convert(tt.value)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestScale_RoundTrip(t *testing.T) {
+	scale := Scale{PriceDecimals: 2, SizeDecimals: 8}
+
+	for _, price := range []float64{0.01, 0.1, 19.99, 50000, 123456.78} {
+		units, err := scale.ToPrice(price)
+		require.NoError(t, err)
+		assert.Equal(t, price, scale.FromPrice(units))
+	}
+
+	for _, size := range []float64{0.00000001, 0.1, 1.23456789, 21000000} {
+		units, err := scale.ToSize(size)
+		require.NoError(t, err)
+		assert.Equal(t, size, scale.FromSize(units))
+	}
+}
+
+func TestScale_Format(t *testing.T) {
+	scale := Scale{PriceDecimals: 2, SizeDecimals: 8}
+
+	assert.Equal(t, "123.45", scale.FormatPrice(12345))
+	assert.Equal(t, "0.05", scale.FormatPrice(5))
+	assert.Equal(t, "-1.50", scale.FormatPrice(-150))
+	assert.Equal(t, "0.00000001", scale.FormatSize(1))
+	assert.Equal(t, "1.00000000", scale.FormatSize(100000000))
+	assert.Equal(t, "42", Scale{}.FormatPrice(42))
+}
+
+func TestScale_SizeFor(t *testing.T) {
+	scale := Scale{PriceDecimals: 2, SizeDecimals: 8}
+
+	tests := []struct {
+		name    string
+		quote   int64
+		price   int64
+		lotSize int64
+		want    int64
+	}{
+		{name: "exact", quote: 10_000, price: 5_000_000, want: 200_000},
+		{name: "rounds down to the size unit", quote: 10_000, price: 3_000_000, want: 333_333},
+		{name: "rounds down to the lot", quote: 10_000, price: 3_000_000, lotSize: 100_000, want: 300_000},
+		{name: "less than a lot", quote: 10_000, price: 5_000_000, lotSize: 1_000_000, want: 0},
+		{name: "no budget", quote: 0, price: 5_000_000, want: 0},
+		{name: "larger than a size", quote: math.MaxInt64, price: 1, want: math.MaxInt64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, scale.SizeFor(tt.quote, tt.price, tt.lotSize))
+		})
+	}
+}
+
+func TestScale_Notional(t *testing.T) {
+	scale := Scale{PriceDecimals: 2, SizeDecimals: 8}
+
+	assert.Equal(t, int64(10_000), scale.Notional(5_000_000, 200_000))
+	// 30000.00 x 0.00333333 is 99.9999, rounded up to the cent
+	assert.Equal(t, int64(10_000), scale.Notional(3_000_000, 333_333))
+	assert.Equal(t, int64(math.MaxInt64), scale.Notional(math.MaxInt64, math.MaxInt64))
+}
@@ -1,24 +1,86 @@
 package snapshotv1
 
+// CurrentVersion is the snapshot format written by this engine. Version 0 snapshots
+// stored prices and sizes as floats and are migrated on load, see LegacySnapshot.
+const CurrentVersion = 1
+
 // Snapshot represents a snapshot of the order book at a specific point in time.
+// Prices and sizes are integer units at the recorded precision.
 type Snapshot struct {
+	Version           int               `json:"version"`
+	PriceDecimals     int32             `json:"priceDecimals"`
+	SizeDecimals      int32             `json:"sizeDecimals"`
 	OrderOffset       int64             `json:"orderOffset"`
 	OrderBookSnapshot OrderBookSnapshot `json:"orderBookSnapshot"`
 }
 
 // OrderBookSnapshot represents the state of the order book at a specific point in time.
 type OrderBookSnapshot struct {
-	Orders        []BookOrder `json:"orders"`
-	TradeSequence int64       `json:"tradeSequence"`
-	LogSequence   int64       `json:"logSequence"`
+	Orders         []BookOrder `json:"orders"`
+	StopOrders     []StopOrder `json:"stopOrders,omitempty"`
+	LastTradePrice int64       `json:"lastTradePrice,omitempty"`
+	TradeSequence  int64       `json:"tradeSequence"`
+	LogSequence    int64       `json:"logSequence"`
+	MarketState    string      `json:"marketState,omitempty"`
+
+	// Price protection state: the prices of the moving average reference, the highs and
+	// lows of the circuit breaker window, and the end of its cool-down
+	AveragePrices []int64      `json:"averagePrices,omitempty"`
+	BreakerHighs  []TradePrint `json:"breakerHighs,omitempty"`
+	BreakerLows   []TradePrint `json:"breakerLows,omitempty"`
+	HaltedUntil   int64        `json:"haltedUntil,omitempty"`
+
+	// Uncross last published during an auction
+	IndicativePrice  int64 `json:"indicativePrice,omitempty"`
+	IndicativeVolume int64 `json:"indicativeVolume,omitempty"`
+}
+
+// TradePrint is the price of a trade and the time it was made.
+type TradePrint struct {
+	Price     int64 `json:"price"`
+	Timestamp int64 `json:"timestamp"`
 }
 
 // BookOrder represents an order in the order book with its details.
 type BookOrder struct {
-	OrderID   string  `json:"orderID"`
-	Size      float64 `json:"size"`
-	Bid       bool    `json:"bid"`
-	Price     float64 `json:"price"`
-	UserID    string  `json:"userID"`
-	Timestamp int64   `json:"timestamp"`
+	OrderID     string `json:"orderID"`
+	Size        int64  `json:"size"`
+	Bid         bool   `json:"bid"`
+	Price       int64  `json:"price"`
+	UserID      string `json:"userID"`
+	Timestamp   int64  `json:"timestamp"`
+	TimeInForce string `json:"timeInForce,omitempty"`
+	ExpireAt    int64  `json:"expireAt,omitempty"`
+	DisplaySize int64  `json:"displaySize,omitempty"`
+	HiddenSize  int64  `json:"hiddenSize,omitempty"`
+
+	PostOnly     bool   `json:"postOnly,omitempty"`
+	PostOnlyMode string `json:"postOnlyMode,omitempty"`
+
+	SelfTradePrevention string `json:"selfTradePrevention,omitempty"`
+
+	Sequence int64 `json:"sequence,omitempty"`
+}
+
+// StopOrder represents a pending stop or stop-limit order waiting for its trigger.
+type StopOrder struct {
+	OrderID      string `json:"orderID"`
+	UserID       string `json:"userID"`
+	Type         string `json:"type"`
+	Bid          bool   `json:"bid"`
+	Size         int64  `json:"size"`
+	StopPrice    int64  `json:"stopPrice"`
+	LimitPrice   int64  `json:"limitPrice,omitempty"`
+	TimeInForce  string `json:"timeInForce,omitempty"`
+	ExpireAt     int64  `json:"expireAt,omitempty"`
+	PostOnly     bool   `json:"postOnly,omitempty"`
+	PostOnlyMode string `json:"postOnlyMode,omitempty"`
+	DisplaySize  int64  `json:"displaySize,omitempty"`
+
+	SelfTradePrevention string `json:"selfTradePrevention,omitempty"`
+
+	WorstPrice     int64 `json:"worstPrice,omitempty"`
+	MaxSlippageBps int64 `json:"maxSlippageBps,omitempty"`
+
+	Sequence int64 `json:"sequence"`
 }
@@ -0,0 +1,137 @@
+package snapshotv1
+
+import "fmt"
+
+// Scaler converts legacy float prices and sizes to integer units at the pair precision.
+type Scaler interface {
+	ToPrice(price float64) (int64, error)
+	ToSize(size float64) (int64, error)
+}
+
+// LegacySnapshot is the version 0 snapshot format, which stored prices and sizes as floats.
+type LegacySnapshot struct {
+	OrderOffset       int64                   `json:"orderOffset"`
+	OrderBookSnapshot LegacyOrderBookSnapshot `json:"orderBookSnapshot"`
+}
+
+// LegacyOrderBookSnapshot is the version 0 order book state.
+type LegacyOrderBookSnapshot struct {
+	Orders         []LegacyBookOrder `json:"orders"`
+	StopOrders     []LegacyStopOrder `json:"stopOrders,omitempty"`
+	LastTradePrice float64           `json:"lastTradePrice,omitempty"`
+	TradeSequence  int64             `json:"tradeSequence"`
+	LogSequence    int64             `json:"logSequence"`
+}
+
+// LegacyBookOrder is a version 0 resting order.
+type LegacyBookOrder struct {
+	OrderID     string  `json:"orderID"`
+	Size        float64 `json:"size"`
+	Bid         bool    `json:"bid"`
+	Price       float64 `json:"price"`
+	UserID      string  `json:"userID"`
+	Timestamp   int64   `json:"timestamp"`
+	TimeInForce string  `json:"timeInForce,omitempty"`
+	ExpireAt    int64   `json:"expireAt,omitempty"`
+	DisplaySize float64 `json:"displaySize,omitempty"`
+	HiddenSize  float64 `json:"hiddenSize,omitempty"`
+
+	SelfTradePrevention string `json:"selfTradePrevention,omitempty"`
+}
+
+// LegacyStopOrder is a version 0 pending stop order.
+type LegacyStopOrder struct {
+	OrderID      string  `json:"orderID"`
+	UserID       string  `json:"userID"`
+	Type         string  `json:"type"`
+	Bid          bool    `json:"bid"`
+	Size         float64 `json:"size"`
+	StopPrice    float64 `json:"stopPrice"`
+	LimitPrice   float64 `json:"limitPrice,omitempty"`
+	TimeInForce  string  `json:"timeInForce,omitempty"`
+	ExpireAt     int64   `json:"expireAt,omitempty"`
+	PostOnly     bool    `json:"postOnly,omitempty"`
+	PostOnlyMode string  `json:"postOnlyMode,omitempty"`
+	DisplaySize  float64 `json:"displaySize,omitempty"`
+
+	SelfTradePrevention string `json:"selfTradePrevention,omitempty"`
+
+	Sequence int64 `json:"sequence"`
+}
+
+// Migrate converts the legacy snapshot to the current format. It fails if any value
+// cannot be represented exactly at the scaler's precision, so a precision that is too
+// small for the stored book is noticed instead of silently rounding client orders.
+func (l *LegacySnapshot) Migrate(scaler Scaler, priceDecimals, sizeDecimals int32) (*Snapshot, error) {
+	snapshot := &Snapshot{
+		Version:       CurrentVersion,
+		PriceDecimals: priceDecimals,
+		SizeDecimals:  sizeDecimals,
+		OrderOffset:   l.OrderOffset,
+		OrderBookSnapshot: OrderBookSnapshot{
+			TradeSequence: l.OrderBookSnapshot.TradeSequence,
+			LogSequence:   l.OrderBookSnapshot.LogSequence,
+		},
+	}
+
+	lastTradePrice, err := scaler.ToPrice(l.OrderBookSnapshot.LastTradePrice)
+	if err != nil {
+		return nil, fmt.Errorf("last trade price: %w", err)
+	}
+	snapshot.OrderBookSnapshot.LastTradePrice = lastTradePrice
+
+	for _, o := range l.OrderBookSnapshot.Orders {
+		order := BookOrder{
+			OrderID:             o.OrderID,
+			Bid:                 o.Bid,
+			UserID:              o.UserID,
+			Timestamp:           o.Timestamp,
+			TimeInForce:         o.TimeInForce,
+			ExpireAt:            o.ExpireAt,
+			SelfTradePrevention: o.SelfTradePrevention,
+		}
+		if err := convertAll(scaler.ToSize, []float64{o.Size, o.DisplaySize, o.HiddenSize}, &order.Size, &order.DisplaySize, &order.HiddenSize); err != nil {
+			return nil, fmt.Errorf("order %s: %w", o.OrderID, err)
+		}
+		if err := convertAll(scaler.ToPrice, []float64{o.Price}, &order.Price); err != nil {
+			return nil, fmt.Errorf("order %s: %w", o.OrderID, err)
+		}
+		snapshot.OrderBookSnapshot.Orders = append(snapshot.OrderBookSnapshot.Orders, order)
+	}
+
+	for _, s := range l.OrderBookSnapshot.StopOrders {
+		stop := StopOrder{
+			OrderID:             s.OrderID,
+			UserID:              s.UserID,
+			Type:                s.Type,
+			Bid:                 s.Bid,
+			TimeInForce:         s.TimeInForce,
+			ExpireAt:            s.ExpireAt,
+			PostOnly:            s.PostOnly,
+			PostOnlyMode:        s.PostOnlyMode,
+			SelfTradePrevention: s.SelfTradePrevention,
+			Sequence:            s.Sequence,
+		}
+		if err := convertAll(scaler.ToSize, []float64{s.Size, s.DisplaySize}, &stop.Size, &stop.DisplaySize); err != nil {
+			return nil, fmt.Errorf("stop order %s: %w", s.OrderID, err)
+		}
+		if err := convertAll(scaler.ToPrice, []float64{s.StopPrice, s.LimitPrice}, &stop.StopPrice, &stop.LimitPrice); err != nil {
+			return nil, fmt.Errorf("stop order %s: %w", s.OrderID, err)
+		}
+		snapshot.OrderBookSnapshot.StopOrders = append(snapshot.OrderBookSnapshot.StopOrders, stop)
+	}
+
+	return snapshot, nil
+}
+
+// convertAll converts each value with convert and stores it in the matching target.
+func convertAll(convert func(float64) (int64, error), values []float64, targets ...*int64) error {
+	for i, value := range values {
+		units, err := convert(value)
+		if err != nil {
+			return err
+		}
+		*targets[i] = units
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package stopbookv1
+
+import snapshotv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/snapshot/v1"
+
+// StopBook defines the interface for the pending stop orders of a trading pair.
+type StopBook interface {
+	AddStopOrder(stop *StopOrder) error
+	CancelStopOrder(orderID string) (*StopOrder, error)
+	HasStopOrder(orderID string) bool
+	GetStopOrder(orderID string) (*StopOrder, error)
+	UserStopOrders(userID string) []*StopOrder
+	TriggerStops(lastPrice int64) []*StopOrder
+	HasExpiredStopOrders(now int64) bool
+	ExpireStopOrders(now int64) []*StopOrder
+	CreateSnapshot() []snapshotv1.StopOrder
+	RestoreStopBook(stops []snapshotv1.StopOrder) error
+}
@@ -0,0 +1,129 @@
+package stopbookv1
+
+import (
+	"errors"
+
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+)
+
+var (
+	ErrNilStopOrder      = errors.New("stop order cannot be nil")
+	ErrInvalidStopType   = errors.New("order type must be stop or stop_limit")
+	ErrInvalidStopPrice  = errors.New("stop price must be positive")
+	ErrInvalidLimitPrice = errors.New("stop-limit order requires a positive limit price")
+	ErrStopOrderExists   = errors.New("stop order already exists")
+	ErrStopOrderNotFound = errors.New("stop order not found")
+	ErrEmptyStopOrderID  = errors.New("order ID cannot be empty")
+	ErrInvalidStopSize   = errors.New("size must be positive")
+)
+
+// StopOrder represents a pending stop or stop-limit order waiting for its trigger price.
+// A buy stop triggers when the last trade price rises to or above StopPrice,
+// a sell stop when it falls to or below StopPrice.
+type StopOrder struct {
+	OrderID      string                   `json:"orderID"`
+	UserID       string                   `json:"userID"`
+	Type         orderbookv1.OrderType    `json:"type"`
+	Bid          bool                     `json:"bid"`
+	Size         int64                    `json:"size"`
+	StopPrice    int64                    `json:"stopPrice"`
+	LimitPrice   int64                    `json:"limitPrice"` // Only used by stop-limit orders
+	TimeInForce  orderbookv1.TimeInForce  `json:"timeInForce"`
+	ExpireAt     int64                    `json:"expireAt"`
+	PostOnly     bool                     `json:"postOnly"`
+	PostOnlyMode orderbookv1.PostOnlyMode `json:"postOnlyMode"`
+	DisplaySize  int64                    `json:"displaySize"`
+
+	SelfTradePrevention orderbookv1.SelfTradePrevention `json:"selfTradePrevention"`
+
+	// Worst price and slippage limit of the market order a stop order becomes
+	WorstPrice     int64 `json:"worstPrice"`
+	MaxSlippageBps int64 `json:"maxSlippageBps"`
+
+	Sequence int64 `json:"sequence"` // Arrival order, breaks ties between equal stop prices
+}
+
+// NewStopOrder creates a stop order from a place order request.
+func NewStopOrder(r *orderbookv1.PlaceOrderRequest) *StopOrder {
+	return &StopOrder{
+		OrderID:      r.OrderID,
+		UserID:       r.UserID,
+		Type:         r.Type,
+		Bid:          r.Bid,
+		Size:         r.Size,
+		StopPrice:    r.StopPrice,
+		LimitPrice:   r.Price,
+		TimeInForce:  r.TimeInForce,
+		ExpireAt:     r.ExpireAt,
+		PostOnly:     r.PostOnly,
+		PostOnlyMode: r.PostOnlyMode,
+		DisplaySize:  r.DisplaySize,
+
+		SelfTradePrevention: r.SelfTradePrevention,
+
+		WorstPrice:     r.WorstPrice,
+		MaxSlippageBps: r.MaxSlippageBps,
+	}
+}
+
+// Validate performs basic validation of the stop order.
+func (s *StopOrder) Validate() error {
+	if s.OrderID == "" {
+		return ErrEmptyStopOrderID
+	}
+	if s.Type != orderbookv1.OrderTypeStop && s.Type != orderbookv1.OrderTypeStopLimit {
+		return ErrInvalidStopType
+	}
+	if s.Size <= 0 {
+		return ErrInvalidStopSize
+	}
+	if s.StopPrice <= 0 {
+		return ErrInvalidStopPrice
+	}
+	if s.Type == orderbookv1.OrderTypeStopLimit && s.LimitPrice <= 0 {
+		return ErrInvalidLimitPrice
+	}
+	return nil
+}
+
+// IsTriggered checks if the last trade price has reached the stop price.
+func (s *StopOrder) IsTriggered(lastPrice int64) bool {
+	if lastPrice <= 0 {
+		return false
+	}
+	if s.Bid {
+		return lastPrice >= s.StopPrice
+	}
+	return lastPrice <= s.StopPrice
+}
+
+// IsExpired checks if a good-till-date stop order has expired at the given Unix nanosecond time.
+func (s *StopOrder) IsExpired(now int64) bool {
+	return s.TimeInForce == orderbookv1.TimeInForceGTD && s.ExpireAt <= now
+}
+
+// ToPlaceOrderRequest converts a triggered stop into the market or limit order it releases.
+func (s *StopOrder) ToPlaceOrderRequest() *orderbookv1.PlaceOrderRequest {
+	r := &orderbookv1.PlaceOrderRequest{
+		OrderID:      s.OrderID,
+		UserID:       s.UserID,
+		Type:         orderbookv1.OrderTypeMarket,
+		Bid:          s.Bid,
+		Size:         s.Size,
+		TimeInForce:  s.TimeInForce,
+		ExpireAt:     s.ExpireAt,
+		PostOnly:     s.PostOnly,
+		PostOnlyMode: s.PostOnlyMode,
+		DisplaySize:  s.DisplaySize,
+
+		SelfTradePrevention: s.SelfTradePrevention,
+
+		WorstPrice:     s.WorstPrice,
+		MaxSlippageBps: s.MaxSlippageBps,
+	}
+	if s.Type == orderbookv1.OrderTypeStopLimit {
+		r.Type = orderbookv1.OrderTypeLimit
+		r.Price = s.LimitPrice
+	}
+	return r
+}
@@ -0,0 +1,526 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/muhammadchandra19/exchange/pkg/logger"
+	pb "github.com/muhammadchandra19/exchange/proto/go/modules/matching-engine/v1/private"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/internal/app/engine"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/internal/app/supervisor"
+	matchpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/match-publisher/v1"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+	snapshotv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/snapshot/v1"
+	stopbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/stopbook/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// BookLevelL2 aggregates the orders of each price level.
+	BookLevelL2 = "l2"
+	// BookLevelL3 also lists the orders of each price level.
+	BookLevelL3 = "l3"
+
+	// statePaused is reported for a running pair an operator halted.
+	statePaused = "paused"
+)
+
+var (
+	ErrInvalidBookLevel = errors.New("book level must be l2 or l3")
+	ErrInvalidDepth     = errors.New("depth must not be negative")
+	ErrMissingSymbol    = errors.New("symbol is required")
+	ErrMissingUserID    = errors.New("user ID is required")
+	ErrNotAdminEngine   = errors.New("pair engine does not support the admin API")
+)
+
+// Pairs is the set of pairs the admin API inspects and controls.
+type Pairs interface {
+	// Engine returns the engine a pair currently runs
+	Engine(pair string) (supervisor.Engine, error)
+	// Status returns the state of every pair
+	Status() []supervisor.PairStatus
+	// StartPair starts a pair that is halted or stopped
+	StartPair(pair string) error
+}
+
+// AdminEngine is the part of a pair's engine the admin API uses.
+type AdminEngine interface {
+	Book(depth int, withOrders bool) *engine.BookView
+	GetOrder(orderID string) (*orderbookv1.Order, error)
+	GetStopOrder(orderID string) (*stopbookv1.StopOrder, error)
+	ForceSnapshot(ctx context.Context) (*snapshotv1.Snapshot, error)
+	Pause(reason string) error
+	Resume()
+	IsPaused() bool
+	CancelUserOrders(userID string) ([]string, error)
+	MarketState() orderbookv1.MarketState
+	SetMarketState(state orderbookv1.MarketState) error
+	Lag(ctx context.Context) (int64, error)
+	Scale() orderbookv1.Scale
+	GetOrderOffset() int64
+	GetLastSnapshotOffset() int64
+	GetTradeSequence() int64
+	GetTotalMatches() int64
+}
+
+// AdminRPC is the service for the operator API of the matching engine.
+type AdminRPC struct {
+	pb.UnimplementedAdminServiceServer
+
+	pairs  Pairs
+	logger logger.Interface
+}
+
+// NewAdminRPC creates a new AdminRPC.
+func NewAdminRPC(pairs Pairs, logger logger.Interface) *AdminRPC {
+	return &AdminRPC{
+		pairs:  pairs,
+		logger: logger,
+	}
+}
+
+// GetBook returns the L2 or L3 book of a pair.
+func (s *AdminRPC) GetBook(ctx context.Context, req *pb.GetBookRequest) (*pb.GetBookResponse, error) {
+	withOrders, err := parseBookLevel(req.Level)
+	if err == nil && req.Depth < 0 {
+		err = ErrInvalidDepth
+	}
+	var e AdminEngine
+	if err == nil {
+		e, err = s.engine(req.Symbol)
+	}
+	if err != nil {
+		code, err := s.fail(ctx, err, "get_book", req.Symbol)
+		return &pb.GetBookResponse{
+			Status:    "error",
+			Message:   "failed to get book",
+			Error:     err.Error(),
+			Timestamp: timestamppb.New(time.Now()),
+			Code:      code.String(),
+		}, err
+	}
+
+	view := e.Book(int(req.Depth), withOrders)
+	return &pb.GetBookResponse{
+		Status:    "success",
+		Message:   "success",
+		Data:      toBook(req.Symbol, view, e.Scale()),
+		Timestamp: timestamppb.New(time.Now()),
+		Code:      codes.OK.String(),
+	}, nil
+}
+
+// GetOrder looks an order up in the book of a pair, then in its stop book.
+func (s *AdminRPC) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.GetOrderResponse, error) {
+	order, err := s.getOrder(req.Symbol, req.OrderID)
+	if err != nil {
+		code, err := s.fail(ctx, err, "get_order", req.Symbol)
+		return &pb.GetOrderResponse{
+			Status:    "error",
+			Message:   "failed to get order",
+			Error:     err.Error(),
+			Timestamp: timestamppb.New(time.Now()),
+			Code:      code.String(),
+		}, err
+	}
+
+	return &pb.GetOrderResponse{
+		Status:    "success",
+		Message:   "success",
+		Data:      order,
+		Timestamp: timestamppb.New(time.Now()),
+		Code:      codes.OK.String(),
+	}, nil
+}
+
+func (s *AdminRPC) getOrder(symbol, orderID string) (*pb.BookOrder, error) {
+	e, err := s.engine(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := e.GetOrder(orderID)
+	if err == nil {
+		return toBookOrder(order, e.Scale()), nil
+	}
+	if !errors.Is(err, orderbookv1.ErrUnknownOrder) {
+		return nil, err
+	}
+
+	stop, err := e.GetStopOrder(orderID)
+	if errors.Is(err, stopbookv1.ErrStopOrderNotFound) {
+		return nil, fmt.Errorf("%w: %s", orderbookv1.ErrUnknownOrder, orderID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toStopBookOrder(stop, e.Scale()), nil
+}
+
+// ForceSnapshot stores a snapshot of a pair now.
+func (s *AdminRPC) ForceSnapshot(ctx context.Context, req *pb.ForceSnapshotRequest) (*pb.ForceSnapshotResponse, error) {
+	e, err := s.engine(req.Symbol)
+	var snapshot *snapshotv1.Snapshot
+	if err == nil {
+		snapshot, err = e.ForceSnapshot(ctx)
+	}
+	if err != nil {
+		code, err := s.fail(ctx, err, "force_snapshot", req.Symbol)
+		return &pb.ForceSnapshotResponse{
+			Status:    "error",
+			Message:   "failed to store snapshot",
+			Error:     err.Error(),
+			Timestamp: timestamppb.New(time.Now()),
+			Code:      code.String(),
+		}, err
+	}
+
+	s.logger.Info("Snapshot forced by operator",
+		logger.Field{Key: "pair", Value: req.Symbol},
+		logger.Field{Key: "offset", Value: snapshot.OrderOffset},
+	)
+	return &pb.ForceSnapshotResponse{
+		Status:  "success",
+		Message: "success",
+		Data: &pb.Snapshot{
+			Symbol:        req.Symbol,
+			OrderOffset:   snapshot.OrderOffset,
+			TradeSequence: snapshot.OrderBookSnapshot.TradeSequence,
+		},
+		Timestamp: timestamppb.New(time.Now()),
+		Code:      codes.OK.String(),
+	}, nil
+}
+
+// HaltPair stops a pair from consuming orders until it is resumed. Its book stays
+// readable.
+func (s *AdminRPC) HaltPair(ctx context.Context, req *pb.HaltPairRequest) (*pb.HaltPairResponse, error) {
+	e, err := s.engine(req.Symbol)
+	if err == nil {
+		err = e.Pause(req.Reason)
+	}
+	if err != nil {
+		code, err := s.fail(ctx, err, "halt_pair", req.Symbol)
+		return &pb.HaltPairResponse{
+			Status:    "error",
+			Message:   "failed to halt pair",
+			Error:     err.Error(),
+			Timestamp: timestamppb.New(time.Now()),
+			Code:      code.String(),
+		}, err
+	}
+
+	return &pb.HaltPairResponse{
+		Status:    "success",
+		Message:   "success",
+		Data:      s.pairStatus(ctx, req.Symbol),
+		Timestamp: timestamppb.New(time.Now()),
+		Code:      codes.OK.String(),
+	}, nil
+}
+
+// ResumePair resumes a pair an operator halted, or starts a pair that halted on a
+// failure or was stopped.
+func (s *AdminRPC) ResumePair(ctx context.Context, req *pb.ResumePairRequest) (*pb.ResumePairResponse, error) {
+	err := s.resumePair(req.Symbol)
+	if err != nil {
+		code, err := s.fail(ctx, err, "resume_pair", req.Symbol)
+		return &pb.ResumePairResponse{
+			Status:    "error",
+			Message:   "failed to resume pair",
+			Error:     err.Error(),
+			Timestamp: timestamppb.New(time.Now()),
+			Code:      code.String(),
+		}, err
+	}
+
+	return &pb.ResumePairResponse{
+		Status:    "success",
+		Message:   "success",
+		Data:      s.pairStatus(ctx, req.Symbol),
+		Timestamp: timestamppb.New(time.Now()),
+		Code:      codes.OK.String(),
+	}, nil
+}
+
+func (s *AdminRPC) resumePair(symbol string) error {
+	e, err := s.engine(symbol)
+	if errors.Is(err, supervisor.ErrPairStopped) {
+		return s.pairs.StartPair(symbol)
+	}
+	if err != nil {
+		return err
+	}
+	if !e.IsPaused() {
+		return fmt.Errorf("%w: %s", supervisor.ErrPairRunning, symbol)
+	}
+	e.Resume()
+	return nil
+}
+
+// CancelUserOrders cancels every order of a user in a pair.
+func (s *AdminRPC) CancelUserOrders(ctx context.Context, req *pb.CancelUserOrdersRequest) (*pb.CancelUserOrdersResponse, error) {
+	var cancelled []string
+	e, err := s.engine(req.Symbol)
+	if err == nil && req.UserID == "" {
+		err = ErrMissingUserID
+	}
+	if err == nil {
+		cancelled, err = e.CancelUserOrders(req.UserID)
+	}
+	if err != nil {
+		code, err := s.fail(ctx, err, "cancel_user_orders", req.Symbol)
+		return &pb.CancelUserOrdersResponse{
+			Status:    "error",
+			Message:   "failed to cancel user orders",
+			Error:     err.Error(),
+			Data:      cancelled,
+			Timestamp: timestamppb.New(time.Now()),
+			Code:      code.String(),
+		}, err
+	}
+
+	return &pb.CancelUserOrdersResponse{
+		Status:    "success",
+		Message:   "success",
+		Data:      cancelled,
+		Timestamp: timestamppb.New(time.Now()),
+		Code:      codes.OK.String(),
+	}, nil
+}
+
+// SetMarketState moves a pair to a market state, which decides the orders it accepts.
+func (s *AdminRPC) SetMarketState(ctx context.Context, req *pb.SetMarketStateRequest) (*pb.SetMarketStateResponse, error) {
+	e, err := s.engine(req.Symbol)
+	if err == nil {
+		err = e.SetMarketState(orderbookv1.MarketState(req.State))
+	}
+	if err != nil {
+		code, err := s.fail(ctx, err, "set_market_state", req.Symbol)
+		return &pb.SetMarketStateResponse{
+			Status:    "error",
+			Message:   "failed to set market state",
+			Error:     err.Error(),
+			Timestamp: timestamppb.New(time.Now()),
+			Code:      code.String(),
+		}, err
+	}
+
+	s.logger.Info("Market state set by operator",
+		logger.Field{Key: "pair", Value: req.Symbol},
+		logger.Field{Key: "state", Value: req.State},
+	)
+	return &pb.SetMarketStateResponse{
+		Status:    "success",
+		Message:   "success",
+		Data:      s.pairStatus(ctx, req.Symbol),
+		Timestamp: timestamppb.New(time.Now()),
+		Code:      codes.OK.String(),
+	}, nil
+}
+
+// GetPairStatus reports the state, offsets and lag of a pair, or of every pair when no
+// symbol is given.
+func (s *AdminRPC) GetPairStatus(ctx context.Context, req *pb.GetPairStatusRequest) (*pb.GetPairStatusResponse, error) {
+	var statuses []*pb.PairStatus
+	for _, pairStatus := range s.pairs.Status() {
+		if req.Symbol == "" || req.Symbol == pairStatus.Pair {
+			statuses = append(statuses, s.toPairStatus(ctx, pairStatus))
+		}
+	}
+
+	if req.Symbol != "" && len(statuses) == 0 {
+		code, err := s.fail(ctx, fmt.Errorf("%w: %s", supervisor.ErrUnknownPair, req.Symbol), "get_pair_status", req.Symbol)
+		return &pb.GetPairStatusResponse{
+			Status:    "error",
+			Message:   "failed to get pair status",
+			Error:     err.Error(),
+			Timestamp: timestamppb.New(time.Now()),
+			Code:      code.String(),
+		}, err
+	}
+
+	return &pb.GetPairStatusResponse{
+		Status:    "success",
+		Message:   "success",
+		Data:      statuses,
+		Timestamp: timestamppb.New(time.Now()),
+		Code:      codes.OK.String(),
+	}, nil
+}
+
+// engine returns the admin view of the engine a pair runs.
+func (s *AdminRPC) engine(symbol string) (AdminEngine, error) {
+	if symbol == "" {
+		return nil, ErrMissingSymbol
+	}
+
+	e, err := s.pairs.Engine(symbol)
+	if err != nil {
+		return nil, err
+	}
+	adminEngine, ok := e.(AdminEngine)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotAdminEngine, symbol)
+	}
+	return adminEngine, nil
+}
+
+// pairStatus returns the status of a single pair, nil if it is not supervised.
+func (s *AdminRPC) pairStatus(ctx context.Context, symbol string) *pb.PairStatus {
+	for _, pairStatus := range s.pairs.Status() {
+		if pairStatus.Pair == symbol {
+			return s.toPairStatus(ctx, pairStatus)
+		}
+	}
+	return nil
+}
+
+// toPairStatus completes the supervisor's status of a pair with the state of its engine.
+func (s *AdminRPC) toPairStatus(ctx context.Context, pairStatus supervisor.PairStatus) *pb.PairStatus {
+	result := &pb.PairStatus{
+		Symbol:   pairStatus.Pair,
+		State:    string(pairStatus.State),
+		Restarts: int32(pairStatus.Restarts),
+		Lag:      -1,
+	}
+	if pairStatus.Err != nil {
+		result.Error = pairStatus.Err.Error()
+	}
+
+	e, err := s.engine(pairStatus.Pair)
+	if err != nil {
+		return result
+	}
+	if e.IsPaused() {
+		result.State = statePaused
+	}
+	result.OrderOffset = e.GetOrderOffset()
+	result.LastSnapshotOffset = e.GetLastSnapshotOffset()
+	result.TradeSequence = e.GetTradeSequence()
+	result.TotalMatches = e.GetTotalMatches()
+	result.MarketState = string(e.MarketState())
+
+	if lag, err := e.Lag(ctx); err == nil {
+		result.Lag = lag
+	} else {
+		s.logger.Warn("Failed to read pair lag",
+			logger.Field{Key: "pair", Value: pairStatus.Pair},
+			logger.Field{Key: "error", Value: err.Error()},
+		)
+	}
+	return result
+}
+
+// fail logs a failed call and returns its gRPC code and status error.
+func (s *AdminRPC) fail(ctx context.Context, err error, action, symbol string) (codes.Code, error) {
+	code := errorCode(err)
+	s.logger.ErrorContext(ctx, err,
+		logger.Field{Key: "action", Value: action},
+		logger.Field{Key: "pair", Value: symbol},
+		logger.Field{Key: "code", Value: code.String()},
+	)
+	return code, status.Error(code, err.Error())
+}
+
+// errorCode maps an error of the engine or supervisor to a gRPC code.
+func errorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, ErrInvalidBookLevel), errors.Is(err, ErrInvalidDepth),
+		errors.Is(err, ErrMissingSymbol), errors.Is(err, ErrMissingUserID),
+		errors.Is(err, orderbookv1.ErrInvalidMarketState):
+		return codes.InvalidArgument
+	case errors.Is(err, supervisor.ErrUnknownPair), errors.Is(err, orderbookv1.ErrUnknownOrder),
+		errors.Is(err, stopbookv1.ErrStopOrderNotFound):
+		return codes.NotFound
+	case errors.Is(err, supervisor.ErrPairStopped), errors.Is(err, supervisor.ErrPairRunning),
+		errors.Is(err, engine.ErrPaused), errors.Is(err, orderbookv1.ErrMarketState):
+		return codes.FailedPrecondition
+	case errors.Is(err, matchpublisherv1.ErrMatchesPending), errors.Is(err, supervisor.ErrNotStarted):
+		return codes.Unavailable
+	case errors.Is(err, ErrNotAdminEngine):
+		return codes.Unimplemented
+	}
+	return codes.Internal
+}
+
+// parseBookLevel reports whether the book level lists the orders of each price level.
+func parseBookLevel(level string) (bool, error) {
+	switch level {
+	case "", BookLevelL2:
+		return false, nil
+	case BookLevelL3:
+		return true, nil
+	}
+	return false, fmt.Errorf("%w: got %q", ErrInvalidBookLevel, level)
+}
+
+func toBook(symbol string, view *engine.BookView, scale orderbookv1.Scale) *pb.Book {
+	levels := func(levels []orderbookv1.Level) []*pb.BookLevel {
+		result := make([]*pb.BookLevel, 0, len(levels))
+		for _, level := range levels {
+			bookLevel := &pb.BookLevel{
+				Price:      scale.FromPrice(level.Price),
+				Size:       scale.FromSize(level.Size),
+				HiddenSize: scale.FromSize(level.HiddenSize),
+				PriceUnits: level.Price,
+				SizeUnits:  level.Size,
+				OrderCount: int32(level.OrderCount),
+			}
+			for _, order := range level.Orders {
+				bookLevel.Orders = append(bookLevel.Orders, toBookOrder(order, scale))
+			}
+			result = append(result, bookLevel)
+		}
+		return result
+	}
+
+	return &pb.Book{
+		Symbol:      symbol,
+		Bids:        levels(view.Bids),
+		Asks:        levels(view.Asks),
+		OrderOffset: view.OrderOffset,
+	}
+}
+
+func toBookOrder(order *orderbookv1.Order, scale orderbookv1.Scale) *pb.BookOrder {
+	return &pb.BookOrder{
+		OrderID:     order.ID,
+		UserID:      order.UserID,
+		Side:        side(order.Bid),
+		Price:       scale.FromPrice(order.Price),
+		Size:        scale.FromSize(order.Size),
+		HiddenSize:  scale.FromSize(order.HiddenSize),
+		PriceUnits:  order.Price,
+		SizeUnits:   order.Size,
+		TimeInForce: string(order.TimeInForce),
+		Timestamp:   timestamppb.New(time.Unix(0, order.Timestamp)),
+		Type:        string(orderbookv1.OrderTypeLimit),
+	}
+}
+
+func toStopBookOrder(stop *stopbookv1.StopOrder, scale orderbookv1.Scale) *pb.BookOrder {
+	return &pb.BookOrder{
+		OrderID:     stop.OrderID,
+		UserID:      stop.UserID,
+		Side:        side(stop.Bid),
+		Price:       scale.FromPrice(stop.LimitPrice),
+		Size:        scale.FromSize(stop.Size),
+		PriceUnits:  stop.LimitPrice,
+		SizeUnits:   stop.Size,
+		TimeInForce: string(stop.TimeInForce),
+		Type:        string(stop.Type),
+		StopPrice:   scale.FromPrice(stop.StopPrice),
+	}
+}
+
+func side(bid bool) string {
+	if bid {
+		return "buy"
+	}
+	return "sell"
+}
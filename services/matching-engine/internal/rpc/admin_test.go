@@ -0,0 +1,298 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/muhammadchandra19/exchange/pkg/logger"
+	pb "github.com/muhammadchandra19/exchange/proto/go/modules/matching-engine/v1/private"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/internal/app/engine"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/internal/app/supervisor"
+	matchpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/match-publisher/v1"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+	snapshotv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/snapshot/v1"
+	stopbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/stopbook/v1"
+)
+
+// fakeEngine is a pair engine with a fixed book
+type fakeEngine struct {
+	supervisor.Engine
+
+	book        *engine.BookView
+	orders      map[string]*orderbookv1.Order
+	stops       map[string]*stopbookv1.StopOrder
+	snapshotErr error
+	paused      bool
+	cancelled   []string
+	marketState orderbookv1.MarketState
+}
+
+func (e *fakeEngine) Book(depth int, withOrders bool) *engine.BookView { return e.book }
+
+func (e *fakeEngine) GetOrder(orderID string) (*orderbookv1.Order, error) {
+	if order, ok := e.orders[orderID]; ok {
+		return order, nil
+	}
+	return nil, fmt.Errorf("%w: %s", orderbookv1.ErrUnknownOrder, orderID)
+}
+
+func (e *fakeEngine) GetStopOrder(orderID string) (*stopbookv1.StopOrder, error) {
+	if stop, ok := e.stops[orderID]; ok {
+		return stop, nil
+	}
+	return nil, fmt.Errorf("%w: %s", stopbookv1.ErrStopOrderNotFound, orderID)
+}
+
+func (e *fakeEngine) ForceSnapshot(ctx context.Context) (*snapshotv1.Snapshot, error) {
+	if e.snapshotErr != nil {
+		return nil, e.snapshotErr
+	}
+	return &snapshotv1.Snapshot{OrderOffset: 41, OrderBookSnapshot: snapshotv1.OrderBookSnapshot{TradeSequence: 7}}, nil
+}
+
+func (e *fakeEngine) Pause(reason string) error {
+	if e.paused {
+		return engine.ErrPaused
+	}
+	e.paused = true
+	return nil
+}
+
+func (e *fakeEngine) Resume()        { e.paused = false }
+func (e *fakeEngine) IsPaused() bool { return e.paused }
+
+func (e *fakeEngine) CancelUserOrders(userID string) ([]string, error) {
+	e.cancelled = append(e.cancelled, userID)
+	return []string{userID + "-1"}, nil
+}
+
+func (e *fakeEngine) MarketState() orderbookv1.MarketState { return e.marketState }
+
+func (e *fakeEngine) SetMarketState(state orderbookv1.MarketState) error {
+	if err := state.Validate(); err != nil {
+		return err
+	}
+	e.marketState = state
+	return nil
+}
+
+func (e *fakeEngine) Lag(ctx context.Context) (int64, error) { return 3, nil }
+func (e *fakeEngine) Scale() orderbookv1.Scale {
+	return orderbookv1.Scale{PriceDecimals: 2, SizeDecimals: 4}
+}
+func (e *fakeEngine) GetOrderOffset() int64        { return 42 }
+func (e *fakeEngine) GetLastSnapshotOffset() int64 { return 40 }
+func (e *fakeEngine) GetTradeSequence() int64      { return 7 }
+func (e *fakeEngine) GetTotalMatches() int64       { return 9 }
+
+// fakePairs supervises BTC-USD, running fakeEngine, and ETH-USD, halted
+type fakePairs struct {
+	engine  *fakeEngine
+	started []string
+}
+
+func (p *fakePairs) Engine(pair string) (supervisor.Engine, error) {
+	switch pair {
+	case "BTC-USD":
+		return p.engine, nil
+	case "ETH-USD":
+		return nil, fmt.Errorf("%w: %s is halted", supervisor.ErrPairStopped, pair)
+	}
+	return nil, fmt.Errorf("%w: %s", supervisor.ErrUnknownPair, pair)
+}
+
+func (p *fakePairs) Status() []supervisor.PairStatus {
+	return []supervisor.PairStatus{
+		{Pair: "BTC-USD", State: supervisor.StateRunning},
+		{Pair: "ETH-USD", State: supervisor.StateHalted, Restarts: 2, Err: errors.New("boom")},
+	}
+}
+
+func (p *fakePairs) StartPair(pair string) error {
+	p.started = append(p.started, pair)
+	return nil
+}
+
+func newTestAdminRPC(t *testing.T) (*AdminRPC, *fakePairs) {
+	log, err := logger.NewLogger()
+	require.NoError(t, err)
+
+	pairs := &fakePairs{engine: &fakeEngine{
+		book: &engine.BookView{
+			Bids: []orderbookv1.Level{{Price: 9950, Size: 15000, HiddenSize: 5000, OrderCount: 1, Orders: []*orderbookv1.Order{
+				{ID: "o1", UserID: "alice", Bid: true, Price: 9950, Size: 15000, HiddenSize: 5000, TimeInForce: orderbookv1.TimeInForceGTC},
+			}}},
+			OrderOffset: 42,
+		},
+		orders: map[string]*orderbookv1.Order{
+			"o1": {ID: "o1", UserID: "alice", Bid: true, Price: 9950, Size: 15000},
+		},
+		stops: map[string]*stopbookv1.StopOrder{
+			"s1": {OrderID: "s1", UserID: "bob", Type: orderbookv1.OrderTypeStopLimit, Size: 10000, StopPrice: 9000, LimitPrice: 8900},
+		},
+		marketState: orderbookv1.MarketStateContinuous,
+	}}
+	return NewAdminRPC(pairs, log), pairs
+}
+
+func assertCode(t *testing.T, expected codes.Code, code string, err error) {
+	t.Helper()
+	assert.Equal(t, expected.String(), code)
+	if expected == codes.OK {
+		assert.NoError(t, err)
+		return
+	}
+	assert.Equal(t, expected, status.Code(err))
+}
+
+func TestAdmin_GetBook(t *testing.T) {
+	testCases := []struct {
+		name     string
+		req      *pb.GetBookRequest
+		code     codes.Code
+		assertFn func(t *testing.T, book *pb.Book)
+	}{
+		{
+			name: "l3 book",
+			req:  &pb.GetBookRequest{Symbol: "BTC-USD", Level: BookLevelL3},
+			code: codes.OK,
+			assertFn: func(t *testing.T, book *pb.Book) {
+				assert.Equal(t, int64(42), book.OrderOffset)
+				require.Len(t, book.Bids, 1)
+				assert.Equal(t, 99.5, book.Bids[0].Price)
+				assert.Equal(t, 1.5, book.Bids[0].Size)
+				assert.Equal(t, 0.5, book.Bids[0].HiddenSize)
+				assert.Equal(t, int64(9950), book.Bids[0].PriceUnits)
+				require.Len(t, book.Bids[0].Orders, 1)
+				assert.Equal(t, "buy", book.Bids[0].Orders[0].Side)
+				assert.Equal(t, "gtc", book.Bids[0].Orders[0].TimeInForce)
+			},
+		},
+		{name: "invalid level", req: &pb.GetBookRequest{Symbol: "BTC-USD", Level: "l4"}, code: codes.InvalidArgument},
+		{name: "negative depth", req: &pb.GetBookRequest{Symbol: "BTC-USD", Depth: -1}, code: codes.InvalidArgument},
+		{name: "no symbol", req: &pb.GetBookRequest{}, code: codes.InvalidArgument},
+		{name: "unknown pair", req: &pb.GetBookRequest{Symbol: "DOGE-USD"}, code: codes.NotFound},
+		{name: "halted pair", req: &pb.GetBookRequest{Symbol: "ETH-USD"}, code: codes.FailedPrecondition},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			admin, _ := newTestAdminRPC(t)
+			res, err := admin.GetBook(context.Background(), testCase.req)
+			assertCode(t, testCase.code, res.Code, err)
+			if testCase.assertFn != nil {
+				testCase.assertFn(t, res.Data)
+			}
+		})
+	}
+}
+
+func TestAdmin_GetOrder(t *testing.T) {
+	admin, _ := newTestAdminRPC(t)
+
+	res, err := admin.GetOrder(context.Background(), &pb.GetOrderRequest{Symbol: "BTC-USD", OrderID: "o1"})
+	assertCode(t, codes.OK, res.Code, err)
+	assert.Equal(t, "limit", res.Data.Type)
+	assert.Equal(t, 1.5, res.Data.Size)
+
+	// Orders that are not in the book are looked up in the stop book
+	res, err = admin.GetOrder(context.Background(), &pb.GetOrderRequest{Symbol: "BTC-USD", OrderID: "s1"})
+	assertCode(t, codes.OK, res.Code, err)
+	assert.Equal(t, "stop_limit", res.Data.Type)
+	assert.Equal(t, "sell", res.Data.Side)
+	assert.Equal(t, 90.0, res.Data.StopPrice)
+	assert.Equal(t, 89.0, res.Data.Price)
+
+	res, err = admin.GetOrder(context.Background(), &pb.GetOrderRequest{Symbol: "BTC-USD", OrderID: "missing"})
+	assertCode(t, codes.NotFound, res.Code, err)
+}
+
+func TestAdmin_ForceSnapshot(t *testing.T) {
+	admin, pairs := newTestAdminRPC(t)
+
+	res, err := admin.ForceSnapshot(context.Background(), &pb.ForceSnapshotRequest{Symbol: "BTC-USD"})
+	assertCode(t, codes.OK, res.Code, err)
+	assert.Equal(t, &pb.Snapshot{Symbol: "BTC-USD", OrderOffset: 41, TradeSequence: 7}, res.Data)
+
+	pairs.engine.snapshotErr = matchpublisherv1.ErrMatchesPending
+	res, err = admin.ForceSnapshot(context.Background(), &pb.ForceSnapshotRequest{Symbol: "BTC-USD"})
+	assertCode(t, codes.Unavailable, res.Code, err)
+}
+
+func TestAdmin_HaltAndResumePair(t *testing.T) {
+	admin, pairs := newTestAdminRPC(t)
+	ctx := context.Background()
+
+	halted, err := admin.HaltPair(ctx, &pb.HaltPairRequest{Symbol: "BTC-USD", Reason: "incident"})
+	assertCode(t, codes.OK, halted.Code, err)
+	assert.Equal(t, "paused", halted.Data.State)
+
+	halted, err = admin.HaltPair(ctx, &pb.HaltPairRequest{Symbol: "BTC-USD"})
+	assertCode(t, codes.FailedPrecondition, halted.Code, err)
+
+	resumed, err := admin.ResumePair(ctx, &pb.ResumePairRequest{Symbol: "BTC-USD"})
+	assertCode(t, codes.OK, resumed.Code, err)
+	assert.Equal(t, "running", resumed.Data.State)
+
+	resumed, err = admin.ResumePair(ctx, &pb.ResumePairRequest{Symbol: "BTC-USD"})
+	assertCode(t, codes.FailedPrecondition, resumed.Code, err)
+
+	// A pair that halted on a failure is started again
+	resumed, err = admin.ResumePair(ctx, &pb.ResumePairRequest{Symbol: "ETH-USD"})
+	assertCode(t, codes.OK, resumed.Code, err)
+	assert.Equal(t, []string{"ETH-USD"}, pairs.started)
+}
+
+func TestAdmin_CancelUserOrders(t *testing.T) {
+	admin, pairs := newTestAdminRPC(t)
+
+	res, err := admin.CancelUserOrders(context.Background(), &pb.CancelUserOrdersRequest{Symbol: "BTC-USD", UserID: "alice"})
+	assertCode(t, codes.OK, res.Code, err)
+	assert.Equal(t, []string{"alice-1"}, res.Data)
+
+	res, err = admin.CancelUserOrders(context.Background(), &pb.CancelUserOrdersRequest{Symbol: "BTC-USD"})
+	assertCode(t, codes.InvalidArgument, res.Code, err)
+	assert.Equal(t, []string{"alice"}, pairs.engine.cancelled)
+}
+
+func TestAdmin_GetPairStatus(t *testing.T) {
+	admin, _ := newTestAdminRPC(t)
+
+	res, err := admin.GetPairStatus(context.Background(), &pb.GetPairStatusRequest{})
+	assertCode(t, codes.OK, res.Code, err)
+	assert.Equal(t, []*pb.PairStatus{
+		{Symbol: "BTC-USD", State: "running", OrderOffset: 42, LastSnapshotOffset: 40, Lag: 3, TradeSequence: 7, TotalMatches: 9, MarketState: "continuous"},
+		{Symbol: "ETH-USD", State: "halted", Error: "boom", Restarts: 2, Lag: -1},
+	}, res.Data)
+
+	res, err = admin.GetPairStatus(context.Background(), &pb.GetPairStatusRequest{Symbol: "ETH-USD"})
+	assertCode(t, codes.OK, res.Code, err)
+	require.Len(t, res.Data, 1)
+
+	res, err = admin.GetPairStatus(context.Background(), &pb.GetPairStatusRequest{Symbol: "DOGE-USD"})
+	assertCode(t, codes.NotFound, res.Code, err)
+}
+
+func TestAdmin_SetMarketState(t *testing.T) {
+	admin, pairs := newTestAdminRPC(t)
+	ctx := context.Background()
+
+	res, err := admin.SetMarketState(ctx, &pb.SetMarketStateRequest{Symbol: "BTC-USD", State: "cancel_only"})
+	assertCode(t, codes.OK, res.Code, err)
+	assert.Equal(t, "cancel_only", res.Data.MarketState)
+	assert.Equal(t, orderbookv1.MarketStateCancelOnly, pairs.engine.marketState)
+
+	res, err = admin.SetMarketState(ctx, &pb.SetMarketStateRequest{Symbol: "BTC-USD", State: "suspended"})
+	assertCode(t, codes.InvalidArgument, res.Code, err)
+	assert.Equal(t, orderbookv1.MarketStateCancelOnly, pairs.engine.marketState)
+
+	res, err = admin.SetMarketState(ctx, &pb.SetMarketStateRequest{Symbol: "ETH-USD", State: "halted"})
+	assertCode(t, codes.FailedPrecondition, res.Code, err)
+}
@@ -0,0 +1,51 @@
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	pb "github.com/muhammadchandra19/exchange/proto/go/modules/matching-engine/v1/private"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TokenInterceptor rejects calls that do not carry token as "authorization: Bearer <token>"
+// metadata. An empty token rejects every call.
+func TokenInterceptor(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !authorized(ctx, token) {
+			return nil, status.Error(codes.Unauthenticated, "missing or invalid operator token")
+		}
+		return handler(ctx, req)
+	}
+}
+
+func authorized(ctx context.Context, token string) bool {
+	if token == "" {
+		return false
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	for _, value := range md.Get("authorization") {
+		scheme, credentials, found := strings.Cut(value, " ")
+		if found && strings.EqualFold(scheme, "bearer") &&
+			subtle.ConstantTimeCompare([]byte(credentials), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// NewServer creates the gRPC server of the admin API, which only accepts calls carrying
+// the operator token.
+func NewServer(token string, admin *AdminRPC) *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(TokenInterceptor(token)))
+	pb.RegisterAdminServiceServer(server, admin)
+	return server
+}
@@ -0,0 +1,51 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestTokenInterceptor(t *testing.T) {
+	testCases := []struct {
+		name     string
+		token    string
+		metadata metadata.MD
+		code     codes.Code
+	}{
+		{name: "valid token", token: "secret", metadata: metadata.Pairs("authorization", "Bearer secret"), code: codes.OK},
+		{name: "scheme is case insensitive", token: "secret", metadata: metadata.Pairs("authorization", "bearer secret"), code: codes.OK},
+		{name: "wrong token", token: "secret", metadata: metadata.Pairs("authorization", "Bearer guess"), code: codes.Unauthenticated},
+		{name: "not a bearer token", token: "secret", metadata: metadata.Pairs("authorization", "Basic secret"), code: codes.Unauthenticated},
+		{name: "no authorization", token: "secret", metadata: metadata.Pairs("x-token", "secret"), code: codes.Unauthenticated},
+		{name: "no metadata", token: "secret", code: codes.Unauthenticated},
+		{name: "no token configured", token: "", metadata: metadata.Pairs("authorization", "Bearer "), code: codes.Unauthenticated},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctx := context.Background()
+			if testCase.metadata != nil {
+				ctx = metadata.NewIncomingContext(ctx, testCase.metadata)
+			}
+
+			called := false
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				called = true
+				return "ok", nil
+			}
+
+			res, err := TokenInterceptor(testCase.token)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/AdminService/GetBook"}, handler)
+			assert.Equal(t, testCase.code, status.Code(err))
+			assert.Equal(t, testCase.code == codes.OK, called)
+			if testCase.code == codes.OK {
+				assert.Equal(t, "ok", res)
+			}
+		})
+	}
+}
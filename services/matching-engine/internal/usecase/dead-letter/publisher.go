@@ -0,0 +1,52 @@
+package deadletter
+
+import (
+	"context"
+
+	"github.com/muhammadchandra19/exchange/pkg/errors"
+	"github.com/muhammadchandra19/exchange/pkg/logger"
+	deadletterv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/dead-letter/v1"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/pkg/config"
+	"github.com/segmentio/kafka-go"
+)
+
+// Publisher represents a Kafka Publisher for publishing order messages to the dead-letter topic.
+type Publisher struct {
+	kafkaWriter *kafka.Writer
+	logger      logger.Logger
+}
+
+// NewPublisher creates a new Kafka publisher for the dead-letter topic.
+func NewPublisher(config config.DeadLetterConfig, logger logger.Logger) *Publisher {
+	// The engine consumes a dead-lettered order message once it is confirmed, so every
+	// in-sync replica has to acknowledge it. Messages are keyed by pair.
+	kafkaWriter := kafka.NewWriter(kafka.WriterConfig{
+		Brokers:      config.Brokers,
+		Topic:        config.Topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: int(kafka.RequireAll),
+	})
+
+	return &Publisher{
+		kafkaWriter: kafkaWriter,
+		logger:      logger,
+	}
+}
+
+// PublishDeadLetter publishes a dead-letter message to the Kafka topic.
+func (p *Publisher) PublishDeadLetter(ctx context.Context, message *deadletterv1.Message) error {
+	msg := kafka.Message{
+		Key:   []byte(message.Pair),
+		Value: deadletterv1.ToBytes(message),
+	}
+
+	if err := p.kafkaWriter.WriteMessages(ctx, msg); err != nil {
+		p.logger.Error(err,
+			logger.Field{Key: "error", Value: err.Error()},
+			logger.Field{Key: "offset", Value: message.Offset},
+			logger.Field{Key: "class", Value: message.Class},
+		)
+		return errors.NewTracer("failed to publish dead letter")
+	}
+	return nil
+}
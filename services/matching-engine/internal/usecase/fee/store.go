@@ -0,0 +1,64 @@
+package fee
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/muhammadchandra19/exchange/pkg/errors"
+	logger "github.com/muhammadchandra19/exchange/pkg/logger"
+	"github.com/muhammadchandra19/exchange/pkg/redis"
+)
+
+// TierStore loads the fee tiers of users from a Redis key holding a JSON object of tier
+// names by user ID, e.g. {"alice": "vip"}.
+type TierStore struct {
+	key         string
+	logger      *logger.Logger
+	redisclient redis.Client
+}
+
+// NewTierStore creates a store reading the user tiers from the given Redis key.
+func NewTierStore(redisclient redis.Client, key string, logger *logger.Logger) *TierStore {
+	return &TierStore{
+		key:         key,
+		redisclient: redisclient,
+		logger:      logger,
+	}
+}
+
+// LoadUserTiers returns the tier of each user by user ID, nil when the key does not exist.
+func (s *TierStore) LoadUserTiers(ctx context.Context) (map[string]string, error) {
+	data, err := s.redisclient.Get(ctx, s.key)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err, logger.Field{
+			Key:   "key",
+			Value: s.key,
+		}, logger.Field{
+			Key:   "action",
+			Value: "load fee tiers",
+		})
+		return nil, errors.NewTracer("fee_tiers_load_error").Wrap(err)
+	}
+
+	if data == "" {
+		s.logger.WarnContext(ctx, "No fee tiers found", logger.Field{
+			Key:   "key",
+			Value: s.key,
+		})
+		return nil, nil
+	}
+
+	var tiers map[string]string
+	if err := json.Unmarshal([]byte(data), &tiers); err != nil {
+		s.logger.ErrorContext(ctx, err, logger.Field{
+			Key:   "key",
+			Value: s.key,
+		}, logger.Field{
+			Key:   "action",
+			Value: "unmarshal fee tiers",
+		})
+		return nil, errors.NewTracer("fee_tiers_unmarshal_error").Wrap(err)
+	}
+
+	return tiers, nil
+}
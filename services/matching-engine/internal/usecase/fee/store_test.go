@@ -0,0 +1,73 @@
+package fee
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/muhammadchandra19/exchange/pkg/logger"
+	"github.com/muhammadchandra19/exchange/pkg/redis"
+)
+
+// getClient is a Redis client whose Get returns a fixed value for a single key.
+type getClient struct {
+	redis.Client
+	key  string
+	data string
+	err  error
+}
+
+func (c *getClient) Get(_ context.Context, key string) (string, error) {
+	if key != c.key {
+		return "", nil
+	}
+	return c.data, c.err
+}
+
+func TestTierStore_LoadUserTiers(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		err      error
+		expected map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "tiers",
+			data:     `{"alice":"vip","bob":"pro"}`,
+			expected: map[string]string{"alice": "vip", "bob": "pro"},
+		},
+		{
+			name: "missing key",
+		},
+		{
+			name:    "invalid JSON",
+			data:    `["alice"]`,
+			wantErr: true,
+		},
+		{
+			name:    "redis error",
+			err:     errors.New("connection refused"),
+			wantErr: true,
+		},
+	}
+
+	log, err := logger.NewLogger()
+	require.NoError(t, err)
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			client := &getClient{key: "fee_tiers", data: testCase.data, err: testCase.err}
+			tiers, err := NewTierStore(client, "fee_tiers", log).LoadUserTiers(context.Background())
+			if testCase.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, tiers)
+		})
+	}
+}
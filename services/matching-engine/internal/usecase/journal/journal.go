@@ -0,0 +1,262 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/muhammadchandra19/exchange/pkg/errors"
+	"github.com/muhammadchandra19/exchange/pkg/logger"
+	journalv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/journal/v1"
+)
+
+const (
+	// headerSize is the size of the frame header: payload length and CRC-32C, both big endian.
+	headerSize = 8
+	// maxPayloadSize bounds a single entry, a larger length can only come from a damaged header.
+	maxPayloadSize = 16 << 20
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Journal is an append-only file of checksummed entries. Each entry is a frame holding
+// the length and CRC-32C of its JSON payload followed by the payload.
+type Journal struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	sync     bool
+	sequence int64
+	size     int64 // Size of the valid frames, appends start here
+	logger   *logger.Logger
+}
+
+// NewJournal opens the journal at path, creating it if needed. A frame cut short by a
+// crash while it was written is truncated; a damaged frame followed by more data is
+// reported as journalv1.ErrCorruptEntry. With sync set every append is fsynced before
+// it returns.
+func NewJournal(path string, sync bool, log *logger.Logger) (*Journal, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, errors.NewTracer("journal_open_error").Wrap(err)
+	}
+
+	j := &Journal{
+		path:   path,
+		file:   file,
+		sync:   sync,
+		logger: log,
+	}
+
+	if err := j.recover(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	log.Info("Journal opened",
+		logger.Field{Key: "path", Value: path},
+		logger.Field{Key: "lastSequence", Value: j.sequence},
+	)
+	return j, nil
+}
+
+// recover scans the journal for its last sequence and drops a torn frame at the end.
+func (j *Journal) recover() error {
+	info, err := j.file.Stat()
+	if err != nil {
+		return errors.NewTracer("journal_stat_error").Wrap(err)
+	}
+
+	var end int64
+	err = scan(j.file, info.Size(), func(entry *journalv1.Entry, frameEnd int64) error {
+		if entry.Sequence != j.sequence+1 {
+			return fmt.Errorf("%w: entry %d follows %d", journalv1.ErrSequenceGap, entry.Sequence, j.sequence)
+		}
+		j.sequence = entry.Sequence
+		end = frameEnd
+		return nil
+	})
+	if err != nil {
+		return errors.NewTracer("journal_recover_error").Wrap(err)
+	}
+
+	if end < info.Size() {
+		j.logger.Warn("Truncating torn journal tail",
+			logger.Field{Key: "path", Value: j.path},
+			logger.Field{Key: "validSize", Value: end},
+			logger.Field{Key: "fileSize", Value: info.Size()},
+		)
+		if err := j.file.Truncate(end); err != nil {
+			return errors.NewTracer("journal_truncate_error").Wrap(err)
+		}
+	}
+
+	if _, err := j.file.Seek(end, io.SeekStart); err != nil {
+		return errors.NewTracer("journal_seek_error").Wrap(err)
+	}
+	j.size = end
+	return nil
+}
+
+// Append writes the entries in order and assigns their sequence numbers. Entries are
+// either all written or, if the write fails, the journal keeps its previous sequence.
+func (j *Journal) Append(entries ...*journalv1.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var buf []byte
+	for i, entry := range entries {
+		entry.Sequence = j.sequence + int64(i) + 1
+		payload, err := json.Marshal(entry)
+		if err != nil {
+			return errors.NewTracer("journal_marshal_error").Wrap(err)
+		}
+		buf = appendFrame(buf, payload)
+	}
+
+	if _, err := j.file.Write(buf); err != nil {
+		j.rollback()
+		return errors.NewTracer("journal_write_error").Wrap(err)
+	}
+	if j.sync {
+		if err := j.file.Sync(); err != nil {
+			j.rollback()
+			return errors.NewTracer("journal_sync_error").Wrap(err)
+		}
+	}
+
+	j.sequence += int64(len(entries))
+	j.size += int64(len(buf))
+	return nil
+}
+
+// rollback drops whatever a failed append left behind, so the next append does not
+// follow a partial frame. Caller must hold the lock.
+func (j *Journal) rollback() {
+	if err := j.file.Truncate(j.size); err != nil {
+		j.logger.Error(err, logger.Field{Key: "action", Value: "rollback_journal_append"})
+	}
+	if _, err := j.file.Seek(j.size, io.SeekStart); err != nil {
+		j.logger.Error(err, logger.Field{Key: "action", Value: "rollback_journal_append"})
+	}
+}
+
+// Read calls fn for every entry with a sequence greater than after, in order. It reads
+// through its own file handle, so appends may continue while it runs.
+func (j *Journal) Read(after int64, fn func(*journalv1.Entry) error) error {
+	j.mu.Lock()
+	last := j.sequence
+	j.mu.Unlock()
+
+	file, err := os.Open(j.path)
+	if err != nil {
+		return errors.NewTracer("journal_open_error").Wrap(err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return errors.NewTracer("journal_stat_error").Wrap(err)
+	}
+
+	err = scan(file, info.Size(), func(entry *journalv1.Entry, _ int64) error {
+		if entry.Sequence <= after || entry.Sequence > last {
+			return nil
+		}
+		return fn(entry)
+	})
+	if err != nil {
+		return errors.NewTracer("journal_read_error").Wrap(err)
+	}
+	return nil
+}
+
+// LastSequence returns the sequence of the last entry written, zero for an empty journal.
+func (j *Journal) LastSequence() int64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.sequence
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.file.Close(); err != nil {
+		return errors.NewTracer("journal_close_error").Wrap(err)
+	}
+	return nil
+}
+
+// appendFrame appends the frame of payload to buf.
+func appendFrame(buf, payload []byte) []byte {
+	var header [headerSize]byte
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(header[4:8], crc32.Checksum(payload, castagnoli))
+	buf = append(buf, header[:]...)
+	return append(buf, payload...)
+}
+
+// scan decodes the frames of a journal of the given size from the start of r and calls
+// fn with each entry and the offset its frame ends at. It stops without error at a frame
+// that runs past the end of the journal or fails its checksum as the last frame, which
+// is what a crash during a write leaves behind.
+func scan(r io.ReadSeeker, size int64, fn func(entry *journalv1.Entry, frameEnd int64) error) error {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(r)
+	var offset int64
+	var header [headerSize]byte
+	for {
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+
+		length := int64(binary.BigEndian.Uint32(header[0:4]))
+		checksum := binary.BigEndian.Uint32(header[4:8])
+		frameEnd := offset + headerSize + length
+		if length > maxPayloadSize {
+			return fmt.Errorf("%w: frame at offset %d has length %d", journalv1.ErrCorruptEntry, offset, length)
+		}
+		if frameEnd > size {
+			return nil
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return err
+		}
+
+		if crc32.Checksum(payload, castagnoli) != checksum {
+			if frameEnd == size {
+				return nil
+			}
+			return fmt.Errorf("%w: checksum mismatch at offset %d", journalv1.ErrCorruptEntry, offset)
+		}
+
+		var entry journalv1.Entry
+		if err := json.Unmarshal(payload, &entry); err != nil {
+			return fmt.Errorf("%w: %v", journalv1.ErrCorruptEntry, err)
+		}
+
+		if err := fn(&entry, frameEnd); err != nil {
+			return err
+		}
+		offset = frameEnd
+	}
+}
@@ -0,0 +1,171 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/muhammadchandra19/exchange/pkg/logger"
+	journalv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/journal/v1"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestJournal(t *testing.T, path string) *Journal {
+	log, err := logger.NewLogger()
+	require.NoError(t, err)
+
+	j, err := NewJournal(path, true, log)
+	require.NoError(t, err)
+	t.Cleanup(func() { j.Close() })
+	return j
+}
+
+func orderEntry(orderID string, offset int64) *journalv1.Entry {
+	return &journalv1.Entry{
+		Type:      journalv1.EntryTypeOrder,
+		Offset:    offset,
+		Timestamp: 1_700_000_000_000_000_000 + offset,
+		Order: &orderbookv1.PlaceOrderRequest{
+			OrderID: orderID,
+			UserID:  "user",
+			Type:    orderbookv1.OrderTypeLimit,
+			Size:    10,
+			Price:   100,
+			Offset:  offset,
+		},
+	}
+}
+
+func readAll(t *testing.T, j *Journal, after int64) []*journalv1.Entry {
+	var entries []*journalv1.Entry
+	require.NoError(t, j.Read(after, func(entry *journalv1.Entry) error {
+		entries = append(entries, entry)
+		return nil
+	}))
+	return entries
+}
+
+func TestJournal_AppendAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.journal")
+	j := openTestJournal(t, path)
+
+	require.NoError(t, j.Append(orderEntry("a", 1)))
+	require.NoError(t, j.Append(orderEntry("b", 2), &journalv1.Entry{Type: journalv1.EntryTypeExpiry, Offset: -1, Timestamp: 5}))
+	assert.Equal(t, int64(3), j.LastSequence())
+
+	entries := readAll(t, j, 0)
+	require.Len(t, entries, 3)
+	for i, entry := range entries {
+		assert.Equal(t, int64(i+1), entry.Sequence)
+	}
+	assert.Equal(t, "a", entries[0].Order.OrderID)
+	assert.Equal(t, int64(100), entries[1].Order.Price)
+	assert.Equal(t, journalv1.EntryTypeExpiry, entries[2].Type)
+
+	t.Run("read after a sequence", func(t *testing.T) {
+		entries := readAll(t, j, 2)
+		require.Len(t, entries, 1)
+		assert.Equal(t, int64(3), entries[0].Sequence)
+	})
+
+	t.Run("reopen continues the sequence", func(t *testing.T) {
+		require.NoError(t, j.Close())
+		reopened := openTestJournal(t, path)
+
+		assert.Equal(t, int64(3), reopened.LastSequence())
+		require.NoError(t, reopened.Append(orderEntry("c", 3)))
+		assert.Len(t, readAll(t, reopened, 0), 4)
+	})
+}
+
+func TestJournal_Recovery(t *testing.T) {
+	testCases := []struct {
+		name         string
+		damage       func(t *testing.T, path string, sizes []int64)
+		expectedErr  error
+		expectedLast int64
+	}{
+		{
+			name: "torn header at the end is dropped",
+			damage: func(t *testing.T, path string, sizes []int64) {
+				appendBytes(t, path, []byte{0, 0, 1})
+			},
+			expectedLast: 2,
+		},
+		{
+			name: "torn payload at the end is dropped",
+			damage: func(t *testing.T, path string, sizes []int64) {
+				require.NoError(t, os.Truncate(path, sizes[1]-5))
+			},
+			expectedLast: 1,
+		},
+		{
+			name: "damaged last frame is dropped",
+			damage: func(t *testing.T, path string, sizes []int64) {
+				flipByte(t, path, sizes[1]-2)
+			},
+			expectedLast: 1,
+		},
+		{
+			name: "damaged frame before others is corruption",
+			damage: func(t *testing.T, path string, sizes []int64) {
+				flipByte(t, path, sizes[0]-2)
+			},
+			expectedErr: journalv1.ErrCorruptEntry,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "orders.journal")
+			j := openTestJournal(t, path)
+
+			var sizes []int64
+			for i, id := range []string{"a", "b"} {
+				require.NoError(t, j.Append(orderEntry(id, int64(i))))
+				info, err := os.Stat(path)
+				require.NoError(t, err)
+				sizes = append(sizes, info.Size())
+			}
+			require.NoError(t, j.Close())
+
+			tc.damage(t, path, sizes)
+
+			log, err := logger.NewLogger()
+			require.NoError(t, err)
+			recovered, err := NewJournal(path, true, log)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			defer recovered.Close()
+
+			assert.Equal(t, tc.expectedLast, recovered.LastSequence())
+
+			// New entries follow the last valid one and read back cleanly
+			require.NoError(t, recovered.Append(orderEntry("c", 2)))
+			entries := readAll(t, recovered, 0)
+			require.Len(t, entries, int(tc.expectedLast)+1)
+			assert.Equal(t, "c", entries[len(entries)-1].Order.OrderID)
+			assert.Equal(t, tc.expectedLast+1, entries[len(entries)-1].Sequence)
+		})
+	}
+}
+
+func appendBytes(t *testing.T, path string, data []byte) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = file.Write(data)
+	require.NoError(t, err)
+}
+
+func flipByte(t *testing.T, path string, offset int64) {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[offset] ^= 0xFF
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+}
@@ -19,9 +19,14 @@ type Publisher struct {
 
 // NewPublisher creates a new Kafka publisher for publishing match events.
 func NewPublisher(config config.MatchPublisherConfig, logger logger.Logger) *Publisher {
+	// The engine takes a nil error as the confirmation that a trade is stored, so every
+	// in-sync replica has to acknowledge it. Messages are keyed by pair, which keeps the
+	// trades of a pair in one partition in sequence order.
 	kafkaWriter := kafka.NewWriter(kafka.WriterConfig{
-		Brokers: config.Brokers,
-		Topic:   config.Topic,
+		Brokers:      config.Brokers,
+		Topic:        config.Topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: int(kafka.RequireAll),
 	})
 
 	return &Publisher{
@@ -33,6 +38,7 @@ func NewPublisher(config config.MatchPublisherConfig, logger logger.Logger) *Pub
 // PublishMatchEvent publishes a match event to the Kafka topic.
 func (p *Publisher) PublishMatchEvent(ctx context.Context, matchEvent *pb.MatchEventPayload) error {
 	msg := kafka.Message{
+		Key:   []byte(matchEvent.Symbol),
 		Value: matchpublisherv1.ToBytes(matchEvent),
 	}
 
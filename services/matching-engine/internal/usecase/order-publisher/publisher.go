@@ -0,0 +1,55 @@
+package orderpublisher
+
+import (
+	"context"
+
+	"github.com/muhammadchandra19/exchange/pkg/errors"
+	"github.com/muhammadchandra19/exchange/pkg/logger"
+	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
+	orderpublisherv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-publisher/v1"
+	"github.com/muhammadchandra19/exchange/services/matching-engine/pkg/config"
+	"github.com/segmentio/kafka-go"
+)
+
+// Publisher represents a Kafka Publisher for publishing order events.
+type Publisher struct {
+	kafkaWriter *kafka.Writer
+	logger      logger.Logger
+}
+
+// NewPublisher creates a new Kafka publisher for publishing order events.
+func NewPublisher(config config.OrderPublisherConfig, logger logger.Logger) *Publisher {
+	kafkaWriter := kafka.NewWriter(kafka.WriterConfig{
+		Brokers: config.Brokers,
+		Topic:   config.Topic,
+	})
+
+	return &Publisher{
+		kafkaWriter: kafkaWriter,
+		logger:      logger,
+	}
+}
+
+// PublishOrderEvent publishes an order event to the Kafka topic.
+// Events are keyed by order ID so every event of an order lands on the same partition;
+// events that carry no order, such as market state changes, are keyed by pair.
+func (p *Publisher) PublishOrderEvent(ctx context.Context, orderEvent *pb.OrderEventPayload) error {
+	key := orderEvent.OrderID
+	if key == "" {
+		key = orderEvent.Symbol
+	}
+
+	msg := kafka.Message{
+		Key:   []byte(key),
+		Value: orderpublisherv1.ToBytes(orderEvent),
+	}
+
+	if err := p.kafkaWriter.WriteMessages(ctx, msg); err != nil {
+		p.logger.Error(err,
+			logger.Field{Key: "error", Value: err.Error()},
+			logger.Field{Key: "orderEvent", Value: orderEvent},
+		)
+		return errors.NewTracer("failed to publish order event")
+	}
+	return nil
+}
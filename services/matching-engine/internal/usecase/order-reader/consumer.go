@@ -3,9 +3,11 @@ package orderreader
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	"github.com/muhammadchandra19/exchange/pkg/logger"
 	pb "github.com/muhammadchandra19/exchange/proto/go/kafka/v1"
+	orderreaderv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/order-reader/v1"
 	"github.com/muhammadchandra19/exchange/services/matching-engine/pkg/config"
 	"github.com/segmentio/kafka-go"
 )
@@ -23,7 +25,7 @@ func NewReader(config config.KafkaConfig, log logger.Logger) Reader {
 		Brokers: config.Brokers,
 		Topic:   config.Topic,
 		// GroupID:     config.GroupID,
-		Partition:   0,
+		Partition:   config.Partition,
 		MinBytes:    1,
 		MaxBytes:    10e6,
 		StartOffset: kafka.LastOffset,
@@ -63,7 +65,8 @@ func (r Reader) ReadMessage(ctx context.Context) (kafka.Message, *pb.PlaceOrderP
 	var order pb.PlaceOrderPayload
 	if err := json.Unmarshal(msg.Value, &order); err != nil {
 		r.logError(err, "UnmarshalOrder")
-		return kafka.Message{Offset: 0}, nil, err
+		// The raw message is kept so the engine can apply its error policy to it
+		return msg, nil, fmt.Errorf("%w: %v", orderreaderv1.ErrInvalidMessage, err)
 	}
 
 	r.logger.Info("ReadMessage",
@@ -94,6 +97,16 @@ func (r Reader) ReadMessage(ctx context.Context) (kafka.Message, *pb.PlaceOrderP
 	return msg, &order, nil
 }
 
+// Lag returns the number of messages of the partition that were not read yet.
+func (r Reader) Lag(ctx context.Context) (int64, error) {
+	lag, err := r.kafkaReader.ReadLag(ctx)
+	if err != nil {
+		r.logError(err, "ReadLag")
+		return -1, err
+	}
+	return lag, nil
+}
+
 // Close properly closes the Kafka reader.
 func (r Reader) Close() error {
 	if err := r.kafkaReader.Close(); err != nil {
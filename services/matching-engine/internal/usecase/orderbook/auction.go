@@ -0,0 +1,109 @@
+package orderbook
+
+import (
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+)
+
+// SetAuction starts or ends the call period of an auction. While it lasts, limit orders
+// and amends rest without matching and market orders do not fill.
+func (ob *Orderbook) SetAuction(auction bool) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	ob.auction = auction
+}
+
+// IndicativeUncross returns where the book would uncross now, see orderbookv1.FindUncross.
+func (ob *Orderbook) IndicativeUncross(reference int64) orderbookv1.Uncross {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	return orderbookv1.FindUncross(auctionLevels(ob.bids), auctionLevels(ob.asks), reference)
+}
+
+// Uncross executes every order crossing the equilibrium price at that single price and
+// returns the uncross, its matches and the orders self-trade prevention cancelled. Bids
+// take the asks in price-time priority, the best bid first, as if each was placed again
+// at timestamp; whatever is not filled stays in the book with its priority.
+func (ob *Orderbook) Uncross(reference, timestamp int64) (orderbookv1.Uncross, []orderbookv1.Match, []orderbookv1.SelfTradeCancel) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	uncross := orderbookv1.FindUncross(auctionLevels(ob.bids), auctionLevels(ob.asks), reference)
+	if uncross.Volume == 0 {
+		return uncross, nil, nil
+	}
+
+	canMatch := func(limit *orderbookv1.Limit) bool {
+		return limit.Price <= uncross.Price
+	}
+
+	var (
+		matches []orderbookv1.Match
+		cancels []orderbookv1.SelfTradeCancel
+	)
+	for _, limit := range ob.bids.limits() {
+		if limit.Price < uncross.Price {
+			break
+		}
+
+		for _, bid := range limit.GetOrders() {
+			best := ob.asks.best()
+			if best == nil || !canMatch(best) {
+				return uncross, matches, cancels
+			}
+
+			// The bid leaves the book and matches with its whole size, hidden reserve included
+			if err := ob.removeOrder(bid); err != nil {
+				continue
+			}
+			bid.Size += bid.HiddenSize
+			bid.HiddenSize = 0
+			queuedAt := bid.Timestamp
+			bid.Timestamp = timestamp
+
+			for _, match := range ob.matchOrder(bid, canMatch) {
+				match.Price = uncross.Price
+				matches = append(matches, match)
+			}
+			for _, cancel := range bid.SelfTradeCancels {
+				if cancel.Order == bid {
+					cancel.Price = limit.Price
+				}
+				cancels = append(cancels, cancel)
+			}
+
+			bid.Timestamp = queuedAt
+			if bid.Size > 0 {
+				ob.restOrder(limit.Price, bid)
+			}
+		}
+	}
+
+	return uncross, matches, cancels
+}
+
+// restOrder puts an order back in the book at price, in its place by time priority.
+// Caller must hold the write lock.
+func (ob *Orderbook) restOrder(price int64, order *orderbookv1.Order) {
+	order.Hide()
+	if err := ob.getOrCreateLimit(order.IsBid(), price).AddOrder(order); err != nil {
+		return
+	}
+
+	ob.indexOrder(order)
+}
+
+// auctionLevels returns the size of each limit of one side, hidden reserves included,
+// best price first. Caller must hold the lock.
+func auctionLevels(levels *priceLevels) []orderbookv1.AuctionLevel {
+	result := make([]orderbookv1.AuctionLevel, 0, levels.len())
+	levels.ascend(func(limit *orderbookv1.Limit) bool {
+		result = append(result, orderbookv1.AuctionLevel{
+			Price: limit.Price,
+			Size:  limit.GetTotalVolume() + limit.GetHiddenVolume(),
+		})
+		return true
+	})
+	return result
+}
@@ -0,0 +1,74 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+)
+
+func TestOrderbook_Auction(t *testing.T) {
+	ob := NewOrderbook()
+	ob.SetAuction(true)
+
+	iceberg := createTestOrder("seller2", "sell2", 4, false)
+	iceberg.DisplaySize = 1
+	for _, placed := range []struct {
+		price int64
+		order *orderbookv1.Order
+	}{
+		{price: 99, order: createTestOrder("seller1", "sell1", 3, false)},
+		{price: 100, order: iceberg},
+		{price: 103, order: createTestOrder("seller3", "sell3", 10, false)},
+		{price: 102, order: createTestOrder("buyer1", "buy1", 5, true)},
+		{price: 101, order: createTestOrder("buyer2", "buy2", 5, true)},
+	} {
+		matches, err := ob.PlaceLimitOrder(placed.price, placed.order)
+		require.NoError(t, err)
+		assert.Empty(t, matches)
+	}
+
+	// Crossing orders rest until the uncross, and market orders do not fill
+	assert.Len(t, ob.Orders, 5)
+	market := createTestOrder("buyer3", "buy3", 1, true)
+	matches, err := ob.PlaceMarketOrder(market)
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+	assert.False(t, market.Protected)
+
+	expected := orderbookv1.Uncross{Price: 101, Volume: 7, Surplus: 3}
+	assert.Equal(t, expected, ob.IndicativeUncross(0))
+
+	uncross, matches, cancels := ob.Uncross(0, 1_000)
+	assert.Equal(t, expected, uncross)
+	assert.Empty(t, cancels)
+
+	volume := int64(0)
+	for _, match := range matches {
+		assert.Equal(t, int64(101), match.Price)
+		volume += match.SizeFilled
+	}
+	assert.Equal(t, int64(7), volume)
+	assert.Equal(t, "buy1", matches[0].Bid.ID)
+	assert.Equal(t, "sell1", matches[0].Ask.ID)
+
+	// The bid left over keeps resting at its price, the ask outside the uncross is untouched
+	require.Len(t, ob.Orders, 2)
+	assert.Equal(t, int64(3), ob.BidLimits[101].GetTotalVolume())
+	assert.Equal(t, int64(10), ob.AskLimits[103].GetTotalVolume())
+	assert.NotContains(t, ob.AskLimits, int64(99))
+	assert.NotContains(t, ob.AskLimits, int64(100))
+
+	// Nothing crosses any more
+	uncross, matches, _ = ob.Uncross(0, 2_000)
+	assert.Zero(t, uncross.Volume)
+	assert.Empty(t, matches)
+
+	// Out of the auction orders match again
+	ob.SetAuction(false)
+	matches, err = ob.PlaceLimitOrder(101, createTestOrder("seller4", "sell4", 1, false))
+	require.NoError(t, err)
+	assert.Len(t, matches, 1)
+}
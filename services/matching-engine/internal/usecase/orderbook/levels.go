@@ -0,0 +1,148 @@
+package orderbook
+
+import orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+
+// maxLevelHeight bounds the skip list height; 2^24 price levels per side is far beyond
+// any realistic book.
+const maxLevelHeight = 24
+
+// levelNode is a price level in the skip list.
+type levelNode struct {
+	limit *orderbookv1.Limit
+	next  []*levelNode
+}
+
+// priceLevels keeps the limits of one side of the book in a skip list ordered best
+// price first: ascending for asks, descending for bids. The best limit is the first
+// node, and inserting or removing a price level is O(log n). It is not safe for
+// concurrent use; the orderbook lock guards it.
+type priceLevels struct {
+	head   levelNode
+	height int
+	length int
+	bids   bool   // Orders levels highest price first
+	seed   uint64 // State of the xorshift generator picking node heights
+}
+
+// newPriceLevels creates an empty side of the book. bids orders levels highest price
+// first, otherwise lowest price first.
+func newPriceLevels(bids bool) *priceLevels {
+	return &priceLevels{
+		head:   levelNode{next: make([]*levelNode, maxLevelHeight)},
+		height: 1,
+		bids:   bids,
+		seed:   0x9E3779B97F4A7C15,
+	}
+}
+
+// before reports whether price a ranks ahead of price b on this side.
+func (p *priceLevels) before(a, b int64) bool {
+	if p.bids {
+		return a > b
+	}
+	return a < b
+}
+
+// best returns the limit with the best price, or nil if the side is empty.
+func (p *priceLevels) best() *orderbookv1.Limit {
+	if first := p.head.next[0]; first != nil {
+		return first.limit
+	}
+	return nil
+}
+
+// len returns the number of price levels.
+func (p *priceLevels) len() int {
+	return p.length
+}
+
+// insert adds a limit. The caller makes sure no limit with the same price exists.
+func (p *priceLevels) insert(limit *orderbookv1.Limit) {
+	var update [maxLevelHeight]*levelNode
+	node := &p.head
+	for level := p.height - 1; level >= 0; level-- {
+		for node.next[level] != nil && p.before(node.next[level].limit.Price, limit.Price) {
+			node = node.next[level]
+		}
+		update[level] = node
+	}
+
+	height := p.randomHeight()
+	if height > p.height {
+		for level := p.height; level < height; level++ {
+			update[level] = &p.head
+		}
+		p.height = height
+	}
+
+	inserted := &levelNode{limit: limit, next: make([]*levelNode, height)}
+	for level := 0; level < height; level++ {
+		inserted.next[level] = update[level].next[level]
+		update[level].next[level] = inserted
+	}
+	p.length++
+}
+
+// remove deletes the limit at price and reports whether it was present.
+func (p *priceLevels) remove(price int64) bool {
+	var update [maxLevelHeight]*levelNode
+	node := &p.head
+	for level := p.height - 1; level >= 0; level-- {
+		for node.next[level] != nil && p.before(node.next[level].limit.Price, price) {
+			node = node.next[level]
+		}
+		update[level] = node
+	}
+
+	target := node.next[0]
+	if target == nil || target.limit.Price != price {
+		return false
+	}
+
+	for level := 0; level < len(target.next); level++ {
+		update[level].next[level] = target.next[level]
+	}
+	for p.height > 1 && p.head.next[p.height-1] == nil {
+		p.height--
+	}
+	p.length--
+	return true
+}
+
+// ascend calls fn for each limit from the best price on, until fn returns false.
+// fn may remove the limit it is called with.
+func (p *priceLevels) ascend(fn func(*orderbookv1.Limit) bool) {
+	for node := p.head.next[0]; node != nil; {
+		next := node.next[0]
+		if !fn(node.limit) {
+			return
+		}
+		node = next
+	}
+}
+
+// limits returns every limit, best price first.
+func (p *priceLevels) limits() []*orderbookv1.Limit {
+	limits := make([]*orderbookv1.Limit, 0, p.length)
+	p.ascend(func(limit *orderbookv1.Limit) bool {
+		limits = append(limits, limit)
+		return true
+	})
+	return limits
+}
+
+// randomHeight picks a node height with a geometric distribution (p = 1/4), using a
+// fixed-seed xorshift generator so the book behaves the same on every run.
+func (p *priceLevels) randomHeight() int {
+	height := 1
+	for height < maxLevelHeight {
+		p.seed ^= p.seed << 13
+		p.seed ^= p.seed >> 7
+		p.seed ^= p.seed << 17
+		if p.seed&3 != 0 {
+			break
+		}
+		height++
+	}
+	return height
+}
@@ -0,0 +1,110 @@
+package orderbook
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func levelPrices(levels *priceLevels) []int64 {
+	var prices []int64
+	for _, limit := range levels.limits() {
+		prices = append(prices, limit.Price)
+	}
+	return prices
+}
+
+func TestPriceLevels_Order(t *testing.T) {
+	testCases := []struct {
+		name     string
+		bids     bool
+		inserted []int64
+		removed  []int64
+		expected []int64
+	}{
+		{
+			name:     "asks lowest price first",
+			inserted: []int64{105, 101, 110, 103},
+			expected: []int64{101, 103, 105, 110},
+		},
+		{
+			name:     "bids highest price first",
+			bids:     true,
+			inserted: []int64{95, 99, 90, 97},
+			expected: []int64{99, 97, 95, 90},
+		},
+		{
+			name:     "removing the best level exposes the next",
+			inserted: []int64{101, 102, 103},
+			removed:  []int64{101},
+			expected: []int64{102, 103},
+		},
+		{
+			name:     "removing a missing level changes nothing",
+			bids:     true,
+			inserted: []int64{99, 98},
+			removed:  []int64{97},
+			expected: []int64{99, 98},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			levels := newPriceLevels(tc.bids)
+			for _, price := range tc.inserted {
+				levels.insert(orderbookv1.NewLimit(price))
+			}
+			for _, price := range tc.removed {
+				levels.remove(price)
+			}
+
+			assert.Equal(t, tc.expected, levelPrices(levels))
+			assert.Equal(t, len(tc.expected), levels.len())
+			require.NotNil(t, levels.best())
+			assert.Equal(t, tc.expected[0], levels.best().Price)
+		})
+	}
+}
+
+func TestPriceLevels_MatchesSortedReference(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	levels := newPriceLevels(false)
+	present := make(map[int64]bool)
+
+	for i := 0; i < 5_000; i++ {
+		price := int64(rng.Intn(1_000) + 1)
+		if present[price] {
+			assert.True(t, levels.remove(price))
+			delete(present, price)
+		} else {
+			levels.insert(orderbookv1.NewLimit(price))
+			present[price] = true
+		}
+	}
+
+	expected := make([]int64, 0, len(present))
+	for price := range present {
+		expected = append(expected, price)
+	}
+	sort.Slice(expected, func(i, j int) bool { return expected[i] < expected[j] })
+
+	assert.Equal(t, expected, levelPrices(levels))
+	assert.Equal(t, len(expected), levels.len())
+}
+
+func TestPriceLevels_Empty(t *testing.T) {
+	levels := newPriceLevels(true)
+
+	assert.Nil(t, levels.best())
+	assert.Empty(t, levels.limits())
+	assert.False(t, levels.remove(100))
+
+	levels.insert(orderbookv1.NewLimit(100))
+	assert.True(t, levels.remove(100))
+	assert.Nil(t, levels.best())
+	assert.Zero(t, levels.len())
+}
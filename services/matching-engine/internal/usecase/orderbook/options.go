@@ -0,0 +1,28 @@
+package orderbook
+
+import orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+
+// Options represents configuration options for the Orderbook.
+type Options struct {
+	TickSize int64 // Minimum price increment in price units, used to reprice post-only orders
+
+	// Precision and lot size of the pair, used to turn the budget of quote-size market
+	// orders into whole lots
+	Scale   orderbookv1.Scale
+	LotSize int64
+
+	// Self-trade prevention mode for orders that do not set their own
+	SelfTradePrevention orderbookv1.SelfTradePrevention
+
+	// Allocation splits incoming orders between the resting orders of a price level, nil
+	// fills them in time priority
+	Allocation orderbookv1.Allocation
+}
+
+// DefaultOrderbookOptions returns the default orderbook options.
+func DefaultOrderbookOptions() *Options {
+	return &Options{
+		TickSize:            1,
+		SelfTradePrevention: orderbookv1.SelfTradePreventionNone,
+	}
+}
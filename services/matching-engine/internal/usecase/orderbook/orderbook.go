@@ -9,36 +9,97 @@ import (
 	snapshotv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/snapshot/v1"
 )
 
-// Orderbook represents a simple order book
+// Orderbook represents a simple order book. Each side keeps its limits in a map for
+// lookup by price and in a skip list ordered best price first for matching.
 type Orderbook struct {
 	mu        sync.RWMutex
-	AskLimits map[float64]*orderbookv1.Limit // price -> limit
-	BidLimits map[float64]*orderbookv1.Limit // price -> limit
-	Orders    map[string]*orderbookv1.Order  // orderID -> order
+	AskLimits map[int64]*orderbookv1.Limit  // price -> limit
+	BidLimits map[int64]*orderbookv1.Limit  // price -> limit
+	Orders    map[string]*orderbookv1.Order // orderID -> order
+	GTDOrders map[string]*orderbookv1.Order // orderID -> resting good-till-date order
+
+	UserOrders map[string]map[string]*orderbookv1.Order // userID -> orderID -> resting order
+
+	asks *priceLevels // Ask limits, lowest price first
+	bids *priceLevels // Bid limits, highest price first
+
+	tickSize            int64
+	scale               orderbookv1.Scale
+	lotSize             int64
+	selfTradePrevention orderbookv1.SelfTradePrevention
+	allocation          orderbookv1.Allocation // Fill strategy of every price level, nil for FIFO
+	auction             bool                   // Orders rest without matching until the book is uncrossed
 }
 
 // NewOrderbook creates a new orderbook
 func NewOrderbook() *Orderbook {
+	return NewOrderbookWithOptions(DefaultOrderbookOptions())
+}
+
+// NewOrderbookWithOptions creates a new orderbook with custom options
+func NewOrderbookWithOptions(options *Options) *Orderbook {
+	// A pair without a tick rule still needs a step to reprice post-only orders by
+	tickSize := options.TickSize
+	if tickSize <= 0 {
+		tickSize = DefaultOrderbookOptions().TickSize
+	}
+
 	return &Orderbook{
-		AskLimits: make(map[float64]*orderbookv1.Limit),
-		BidLimits: make(map[float64]*orderbookv1.Limit),
+		AskLimits: make(map[int64]*orderbookv1.Limit),
+		BidLimits: make(map[int64]*orderbookv1.Limit),
 		Orders:    make(map[string]*orderbookv1.Order),
+		GTDOrders: make(map[string]*orderbookv1.Order),
+		asks:      newPriceLevels(false),
+		bids:      newPriceLevels(true),
+		tickSize:  tickSize,
+		scale:     options.Scale,
+		lotSize:   options.LotSize,
+
+		UserOrders: make(map[string]map[string]*orderbookv1.Order),
+
+		selfTradePrevention: options.SelfTradePrevention,
+		allocation:          options.Allocation,
 	}
 }
 
-// PlaceLimitOrder places a limit order
-func (ob *Orderbook) PlaceLimitOrder(price float64, order *orderbookv1.Order) error {
+// PlaceLimitOrder places a limit order. The order is first matched against the
+// opposite side up to its limit price, and only the unfilled remainder rests in the book.
+// IOC and FOK orders never rest: whatever is left in order.Size after matching is cancelled.
+// A post-only order that would cross is rejected with ErrPostOnlyWouldCross, or in reprice
+// mode rests one tick behind the best opposite price instead.
+// An iceberg order matches with its full size, but only DisplaySize of the remainder
+// is visible once it rests; the rest is kept in its hidden reserve.
+// During an auction the order rests without matching, even if it crosses the book.
+func (ob *Orderbook) PlaceLimitOrder(price int64, order *orderbookv1.Order) ([]orderbookv1.Match, error) {
 	if order == nil {
-		return fmt.Errorf("order cannot be nil")
+		return nil, fmt.Errorf("order cannot be nil")
 	}
 	if price <= 0 {
-		return fmt.Errorf("price must be positive")
+		return nil, fmt.Errorf("price must be positive")
 	}
 	if order.Size <= 0 {
-		return fmt.Errorf("order size must be positive")
+		return nil, fmt.Errorf("order size must be positive")
 	}
 	if order.ID == "" {
-		return fmt.Errorf("order ID cannot be empty")
+		return nil, fmt.Errorf("order ID cannot be empty")
+	}
+	if err := order.TimeInForce.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, order.TimeInForce)
+	}
+	if order.TimeInForce == orderbookv1.TimeInForceGTD && order.ExpireAt <= 0 {
+		return nil, orderbookv1.ErrInvalidExpireAt
+	}
+	if err := order.PostOnlyMode.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, order.PostOnlyMode)
+	}
+	if order.PostOnly && order.TimeInForce.IsImmediate() {
+		return nil, orderbookv1.ErrPostOnlyNotAllowed
+	}
+	if order.DisplaySize < 0 {
+		return nil, fmt.Errorf("%w: got %d", orderbookv1.ErrInvalidDisplaySize, order.DisplaySize)
+	}
+	if err := order.SelfTradePrevention.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, order.SelfTradePrevention)
 	}
 
 	ob.mu.Lock()
@@ -46,91 +107,422 @@ func (ob *Orderbook) PlaceLimitOrder(price float64, order *orderbookv1.Order) er
 
 	// Check if order already exists
 	if _, exists := ob.Orders[order.ID]; exists {
-		return fmt.Errorf("order with ID %s already exists", order.ID)
+		return nil, fmt.Errorf("order with ID %s already exists", order.ID)
 	}
 
-	// Find or create limit
-	var limits map[float64]*orderbookv1.Limit
-	if order.IsBid() {
-		limits = ob.BidLimits
-	} else {
-		limits = ob.AskLimits
+	return ob.placeLimitOrder(price, order)
+}
+
+// placeLimitOrder matches a validated limit order and rests the remainder.
+// Caller must hold the write lock.
+func (ob *Orderbook) placeLimitOrder(price int64, order *orderbookv1.Order) ([]orderbookv1.Match, error) {
+	if order.PostOnly && !ob.auction {
+		repriced, err := ob.postOnlyPrice(order, price)
+		if err != nil {
+			return nil, err
+		}
+		price = repriced
 	}
 
-	limit, exists := limits[price]
-	if !exists {
-		limit = orderbookv1.NewLimit(price)
-		limits[price] = limit
+	// Match against the opposite side while the book crosses the limit price
+	canMatch := func(limit *orderbookv1.Limit) bool {
+		if ob.auction {
+			return false
+		}
+		if order.IsBid() {
+			return limit.Price <= price
+		}
+		return limit.Price >= price
 	}
 
-	// Add order to limit
+	if order.TimeInForce == orderbookv1.TimeInForceFOK && ob.availableVolume(order, canMatch) < order.Size {
+		return nil, nil
+	}
+
+	matches := ob.matchOrder(order, canMatch)
+
+	if order.Size <= 0 || order.TimeInForce.IsImmediate() {
+		return matches, nil
+	}
+
+	limit := ob.getOrCreateLimit(order.IsBid(), price)
+
+	// Add order to limit, showing only the display size of an iceberg order
+	order.Hide()
 	if err := limit.AddOrder(order); err != nil {
-		return err
+		return matches, err
 	}
 
-	// Add to orders map
-	ob.Orders[order.ID] = order
+	ob.indexOrder(order)
 
-	return nil
+	return matches, nil
 }
 
-// PlaceMarketOrder places a market order and returns matches
+// PlaceMarketOrder places a market order and returns matches.
+// A FOK market order is only executed if the book can fill it completely. An order with a
+// protection price does not fill beyond it; if it stops there with size left, it is marked
+// Protected. Likewise it does not fill beyond the owner's WorstPrice or MaxSlippageBps from
+// the best opposite price on arrival, and is marked SlippageLimited if it stops there. An
+// order with a QuoteSize spends that budget instead of filling a size, see matchQuoteOrder;
+// it cannot be FOK.
 func (ob *Orderbook) PlaceMarketOrder(order *orderbookv1.Order) ([]orderbookv1.Match, error) {
 	if order == nil {
 		return nil, fmt.Errorf("order cannot be nil")
 	}
+	if err := order.TimeInForce.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, order.TimeInForce)
+	}
+	if order.PostOnly {
+		return nil, orderbookv1.ErrPostOnlyNotAllowed
+	}
+	if err := order.SelfTradePrevention.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, order.SelfTradePrevention)
+	}
+	if order.IsQuote() && (order.Size != 0 || order.TimeInForce == orderbookv1.TimeInForceFOK) {
+		return nil, orderbookv1.ErrInvalidQuoteSize
+	}
+	if !order.IsQuote() && order.Size <= 0 {
+		return nil, fmt.Errorf("%w: got %d", orderbookv1.ErrInvalidSize, order.Size)
+	}
+	if order.WorstPrice < 0 || order.MaxSlippageBps < 0 {
+		return nil, orderbookv1.ErrInvalidSlippage
+	}
 
 	ob.mu.Lock()
 	defer ob.mu.Unlock()
 
-	var matches []orderbookv1.Match
-	var limits []*orderbookv1.Limit
+	worstPrice := order.WorstPrice
+	if best := ob.oppositeLevels(order).best(); best != nil {
+		worstPrice = order.WorstAcceptablePrice(best.Price)
+	}
+	canMatch := func(limit *orderbookv1.Limit) bool {
+		return !ob.auction && reaches(order, limit.Price, order.ProtectionPrice) && reaches(order, limit.Price, worstPrice)
+	}
+	// markStopped records which limit kept an order with something left from filling
+	markStopped := func() {
+		best := ob.oppositeLevels(order).best()
+		if ob.auction || best == nil {
+			return
+		}
+		order.Protected = !reaches(order, best.Price, order.ProtectionPrice)
+		order.SlippageLimited = !reaches(order, best.Price, worstPrice)
+	}
 
-	// Get limits in price priority order
-	if order.IsBid() {
-		// Buy order: match against asks (lowest price first)
-		for price := range ob.AskLimits {
-			limits = append(limits, ob.AskLimits[price])
+	if order.IsQuote() {
+		matches := ob.matchQuoteOrder(order, canMatch)
+		if order.QuoteRemaining > 0 && !order.QuoteDust {
+			markStopped()
+		}
+		return matches, nil
+	}
+	if order.TimeInForce == orderbookv1.TimeInForceFOK && ob.availableVolume(order, canMatch) < order.Size {
+		return nil, nil
+	}
+
+	matches := ob.matchOrder(order, canMatch)
+	if order.Size > 0 {
+		markStopped()
+	}
+	return matches, nil
+}
+
+// reaches reports whether order may fill at price given a limit on the worst price it
+// accepts, zero for none.
+func reaches(order *orderbookv1.Order, price, limit int64) bool {
+	switch {
+	case limit <= 0:
+		return true
+	case order.IsBid():
+		return price <= limit
+	}
+	return price >= limit
+}
+
+// matchQuoteOrder matches a quote-size market order one price level at a time. At each
+// level the order takes the whole lots its remaining budget pays for, and the walk stops
+// once the budget cannot pay for a lot at the best price, which sets order.QuoteDust.
+// The order is left with no size; the remaining size recorded with each match is the size
+// the order went on to fill, so its last fill is a filled event.
+// Caller must hold the write lock.
+func (ob *Orderbook) matchQuoteOrder(order *orderbookv1.Order, canMatch func(*orderbookv1.Limit) bool) []orderbookv1.Match {
+	var (
+		matches []orderbookv1.Match
+		cancels []orderbookv1.SelfTradeCancel
+	)
+	order.QuoteRemaining = order.QuoteSize
+
+	for {
+		best := ob.oppositeLevels(order).best()
+		if best == nil || !canMatch(best) {
+			break
+		}
+		size := ob.scale.SizeFor(order.QuoteRemaining, best.Price, ob.lotSize)
+		if size <= 0 {
+			order.QuoteDust = true
+			break
+		}
+
+		order.Size = size
+		levelMatches := ob.matchOrder(order, func(limit *orderbookv1.Limit) bool { return limit == best })
+		for i := range levelMatches {
+			order.QuoteRemaining -= ob.scale.Notional(levelMatches[i].Price, levelMatches[i].SizeFilled)
+			levelMatches[i].QuoteRemaining = order.QuoteRemaining
+		}
+		matches = append(matches, levelMatches...)
+
+		// Self-trade prevention cancelling the incoming order gives up the rest of its
+		// budget; the size of the level it was filling means nothing to its owner, nor
+		// does a decrement of it, which costs no budget
+		stopped := false
+		for _, cancel := range order.SelfTradeCancels {
+			if cancel.Order == order {
+				if cancel.Partial {
+					continue
+				}
+				cancel.Size = 0
+				stopped = true
+			}
+			cancels = append(cancels, cancel)
+		}
+		if stopped || len(levelMatches) == 0 && len(order.SelfTradeCancels) == 0 {
+			break
+		}
+	}
+
+	order.Size = 0
+	order.SelfTradeCancels = cancels
+
+	filledAfter := int64(0)
+	for i := len(matches) - 1; i >= 0; i-- {
+		if order.IsBid() {
+			matches[i].BidRemaining = filledAfter
+		} else {
+			matches[i].AskRemaining = filledAfter
 		}
-		sort.Slice(limits, func(i, j int) bool {
-			return limits[i].Price < limits[j].Price
-		})
+		filledAfter += matches[i].SizeFilled
+	}
+	return matches
+}
+
+// AmendOrder changes the price and remaining size of a resting order and returns it.
+// Reducing the size at the same price keeps the order's place in the queue; the hidden
+// reserve of an iceberg order is reduced first. A price change or a size increase
+// re-enters the order with timestamp, the time of the amend in Unix nanoseconds, so it
+// loses priority and can trade if it now crosses the book. Only userID, the owner of the
+// order, may amend it; another user's order is reported as unknown.
+func (ob *Orderbook) AmendOrder(orderID, userID string, price, size, timestamp int64) (*orderbookv1.Order, []orderbookv1.Match, error) {
+	if orderID == "" {
+		return nil, nil, fmt.Errorf("order ID cannot be empty")
+	}
+	if price <= 0 {
+		return nil, nil, fmt.Errorf("price must be positive")
+	}
+	if size <= 0 {
+		return nil, nil, fmt.Errorf("order size must be positive")
+	}
+
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	order, exists := ob.Orders[orderID]
+	if !exists || order.UserID != userID {
+		return nil, nil, fmt.Errorf("%w: %s", orderbookv1.ErrUnknownOrder, orderID)
+	}
+
+	limit := order.Limit
+	if limit != nil && limit.Price == price && size <= order.TotalSize() {
+		if err := limit.ReduceOrder(order, size); err != nil {
+			return nil, nil, err
+		}
+		return order, nil, nil
+	}
+
+	// Refuse the amend before touching the book if a post-only order would now cross
+	if order.PostOnly && !ob.auction {
+		if _, err := ob.postOnlyPrice(order, price); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := ob.removeOrder(order); err != nil {
+		return nil, nil, err
+	}
+
+	order.Size = size
+	order.HiddenSize = 0
+	order.Timestamp = timestamp
+	order.NextSequence()
+
+	matches, err := ob.placeLimitOrder(price, order)
+	return order, matches, err
+}
+
+// postOnlyPrice returns the price a post-only order can rest at without taking liquidity.
+// Caller must hold the lock.
+func (ob *Orderbook) postOnlyPrice(order *orderbookv1.Order, price int64) (int64, error) {
+	bestLimit := ob.oppositeLevels(order).best()
+	if bestLimit == nil {
+		return price, nil
+	}
+
+	best := bestLimit.Price
+	if order.IsBid() && price < best || order.IsAsk() && price > best {
+		return price, nil
+	}
+
+	if order.PostOnlyMode != orderbookv1.PostOnlyModeReprice {
+		return 0, orderbookv1.ErrPostOnlyWouldCross
+	}
+
+	if order.IsBid() {
+		price = best - ob.tickSize
 	} else {
-		// Sell order: match against bids (highest price first)
-		for price := range ob.BidLimits {
-			limits = append(limits, ob.BidLimits[price])
+		price = best + ob.tickSize
+	}
+	if price <= 0 {
+		return 0, orderbookv1.ErrPostOnlyWouldCross
+	}
+
+	return price, nil
+}
+
+// availableVolume returns the opposite-side volume the order could trade against,
+// including hidden iceberg reserves, summed over the limits accepted by canMatch.
+// Resting orders of the same user that self-trade prevention keeps the order from
+// trading with do not count: cancel_oldest removes them and matching goes on, every
+// other mode stops the order at the first of them in queue order, so nothing after it
+// counts either. Caller must hold the lock.
+func (ob *Orderbook) availableVolume(order *orderbookv1.Order, canMatch func(*orderbookv1.Limit) bool) int64 {
+	mode := order.SelfTradePrevention
+	if mode == "" {
+		mode = ob.selfTradePrevention
+	}
+	selfTrades := mode.IsEnabled() && ob.hasOppositeOrders(order)
+
+	total := int64(0)
+	ob.oppositeLevels(order).ascend(func(limit *orderbookv1.Limit) bool {
+		if !canMatch(limit) {
+			return false
+		}
+		if !selfTrades {
+			total += limit.GetTotalVolume() + limit.GetHiddenVolume()
+			return true
+		}
+
+		for _, resting := range limit.GetOrdersByPriority() {
+			if resting.UserID != order.UserID {
+				total += resting.TotalSize()
+				continue
+			}
+			if mode != orderbookv1.SelfTradePreventionCancelOldest {
+				return false
+			}
+		}
+		return true
+	})
+	return total
+}
+
+// hasOppositeOrders reports whether the owner of order has resting orders on the side
+// it trades against. Caller must hold the lock.
+func (ob *Orderbook) hasOppositeOrders(order *orderbookv1.Order) bool {
+	for _, resting := range ob.UserOrders[order.UserID] {
+		if resting.Bid != order.Bid {
+			return true
 		}
-		sort.Slice(limits, func(i, j int) bool {
-			return limits[i].Price > limits[j].Price
-		})
 	}
+	return false
+}
+
+// oppositeLevels returns the side the order can trade against. Caller must hold the lock.
+func (ob *Orderbook) oppositeLevels(order *orderbookv1.Order) *priceLevels {
+	if order.IsBid() {
+		return ob.asks
+	}
+	return ob.bids
+}
+
+// getOrCreateLimit returns the limit at price on the bid or ask side, adding a new
+// price level if there is none. Caller must hold the write lock.
+func (ob *Orderbook) getOrCreateLimit(bid bool, price int64) *orderbookv1.Limit {
+	limits, levels := ob.AskLimits, ob.asks
+	if bid {
+		limits, levels = ob.BidLimits, ob.bids
+	}
+
+	limit, exists := limits[price]
+	if !exists {
+		limit = orderbookv1.NewLimit(price)
+		limit.SetAllocation(ob.allocation)
+		limits[price] = limit
+		levels.insert(limit)
+	}
+	return limit
+}
+
+// deleteLimit removes a price level from the bid or ask side. Caller must hold the write lock.
+func (ob *Orderbook) deleteLimit(bid bool, limit *orderbookv1.Limit) {
+	if bid {
+		delete(ob.BidLimits, limit.Price)
+		ob.bids.remove(limit.Price)
+		return
+	}
+	delete(ob.AskLimits, limit.Price)
+	ob.asks.remove(limit.Price)
+}
+
+// matchOrder fills the order against the opposite side in price priority order,
+// stopping at the first limit rejected by canMatch. Orders without a self-trade
+// prevention mode use the pair's mode. Caller must hold the write lock.
+func (ob *Orderbook) matchOrder(order *orderbookv1.Order, canMatch func(*orderbookv1.Limit) bool) []orderbookv1.Match {
+	var matches []orderbookv1.Match
+
+	if order.SelfTradePrevention == "" {
+		order.SelfTradePrevention = ob.selfTradePrevention
+	}
+	order.SelfTradeCancels = nil
 
 	// Process limits until order is filled
-	for _, limit := range limits {
-		if order.Size <= 0 {
-			break
+	ob.oppositeLevels(order).ascend(func(limit *orderbookv1.Limit) bool {
+		if order.Size <= 0 || !canMatch(limit) {
+			return false
 		}
 
 		limitMatches := limit.Fill(order)
 		matches = append(matches, limitMatches...)
 
+		// Forget resting orders that were completely filled
+		for _, match := range limitMatches {
+			resting := match.Ask
+			if order.IsAsk() {
+				resting = match.Bid
+			}
+			if resting.Size <= 0 {
+				ob.forgetOrder(resting)
+			}
+		}
+
+		// Forget resting orders cancelled by self-trade prevention
+		for _, cancel := range order.SelfTradeCancels {
+			if cancel.Order != order && cancel.Order.Size <= 0 {
+				ob.forgetOrder(cancel.Order)
+			}
+		}
+
 		// Remove empty limits
 		if limit.IsEmpty() {
-			if order.IsBid() {
-				delete(ob.AskLimits, limit.Price)
-			} else {
-				delete(ob.BidLimits, limit.Price)
-			}
+			ob.deleteLimit(order.IsAsk(), limit)
 		}
-	}
+		return true
+	})
 
-	return matches, nil
+	return matches
 }
 
 // CancelOrder removes an order
-func (ob *Orderbook) CancelOrder(orderID string) error {
+func (ob *Orderbook) CancelOrder(orderID string) (*orderbookv1.Order, error) {
 	if orderID == "" {
-		return fmt.Errorf("order ID cannot be empty")
+		return nil, fmt.Errorf("order ID cannot be empty")
 	}
 
 	ob.mu.Lock()
@@ -138,9 +530,69 @@ func (ob *Orderbook) CancelOrder(orderID string) error {
 
 	order, exists := ob.Orders[orderID]
 	if !exists {
-		return fmt.Errorf("order with ID %s does not exist", orderID)
+		return nil, fmt.Errorf("%w: %s", orderbookv1.ErrUnknownOrder, orderID)
 	}
 
+	if err := ob.removeOrder(order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// MassCancel removes every resting order of filter.UserID that matches the filter's side
+// and price range, and returns them bids first, then asks, each best price first and in
+// queue order within a price. Only the user's own orders are looked at, through the
+// per-user index.
+func (ob *Orderbook) MassCancel(filter orderbookv1.MassCancelFilter) ([]*orderbookv1.Order, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	var cancelled []*orderbookv1.Order
+	for _, order := range ob.UserOrders[filter.UserID] {
+		if filter.Matches(order) {
+			cancelled = append(cancelled, order)
+		}
+	}
+	sort.Slice(cancelled, func(i, j int) bool {
+		a, b := cancelled[i], cancelled[j]
+		switch {
+		case a.Bid != b.Bid:
+			return a.Bid
+		case a.Price != b.Price:
+			return a.Bid == (a.Price > b.Price)
+		case a.Timestamp != b.Timestamp:
+			return a.Timestamp < b.Timestamp
+		}
+		return a.Sequence < b.Sequence
+	})
+
+	for _, order := range cancelled {
+		if err := ob.removeOrder(order); err != nil {
+			return nil, err
+		}
+	}
+	return cancelled, nil
+}
+
+// GetOrder returns a copy of a resting order
+func (ob *Orderbook) GetOrder(orderID string) (*orderbookv1.Order, error) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	order, exists := ob.Orders[orderID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", orderbookv1.ErrUnknownOrder, orderID)
+	}
+	return order.Clone(), nil
+}
+
+// removeOrder takes a resting order out of its limit and the order maps.
+// Caller must hold the write lock.
+func (ob *Orderbook) removeOrder(order *orderbookv1.Order) error {
 	// Store limit reference before removing order (since RemoveOrder sets order.Limit to nil)
 	limit := order.Limit
 
@@ -152,56 +604,110 @@ func (ob *Orderbook) CancelOrder(orderID string) error {
 
 		// Remove empty limit (use stored reference since order.Limit is now nil)
 		if limit.IsEmpty() {
-			if order.IsBid() {
-				delete(ob.BidLimits, limit.Price)
-			} else {
-				delete(ob.AskLimits, limit.Price)
-			}
+			ob.deleteLimit(order.IsBid(), limit)
 		}
 	}
 
-	// Remove from orders map
-	delete(ob.Orders, orderID)
+	ob.forgetOrder(order)
 
 	return nil
 }
 
-// Asks returns ask limits sorted by price (ascending)
-func (ob *Orderbook) Asks() []*orderbookv1.Limit {
+// indexOrder adds a resting order to the order maps. Caller must hold the write lock.
+func (ob *Orderbook) indexOrder(order *orderbookv1.Order) {
+	ob.Orders[order.ID] = order
+	if order.TimeInForce == orderbookv1.TimeInForceGTD {
+		ob.GTDOrders[order.ID] = order
+	}
+
+	userOrders, exists := ob.UserOrders[order.UserID]
+	if !exists {
+		userOrders = make(map[string]*orderbookv1.Order)
+		ob.UserOrders[order.UserID] = userOrders
+	}
+	userOrders[order.ID] = order
+}
+
+// forgetOrder removes an order from the order maps. Caller must hold the write lock.
+func (ob *Orderbook) forgetOrder(order *orderbookv1.Order) {
+	delete(ob.Orders, order.ID)
+	delete(ob.GTDOrders, order.ID)
+
+	if userOrders, exists := ob.UserOrders[order.UserID]; exists {
+		delete(userOrders, order.ID)
+		if len(userOrders) == 0 {
+			delete(ob.UserOrders, order.UserID)
+		}
+	}
+}
+
+// HasExpiredOrders reports whether a good-till-date order has expired at the given
+// Unix nanosecond time, without removing anything.
+func (ob *Orderbook) HasExpiredOrders(now int64) bool {
 	ob.mu.RLock()
 	defer ob.mu.RUnlock()
 
-	var limits []*orderbookv1.Limit
-	for _, limit := range ob.AskLimits {
-		limits = append(limits, limit)
+	for _, order := range ob.GTDOrders {
+		if order.IsExpired(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpireOrders removes every good-till-date order that has expired at the given
+// Unix nanosecond time and returns them, oldest expiry first.
+func (ob *Orderbook) ExpireOrders(now int64) []*orderbookv1.Order {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	var expired []*orderbookv1.Order
+	for _, order := range ob.GTDOrders {
+		if order.IsExpired(now) {
+			expired = append(expired, order)
+		}
 	}
-	sort.Slice(limits, func(i, j int) bool {
-		return limits[i].Price < limits[j].Price
+	sort.Slice(expired, func(i, j int) bool {
+		if expired[i].ExpireAt == expired[j].ExpireAt {
+			return expired[i].ID < expired[j].ID
+		}
+		return expired[i].ExpireAt < expired[j].ExpireAt
 	})
-	return limits
+
+	for _, order := range expired {
+		if limit := order.Limit; limit != nil {
+			if err := limit.RemoveOrder(order); err == nil && limit.IsEmpty() {
+				ob.deleteLimit(order.IsBid(), limit)
+			}
+		}
+		ob.forgetOrder(order)
+	}
+
+	return expired
+}
+
+// Asks returns ask limits sorted by price (ascending). Limit volumes only include visible size.
+func (ob *Orderbook) Asks() []*orderbookv1.Limit {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	return ob.asks.limits()
 }
 
-// Bids returns bid limits sorted by price (descending)
+// Bids returns bid limits sorted by price (descending). Limit volumes only include visible size.
 func (ob *Orderbook) Bids() []*orderbookv1.Limit {
 	ob.mu.RLock()
 	defer ob.mu.RUnlock()
 
-	var limits []*orderbookv1.Limit
-	for _, limit := range ob.BidLimits {
-		limits = append(limits, limit)
-	}
-	sort.Slice(limits, func(i, j int) bool {
-		return limits[i].Price > limits[j].Price
-	})
-	return limits
+	return ob.bids.limits()
 }
 
 // AskTotalVolume returns total ask volume
-func (ob *Orderbook) AskTotalVolume() float64 {
+func (ob *Orderbook) AskTotalVolume() int64 {
 	ob.mu.RLock()
 	defer ob.mu.RUnlock()
 
-	total := 0.0
+	total := int64(0)
 	for _, limit := range ob.AskLimits {
 		total += limit.GetTotalVolume()
 	}
@@ -209,11 +715,11 @@ func (ob *Orderbook) AskTotalVolume() float64 {
 }
 
 // BidTotalVolume returns total bid volume
-func (ob *Orderbook) BidTotalVolume() float64 {
+func (ob *Orderbook) BidTotalVolume() int64 {
 	ob.mu.RLock()
 	defer ob.mu.RUnlock()
 
-	total := 0.0
+	total := int64(0)
 	for _, limit := range ob.BidLimits {
 		total += limit.GetTotalVolume()
 	}
@@ -227,41 +733,64 @@ func (ob *Orderbook) CreateSnapshot() *snapshotv1.Snapshot {
 
 	var bookOrders []snapshotv1.BookOrder
 
-	// Collect all orders from all limits
-	for _, limit := range ob.AskLimits {
+	// Collect all orders from all limits, best price first
+	for _, limit := range ob.asks.limits() {
 		orders := limit.GetOrders()
 		for _, order := range orders {
 			bookOrders = append(bookOrders, snapshotv1.BookOrder{
-				OrderID:   order.ID,
-				Size:      order.Size,
-				Bid:       order.Bid,
-				Price:     limit.Price,
-				UserID:    order.UserID,
-				Timestamp: order.Timestamp,
+				OrderID:     order.ID,
+				Size:        order.Size,
+				Bid:         order.Bid,
+				Price:       limit.Price,
+				UserID:      order.UserID,
+				Timestamp:   order.Timestamp,
+				TimeInForce: string(order.TimeInForce),
+				ExpireAt:    order.ExpireAt,
+				DisplaySize: order.DisplaySize,
+				HiddenSize:  order.HiddenSize,
+
+				PostOnly:     order.PostOnly,
+				PostOnlyMode: string(order.PostOnlyMode),
+
+				SelfTradePrevention: string(order.SelfTradePrevention),
+
+				Sequence: order.Sequence,
 			})
 		}
 	}
 
-	for _, limit := range ob.BidLimits {
+	for _, limit := range ob.bids.limits() {
 		orders := limit.GetOrders()
 		for _, order := range orders {
 			bookOrders = append(bookOrders, snapshotv1.BookOrder{
-				OrderID:   order.ID,
-				Size:      order.Size,
-				Bid:       order.Bid,
-				Price:     limit.Price,
-				UserID:    order.UserID,
-				Timestamp: order.Timestamp,
+				OrderID:     order.ID,
+				Size:        order.Size,
+				Bid:         order.Bid,
+				Price:       limit.Price,
+				UserID:      order.UserID,
+				Timestamp:   order.Timestamp,
+				TimeInForce: string(order.TimeInForce),
+				ExpireAt:    order.ExpireAt,
+				DisplaySize: order.DisplaySize,
+				HiddenSize:  order.HiddenSize,
+
+				PostOnly:     order.PostOnly,
+				PostOnlyMode: string(order.PostOnlyMode),
+
+				SelfTradePrevention: string(order.SelfTradePrevention),
+
+				Sequence: order.Sequence,
 			})
 		}
 	}
 
 	return &snapshotv1.Snapshot{
+		Version:     snapshotv1.CurrentVersion,
 		OrderOffset: 0, // This will be set by the engine
 		OrderBookSnapshot: snapshotv1.OrderBookSnapshot{
 			Orders:        bookOrders,
-			TradeSequence: 0, // Not used in simplified version
-			LogSequence:   0, // Not used in simplified version
+			TradeSequence: 0, // Set by the engine, which numbers the trades
+			LogSequence:   0, // Set by the engine, which owns the journal
 		},
 	}
 }
@@ -276,42 +805,45 @@ func (ob *Orderbook) RestoreOrderbook(snapshot *snapshotv1.Snapshot) error {
 	defer ob.mu.Unlock()
 
 	// Clear current state
-	ob.AskLimits = make(map[float64]*orderbookv1.Limit)
-	ob.BidLimits = make(map[float64]*orderbookv1.Limit)
+	ob.AskLimits = make(map[int64]*orderbookv1.Limit)
+	ob.BidLimits = make(map[int64]*orderbookv1.Limit)
 	ob.Orders = make(map[string]*orderbookv1.Order)
+	ob.GTDOrders = make(map[string]*orderbookv1.Order)
+	ob.UserOrders = make(map[string]map[string]*orderbookv1.Order)
+	ob.asks = newPriceLevels(false)
+	ob.bids = newPriceLevels(true)
 
 	// Restore orders from snapshot
 	for _, bookOrder := range snapshot.OrderBookSnapshot.Orders {
 		// Create the order
 		order := &orderbookv1.Order{
-			ID:        bookOrder.OrderID,
-			UserID:    bookOrder.UserID,
-			Size:      bookOrder.Size,
-			Bid:       bookOrder.Bid,
-			Timestamp: bookOrder.Timestamp,
+			ID:          bookOrder.OrderID,
+			UserID:      bookOrder.UserID,
+			Size:        bookOrder.Size,
+			Bid:         bookOrder.Bid,
+			Timestamp:   bookOrder.Timestamp,
+			TimeInForce: orderbookv1.TimeInForce(bookOrder.TimeInForce),
+			ExpireAt:    bookOrder.ExpireAt,
+			DisplaySize: bookOrder.DisplaySize,
+			HiddenSize:  bookOrder.HiddenSize,
+
+			PostOnly:     bookOrder.PostOnly,
+			PostOnlyMode: orderbookv1.PostOnlyMode(bookOrder.PostOnlyMode),
+
+			SelfTradePrevention: orderbookv1.SelfTradePrevention(bookOrder.SelfTradePrevention),
+
+			Sequence: bookOrder.Sequence,
 		}
 
 		// Find or create the appropriate limit
-		var limits map[float64]*orderbookv1.Limit
-		if order.IsBid() {
-			limits = ob.BidLimits
-		} else {
-			limits = ob.AskLimits
-		}
-
-		limit, exists := limits[bookOrder.Price]
-		if !exists {
-			limit = orderbookv1.NewLimit(bookOrder.Price)
-			limits[bookOrder.Price] = limit
-		}
+		limit := ob.getOrCreateLimit(order.IsBid(), bookOrder.Price)
 
 		// Add order to limit
 		if err := limit.AddOrder(order); err != nil {
 			return fmt.Errorf("failed to restore order %s: %w", bookOrder.OrderID, err)
 		}
 
-		// Add to orders map
-		ob.Orders[order.ID] = order
+		ob.indexOrder(order)
 	}
 
 	return nil
@@ -0,0 +1,135 @@
+package orderbook
+
+import (
+	"fmt"
+	"testing"
+
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+)
+
+// Book depths (price levels per side) the benchmarks run at
+var benchmarkDepths = []int{100, 1_000, 10_000}
+
+// setupBenchmarkBook builds a book with depth ask levels from 10_001 upwards and depth
+// bid levels from 10_000 downwards, each holding ordersPerLevel orders.
+func setupBenchmarkBook(b *testing.B, depth, ordersPerLevel int) *Orderbook {
+	ob := NewOrderbook()
+	for level := 0; level < depth; level++ {
+		for n := 0; n < ordersPerLevel; n++ {
+			ask := orderbookv1.NewOrder("maker", 10, false, fmt.Sprintf("ask-%d-%d", level, n))
+			if _, err := ob.PlaceLimitOrder(int64(10_001+level), ask); err != nil {
+				b.Fatal(err)
+			}
+			bid := orderbookv1.NewOrder("maker", 10, true, fmt.Sprintf("bid-%d-%d", level, n))
+			if _, err := ob.PlaceLimitOrder(int64(10_000-level), bid); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	return ob
+}
+
+func BenchmarkOrderbook_PlaceMarketOrder(b *testing.B) {
+	for _, depth := range benchmarkDepths {
+		b.Run(fmt.Sprintf("depth_%d", depth), func(b *testing.B) {
+			ob := setupBenchmarkBook(b, depth, 4)
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				// Take one order off the best ask and put it back to keep the depth
+				taker := orderbookv1.NewOrder("taker", 10, true, fmt.Sprintf("taker-%d", i))
+				if _, err := ob.PlaceMarketOrder(taker); err != nil {
+					b.Fatal(err)
+				}
+				maker := orderbookv1.NewOrder("maker", 10, false, fmt.Sprintf("refill-%d", i))
+				if _, err := ob.PlaceLimitOrder(10_001, maker); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkOrderbook_PlaceLimitOrder(b *testing.B) {
+	for _, depth := range benchmarkDepths {
+		b.Run(fmt.Sprintf("depth_%d", depth), func(b *testing.B) {
+			ob := setupBenchmarkBook(b, depth, 1)
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				// Rest a non-crossing order somewhere inside the bid side, then cancel it
+				id := fmt.Sprintf("resting-%d", i)
+				order := orderbookv1.NewOrder("maker", 10, true, id)
+				if _, err := ob.PlaceLimitOrder(int64(10_000-i%depth), order); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := ob.CancelOrder(id); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkOrderbook_NewLevel(b *testing.B) {
+	for _, depth := range benchmarkDepths {
+		b.Run(fmt.Sprintf("depth_%d", depth), func(b *testing.B) {
+			ob := setupBenchmarkBook(b, depth, 1)
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				// Create and remove a price level beyond the deepest ask
+				id := fmt.Sprintf("deep-%d", i)
+				order := orderbookv1.NewOrder("maker", 10, false, id)
+				if _, err := ob.PlaceLimitOrder(int64(10_001+depth+i%100), order); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := ob.CancelOrder(id); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkOrderbook_Asks(b *testing.B) {
+	for _, depth := range benchmarkDepths {
+		b.Run(fmt.Sprintf("depth_%d", depth), func(b *testing.B) {
+			ob := setupBenchmarkBook(b, depth, 1)
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if len(ob.Asks()) != depth {
+					b.Fatal("unexpected depth")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkLimit_Fill(b *testing.B) {
+	for _, queue := range []int{10, 100, 1_000} {
+		b.Run(fmt.Sprintf("queue_%d", queue), func(b *testing.B) {
+			limit := orderbookv1.NewLimit(10_000)
+			for n := 0; n < queue; n++ {
+				if err := limit.AddOrder(orderbookv1.NewOrder("maker", 10, false, fmt.Sprintf("ask-%d", n))); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				// Fill the order at the front of the queue and queue a new one at the back
+				limit.Fill(orderbookv1.NewOrder("taker", 10, true, "taker"))
+				if err := limit.AddOrder(orderbookv1.NewOrder("maker", 10, false, fmt.Sprintf("refill-%d", i))); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
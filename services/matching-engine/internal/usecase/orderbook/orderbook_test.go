@@ -2,8 +2,10 @@ package orderbook
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 	"testing"
+	"time"
 
 	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
 	snapshotv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/snapshot/v1"
@@ -12,9 +14,8 @@ import (
 )
 
 // Helper function to create test order with specific ID
-func createTestOrder(userID, orderID string, size float64, bid bool) *orderbookv1.Order {
-	order := orderbookv1.NewOrder(userID, size, bid)
-	order.ID = orderID
+func createTestOrder(userID, orderID string, size int64, bid bool) *orderbookv1.Order {
+	order := orderbookv1.NewOrder(userID, size, bid, orderID)
 	return order
 }
 
@@ -35,8 +36,8 @@ func TestNewOrderbook(t *testing.T) {
 func TestOrderbook_PlaceLimitOrder_Basic(t *testing.T) {
 	ob := NewOrderbook()
 
-	order := createTestOrder("user1", "order1", 10.0, false) // Ask order
-	err := ob.PlaceLimitOrder(10_000, order)
+	order := createTestOrder("user1", "order1", 10, false) // Ask order
+	_, err := ob.PlaceLimitOrder(10_000, order)
 
 	require.NoError(t, err)
 	assert.Equal(t, 1, len(ob.Orders))
@@ -46,20 +47,20 @@ func TestOrderbook_PlaceLimitOrder_Basic(t *testing.T) {
 	// Check the limit was created correctly
 	limit, exists := ob.AskLimits[10_000]
 	assert.True(t, exists)
-	assert.Equal(t, 10_000.0, limit.Price)
+	assert.Equal(t, int64(10_000), limit.Price)
 	assert.Equal(t, 1, limit.OrderCount())
-	assert.Equal(t, 10.0, limit.GetTotalVolume())
+	assert.Equal(t, int64(10), limit.GetTotalVolume())
 }
 
 // Test 3: Place multiple orders at same price
 func TestOrderbook_SamePriceLevel(t *testing.T) {
 	ob := NewOrderbook()
 
-	order1 := createTestOrder("user1", "order1", 10.0, false)
-	order2 := createTestOrder("user2", "order2", 5.0, false)
+	order1 := createTestOrder("user1", "order1", 10, false)
+	order2 := createTestOrder("user2", "order2", 5, false)
 
-	err1 := ob.PlaceLimitOrder(10_000, order1)
-	err2 := ob.PlaceLimitOrder(10_000, order2)
+	_, err1 := ob.PlaceLimitOrder(10_000, order1)
+	_, err2 := ob.PlaceLimitOrder(10_000, order2)
 
 	require.NoError(t, err1)
 	require.NoError(t, err2)
@@ -69,7 +70,7 @@ func TestOrderbook_SamePriceLevel(t *testing.T) {
 
 	limit := ob.AskLimits[10_000]
 	assert.Equal(t, 2, limit.OrderCount())
-	assert.Equal(t, 15.0, limit.GetTotalVolume())
+	assert.Equal(t, int64(15), limit.GetTotalVolume())
 }
 
 // Test 4: Buy market order (bid) against ask orders
@@ -77,24 +78,24 @@ func TestOrderbook_BuyMarketOrder_Basic(t *testing.T) {
 	ob := NewOrderbook()
 
 	// Place a sell order first
-	sellOrder := createTestOrder("seller", "sell1", 10.0, false)
-	err := ob.PlaceLimitOrder(10_000, sellOrder)
+	sellOrder := createTestOrder("seller", "sell1", 10, false)
+	_, err := ob.PlaceLimitOrder(10_000, sellOrder)
 	require.NoError(t, err)
 
 	// Place a buy market order
-	buyOrder := createTestOrder("buyer", "buy1", 5.0, true)
+	buyOrder := createTestOrder("buyer", "buy1", 5, true)
 	matches, err := ob.PlaceMarketOrder(buyOrder)
 
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(matches))
-	assert.Equal(t, 5.0, matches[0].SizeFilled)
-	assert.Equal(t, 10_000.0, matches[0].Price)
+	assert.Equal(t, int64(5), matches[0].SizeFilled)
+	assert.Equal(t, int64(10_000), matches[0].Price)
 	assert.Equal(t, buyOrder, matches[0].Bid)
 	assert.Equal(t, sellOrder, matches[0].Ask)
 
 	// Check remaining sizes
-	assert.Equal(t, 0.0, buyOrder.Size)  // Fully filled
-	assert.Equal(t, 5.0, sellOrder.Size) // Partially filled
+	assert.Equal(t, int64(0), buyOrder.Size)  // Fully filled
+	assert.Equal(t, int64(5), sellOrder.Size) // Partially filled
 }
 
 // Test 5: Buy market order across multiple ask levels
@@ -102,47 +103,49 @@ func TestOrderbook_BuyMarketOrderMultipleFills(t *testing.T) {
 	ob := NewOrderbook()
 
 	// Add sell orders at different prices
-	sellOrder1 := createTestOrder("seller1", "sell1", 5.0, false)
-	sellOrder2 := createTestOrder("seller2", "sell2", 3.0, false)
-	sellOrder3 := createTestOrder("seller3", "sell3", 7.0, false)
+	sellOrder1 := createTestOrder("seller1", "sell1", 5, false)
+	sellOrder2 := createTestOrder("seller2", "sell2", 3, false)
+	sellOrder3 := createTestOrder("seller3", "sell3", 7, false)
 
 	ob.PlaceLimitOrder(10_000, sellOrder1) // Best ask
 	ob.PlaceLimitOrder(10_100, sellOrder2)
 	ob.PlaceLimitOrder(10_200, sellOrder3)
 
 	// Large buy market order
-	buyOrder := createTestOrder("buyer", "buy1", 12.0, true)
+	buyOrder := createTestOrder("buyer", "buy1", 12, true)
 	matches, err := ob.PlaceMarketOrder(buyOrder)
 
 	assert.NoError(t, err)
 	assert.Equal(t, 3, len(matches))
 
 	// Should match in price priority order
-	assert.Equal(t, 5.0, matches[0].SizeFilled) // First limit fully filled
-	assert.Equal(t, 3.0, matches[1].SizeFilled) // Second limit fully filled
-	assert.Equal(t, 4.0, matches[2].SizeFilled) // Third limit partially filled
+	assert.Equal(t, int64(5), matches[0].SizeFilled) // First limit fully filled
+	assert.Equal(t, int64(3), matches[1].SizeFilled) // Second limit fully filled
+	assert.Equal(t, int64(4), matches[2].SizeFilled) // Third limit partially filled
 
-	assert.Equal(t, 10_000.0, matches[0].Price)
-	assert.Equal(t, 10_100.0, matches[1].Price)
-	assert.Equal(t, 10_200.0, matches[2].Price)
+	assert.Equal(t, int64(10_000), matches[0].Price)
+	assert.Equal(t, int64(10_100), matches[1].Price)
+	assert.Equal(t, int64(10_200), matches[2].Price)
 
 	// Check remaining sizes
-	assert.Equal(t, 0.0, sellOrder1.Size) // Fully filled
-	assert.Equal(t, 0.0, sellOrder2.Size) // Fully filled
-	assert.Equal(t, 3.0, sellOrder3.Size) // Partially filled (7-4=3)
-	assert.Equal(t, 0.0, buyOrder.Size)   // Fully filled
+	assert.Equal(t, int64(0), sellOrder1.Size) // Fully filled
+	assert.Equal(t, int64(0), sellOrder2.Size) // Fully filled
+	assert.Equal(t, int64(3), sellOrder3.Size) // Partially filled (7-4=3)
+	assert.Equal(t, int64(0), buyOrder.Size)   // Fully filled
 }
 
 // Test 6: Cancel order
 func TestOrderbook_CancelOrder(t *testing.T) {
 	ob := NewOrderbook()
 
-	order := createTestOrder("user1", "order1", 10.0, false)
-	err := ob.PlaceLimitOrder(10_000, order)
+	order := createTestOrder("user1", "order1", 10, false)
+	_, err := ob.PlaceLimitOrder(10_000, order)
 	require.NoError(t, err)
 
-	err = ob.CancelOrder("order1")
+	cancelled, err := ob.CancelOrder("order1")
 	assert.NoError(t, err)
+	assert.Same(t, order, cancelled)
+	assert.Equal(t, int64(10_000), cancelled.Price)
 	assert.Equal(t, 0, len(ob.Orders))
 	assert.Equal(t, 0, len(ob.AskLimits)) // Limit removed when empty
 }
@@ -152,18 +155,18 @@ func TestOrderbook_ErrorCases(t *testing.T) {
 	ob := NewOrderbook()
 
 	t.Run("Nil order", func(t *testing.T) {
-		err := ob.PlaceLimitOrder(100.0, nil)
+		_, err := ob.PlaceLimitOrder(100, nil)
 		assert.Error(t, err)
 	})
 
 	t.Run("Invalid price", func(t *testing.T) {
-		order := createTestOrder("user1", "order1", 10.0, false)
-		err := ob.PlaceLimitOrder(0, order)
+		order := createTestOrder("user1", "order1", 10, false)
+		_, err := ob.PlaceLimitOrder(0, order)
 		assert.Error(t, err)
 	})
 
 	t.Run("Cancel non-existent order", func(t *testing.T) {
-		err := ob.CancelOrder("nonexistent")
+		_, err := ob.CancelOrder("nonexistent")
 		assert.Error(t, err)
 	})
 }
@@ -174,10 +177,10 @@ func TestOrderbook_SnapshotAndRestore(t *testing.T) {
 	ob1 := NewOrderbook()
 
 	// Add various orders
-	sellOrder1 := createTestOrder("seller1", "sell1", 10.0, false)
-	sellOrder2 := createTestOrder("seller2", "sell2", 5.0, false)
-	buyOrder1 := createTestOrder("buyer1", "buy1", 8.0, true)
-	buyOrder2 := createTestOrder("buyer2", "buy2", 3.0, true)
+	sellOrder1 := createTestOrder("seller1", "sell1", 10, false)
+	sellOrder2 := createTestOrder("seller2", "sell2", 5, false)
+	buyOrder1 := createTestOrder("buyer1", "buy1", 8, true)
+	buyOrder2 := createTestOrder("buyer2", "buy2", 3, true)
 
 	ob1.PlaceLimitOrder(10_000, sellOrder1)
 	ob1.PlaceLimitOrder(10_100, sellOrder2)
@@ -207,10 +210,10 @@ func TestOrderbook_SnapshotAndRestore(t *testing.T) {
 	assert.Contains(t, ob2.Orders, "buy2")
 
 	// Verify limits exist at correct prices
-	assert.Contains(t, ob2.AskLimits, 10_000.0)
-	assert.Contains(t, ob2.AskLimits, 10_100.0)
-	assert.Contains(t, ob2.BidLimits, 9_900.0)
-	assert.Contains(t, ob2.BidLimits, 9_800.0)
+	assert.Contains(t, ob2.AskLimits, int64(10_000))
+	assert.Contains(t, ob2.AskLimits, int64(10_100))
+	assert.Contains(t, ob2.BidLimits, int64(9_900))
+	assert.Contains(t, ob2.BidLimits, int64(9_800))
 
 	// Verify order details are preserved
 	restoredSell1 := ob2.Orders["sell1"]
@@ -229,7 +232,7 @@ func TestOrderbook_RestoreEmpty(t *testing.T) {
 	ob := NewOrderbook()
 
 	// Add some orders first
-	order := createTestOrder("user1", "order1", 10.0, false)
+	order := createTestOrder("user1", "order1", 10, false)
 	ob.PlaceLimitOrder(10_000, order)
 
 	// Create empty snapshot
@@ -260,7 +263,7 @@ func TestOrderbook_RestoreNil(t *testing.T) {
 func TestOrderbook_RestoredFunctionality(t *testing.T) {
 	// Create and populate original orderbook
 	ob1 := NewOrderbook()
-	sellOrder := createTestOrder("seller", "sell1", 10.0, false)
+	sellOrder := createTestOrder("seller", "sell1", 10, false)
 	ob1.PlaceLimitOrder(10_000, sellOrder)
 
 	// Create snapshot and restore to new orderbook
@@ -270,17 +273,17 @@ func TestOrderbook_RestoredFunctionality(t *testing.T) {
 	require.NoError(t, err)
 
 	// Test that restored orderbook functions correctly
-	buyOrder := createTestOrder("buyer", "buy1", 5.0, true)
+	buyOrder := createTestOrder("buyer", "buy1", 5, true)
 	matches, err := ob2.PlaceMarketOrder(buyOrder)
 
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(matches))
-	assert.Equal(t, 5.0, matches[0].SizeFilled)
-	assert.Equal(t, 10_000.0, matches[0].Price)
+	assert.Equal(t, int64(5), matches[0].SizeFilled)
+	assert.Equal(t, int64(10_000), matches[0].Price)
 
 	// Verify the restored sell order was partially filled
 	restoredSellOrder := ob2.Orders["sell1"]
-	assert.Equal(t, 5.0, restoredSellOrder.Size) // Should have 5.0 remaining
+	assert.Equal(t, int64(5), restoredSellOrder.Size) // Should have 5 remaining
 }
 
 // Test 6: Sell market order (ask) against bid orders
@@ -288,27 +291,27 @@ func TestOrderbook_SellMarketOrder(t *testing.T) {
 	ob := NewOrderbook()
 
 	// Place buy orders first (bids)
-	buyOrder1 := createTestOrder("buyer1", "buy1", 10.0, true)
-	buyOrder2 := createTestOrder("buyer2", "buy2", 5.0, true)
+	buyOrder1 := createTestOrder("buyer1", "buy1", 10, true)
+	buyOrder2 := createTestOrder("buyer2", "buy2", 5, true)
 
 	ob.PlaceLimitOrder(9_900, buyOrder1) // Best bid
 	ob.PlaceLimitOrder(9_800, buyOrder2) // Lower bid
 
 	// Place a sell market order
-	sellOrder := createTestOrder("seller", "sell1", 8.0, false)
+	sellOrder := createTestOrder("seller", "sell1", 8, false)
 	matches, err := ob.PlaceMarketOrder(sellOrder)
 
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(matches))
-	assert.Equal(t, 8.0, matches[0].SizeFilled)
-	assert.Equal(t, 9_900.0, matches[0].Price) // Should match at best bid price
+	assert.Equal(t, int64(8), matches[0].SizeFilled)
+	assert.Equal(t, int64(9_900), matches[0].Price) // Should match at best bid price
 	assert.Equal(t, buyOrder1, matches[0].Bid)
 	assert.Equal(t, sellOrder, matches[0].Ask)
 
 	// Check remaining sizes
-	assert.Equal(t, 0.0, sellOrder.Size) // Fully filled
-	assert.Equal(t, 2.0, buyOrder1.Size) // Partially filled (10-8=2)
-	assert.Equal(t, 5.0, buyOrder2.Size) // Untouched
+	assert.Equal(t, int64(0), sellOrder.Size) // Fully filled
+	assert.Equal(t, int64(2), buyOrder1.Size) // Partially filled (10-8=2)
+	assert.Equal(t, int64(5), buyOrder2.Size) // Untouched
 }
 
 // Test 7: Sell market order across multiple bid levels
@@ -316,35 +319,35 @@ func TestOrderbook_SellMarketOrderMultipleFills(t *testing.T) {
 	ob := NewOrderbook()
 
 	// Add buy orders at different prices (bids)
-	buyOrder1 := createTestOrder("buyer1", "buy1", 5.0, true)
-	buyOrder2 := createTestOrder("buyer2", "buy2", 3.0, true)
-	buyOrder3 := createTestOrder("buyer3", "buy3", 7.0, true)
+	buyOrder1 := createTestOrder("buyer1", "buy1", 5, true)
+	buyOrder2 := createTestOrder("buyer2", "buy2", 3, true)
+	buyOrder3 := createTestOrder("buyer3", "buy3", 7, true)
 
 	ob.PlaceLimitOrder(9_900, buyOrder1) // Best bid
 	ob.PlaceLimitOrder(9_800, buyOrder2) // Middle bid
 	ob.PlaceLimitOrder(9_700, buyOrder3) // Lowest bid
 
 	// Large sell market order
-	sellOrder := createTestOrder("seller", "sell1", 12.0, false)
+	sellOrder := createTestOrder("seller", "sell1", 12, false)
 	matches, err := ob.PlaceMarketOrder(sellOrder)
 
 	assert.NoError(t, err)
 	assert.Equal(t, 3, len(matches))
 
 	// Should match in price priority order (highest bid first)
-	assert.Equal(t, 5.0, matches[0].SizeFilled) // First limit fully filled
-	assert.Equal(t, 3.0, matches[1].SizeFilled) // Second limit fully filled
-	assert.Equal(t, 4.0, matches[2].SizeFilled) // Third limit partially filled
+	assert.Equal(t, int64(5), matches[0].SizeFilled) // First limit fully filled
+	assert.Equal(t, int64(3), matches[1].SizeFilled) // Second limit fully filled
+	assert.Equal(t, int64(4), matches[2].SizeFilled) // Third limit partially filled
 
-	assert.Equal(t, 9_900.0, matches[0].Price)
-	assert.Equal(t, 9_800.0, matches[1].Price)
-	assert.Equal(t, 9_700.0, matches[2].Price)
+	assert.Equal(t, int64(9_900), matches[0].Price)
+	assert.Equal(t, int64(9_800), matches[1].Price)
+	assert.Equal(t, int64(9_700), matches[2].Price)
 
 	// Check remaining sizes
-	assert.Equal(t, 0.0, buyOrder1.Size) // Fully filled
-	assert.Equal(t, 0.0, buyOrder2.Size) // Fully filled
-	assert.Equal(t, 3.0, buyOrder3.Size) // Partially filled (7-4=3)
-	assert.Equal(t, 0.0, sellOrder.Size) // Fully filled
+	assert.Equal(t, int64(0), buyOrder1.Size) // Fully filled
+	assert.Equal(t, int64(0), buyOrder2.Size) // Fully filled
+	assert.Equal(t, int64(3), buyOrder3.Size) // Partially filled (7-4=3)
+	assert.Equal(t, int64(0), sellOrder.Size) // Fully filled
 }
 
 // Test 8: Mixed scenario - both buy and sell market orders
@@ -352,10 +355,10 @@ func TestOrderbook_MixedMarketOrders(t *testing.T) {
 	ob := NewOrderbook()
 
 	// Set up initial book with both asks and bids
-	sellOrder1 := createTestOrder("seller1", "sell1", 10.0, false)
-	sellOrder2 := createTestOrder("seller2", "sell2", 5.0, false)
-	buyOrder1 := createTestOrder("buyer1", "buy1", 8.0, true)
-	buyOrder2 := createTestOrder("buyer2", "buy2", 3.0, true)
+	sellOrder1 := createTestOrder("seller1", "sell1", 10, false)
+	sellOrder2 := createTestOrder("seller2", "sell2", 5, false)
+	buyOrder1 := createTestOrder("buyer1", "buy1", 8, true)
+	buyOrder2 := createTestOrder("buyer2", "buy2", 3, true)
 
 	ob.PlaceLimitOrder(10_100, sellOrder1) // Ask
 	ob.PlaceLimitOrder(10_200, sellOrder2) // Higher ask
@@ -363,24 +366,24 @@ func TestOrderbook_MixedMarketOrders(t *testing.T) {
 	ob.PlaceLimitOrder(9_800, buyOrder2)   // Lower bid
 
 	// Test buy market order
-	buyMarketOrder := createTestOrder("market_buyer", "mbuy1", 7.0, true)
+	buyMarketOrder := createTestOrder("market_buyer", "mbuy1", 7, true)
 	buyMatches, err := ob.PlaceMarketOrder(buyMarketOrder)
 
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(buyMatches))
-	assert.Equal(t, 7.0, buyMatches[0].SizeFilled)
-	assert.Equal(t, 10_100.0, buyMatches[0].Price) // Best ask price
-	assert.Equal(t, 3.0, sellOrder1.Size)          // Partially filled
+	assert.Equal(t, int64(7), buyMatches[0].SizeFilled)
+	assert.Equal(t, int64(10_100), buyMatches[0].Price) // Best ask price
+	assert.Equal(t, int64(3), sellOrder1.Size)          // Partially filled
 
 	// Test sell market order
-	sellMarketOrder := createTestOrder("market_seller", "msell1", 6.0, false)
+	sellMarketOrder := createTestOrder("market_seller", "msell1", 6, false)
 	sellMatches, err := ob.PlaceMarketOrder(sellMarketOrder)
 
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(sellMatches))
-	assert.Equal(t, 6.0, sellMatches[0].SizeFilled)
-	assert.Equal(t, 9_900.0, sellMatches[0].Price) // Best bid price
-	assert.Equal(t, 2.0, buyOrder1.Size)           // Partially filled
+	assert.Equal(t, int64(6), sellMatches[0].SizeFilled)
+	assert.Equal(t, int64(9_900), sellMatches[0].Price) // Best bid price
+	assert.Equal(t, int64(2), buyOrder1.Size)           // Partially filled
 }
 
 // Test 9: Market order with no matching orders
@@ -389,70 +392,84 @@ func TestOrderbook_MarketOrderNoMatches(t *testing.T) {
 
 	t.Run("Buy market order with no asks", func(t *testing.T) {
 		// Only add bid orders
-		buyOrder1 := createTestOrder("buyer1", "buy1", 10.0, true)
+		buyOrder1 := createTestOrder("buyer1", "buy1", 10, true)
 		ob.PlaceLimitOrder(9_900, buyOrder1)
 
 		// Try to place buy market order (should find no asks to match)
-		buyMarketOrder := createTestOrder("market_buyer", "mbuy1", 5.0, true)
+		buyMarketOrder := createTestOrder("market_buyer", "mbuy1", 5, true)
 		matches, err := ob.PlaceMarketOrder(buyMarketOrder)
 
 		assert.NoError(t, err)
 		assert.Equal(t, 0, len(matches))
-		assert.Equal(t, 5.0, buyMarketOrder.Size) // Unchanged
+		assert.Equal(t, int64(5), buyMarketOrder.Size) // Unchanged
 	})
 
 	t.Run("Sell market order with no bids", func(t *testing.T) {
 		// Clear and add only ask orders
 		ob2 := NewOrderbook()
-		sellOrder1 := createTestOrder("seller1", "sell1", 10.0, false)
+		sellOrder1 := createTestOrder("seller1", "sell1", 10, false)
 		ob2.PlaceLimitOrder(10_100, sellOrder1)
 
 		// Try to place sell market order (should find no bids to match)
-		sellMarketOrder := createTestOrder("market_seller", "msell1", 5.0, false)
+		sellMarketOrder := createTestOrder("market_seller", "msell1", 5, false)
 		matches, err := ob2.PlaceMarketOrder(sellMarketOrder)
 
 		assert.NoError(t, err)
 		assert.Equal(t, 0, len(matches))
-		assert.Equal(t, 5.0, sellMarketOrder.Size) // Unchanged
+		assert.Equal(t, int64(5), sellMarketOrder.Size) // Unchanged
 	})
 }
 
+// Test that a market order without a size is refused instead of matching nothing
+func TestOrderbook_MarketOrderInvalidSize(t *testing.T) {
+	ob := NewOrderbook()
+	_, err := ob.PlaceLimitOrder(10_100, createTestOrder("seller1", "sell1", 10, false))
+	require.NoError(t, err)
+
+	for _, size := range []int64{0, -1} {
+		matches, err := ob.PlaceMarketOrder(createTestOrder("market_buyer", "mbuy1", size, true))
+		assert.ErrorIs(t, err, orderbookv1.ErrInvalidSize)
+		assert.Empty(t, matches)
+	}
+	assert.Equal(t, int64(10), ob.AskTotalVolume())
+}
+
 // Test 10: Market order exactly fills available liquidity
 func TestOrderbook_MarketOrderExactFill(t *testing.T) {
 	t.Run("Buy market order exact fill", func(t *testing.T) {
 		ob := NewOrderbook()
 
-		sellOrder1 := createTestOrder("seller1", "sell1", 5.0, false)
-		sellOrder2 := createTestOrder("seller2", "sell2", 3.0, false)
+		sellOrder1 := createTestOrder("seller1", "sell1", 5, false)
+		sellOrder2 := createTestOrder("seller2", "sell2", 3, false)
 		ob.PlaceLimitOrder(10_100, sellOrder1)
 		ob.PlaceLimitOrder(10_200, sellOrder2)
 
 		// Buy market order that exactly matches total ask volume
-		buyMarketOrder := createTestOrder("buyer", "buy1", 8.0, true)
+		buyMarketOrder := createTestOrder("buyer", "buy1", 8, true)
 		matches, err := ob.PlaceMarketOrder(buyMarketOrder)
 
 		assert.NoError(t, err)
 		assert.Equal(t, 2, len(matches))
-		assert.Equal(t, 0.0, buyMarketOrder.Size) // Fully filled
-		assert.Equal(t, 0, len(ob.AskLimits))     // All ask limits consumed
+		assert.Equal(t, int64(0), buyMarketOrder.Size) // Fully filled
+		assert.Equal(t, 0, len(ob.AskLimits))          // All ask limits consumed
 	})
 
 	t.Run("Sell market order exact fill", func(t *testing.T) {
 		ob := NewOrderbook()
 
-		buyOrder1 := createTestOrder("buyer1", "buy1", 5.0, true)
-		buyOrder2 := createTestOrder("buyer2", "buy2", 3.0, true)
+		buyOrder1 := createTestOrder("buyer1", "buy1", 5, true)
+		buyOrder2 := createTestOrder("buyer2", "buy2", 3, true)
 		ob.PlaceLimitOrder(9_900, buyOrder1)
 		ob.PlaceLimitOrder(9_800, buyOrder2)
 
 		// Sell market order that exactly matches total bid volume
-		sellMarketOrder := createTestOrder("seller", "sell1", 8.0, false)
+		sellMarketOrder := createTestOrder("seller", "sell1", 8, false)
 		matches, err := ob.PlaceMarketOrder(sellMarketOrder)
 
 		assert.NoError(t, err)
 		assert.Equal(t, 2, len(matches))
-		assert.Equal(t, 0.0, sellMarketOrder.Size) // Fully filled
-		assert.Equal(t, 0, len(ob.BidLimits))      // All bid limits consumed
+		assert.Equal(t, int64(0), sellMarketOrder.Size) // Fully filled
+		assert.Equal(t, 0, len(ob.BidLimits))           // All bid limits consumed
 	})
 }
 
@@ -466,22 +483,22 @@ func TestOrderbook_Asks(t *testing.T) {
 	})
 
 	t.Run("Single ask limit", func(t *testing.T) {
-		sellOrder := createTestOrder("seller", "sell1", 10.0, false)
+		sellOrder := createTestOrder("seller", "sell1", 10, false)
 		ob.PlaceLimitOrder(10_000, sellOrder)
 
 		asks := ob.Asks()
 		assert.Equal(t, 1, len(asks))
-		assert.Equal(t, 10_000.0, asks[0].Price)
-		assert.Equal(t, 10.0, asks[0].GetTotalVolume())
+		assert.Equal(t, int64(10_000), asks[0].Price)
+		assert.Equal(t, int64(10), asks[0].GetTotalVolume())
 	})
 
 	t.Run("Multiple ask limits sorted by price", func(t *testing.T) {
 		ob := NewOrderbook() // Fresh orderbook
 
 		// Add asks in random price order
-		sellOrder1 := createTestOrder("seller1", "sell1", 5.0, false)
-		sellOrder2 := createTestOrder("seller2", "sell2", 3.0, false)
-		sellOrder3 := createTestOrder("seller3", "sell3", 7.0, false)
+		sellOrder1 := createTestOrder("seller1", "sell1", 5, false)
+		sellOrder2 := createTestOrder("seller2", "sell2", 3, false)
+		sellOrder3 := createTestOrder("seller3", "sell3", 7, false)
 
 		ob.PlaceLimitOrder(10_200, sellOrder1) // Highest price
 		ob.PlaceLimitOrder(10_000, sellOrder2) // Lowest price (best ask)
@@ -491,30 +508,30 @@ func TestOrderbook_Asks(t *testing.T) {
 		assert.Equal(t, 3, len(asks))
 
 		// Should be sorted by price ascending (best ask first)
-		assert.Equal(t, 10_000.0, asks[0].Price) // Best ask (lowest price)
-		assert.Equal(t, 10_100.0, asks[1].Price) // Middle
-		assert.Equal(t, 10_200.0, asks[2].Price) // Worst ask (highest price)
+		assert.Equal(t, int64(10_000), asks[0].Price) // Best ask (lowest price)
+		assert.Equal(t, int64(10_100), asks[1].Price) // Middle
+		assert.Equal(t, int64(10_200), asks[2].Price) // Worst ask (highest price)
 
 		// Verify volumes
-		assert.Equal(t, 3.0, asks[0].GetTotalVolume())
-		assert.Equal(t, 7.0, asks[1].GetTotalVolume())
-		assert.Equal(t, 5.0, asks[2].GetTotalVolume())
+		assert.Equal(t, int64(3), asks[0].GetTotalVolume())
+		assert.Equal(t, int64(7), asks[1].GetTotalVolume())
+		assert.Equal(t, int64(5), asks[2].GetTotalVolume())
 	})
 
 	t.Run("Multiple orders at same price level", func(t *testing.T) {
 		ob := NewOrderbook()
 
-		sellOrder1 := createTestOrder("seller1", "sell1", 5.0, false)
-		sellOrder2 := createTestOrder("seller2", "sell2", 3.0, false)
+		sellOrder1 := createTestOrder("seller1", "sell1", 5, false)
+		sellOrder2 := createTestOrder("seller2", "sell2", 3, false)
 
 		ob.PlaceLimitOrder(10_000, sellOrder1)
 		ob.PlaceLimitOrder(10_000, sellOrder2) // Same price level
 
 		asks := ob.Asks()
 		assert.Equal(t, 1, len(asks)) // Only one limit at this price
-		assert.Equal(t, 10_000.0, asks[0].Price)
-		assert.Equal(t, 8.0, asks[0].GetTotalVolume()) // Combined volume
-		assert.Equal(t, 2, asks[0].OrderCount())       // Two orders
+		assert.Equal(t, int64(10_000), asks[0].Price)
+		assert.Equal(t, int64(8), asks[0].GetTotalVolume()) // Combined volume
+		assert.Equal(t, 2, asks[0].OrderCount())            // Two orders
 	})
 
 	t.Run("Thread safety - concurrent access", func(t *testing.T) {
@@ -522,8 +539,8 @@ func TestOrderbook_Asks(t *testing.T) {
 
 		// Add some initial orders
 		for i := 0; i < 5; i++ {
-			order := createTestOrder(fmt.Sprintf("seller%d", i), fmt.Sprintf("sell%d", i), 10.0, false)
-			ob.PlaceLimitOrder(float64(10_000+i*100), order)
+			order := createTestOrder(fmt.Sprintf("seller%d", i), fmt.Sprintf("sell%d", i), 10, false)
+			ob.PlaceLimitOrder(int64(10_000+i*100), order)
 		}
 
 		// Test concurrent reads
@@ -554,22 +571,22 @@ func TestOrderbook_Bids(t *testing.T) {
 	})
 
 	t.Run("Single bid limit", func(t *testing.T) {
-		buyOrder := createTestOrder("buyer", "buy1", 8.0, true)
+		buyOrder := createTestOrder("buyer", "buy1", 8, true)
 		ob.PlaceLimitOrder(9_900, buyOrder)
 
 		bids := ob.Bids()
 		assert.Equal(t, 1, len(bids))
-		assert.Equal(t, 9_900.0, bids[0].Price)
-		assert.Equal(t, 8.0, bids[0].GetTotalVolume())
+		assert.Equal(t, int64(9_900), bids[0].Price)
+		assert.Equal(t, int64(8), bids[0].GetTotalVolume())
 	})
 
 	t.Run("Multiple bid limits sorted by price", func(t *testing.T) {
 		ob := NewOrderbook() // Fresh orderbook
 
 		// Add bids in random price order
-		buyOrder1 := createTestOrder("buyer1", "buy1", 5.0, true)
-		buyOrder2 := createTestOrder("buyer2", "buy2", 3.0, true)
-		buyOrder3 := createTestOrder("buyer3", "buy3", 7.0, true)
+		buyOrder1 := createTestOrder("buyer1", "buy1", 5, true)
+		buyOrder2 := createTestOrder("buyer2", "buy2", 3, true)
+		buyOrder3 := createTestOrder("buyer3", "buy3", 7, true)
 
 		ob.PlaceLimitOrder(9_700, buyOrder1) // Lowest price
 		ob.PlaceLimitOrder(9_900, buyOrder2) // Highest price (best bid)
@@ -579,30 +596,30 @@ func TestOrderbook_Bids(t *testing.T) {
 		assert.Equal(t, 3, len(bids))
 
 		// Should be sorted by price descending (best bid first)
-		assert.Equal(t, 9_900.0, bids[0].Price) // Best bid (highest price)
-		assert.Equal(t, 9_800.0, bids[1].Price) // Middle
-		assert.Equal(t, 9_700.0, bids[2].Price) // Worst bid (lowest price)
+		assert.Equal(t, int64(9_900), bids[0].Price) // Best bid (highest price)
+		assert.Equal(t, int64(9_800), bids[1].Price) // Middle
+		assert.Equal(t, int64(9_700), bids[2].Price) // Worst bid (lowest price)
 
 		// Verify volumes
-		assert.Equal(t, 3.0, bids[0].GetTotalVolume())
-		assert.Equal(t, 7.0, bids[1].GetTotalVolume())
-		assert.Equal(t, 5.0, bids[2].GetTotalVolume())
+		assert.Equal(t, int64(3), bids[0].GetTotalVolume())
+		assert.Equal(t, int64(7), bids[1].GetTotalVolume())
+		assert.Equal(t, int64(5), bids[2].GetTotalVolume())
 	})
 
 	t.Run("Multiple orders at same price level", func(t *testing.T) {
 		ob := NewOrderbook()
 
-		buyOrder1 := createTestOrder("buyer1", "buy1", 5.0, true)
-		buyOrder2 := createTestOrder("buyer2", "buy2", 3.0, true)
+		buyOrder1 := createTestOrder("buyer1", "buy1", 5, true)
+		buyOrder2 := createTestOrder("buyer2", "buy2", 3, true)
 
 		ob.PlaceLimitOrder(9_900, buyOrder1)
 		ob.PlaceLimitOrder(9_900, buyOrder2) // Same price level
 
 		bids := ob.Bids()
 		assert.Equal(t, 1, len(bids)) // Only one limit at this price
-		assert.Equal(t, 9_900.0, bids[0].Price)
-		assert.Equal(t, 8.0, bids[0].GetTotalVolume()) // Combined volume
-		assert.Equal(t, 2, bids[0].OrderCount())       // Two orders
+		assert.Equal(t, int64(9_900), bids[0].Price)
+		assert.Equal(t, int64(8), bids[0].GetTotalVolume()) // Combined volume
+		assert.Equal(t, 2, bids[0].OrderCount())            // Two orders
 	})
 }
 
@@ -611,10 +628,10 @@ func TestOrderbook_AsksAndBids(t *testing.T) {
 	ob := NewOrderbook()
 
 	// Add mixed orders
-	sellOrder1 := createTestOrder("seller1", "sell1", 10.0, false)
-	sellOrder2 := createTestOrder("seller2", "sell2", 5.0, false)
-	buyOrder1 := createTestOrder("buyer1", "buy1", 8.0, true)
-	buyOrder2 := createTestOrder("buyer2", "buy2", 3.0, true)
+	sellOrder1 := createTestOrder("seller1", "sell1", 10, false)
+	sellOrder2 := createTestOrder("seller2", "sell2", 5, false)
+	buyOrder1 := createTestOrder("buyer1", "buy1", 8, true)
+	buyOrder2 := createTestOrder("buyer2", "buy2", 3, true)
 
 	ob.PlaceLimitOrder(10_100, sellOrder1) // Ask
 	ob.PlaceLimitOrder(10_200, sellOrder2) // Higher ask
@@ -624,20 +641,20 @@ func TestOrderbook_AsksAndBids(t *testing.T) {
 	// Test asks
 	asks := ob.Asks()
 	assert.Equal(t, 2, len(asks))
-	assert.Equal(t, 10_100.0, asks[0].Price) // Best ask (lowest)
-	assert.Equal(t, 10_200.0, asks[1].Price) // Higher ask
+	assert.Equal(t, int64(10_100), asks[0].Price) // Best ask (lowest)
+	assert.Equal(t, int64(10_200), asks[1].Price) // Higher ask
 
 	// Test bids
 	bids := ob.Bids()
 	assert.Equal(t, 2, len(bids))
-	assert.Equal(t, 9_900.0, bids[0].Price) // Best bid (highest)
-	assert.Equal(t, 9_800.0, bids[1].Price) // Lower bid
+	assert.Equal(t, int64(9_900), bids[0].Price) // Best bid (highest)
+	assert.Equal(t, int64(9_800), bids[1].Price) // Lower bid
 
 	// Verify spread
 	bestAsk := asks[0].Price
 	bestBid := bids[0].Price
 	spread := bestAsk - bestBid
-	assert.Equal(t, 200.0, spread) // 10,100 - 9,900 = 200
+	assert.Equal(t, int64(200), spread) // 10,100 - 9,900 = 200
 }
 
 // Test 14: Dynamic updates - asks/bids after market orders
@@ -645,8 +662,8 @@ func TestOrderbook_AsksAndBidsAfterMarketOrder(t *testing.T) {
 	ob := NewOrderbook()
 
 	// Set up initial book
-	sellOrder1 := createTestOrder("seller1", "sell1", 10.0, false)
-	sellOrder2 := createTestOrder("seller2", "sell2", 5.0, false)
+	sellOrder1 := createTestOrder("seller1", "sell1", 10, false)
+	sellOrder2 := createTestOrder("seller2", "sell2", 5, false)
 	ob.PlaceLimitOrder(10_100, sellOrder1)
 	ob.PlaceLimitOrder(10_200, sellOrder2)
 
@@ -655,16 +672,16 @@ func TestOrderbook_AsksAndBidsAfterMarketOrder(t *testing.T) {
 	assert.Equal(t, 2, len(asks))
 
 	// Place market order that fully consumes best ask
-	buyMarketOrder := createTestOrder("buyer", "buy1", 10.0, true)
+	buyMarketOrder := createTestOrder("buyer", "buy1", 10, true)
 	matches, err := ob.PlaceMarketOrder(buyMarketOrder)
 	require.NoError(t, err)
 	assert.Equal(t, 1, len(matches))
 
 	// Check updated asks
 	updatedAsks := ob.Asks()
-	assert.Equal(t, 1, len(updatedAsks))            // One limit consumed
-	assert.Equal(t, 10_200.0, updatedAsks[0].Price) // Only second ask remains
-	assert.Equal(t, 5.0, updatedAsks[0].GetTotalVolume())
+	assert.Equal(t, 1, len(updatedAsks))                 // One limit consumed
+	assert.Equal(t, int64(10_200), updatedAsks[0].Price) // Only second ask remains
+	assert.Equal(t, int64(5), updatedAsks[0].GetTotalVolume())
 }
 
 // Test 15: Volume calculations
@@ -672,11 +689,11 @@ func TestOrderbook_AskAndBidTotalVolume(t *testing.T) {
 	ob := NewOrderbook()
 
 	// Add various orders
-	sellOrder1 := createTestOrder("seller1", "sell1", 10.0, false)
-	sellOrder2 := createTestOrder("seller2", "sell2", 5.0, false)
-	sellOrder3 := createTestOrder("seller3", "sell3", 3.0, false)
-	buyOrder1 := createTestOrder("buyer1", "buy1", 8.0, true)
-	buyOrder2 := createTestOrder("buyer2", "buy2", 7.0, true)
+	sellOrder1 := createTestOrder("seller1", "sell1", 10, false)
+	sellOrder2 := createTestOrder("seller2", "sell2", 5, false)
+	sellOrder3 := createTestOrder("seller3", "sell3", 3, false)
+	buyOrder1 := createTestOrder("buyer1", "buy1", 8, true)
+	buyOrder2 := createTestOrder("buyer2", "buy2", 7, true)
 
 	ob.PlaceLimitOrder(10_100, sellOrder1)
 	ob.PlaceLimitOrder(10_100, sellOrder2) // Same price level
@@ -685,31 +702,31 @@ func TestOrderbook_AskAndBidTotalVolume(t *testing.T) {
 	ob.PlaceLimitOrder(9_800, buyOrder2)
 
 	// Test total volumes
-	assert.Equal(t, 18.0, ob.AskTotalVolume()) // 10 + 5 + 3 = 18
-	assert.Equal(t, 15.0, ob.BidTotalVolume()) // 8 + 7 = 15
+	assert.Equal(t, int64(18), ob.AskTotalVolume()) // 10 + 5 + 3 = 18
+	assert.Equal(t, int64(15), ob.BidTotalVolume()) // 8 + 7 = 15
 
 	// Verify individual limit volumes
 	asks := ob.Asks()
-	assert.Equal(t, 15.0, asks[0].GetTotalVolume()) // 10 + 5 = 15 at price 10_100
-	assert.Equal(t, 3.0, asks[1].GetTotalVolume())  // 3 at price 10_200
+	assert.Equal(t, int64(15), asks[0].GetTotalVolume()) // 10 + 5 = 15 at price 10_100
+	assert.Equal(t, int64(3), asks[1].GetTotalVolume())  // 3 at price 10_200
 
 	bids := ob.Bids()
-	assert.Equal(t, 8.0, bids[0].GetTotalVolume()) // 8 at price 9_900
-	assert.Equal(t, 7.0, bids[1].GetTotalVolume()) // 7 at price 9_800
+	assert.Equal(t, int64(8), bids[0].GetTotalVolume()) // 8 at price 9_900
+	assert.Equal(t, int64(7), bids[1].GetTotalVolume()) // 7 at price 9_800
 }
 
 // Table-driven test for validation errors
 func TestOrderbook_PlaceLimitOrderValidation(t *testing.T) {
 	tests := []struct {
 		name      string
-		price     float64
+		price     int64
 		order     *orderbookv1.Order
 		wantError bool
 		errorMsg  string
 	}{
 		{
 			name:      "nil order",
-			price:     100.0,
+			price:     100,
 			order:     nil,
 			wantError: true,
 			errorMsg:  "order cannot be nil",
@@ -717,35 +734,35 @@ func TestOrderbook_PlaceLimitOrderValidation(t *testing.T) {
 		{
 			name:      "zero price",
 			price:     0,
-			order:     createTestOrder("user1", "order1", 10.0, false),
+			order:     createTestOrder("user1", "order1", 10, false),
 			wantError: true,
 			errorMsg:  "price must be positive",
 		},
 		{
 			name:      "negative price",
-			price:     -100.0,
-			order:     createTestOrder("user1", "order1", 10.0, false),
+			price:     -100,
+			order:     createTestOrder("user1", "order1", 10, false),
 			wantError: true,
 			errorMsg:  "price must be positive",
 		},
 		{
 			name:      "zero size order",
-			price:     100.0,
+			price:     100,
 			order:     &orderbookv1.Order{ID: "order1", UserID: "user1", Size: 0, Bid: false},
 			wantError: true,
 			errorMsg:  "order size must be positive",
 		},
 		{
 			name:      "empty order ID",
-			price:     100.0,
-			order:     &orderbookv1.Order{ID: "", UserID: "user1", Size: 10.0, Bid: false},
+			price:     100,
+			order:     &orderbookv1.Order{ID: "", UserID: "user1", Size: 10, Bid: false},
 			wantError: true,
 			errorMsg:  "order ID cannot be empty",
 		},
 		{
 			name:      "valid order",
-			price:     100.0,
-			order:     createTestOrder("user1", "order1", 10.0, false),
+			price:     100,
+			order:     createTestOrder("user1", "order1", 10, false),
 			wantError: false,
 		},
 	}
@@ -753,7 +770,7 @@ func TestOrderbook_PlaceLimitOrderValidation(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ob := NewOrderbook()
-			err := ob.PlaceLimitOrder(tt.price, tt.order)
+			_, err := ob.PlaceLimitOrder(tt.price, tt.order)
 
 			if tt.wantError {
 				assert.Error(t, err)
@@ -774,55 +791,55 @@ func TestOrderbook_MarketOrderScenarios(t *testing.T) {
 		setupOrders   func(*Orderbook)
 		marketOrder   *orderbookv1.Order
 		wantMatches   int
-		wantFilled    float64
-		wantRemaining float64
+		wantFilled    int64
+		wantRemaining int64
 	}{
 		{
 			name: "buy against single ask",
 			setupOrders: func(ob *Orderbook) {
-				sell := createTestOrder("seller", "sell1", 10.0, false)
+				sell := createTestOrder("seller", "sell1", 10, false)
 				ob.PlaceLimitOrder(10_000, sell)
 			},
-			marketOrder:   createTestOrder("buyer", "buy1", 5.0, true),
+			marketOrder:   createTestOrder("buyer", "buy1", 5, true),
 			wantMatches:   1,
-			wantFilled:    5.0,
-			wantRemaining: 0.0,
+			wantFilled:    5,
+			wantRemaining: 0,
 		},
 		{
 			name: "buy across multiple asks",
 			setupOrders: func(ob *Orderbook) {
-				sell1 := createTestOrder("seller1", "sell1", 5.0, false)
-				sell2 := createTestOrder("seller2", "sell2", 3.0, false)
+				sell1 := createTestOrder("seller1", "sell1", 5, false)
+				sell2 := createTestOrder("seller2", "sell2", 3, false)
 				ob.PlaceLimitOrder(10_000, sell1)
 				ob.PlaceLimitOrder(10_100, sell2)
 			},
-			marketOrder:   createTestOrder("buyer", "buy1", 7.0, true),
+			marketOrder:   createTestOrder("buyer", "buy1", 7, true),
 			wantMatches:   2,
-			wantFilled:    7.0,
-			wantRemaining: 0.0,
+			wantFilled:    7,
+			wantRemaining: 0,
 		},
 		{
 			name: "sell against single bid",
 			setupOrders: func(ob *Orderbook) {
-				buy := createTestOrder("buyer", "buy1", 10.0, true)
+				buy := createTestOrder("buyer", "buy1", 10, true)
 				ob.PlaceLimitOrder(9_900, buy)
 			},
-			marketOrder:   createTestOrder("seller", "sell1", 5.0, false),
+			marketOrder:   createTestOrder("seller", "sell1", 5, false),
 			wantMatches:   1,
-			wantFilled:    5.0,
-			wantRemaining: 0.0,
+			wantFilled:    5,
+			wantRemaining: 0,
 		},
 		{
 			name: "no matching orders",
 			setupOrders: func(ob *Orderbook) {
 				// Only add bid, try to place buy market order
-				buy := createTestOrder("buyer", "buy1", 10.0, true)
+				buy := createTestOrder("buyer", "buy1", 10, true)
 				ob.PlaceLimitOrder(9_900, buy)
 			},
-			marketOrder:   createTestOrder("buyer2", "buy2", 5.0, true),
+			marketOrder:   createTestOrder("buyer2", "buy2", 5, true),
 			wantMatches:   0,
-			wantFilled:    0.0,
-			wantRemaining: 5.0,
+			wantFilled:    0,
+			wantRemaining: 5,
 		},
 	}
 
@@ -838,7 +855,7 @@ func TestOrderbook_MarketOrderScenarios(t *testing.T) {
 			assert.Equal(t, tt.wantRemaining, tt.marketOrder.Size)
 
 			// Calculate total filled from matches
-			totalFilled := 0.0
+			var totalFilled int64
 			for _, match := range matches {
 				totalFilled += match.SizeFilled
 			}
@@ -846,3 +863,1097 @@ func TestOrderbook_MarketOrderScenarios(t *testing.T) {
 		})
 	}
 }
+
+// Table-driven test for limit orders that cross the spread
+func TestOrderbook_CrossingLimitOrderScenarios(t *testing.T) {
+	tests := []struct {
+		name          string
+		setupOrders   func(*Orderbook)
+		price         int64
+		limitOrder    *orderbookv1.Order
+		wantMatches   int
+		wantFilled    int64
+		wantRemaining int64
+		wantResting   bool
+		wantOrders    int
+	}{
+		{
+			name: "buy at best ask fills completely",
+			setupOrders: func(ob *Orderbook) {
+				ob.PlaceLimitOrder(10_000, createTestOrder("seller", "sell1", 10, false))
+			},
+			price:         10_000,
+			limitOrder:    createTestOrder("buyer", "buy1", 4, true),
+			wantMatches:   1,
+			wantFilled:    4,
+			wantRemaining: 0,
+			wantResting:   false,
+			wantOrders:    1,
+		},
+		{
+			name: "buy sweeps asks up to limit price and rests remainder",
+			setupOrders: func(ob *Orderbook) {
+				ob.PlaceLimitOrder(10_000, createTestOrder("seller1", "sell1", 5, false))
+				ob.PlaceLimitOrder(10_100, createTestOrder("seller2", "sell2", 3, false))
+				ob.PlaceLimitOrder(10_200, createTestOrder("seller3", "sell3", 7, false))
+			},
+			price:         10_100,
+			limitOrder:    createTestOrder("buyer", "buy1", 10, true),
+			wantMatches:   2,
+			wantFilled:    8,
+			wantRemaining: 2,
+			wantResting:   true,
+			wantOrders:    2, // sell3 and the resting buy1
+		},
+		{
+			name: "sell sweeps bids down to limit price",
+			setupOrders: func(ob *Orderbook) {
+				ob.PlaceLimitOrder(9_900, createTestOrder("buyer1", "buy1", 5, true))
+				ob.PlaceLimitOrder(9_800, createTestOrder("buyer2", "buy2", 5, true))
+				ob.PlaceLimitOrder(9_700, createTestOrder("buyer3", "buy3", 5, true))
+			},
+			price:         9_800,
+			limitOrder:    createTestOrder("seller", "sell1", 8, false),
+			wantMatches:   2,
+			wantFilled:    8,
+			wantRemaining: 0,
+			wantResting:   false,
+			wantOrders:    2, // partially filled buy2 and untouched buy3
+		},
+		{
+			name: "non-crossing limit rests without matching",
+			setupOrders: func(ob *Orderbook) {
+				ob.PlaceLimitOrder(10_000, createTestOrder("seller", "sell1", 10, false))
+			},
+			price:         9_900,
+			limitOrder:    createTestOrder("buyer", "buy1", 5, true),
+			wantMatches:   0,
+			wantFilled:    0,
+			wantRemaining: 5,
+			wantResting:   true,
+			wantOrders:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ob := NewOrderbook()
+			tt.setupOrders(ob)
+
+			matches, err := ob.PlaceLimitOrder(tt.price, tt.limitOrder)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantMatches, len(matches))
+			assert.Equal(t, tt.wantRemaining, tt.limitOrder.Size)
+			assert.Equal(t, tt.wantOrders, len(ob.Orders))
+
+			var totalFilled int64
+			for _, match := range matches {
+				totalFilled += match.SizeFilled
+				// Every fill executes at the resting price, never worse than the limit
+				if tt.limitOrder.IsBid() {
+					assert.LessOrEqual(t, match.Price, tt.price)
+				} else {
+					assert.GreaterOrEqual(t, match.Price, tt.price)
+				}
+			}
+			assert.Equal(t, tt.wantFilled, totalFilled)
+
+			_, resting := ob.Orders[tt.limitOrder.ID]
+			assert.Equal(t, tt.wantResting, resting)
+
+			// The book must never be left crossed
+			asks, bids := ob.Asks(), ob.Bids()
+			if len(asks) > 0 && len(bids) > 0 {
+				assert.Less(t, bids[0].Price, asks[0].Price)
+			}
+		})
+	}
+}
+
+// Table-driven test for time-in-force handling of limit orders
+func TestOrderbook_TimeInForceScenarios(t *testing.T) {
+	tests := []struct {
+		name          string
+		tif           orderbookv1.TimeInForce
+		expireAt      int64
+		price         int64
+		size          int64
+		wantErr       error
+		wantMatches   int
+		wantRemaining int64
+		wantResting   bool
+	}{
+		{
+			name:          "GTC rests remainder",
+			tif:           orderbookv1.TimeInForceGTC,
+			price:         10_100,
+			size:          12,
+			wantMatches:   2,
+			wantRemaining: 4,
+			wantResting:   true,
+		},
+		{
+			name:          "IOC cancels remainder",
+			tif:           orderbookv1.TimeInForceIOC,
+			price:         10_100,
+			size:          12,
+			wantMatches:   2,
+			wantRemaining: 4,
+			wantResting:   false,
+		},
+		{
+			name:          "FOK fills when liquidity is sufficient",
+			tif:           orderbookv1.TimeInForceFOK,
+			price:         10_100,
+			size:          8,
+			wantMatches:   2,
+			wantRemaining: 0,
+			wantResting:   false,
+		},
+		{
+			name:          "FOK kills when liquidity is insufficient",
+			tif:           orderbookv1.TimeInForceFOK,
+			price:         10_100,
+			size:          12,
+			wantMatches:   0,
+			wantRemaining: 12,
+			wantResting:   false,
+		},
+		{
+			name:          "GTD rests remainder",
+			tif:           orderbookv1.TimeInForceGTD,
+			expireAt:      1_000,
+			price:         10_100,
+			size:          12,
+			wantMatches:   2,
+			wantRemaining: 4,
+			wantResting:   true,
+		},
+		{
+			name:    "GTD without expiry is rejected",
+			tif:     orderbookv1.TimeInForceGTD,
+			price:   10_100,
+			size:    12,
+			wantErr: orderbookv1.ErrInvalidExpireAt,
+		},
+		{
+			name:    "unknown time in force is rejected",
+			tif:     orderbookv1.TimeInForce("day"),
+			price:   10_100,
+			size:    12,
+			wantErr: orderbookv1.ErrInvalidTimeInForce,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ob := NewOrderbook()
+			ob.PlaceLimitOrder(10_000, createTestOrder("seller1", "sell1", 5, false))
+			ob.PlaceLimitOrder(10_100, createTestOrder("seller2", "sell2", 3, false))
+			ob.PlaceLimitOrder(10_200, createTestOrder("seller3", "sell3", 7, false))
+
+			order := createTestOrder("buyer", "buy1", tt.size, true)
+			order.TimeInForce = tt.tif
+			order.ExpireAt = tt.expireAt
+
+			matches, err := ob.PlaceLimitOrder(tt.price, order)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, int64(15), ob.AskTotalVolume())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantMatches, len(matches))
+			assert.Equal(t, tt.wantRemaining, order.Size)
+
+			_, resting := ob.Orders[order.ID]
+			assert.Equal(t, tt.wantResting, resting)
+			assert.Equal(t, tt.wantResting, ob.BidTotalVolume() > 0)
+		})
+	}
+}
+
+// Test FOK market order against insufficient liquidity
+func TestOrderbook_FOKMarketOrder(t *testing.T) {
+	ob := NewOrderbook()
+	ob.PlaceLimitOrder(10_000, createTestOrder("seller", "sell1", 5, false))
+
+	buyOrder := createTestOrder("buyer", "buy1", 6, true)
+	buyOrder.TimeInForce = orderbookv1.TimeInForceFOK
+
+	matches, err := ob.PlaceMarketOrder(buyOrder)
+
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+	assert.Equal(t, int64(6), buyOrder.Size)
+	assert.Equal(t, int64(5), ob.AskTotalVolume())
+}
+
+func TestOrderbook_MarketOrderProtectionPrice(t *testing.T) {
+	ob := NewOrderbook()
+	ob.PlaceLimitOrder(10_000, createTestOrder("seller1", "sell1", 2, false))
+	ob.PlaceLimitOrder(10_100, createTestOrder("seller2", "sell2", 2, false))
+	ob.PlaceLimitOrder(10_200, createTestOrder("seller3", "sell3", 2, false))
+
+	// Levels beyond the protection price are left in the book
+	buyOrder := createTestOrder("buyer", "buy1", 5, true)
+	buyOrder.ProtectionPrice = 10_100
+	matches, err := ob.PlaceMarketOrder(buyOrder)
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, int64(10_100), matches[1].Price)
+	assert.Equal(t, int64(1), buyOrder.Size)
+	assert.True(t, buyOrder.Protected)
+	assert.Equal(t, int64(2), ob.AskTotalVolume())
+
+	// A FOK order that would fill beyond it does not fill at all
+	fokOrder := createTestOrder("buyer", "buy2", 2, true)
+	fokOrder.TimeInForce = orderbookv1.TimeInForceFOK
+	fokOrder.ProtectionPrice = 10_100
+	matches, err = ob.PlaceMarketOrder(fokOrder)
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+	assert.Equal(t, int64(2), fokOrder.Size)
+
+	// Running out of liquidity is not a protection stop
+	sellOrder := createTestOrder("seller", "sell4", 1, false)
+	sellOrder.ProtectionPrice = 9_000
+	matches, err = ob.PlaceMarketOrder(sellOrder)
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+	assert.False(t, sellOrder.Protected)
+}
+
+func TestOrderbook_MarketOrderSlippageLimit(t *testing.T) {
+	ob := NewOrderbook()
+	ob.PlaceLimitOrder(10_000, createTestOrder("seller1", "sell1", 2, false))
+	ob.PlaceLimitOrder(10_100, createTestOrder("seller2", "sell2", 2, false))
+	ob.PlaceLimitOrder(10_300, createTestOrder("seller3", "sell3", 2, false))
+
+	// 2% slippage from the best ask of 100.00 stops before 103.00
+	buyOrder := createTestOrder("buyer", "buy1", 5, true)
+	buyOrder.MaxSlippageBps = 200
+	matches, err := ob.PlaceMarketOrder(buyOrder)
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, int64(1), buyOrder.Size)
+	assert.True(t, buyOrder.SlippageLimited)
+	assert.False(t, buyOrder.Protected)
+
+	// The tighter of the protection price and the worst price applies
+	limited := createTestOrder("buyer", "buy2", 1, true)
+	limited.ProtectionPrice = 10_400
+	limited.WorstPrice = 10_200
+	matches, err = ob.PlaceMarketOrder(limited)
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+	assert.True(t, limited.SlippageLimited)
+	assert.False(t, limited.Protected)
+
+	// A FOK order that would fill beyond its worst price does not fill at all
+	fokOrder := createTestOrder("buyer", "buy3", 2, true)
+	fokOrder.TimeInForce = orderbookv1.TimeInForceFOK
+	fokOrder.WorstPrice = 10_200
+	matches, err = ob.PlaceMarketOrder(fokOrder)
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+	assert.Equal(t, int64(2), ob.AskTotalVolume())
+
+	invalid := createTestOrder("buyer", "buy4", 1, true)
+	invalid.MaxSlippageBps = -1
+	_, err = ob.PlaceMarketOrder(invalid)
+	assert.ErrorIs(t, err, orderbookv1.ErrInvalidSlippage)
+}
+
+func TestOrderbook_QuoteSizeMarketOrder(t *testing.T) {
+	// Prices in cents, sizes in thousandths with a lot of 0.010
+	newBook := func() *Orderbook {
+		return NewOrderbookWithOptions(&Options{
+			TickSize: 1,
+			Scale:    orderbookv1.Scale{PriceDecimals: 2, SizeDecimals: 3},
+			LotSize:  10,
+		})
+	}
+	quoteOrder := func(id string, budget int64, bid bool) *orderbookv1.Order {
+		order := createTestOrder("buyer", id, 0, bid)
+		order.QuoteSize = budget
+		return order
+	}
+
+	t.Run("budget is spent level by level in whole lots", func(t *testing.T) {
+		ob := newBook()
+		ob.PlaceLimitOrder(10_000, createTestOrder("seller1", "sell1", 50, false))
+		ob.PlaceLimitOrder(10_100, createTestOrder("seller2", "sell2", 1_000, false))
+
+		// 100.00 buys 0.050 at 100.00 for 0.50, then 0.940 at 101.00 for 94.94; the 0.06
+		// left cannot buy a lot of 0.010
+		order := quoteOrder("buy1", 10_000, true)
+		matches, err := ob.PlaceMarketOrder(order)
+
+		require.NoError(t, err)
+		require.Len(t, matches, 2)
+		assert.Equal(t, []int64{50, 940}, []int64{matches[0].SizeFilled, matches[1].SizeFilled})
+		assert.Equal(t, []int64{9_500, 6}, []int64{matches[0].QuoteRemaining, matches[1].QuoteRemaining})
+		assert.Equal(t, []int64{940, 0}, []int64{matches[0].BidRemaining, matches[1].BidRemaining})
+		assert.Equal(t, int64(6), order.QuoteRemaining)
+		assert.True(t, order.QuoteDust)
+		assert.False(t, order.Protected)
+		assert.Equal(t, int64(0), order.Size)
+		assert.Equal(t, int64(60), ob.AskTotalVolume())
+	})
+
+	t.Run("sell order stops when the book runs out", func(t *testing.T) {
+		ob := newBook()
+		ob.PlaceLimitOrder(10_000, createTestOrder("bidder", "buy1", 30, true))
+
+		order := quoteOrder("sell1", 10_000, false)
+		matches, err := ob.PlaceMarketOrder(order)
+
+		require.NoError(t, err)
+		require.Len(t, matches, 1)
+		assert.Equal(t, int64(30), matches[0].SizeFilled)
+		assert.Equal(t, int64(0), matches[0].AskRemaining)
+		assert.Equal(t, int64(9_700), order.QuoteRemaining)
+		assert.False(t, order.QuoteDust)
+		assert.False(t, order.Protected)
+		assert.Equal(t, int64(0), ob.BidTotalVolume())
+	})
+
+	t.Run("protection price stops the walk", func(t *testing.T) {
+		ob := newBook()
+		ob.PlaceLimitOrder(10_000, createTestOrder("seller1", "sell1", 20, false))
+		ob.PlaceLimitOrder(10_500, createTestOrder("seller2", "sell2", 1_000, false))
+
+		order := quoteOrder("buy1", 10_000, true)
+		order.ProtectionPrice = 10_400
+		matches, err := ob.PlaceMarketOrder(order)
+
+		require.NoError(t, err)
+		require.Len(t, matches, 1)
+		assert.Equal(t, int64(9_800), order.QuoteRemaining)
+		assert.False(t, order.QuoteDust)
+		assert.True(t, order.Protected)
+	})
+
+	t.Run("budget below one lot does not fill", func(t *testing.T) {
+		ob := newBook()
+		ob.PlaceLimitOrder(10_000, createTestOrder("seller1", "sell1", 50, false))
+
+		order := quoteOrder("buy1", 99, true)
+		matches, err := ob.PlaceMarketOrder(order)
+
+		require.NoError(t, err)
+		assert.Empty(t, matches)
+		assert.Equal(t, int64(99), order.QuoteRemaining)
+		assert.True(t, order.QuoteDust)
+	})
+
+	t.Run("self-trade prevention gives up the budget", func(t *testing.T) {
+		ob := newBook()
+		ob.PlaceLimitOrder(10_000, createTestOrder("buyer", "sell1", 50, false))
+
+		order := quoteOrder("buy1", 10_000, true)
+		order.SelfTradePrevention = orderbookv1.SelfTradePreventionCancelNewest
+		matches, err := ob.PlaceMarketOrder(order)
+
+		require.NoError(t, err)
+		assert.Empty(t, matches)
+		require.Len(t, order.SelfTradeCancels, 1)
+		assert.Equal(t, orderbookv1.SelfTradeCancel{Order: order}, order.SelfTradeCancels[0])
+		assert.Equal(t, int64(10_000), order.QuoteRemaining)
+		assert.Equal(t, int64(50), ob.AskTotalVolume())
+	})
+
+	t.Run("quote size with a size or fill-or-kill is rejected", func(t *testing.T) {
+		ob := newBook()
+
+		sized := quoteOrder("buy1", 10_000, true)
+		sized.Size = 10
+		_, err := ob.PlaceMarketOrder(sized)
+		assert.ErrorIs(t, err, orderbookv1.ErrInvalidQuoteSize)
+
+		fok := quoteOrder("buy2", 10_000, true)
+		fok.TimeInForce = orderbookv1.TimeInForceFOK
+		_, err = ob.PlaceMarketOrder(fok)
+		assert.ErrorIs(t, err, orderbookv1.ErrInvalidQuoteSize)
+	})
+}
+
+func TestOrderbook_ProRataAllocation(t *testing.T) {
+	options := DefaultOrderbookOptions()
+	options.Allocation = orderbookv1.ProRataAllocation{Remainder: orderbookv1.RemainderFIFO}
+	ob := NewOrderbookWithOptions(options)
+	ob.PlaceLimitOrder(10_000, createTestOrder("seller1", "sell1", 2, false))
+	ob.PlaceLimitOrder(10_000, createTestOrder("seller2", "sell2", 6, false))
+	ob.PlaceLimitOrder(10_100, createTestOrder("seller3", "sell3", 4, false))
+
+	// The best level is split pro-rata, only what it cannot fill moves to the next level
+	matches, err := ob.PlaceMarketOrder(createTestOrder("buyer1", "buy1", 4, true))
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, []int64{1, 3}, []int64{matches[0].SizeFilled, matches[1].SizeFilled})
+	assert.Equal(t, []string{"sell1", "sell2"}, []string{matches[0].Ask.ID, matches[1].Ask.ID})
+
+	// Limits restored from a snapshot keep the allocation
+	restored := NewOrderbookWithOptions(options)
+	require.NoError(t, restored.RestoreOrderbook(ob.CreateSnapshot()))
+	matches, err = restored.PlaceMarketOrder(createTestOrder("buyer2", "buy2", 2, true))
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, []int64{1, 1}, []int64{matches[0].SizeFilled, matches[1].SizeFilled})
+	assert.Equal(t, int64(6), restored.AskTotalVolume())
+}
+
+// Test GTD expiry and its persistence across snapshot/restore
+func TestOrderbook_ExpireOrders(t *testing.T) {
+	ob1 := NewOrderbook()
+
+	gtdEarly := createTestOrder("user1", "gtd1", 5, true)
+	gtdEarly.TimeInForce = orderbookv1.TimeInForceGTD
+	gtdEarly.ExpireAt = 1_000
+
+	gtdLate := createTestOrder("user2", "gtd2", 4, true)
+	gtdLate.TimeInForce = orderbookv1.TimeInForceGTD
+	gtdLate.ExpireAt = 2_000
+
+	gtc := createTestOrder("user3", "gtc1", 3, true)
+
+	ob1.PlaceLimitOrder(9_900, gtdEarly)
+	ob1.PlaceLimitOrder(9_900, gtdLate)
+	ob1.PlaceLimitOrder(9_800, gtc)
+
+	// Expiries survive a snapshot round trip
+	ob2 := NewOrderbook()
+	require.NoError(t, ob2.RestoreOrderbook(ob1.CreateSnapshot()))
+	assert.Equal(t, 2, len(ob2.GTDOrders))
+
+	assert.Empty(t, ob2.ExpireOrders(999))
+
+	expired := ob2.ExpireOrders(1_000)
+	require.Equal(t, 1, len(expired))
+	assert.Equal(t, "gtd1", expired[0].ID)
+	assert.Equal(t, int64(4), ob2.BidLimits[9_900].GetTotalVolume())
+
+	expired = ob2.ExpireOrders(5_000)
+	require.Equal(t, 1, len(expired))
+	assert.Equal(t, "gtd2", expired[0].ID)
+	assert.NotContains(t, ob2.BidLimits, int64(9_900))
+
+	assert.Equal(t, 1, len(ob2.Orders))
+	assert.Empty(t, ob2.GTDOrders)
+}
+
+func TestOrderbook_MassCancel(t *testing.T) {
+	setup := func() *Orderbook {
+		ob := NewOrderbook()
+		for i, o := range []struct {
+			user, id string
+			price    int64
+			bid      bool
+		}{
+			{"maker", "bid1", 9_900, true},
+			{"maker", "bid2", 9_800, true},
+			{"maker", "bid3", 9_900, true},
+			{"other", "bid4", 9_900, true},
+			{"maker", "ask1", 10_100, false},
+			{"maker", "ask2", 10_300, false},
+			{"other", "ask3", 10_100, false},
+		} {
+			order := createTestOrder(o.user, o.id, 5, o.bid)
+			order.Timestamp = int64(i + 1)
+			_, err := ob.PlaceLimitOrder(o.price, order)
+			require.NoError(t, err)
+		}
+		return ob
+	}
+	ids := func(orders []*orderbookv1.Order) []string {
+		var ids []string
+		for _, order := range orders {
+			ids = append(ids, order.ID)
+		}
+		return ids
+	}
+
+	t.Run("every order of the user, bids then asks, best price first", func(t *testing.T) {
+		ob := setup()
+
+		cancelled, err := ob.MassCancel(orderbookv1.MassCancelFilter{UserID: "maker"})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"bid1", "bid3", "bid2", "ask1", "ask2"}, ids(cancelled))
+		assert.Equal(t, []string{"ask3", "bid4"}, sortedKeys(ob.Orders))
+		assert.NotContains(t, ob.UserOrders, "maker")
+		assert.NotContains(t, ob.BidLimits, int64(9_800))
+		assert.NotContains(t, ob.AskLimits, int64(10_300))
+		assert.Equal(t, int64(5), ob.BidLimits[9_900].GetTotalVolume())
+	})
+
+	t.Run("side and price range", func(t *testing.T) {
+		ob := setup()
+
+		cancelled, err := ob.MassCancel(orderbookv1.MassCancelFilter{UserID: "maker", Side: orderbookv1.SideBuy, MinPrice: 9_850})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"bid1", "bid3"}, ids(cancelled))
+
+		cancelled, err = ob.MassCancel(orderbookv1.MassCancelFilter{UserID: "maker", MaxPrice: 10_200})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"bid2", "ask1"}, ids(cancelled))
+		assert.Equal(t, []string{"ask2"}, sortedKeys(ob.UserOrders["maker"]))
+	})
+
+	t.Run("index follows fills, cancels and restores", func(t *testing.T) {
+		ob := setup()
+
+		_, err := ob.PlaceMarketOrder(createTestOrder("taker", "market1", 5, true))
+		require.NoError(t, err)
+		_, err = ob.CancelOrder("bid2")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"ask2", "bid1", "bid3"}, sortedKeys(ob.UserOrders["maker"]))
+
+		restored := NewOrderbook()
+		require.NoError(t, restored.RestoreOrderbook(ob.CreateSnapshot()))
+		assert.Equal(t, []string{"ask2", "bid1", "bid3"}, sortedKeys(restored.UserOrders["maker"]))
+		assert.Equal(t, []string{"ask3", "bid4"}, sortedKeys(restored.UserOrders["other"]))
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		ob := setup()
+
+		_, err := ob.MassCancel(orderbookv1.MassCancelFilter{Side: orderbookv1.SideBuy})
+		assert.ErrorIs(t, err, orderbookv1.ErrInvalidMassCancel)
+		assert.Len(t, ob.Orders, 7)
+	})
+}
+
+// sortedKeys returns the order IDs of an order map in ascending order
+func sortedKeys(orders map[string]*orderbookv1.Order) []string {
+	keys := make([]string, 0, len(orders))
+	for key := range orders {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Table-driven test for post-only limit orders
+func TestOrderbook_PostOnlyScenarios(t *testing.T) {
+	tests := []struct {
+		name      string
+		bid       bool
+		price     int64
+		mode      orderbookv1.PostOnlyMode
+		tif       orderbookv1.TimeInForce
+		wantErr   error
+		wantPrice int64
+	}{
+		{
+			name:      "bid below best ask rests unchanged",
+			bid:       true,
+			price:     9_950,
+			wantPrice: 9_950,
+		},
+		{
+			name:    "crossing bid is rejected by default",
+			bid:     true,
+			price:   10_000,
+			wantErr: orderbookv1.ErrPostOnlyWouldCross,
+		},
+		{
+			name:      "crossing bid is repriced one tick below best ask",
+			bid:       true,
+			price:     10_100,
+			mode:      orderbookv1.PostOnlyModeReprice,
+			wantPrice: 9_999,
+		},
+		{
+			name:    "crossing ask is rejected",
+			bid:     false,
+			price:   9_900,
+			mode:    orderbookv1.PostOnlyModeReject,
+			wantErr: orderbookv1.ErrPostOnlyWouldCross,
+		},
+		{
+			name:      "crossing ask is repriced one tick above best bid",
+			bid:       false,
+			price:     9_800,
+			mode:      orderbookv1.PostOnlyModeReprice,
+			wantPrice: 9_901,
+		},
+		{
+			name:    "post-only IOC is not allowed",
+			bid:     true,
+			price:   9_950,
+			tif:     orderbookv1.TimeInForceIOC,
+			wantErr: orderbookv1.ErrPostOnlyNotAllowed,
+		},
+		{
+			name:    "unknown post-only mode is rejected",
+			bid:     true,
+			price:   9_950,
+			mode:    orderbookv1.PostOnlyMode("slide"),
+			wantErr: orderbookv1.ErrInvalidPostOnlyMode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ob := NewOrderbookWithOptions(&Options{TickSize: 1})
+			ob.PlaceLimitOrder(10_000, createTestOrder("seller", "sell1", 5, false))
+			ob.PlaceLimitOrder(9_900, createTestOrder("buyer", "buy1", 5, true))
+
+			order := createTestOrder("maker", "maker1", 2, tt.bid)
+			order.PostOnly = true
+			order.PostOnlyMode = tt.mode
+			order.TimeInForce = tt.tif
+
+			matches, err := ob.PlaceLimitOrder(tt.price, order)
+			assert.Empty(t, matches)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.NotContains(t, ob.Orders, order.ID)
+				return
+			}
+
+			require.NoError(t, err)
+			require.NotNil(t, order.Limit)
+			assert.Equal(t, tt.wantPrice, order.Limit.Price)
+			assert.Equal(t, int64(2), order.Size)
+		})
+	}
+}
+
+func TestOrderbook_IcebergScenarios(t *testing.T) {
+	testCases := []struct {
+		name           string
+		incomingSize   int64
+		fok            bool
+		expectedFilled int64
+		expectedAsk    int64
+		expectedHidden int64
+	}{
+		{
+			name:           "only the display size is visible",
+			incomingSize:   0,
+			expectedAsk:    2,
+			expectedHidden: 8,
+		},
+		{
+			name:           "market order trades through the hidden reserve",
+			incomingSize:   5,
+			expectedFilled: 5,
+			expectedAsk:    1,
+			expectedHidden: 4,
+		},
+		{
+			name:           "FOK order counts the hidden reserve as available",
+			incomingSize:   10,
+			fok:            true,
+			expectedFilled: 10,
+		},
+		{
+			name:           "FOK order larger than the hidden reserve is not executed",
+			incomingSize:   11,
+			fok:            true,
+			expectedAsk:    2,
+			expectedHidden: 8,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ob := NewOrderbook()
+
+			iceberg := createTestOrder("maker", "iceberg", 10, false)
+			iceberg.DisplaySize = 2
+			_, err := ob.PlaceLimitOrder(100, iceberg)
+			require.NoError(t, err)
+
+			if tc.incomingSize > 0 {
+				incoming := createTestOrder("taker", "taker", tc.incomingSize, true)
+				if tc.fok {
+					incoming.TimeInForce = orderbookv1.TimeInForceFOK
+				}
+				matches, err := ob.PlaceMarketOrder(incoming)
+				require.NoError(t, err)
+
+				var filled int64
+				for _, match := range matches {
+					filled += match.SizeFilled
+				}
+				assert.Equal(t, tc.expectedFilled, filled)
+			}
+
+			assert.Equal(t, tc.expectedAsk, ob.AskTotalVolume())
+			if tc.expectedAsk > 0 {
+				asks := ob.Asks()
+				require.Len(t, asks, 1)
+				assert.Equal(t, tc.expectedAsk, asks[0].GetTotalVolume())
+				assert.Equal(t, tc.expectedHidden, ob.Orders["iceberg"].HiddenSize)
+			} else {
+				assert.NotContains(t, ob.Orders, "iceberg")
+			}
+		})
+	}
+}
+
+func TestOrderbook_IcebergOrderValidation(t *testing.T) {
+	ob := NewOrderbook()
+
+	order := createTestOrder("maker", "iceberg", 10, false)
+	order.DisplaySize = -1
+	_, err := ob.PlaceLimitOrder(100, order)
+
+	assert.ErrorIs(t, err, orderbookv1.ErrInvalidDisplaySize)
+	assert.Empty(t, ob.Orders)
+}
+
+func TestOrderbook_IcebergSnapshotRoundTrip(t *testing.T) {
+	ob := NewOrderbook()
+
+	iceberg := createTestOrder("maker", "iceberg", 10, true)
+	iceberg.DisplaySize = 3
+	_, err := ob.PlaceLimitOrder(100, iceberg)
+	require.NoError(t, err)
+
+	restored := NewOrderbook()
+	require.NoError(t, restored.RestoreOrderbook(ob.CreateSnapshot()))
+
+	order := restored.Orders["iceberg"]
+	require.NotNil(t, order)
+	assert.Equal(t, int64(3), order.Size)
+	assert.Equal(t, int64(3), order.DisplaySize)
+	assert.Equal(t, int64(7), order.HiddenSize)
+	assert.Equal(t, int64(3), restored.BidTotalVolume())
+}
+
+func TestOrderbook_AmendSnapshotRoundTrip(t *testing.T) {
+	ob := NewOrderbook()
+
+	_, err := ob.PlaceLimitOrder(100, createTestOrder("seller", "ask", 5, false))
+	require.NoError(t, err)
+	maker := createTestOrder("maker", "maker", 4, true)
+	maker.PostOnly = true
+	maker.PostOnlyMode = orderbookv1.PostOnlyModeReject
+	_, err = ob.PlaceLimitOrder(98, maker)
+	require.NoError(t, err)
+	_, _, err = ob.AmendOrder("maker", "maker", 99, 6, time.Now().UnixNano())
+	require.NoError(t, err)
+
+	restored := NewOrderbook()
+	require.NoError(t, restored.RestoreOrderbook(ob.CreateSnapshot()))
+
+	order := restored.Orders["maker"]
+	require.NotNil(t, order)
+	assert.True(t, order.PostOnly)
+	assert.Equal(t, orderbookv1.PostOnlyModeReject, order.PostOnlyMode)
+	assert.Equal(t, int64(1), order.Sequence)
+
+	// The restored order is still post-only, so an amend onto the ask is refused
+	_, _, err = restored.AmendOrder("maker", "maker", 100, 6, time.Now().UnixNano())
+	assert.ErrorIs(t, err, orderbookv1.ErrPostOnlyWouldCross)
+	assert.Equal(t, int64(99), restored.Orders["maker"].Limit.Price)
+	assert.Equal(t, int64(5), restored.AskTotalVolume())
+}
+
+func TestOrderbook_LegacySnapshotMigration(t *testing.T) {
+	scale := orderbookv1.Scale{PriceDecimals: 2, SizeDecimals: 8}
+
+	legacy := &snapshotv1.LegacySnapshot{
+		OrderOffset: 42,
+		OrderBookSnapshot: snapshotv1.LegacyOrderBookSnapshot{
+			Orders: []snapshotv1.LegacyBookOrder{
+				{OrderID: "ask", UserID: "seller", Size: 0.5, Price: 100.25, Timestamp: 1},
+				{OrderID: "bid", UserID: "buyer", Size: 0.4, Price: 99.99, Bid: true, Timestamp: 2, DisplaySize: 0.4, HiddenSize: 0.8},
+			},
+			LastTradePrice: 100.1,
+		},
+	}
+
+	t.Run("converts floats to units", func(t *testing.T) {
+		snapshot, err := legacy.Migrate(scale, scale.PriceDecimals, scale.SizeDecimals)
+		require.NoError(t, err)
+		assert.Equal(t, snapshotv1.CurrentVersion, snapshot.Version)
+		assert.Equal(t, int64(42), snapshot.OrderOffset)
+		assert.Equal(t, int64(10010), snapshot.OrderBookSnapshot.LastTradePrice)
+
+		ob := NewOrderbook()
+		require.NoError(t, ob.RestoreOrderbook(snapshot))
+
+		require.Contains(t, ob.AskLimits, int64(10025))
+		require.Contains(t, ob.BidLimits, int64(9999))
+		assert.Equal(t, int64(50000000), ob.AskTotalVolume())
+		assert.Equal(t, int64(40000000), ob.Orders["bid"].Size)
+		assert.Equal(t, int64(80000000), ob.Orders["bid"].HiddenSize)
+	})
+
+	t.Run("fails when the pair precision is too small", func(t *testing.T) {
+		coarse := orderbookv1.Scale{PriceDecimals: 1, SizeDecimals: 8}
+
+		_, err := legacy.Migrate(coarse, coarse.PriceDecimals, coarse.SizeDecimals)
+		assert.ErrorIs(t, err, orderbookv1.ErrPrecisionLoss)
+	})
+}
+
+func TestOrderbook_AmendOrderScenarios(t *testing.T) {
+	testCases := []struct {
+		name          string
+		price         int64
+		size          int64
+		expectedErr   bool
+		expectedFirst string
+		expectedPrice int64
+		expectedSize  int64
+		expectMatches int
+	}{
+		{
+			name:          "size down keeps priority",
+			price:         100,
+			size:          4,
+			expectedFirst: "first",
+			expectedPrice: 100,
+			expectedSize:  4,
+		},
+		{
+			name:          "size up loses priority",
+			price:         100,
+			size:          12,
+			expectedFirst: "second",
+			expectedPrice: 100,
+			expectedSize:  12,
+		},
+		{
+			name:          "price change moves the order",
+			price:         101,
+			size:          10,
+			expectedFirst: "second",
+			expectedPrice: 101,
+			expectedSize:  10,
+		},
+		{
+			name:          "price change that crosses trades",
+			price:         99,
+			size:          10,
+			expectedFirst: "second",
+			expectedPrice: 99,
+			expectedSize:  7,
+			expectMatches: 1,
+		},
+		{
+			name:        "zero size is rejected",
+			price:       100,
+			size:        0,
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ob := NewOrderbook()
+
+			_, err := ob.PlaceLimitOrder(100, createTestOrder("user1", "first", 10, false))
+			require.NoError(t, err)
+			_, err = ob.PlaceLimitOrder(100, createTestOrder("user2", "second", 5, false))
+			require.NoError(t, err)
+			_, err = ob.PlaceLimitOrder(99, createTestOrder("user3", "bid", 3, true))
+			require.NoError(t, err)
+
+			order, matches, err := ob.AmendOrder("first", "user1", tc.price, tc.size, time.Now().UnixNano())
+
+			if tc.expectedErr {
+				assert.Error(t, err)
+				assert.Equal(t, int64(10), ob.Orders["first"].Size)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectMatches, len(matches))
+			assert.Equal(t, tc.expectedSize, order.Size)
+			require.NotNil(t, order.Limit)
+			assert.Equal(t, tc.expectedPrice, order.Limit.Price)
+
+			// Queue priority at the original price
+			if tc.expectedFirst != "" {
+				queue := ob.AskLimits[100].GetOrdersByPriority()
+				assert.Equal(t, tc.expectedFirst, queue[0].ID)
+			}
+			assert.NoError(t, order.Limit.Validate())
+		})
+	}
+}
+
+func TestOrderbook_AmendOrderEdgeCases(t *testing.T) {
+	t.Run("unknown order", func(t *testing.T) {
+		ob := NewOrderbook()
+
+		_, _, err := ob.AmendOrder("missing", "user1", 100, 1, time.Now().UnixNano())
+		assert.Error(t, err)
+	})
+
+	t.Run("order of another user is unknown", func(t *testing.T) {
+		ob := NewOrderbook()
+
+		_, err := ob.PlaceLimitOrder(100, createTestOrder("maker", "maker", 2, true))
+		require.NoError(t, err)
+
+		_, _, err = ob.AmendOrder("maker", "intruder", 99, 5, time.Now().UnixNano())
+
+		assert.ErrorIs(t, err, orderbookv1.ErrUnknownOrder)
+		assert.Equal(t, int64(100), ob.Orders["maker"].Limit.Price)
+		assert.Equal(t, int64(2), ob.BidTotalVolume())
+	})
+
+	t.Run("post-only amend that would cross leaves the order untouched", func(t *testing.T) {
+		ob := NewOrderbook()
+
+		_, err := ob.PlaceLimitOrder(101, createTestOrder("seller", "ask", 1, false))
+		require.NoError(t, err)
+		maker := createTestOrder("maker", "maker", 2, true)
+		maker.PostOnly = true
+		_, err = ob.PlaceLimitOrder(100, maker)
+		require.NoError(t, err)
+
+		_, _, err = ob.AmendOrder("maker", "maker", 101, 2, time.Now().UnixNano())
+
+		assert.ErrorIs(t, err, orderbookv1.ErrPostOnlyWouldCross)
+		assert.Equal(t, int64(100), maker.Limit.Price)
+		assert.Equal(t, int64(2), ob.BidTotalVolume())
+	})
+
+	t.Run("iceberg size down reduces the hidden reserve first", func(t *testing.T) {
+		ob := NewOrderbook()
+
+		iceberg := createTestOrder("maker", "iceberg", 10, false)
+		iceberg.DisplaySize = 2
+		_, err := ob.PlaceLimitOrder(100, iceberg)
+		require.NoError(t, err)
+
+		_, _, err = ob.AmendOrder("iceberg", "maker", 100, 1, time.Now().UnixNano())
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), iceberg.Size)
+		assert.Equal(t, int64(0), iceberg.HiddenSize)
+		assert.Equal(t, int64(1), ob.AskTotalVolume())
+	})
+}
+
+func TestOrderbook_SelfTradePrevention(t *testing.T) {
+	t.Run("pair mode applies to orders without their own", func(t *testing.T) {
+		ob := NewOrderbookWithOptions(&Options{
+			TickSize:            1,
+			SelfTradePrevention: orderbookv1.SelfTradePreventionCancelOldest,
+		})
+
+		_, err := ob.PlaceLimitOrder(100, createTestOrder("trader", "resting", 5, false))
+		require.NoError(t, err)
+
+		incoming := createTestOrder("trader", "incoming", 3, true)
+		matches, err := ob.PlaceLimitOrder(100, incoming)
+
+		require.NoError(t, err)
+		assert.Empty(t, matches)
+		assert.Equal(t, orderbookv1.SelfTradePreventionCancelOldest, incoming.SelfTradePrevention)
+		require.Len(t, incoming.SelfTradeCancels, 1)
+		assert.Equal(t, "resting", incoming.SelfTradeCancels[0].Order.ID)
+		assert.NotContains(t, ob.Orders, "resting")
+		assert.Empty(t, ob.AskLimits)
+		assert.Contains(t, ob.Orders, "incoming")
+	})
+
+	t.Run("order mode overrides pair mode", func(t *testing.T) {
+		ob := NewOrderbookWithOptions(&Options{
+			TickSize:            1,
+			SelfTradePrevention: orderbookv1.SelfTradePreventionCancelOldest,
+		})
+
+		_, err := ob.PlaceLimitOrder(100, createTestOrder("trader", "resting", 5, false))
+		require.NoError(t, err)
+
+		incoming := createTestOrder("trader", "incoming", 3, true)
+		incoming.SelfTradePrevention = orderbookv1.SelfTradePreventionNone
+		matches, err := ob.PlaceMarketOrder(incoming)
+
+		require.NoError(t, err)
+		assert.Len(t, matches, 1)
+		assert.Empty(t, incoming.SelfTradeCancels)
+		assert.Equal(t, int64(2), ob.AskTotalVolume())
+	})
+
+	t.Run("fill or kill does not count own volume", func(t *testing.T) {
+		for _, market := range []bool{false, true} {
+			ob := NewOrderbook()
+
+			_, err := ob.PlaceLimitOrder(100, createTestOrder("trader", "own", 5, false))
+			require.NoError(t, err)
+			_, err = ob.PlaceLimitOrder(100, createTestOrder("other", "other", 5, false))
+			require.NoError(t, err)
+
+			incoming := createTestOrder("trader", "incoming", 10, true)
+			incoming.TimeInForce = orderbookv1.TimeInForceFOK
+			incoming.SelfTradePrevention = orderbookv1.SelfTradePreventionCancelOldest
+			var matches []orderbookv1.Match
+			if market {
+				matches, err = ob.PlaceMarketOrder(incoming)
+			} else {
+				matches, err = ob.PlaceLimitOrder(100, incoming)
+			}
+
+			// Only 5 can trade once the own ask is cancelled, so nothing happens
+			require.NoError(t, err)
+			assert.Empty(t, matches)
+			assert.Empty(t, incoming.SelfTradeCancels)
+			assert.Equal(t, int64(10), incoming.Size)
+			assert.Equal(t, int64(10), ob.AskTotalVolume())
+			assert.Contains(t, ob.Orders, "own")
+		}
+	})
+
+	t.Run("fill or kill counts what trades before self-trade prevention stops it", func(t *testing.T) {
+		testCases := []struct {
+			mode         orderbookv1.SelfTradePrevention
+			expectFilled bool
+		}{
+			{mode: orderbookv1.SelfTradePreventionCancelOldest, expectFilled: true},
+			{mode: orderbookv1.SelfTradePreventionCancelNewest},
+			{mode: orderbookv1.SelfTradePreventionCancelBoth},
+			{mode: orderbookv1.SelfTradePreventionDecrementAndCancel},
+		}
+
+		for _, tc := range testCases {
+			ob := NewOrderbook()
+
+			_, err := ob.PlaceLimitOrder(100, createTestOrder("other", "ahead", 5, false))
+			require.NoError(t, err)
+			_, err = ob.PlaceLimitOrder(100, createTestOrder("trader", "own", 5, false))
+			require.NoError(t, err)
+			_, err = ob.PlaceLimitOrder(101, createTestOrder("other", "behind", 5, false))
+			require.NoError(t, err)
+
+			incoming := createTestOrder("trader", "incoming", 10, true)
+			incoming.TimeInForce = orderbookv1.TimeInForceFOK
+			incoming.SelfTradePrevention = tc.mode
+			matches, err := ob.PlaceLimitOrder(101, incoming)
+
+			require.NoError(t, err, tc.mode)
+			if tc.expectFilled {
+				assert.Len(t, matches, 2, tc.mode)
+				assert.Equal(t, int64(0), incoming.Size, tc.mode)
+				assert.Equal(t, int64(0), ob.AskTotalVolume(), tc.mode)
+				continue
+			}
+			assert.Empty(t, matches, tc.mode)
+			assert.Equal(t, int64(15), ob.AskTotalVolume(), tc.mode)
+		}
+	})
+
+	t.Run("invalid mode is rejected", func(t *testing.T) {
+		ob := NewOrderbook()
+
+		order := createTestOrder("trader", "incoming", 3, true)
+		order.SelfTradePrevention = "cancel_everything"
+		_, err := ob.PlaceLimitOrder(100, order)
+
+		assert.ErrorIs(t, err, orderbookv1.ErrInvalidSelfTrade)
+	})
+}
@@ -8,20 +8,24 @@ import (
 	"github.com/muhammadchandra19/exchange/pkg/errors"
 	logger "github.com/muhammadchandra19/exchange/pkg/logger"
 	"github.com/muhammadchandra19/exchange/pkg/redis"
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
 	snapshotv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/snapshot/v1"
 )
 
 // Store represents a snapshot of the order book.
 type Store struct {
 	pair        string
+	scale       orderbookv1.Scale
 	logger      *logger.Logger
 	redisclient redis.Client
 }
 
 // NewSnapshotStore creates a new Snapshot instance with the given Redis client and pair.
-func NewSnapshotStore(redisclient redis.Client, pair string, logger *logger.Logger) *Store {
+// The pair's scale is used to migrate snapshots written before prices and sizes were integers.
+func NewSnapshotStore(redisclient redis.Client, pair string, scale orderbookv1.Scale, logger *logger.Logger) *Store {
 	return &Store{
 		pair:        pair,
+		scale:       scale,
 		redisclient: redisclient,
 		logger:      logger,
 	}
@@ -102,7 +106,25 @@ func (s *Store) LoadStore(ctx context.Context) (*snapshotv1.Snapshot, error) {
 		return nil, nil
 	}
 
-	// Assuming the data can be unmarshalled into a *snapshotv1.Snapshot.
+	// Check the format version before decoding, legacy snapshots hold floats
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal([]byte(data), &header); err != nil {
+		s.logger.ErrorContext(ctx, err, logger.Field{
+			Key:   "pair",
+			Value: s.pair,
+		}, logger.Field{
+			Key:   "action",
+			Value: "unmarshal snapshot",
+		})
+		return nil, errors.NewTracer("snapshot_unmarshal_error").Wrap(err)
+	}
+
+	if header.Version < snapshotv1.CurrentVersion {
+		return s.migrate(ctx, data)
+	}
+
 	var snapshot snapshotv1.Snapshot
 	if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
 		s.logger.ErrorContext(ctx, err, logger.Field{
@@ -117,3 +139,39 @@ func (s *Store) LoadStore(ctx context.Context) (*snapshotv1.Snapshot, error) {
 
 	return &snapshot, nil
 }
+
+// migrate decodes a legacy float snapshot and converts it to integer units at the pair's scale.
+func (s *Store) migrate(ctx context.Context, data string) (*snapshotv1.Snapshot, error) {
+	var legacy snapshotv1.LegacySnapshot
+	if err := json.Unmarshal([]byte(data), &legacy); err != nil {
+		s.logger.ErrorContext(ctx, err, logger.Field{
+			Key:   "pair",
+			Value: s.pair,
+		}, logger.Field{
+			Key:   "action",
+			Value: "unmarshal legacy snapshot",
+		})
+		return nil, errors.NewTracer("snapshot_unmarshal_error").Wrap(err)
+	}
+
+	snapshot, err := legacy.Migrate(s.scale, s.scale.PriceDecimals, s.scale.SizeDecimals)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err, logger.Field{
+			Key:   "pair",
+			Value: s.pair,
+		}, logger.Field{
+			Key:   "action",
+			Value: "migrate snapshot",
+		})
+		return nil, errors.NewTracer("snapshot_migration_error").Wrap(err)
+	}
+
+	s.logger.InfoContext(ctx, fmt.Sprintf("Migrated legacy snapshot for pair %s", s.pair), logger.Field{
+		Key:   "pair",
+		Value: s.pair,
+	}, logger.Field{
+		Key:   "version",
+		Value: snapshot.Version,
+	})
+	return snapshot, nil
+}
@@ -0,0 +1,315 @@
+package stopbook
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+	snapshotv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/snapshot/v1"
+	stopbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/stopbook/v1"
+)
+
+// StopBook holds pending stop orders keyed by their stop price
+type StopBook struct {
+	mu        sync.RWMutex
+	BuyStops  map[int64][]*stopbookv1.StopOrder // stop price -> stops in arrival order
+	SellStops map[int64][]*stopbookv1.StopOrder // stop price -> stops in arrival order
+	Orders    map[string]*stopbookv1.StopOrder  // orderID -> stop
+	// userID -> orderID -> stop
+	UserOrders map[string]map[string]*stopbookv1.StopOrder
+	sequence   int64
+}
+
+// NewStopBook creates a new stop book
+func NewStopBook() *StopBook {
+	return &StopBook{
+		BuyStops:  make(map[int64][]*stopbookv1.StopOrder),
+		SellStops: make(map[int64][]*stopbookv1.StopOrder),
+		Orders:    make(map[string]*stopbookv1.StopOrder),
+
+		UserOrders: make(map[string]map[string]*stopbookv1.StopOrder),
+	}
+}
+
+// AddStopOrder adds a stop order and assigns its arrival sequence
+func (sb *StopBook) AddStopOrder(stop *stopbookv1.StopOrder) error {
+	if stop == nil {
+		return stopbookv1.ErrNilStopOrder
+	}
+	if err := stop.Validate(); err != nil {
+		return err
+	}
+
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	if _, exists := sb.Orders[stop.OrderID]; exists {
+		return fmt.Errorf("%w: %s", stopbookv1.ErrStopOrderExists, stop.OrderID)
+	}
+
+	sb.sequence++
+	stop.Sequence = sb.sequence
+	sb.addUnsafe(stop)
+
+	return nil
+}
+
+// CancelStopOrder removes a pending stop order and returns it
+func (sb *StopBook) CancelStopOrder(orderID string) (*stopbookv1.StopOrder, error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	stop, exists := sb.Orders[orderID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", stopbookv1.ErrStopOrderNotFound, orderID)
+	}
+
+	sb.removeUnsafe(stop)
+	return stop, nil
+}
+
+// HasStopOrder checks if a stop order is pending
+func (sb *StopBook) HasStopOrder(orderID string) bool {
+	sb.mu.RLock()
+	defer sb.mu.RUnlock()
+
+	_, exists := sb.Orders[orderID]
+	return exists
+}
+
+// GetStopOrder returns a copy of a pending stop order
+func (sb *StopBook) GetStopOrder(orderID string) (*stopbookv1.StopOrder, error) {
+	sb.mu.RLock()
+	defer sb.mu.RUnlock()
+
+	stop, exists := sb.Orders[orderID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", stopbookv1.ErrStopOrderNotFound, orderID)
+	}
+	clone := *stop
+	return &clone, nil
+}
+
+// UserStopOrders returns copies of the pending stop orders of a user in arrival order.
+// Only the user's own stops are looked at, through the per-user index.
+func (sb *StopBook) UserStopOrders(userID string) []*stopbookv1.StopOrder {
+	sb.mu.RLock()
+	defer sb.mu.RUnlock()
+
+	stops := make([]*stopbookv1.StopOrder, 0, len(sb.UserOrders[userID]))
+	for _, stop := range sb.UserOrders[userID] {
+		clone := *stop
+		stops = append(stops, &clone)
+	}
+	sort.Slice(stops, func(i, j int) bool { return stops[i].Sequence < stops[j].Sequence })
+
+	return stops
+}
+
+// TriggerStops removes and returns every stop triggered by the last trade price.
+// Buy stops come first, lowest stop price first; then sell stops, highest stop
+// price first. Stops with the same stop price keep their arrival order.
+func (sb *StopBook) TriggerStops(lastPrice int64) []*stopbookv1.StopOrder {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	triggered := append(
+		collectTriggered(sb.BuyStops, lastPrice, func(a, b int64) bool { return a < b }),
+		collectTriggered(sb.SellStops, lastPrice, func(a, b int64) bool { return a > b })...,
+	)
+
+	for _, stop := range triggered {
+		sb.removeUnsafe(stop)
+	}
+
+	return triggered
+}
+
+// HasExpiredStopOrders reports whether a good-till-date stop order has expired at the
+// given Unix nanosecond time, without removing anything.
+func (sb *StopBook) HasExpiredStopOrders(now int64) bool {
+	sb.mu.RLock()
+	defer sb.mu.RUnlock()
+
+	for _, stop := range sb.Orders {
+		if stop.IsExpired(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpireStopOrders removes every good-till-date stop order that has expired at the given
+// Unix nanosecond time and returns them, oldest expiry first, then in arrival order.
+func (sb *StopBook) ExpireStopOrders(now int64) []*stopbookv1.StopOrder {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	var expired []*stopbookv1.StopOrder
+	for _, stop := range sb.Orders {
+		if stop.IsExpired(now) {
+			expired = append(expired, stop)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		if expired[i].ExpireAt == expired[j].ExpireAt {
+			return expired[i].Sequence < expired[j].Sequence
+		}
+		return expired[i].ExpireAt < expired[j].ExpireAt
+	})
+
+	for _, stop := range expired {
+		sb.removeUnsafe(stop)
+	}
+
+	return expired
+}
+
+// collectTriggered returns the triggered stops of one side, ordered by stop price then arrival
+func collectTriggered(stops map[int64][]*stopbookv1.StopOrder, lastPrice int64, less func(a, b int64) bool) []*stopbookv1.StopOrder {
+	var prices []int64
+	for price, level := range stops {
+		if len(level) > 0 && level[0].IsTriggered(lastPrice) {
+			prices = append(prices, price)
+		}
+	}
+	sort.Slice(prices, func(i, j int) bool { return less(prices[i], prices[j]) })
+
+	var triggered []*stopbookv1.StopOrder
+	for _, price := range prices {
+		triggered = append(triggered, stops[price]...)
+	}
+	return triggered
+}
+
+// CreateSnapshot returns the pending stop orders in arrival order
+func (sb *StopBook) CreateSnapshot() []snapshotv1.StopOrder {
+	sb.mu.RLock()
+	defer sb.mu.RUnlock()
+
+	stops := make([]snapshotv1.StopOrder, 0, len(sb.Orders))
+	for _, stop := range sb.Orders {
+		stops = append(stops, snapshotv1.StopOrder{
+			OrderID:      stop.OrderID,
+			UserID:       stop.UserID,
+			Type:         string(stop.Type),
+			Bid:          stop.Bid,
+			Size:         stop.Size,
+			StopPrice:    stop.StopPrice,
+			LimitPrice:   stop.LimitPrice,
+			TimeInForce:  string(stop.TimeInForce),
+			ExpireAt:     stop.ExpireAt,
+			PostOnly:     stop.PostOnly,
+			PostOnlyMode: string(stop.PostOnlyMode),
+			DisplaySize:  stop.DisplaySize,
+
+			SelfTradePrevention: string(stop.SelfTradePrevention),
+			WorstPrice:          stop.WorstPrice,
+			MaxSlippageBps:      stop.MaxSlippageBps,
+			Sequence:            stop.Sequence,
+		})
+	}
+	sort.Slice(stops, func(i, j int) bool { return stops[i].Sequence < stops[j].Sequence })
+
+	return stops
+}
+
+// RestoreStopBook restores the pending stop orders from a snapshot
+func (sb *StopBook) RestoreStopBook(stops []snapshotv1.StopOrder) error {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	// Clear current state
+	sb.BuyStops = make(map[int64][]*stopbookv1.StopOrder)
+	sb.SellStops = make(map[int64][]*stopbookv1.StopOrder)
+	sb.Orders = make(map[string]*stopbookv1.StopOrder)
+	sb.UserOrders = make(map[string]map[string]*stopbookv1.StopOrder)
+	sb.sequence = 0
+
+	// Restore in arrival order so every price level keeps its FIFO order
+	sorted := make([]snapshotv1.StopOrder, len(stops))
+	copy(sorted, stops)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Sequence < sorted[j].Sequence })
+
+	for _, s := range sorted {
+		stop := &stopbookv1.StopOrder{
+			OrderID:      s.OrderID,
+			UserID:       s.UserID,
+			Type:         orderbookv1.OrderType(s.Type),
+			Bid:          s.Bid,
+			Size:         s.Size,
+			StopPrice:    s.StopPrice,
+			LimitPrice:   s.LimitPrice,
+			TimeInForce:  orderbookv1.TimeInForce(s.TimeInForce),
+			ExpireAt:     s.ExpireAt,
+			PostOnly:     s.PostOnly,
+			PostOnlyMode: orderbookv1.PostOnlyMode(s.PostOnlyMode),
+			DisplaySize:  s.DisplaySize,
+
+			SelfTradePrevention: orderbookv1.SelfTradePrevention(s.SelfTradePrevention),
+			WorstPrice:          s.WorstPrice,
+			MaxSlippageBps:      s.MaxSlippageBps,
+			Sequence:            s.Sequence,
+		}
+		if err := stop.Validate(); err != nil {
+			return fmt.Errorf("failed to restore stop order %s: %w", s.OrderID, err)
+		}
+		if _, exists := sb.Orders[stop.OrderID]; exists {
+			return fmt.Errorf("failed to restore stop order %s: %w", s.OrderID, stopbookv1.ErrStopOrderExists)
+		}
+
+		sb.addUnsafe(stop)
+		if stop.Sequence > sb.sequence {
+			sb.sequence = stop.Sequence
+		}
+	}
+
+	return nil
+}
+
+// addUnsafe adds a stop without locking (internal use)
+func (sb *StopBook) addUnsafe(stop *stopbookv1.StopOrder) {
+	stops := sb.SellStops
+	if stop.Bid {
+		stops = sb.BuyStops
+	}
+	stops[stop.StopPrice] = append(stops[stop.StopPrice], stop)
+	sb.Orders[stop.OrderID] = stop
+
+	userStops, exists := sb.UserOrders[stop.UserID]
+	if !exists {
+		userStops = make(map[string]*stopbookv1.StopOrder)
+		sb.UserOrders[stop.UserID] = userStops
+	}
+	userStops[stop.OrderID] = stop
+}
+
+// removeUnsafe removes a stop without locking (internal use)
+func (sb *StopBook) removeUnsafe(stop *stopbookv1.StopOrder) {
+	stops := sb.SellStops
+	if stop.Bid {
+		stops = sb.BuyStops
+	}
+
+	level := stops[stop.StopPrice]
+	for i, s := range level {
+		if s == stop {
+			level = append(level[:i], level[i+1:]...)
+			break
+		}
+	}
+	if len(level) == 0 {
+		delete(stops, stop.StopPrice)
+	} else {
+		stops[stop.StopPrice] = level
+	}
+	delete(sb.Orders, stop.OrderID)
+
+	if userStops, exists := sb.UserOrders[stop.UserID]; exists {
+		delete(userStops, stop.OrderID)
+		if len(userStops) == 0 {
+			delete(sb.UserOrders, stop.UserID)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package stopbook
+
+import (
+	"testing"
+
+	orderbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/orderbook/v1"
+	stopbookv1 "github.com/muhammadchandra19/exchange/services/matching-engine/internal/domain/stopbook/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function to create a test stop order
+func createTestStop(orderID string, bid bool, stopPrice int64) *stopbookv1.StopOrder {
+	return &stopbookv1.StopOrder{
+		OrderID:   orderID,
+		UserID:    "user-" + orderID,
+		Type:      orderbookv1.OrderTypeStop,
+		Bid:       bid,
+		Size:      1,
+		StopPrice: stopPrice,
+	}
+}
+
+func stopIDs(stops []*stopbookv1.StopOrder) []string {
+	ids := make([]string, 0, len(stops))
+	for _, stop := range stops {
+		ids = append(ids, stop.OrderID)
+	}
+	return ids
+}
+
+func TestStopBook_AddStopOrder(t *testing.T) {
+	testCases := []struct {
+		name        string
+		stop        *stopbookv1.StopOrder
+		expectedErr error
+	}{
+		{
+			name:        "nil stop order",
+			stop:        nil,
+			expectedErr: stopbookv1.ErrNilStopOrder,
+		},
+		{
+			name: "stop-limit without limit price",
+			stop: &stopbookv1.StopOrder{
+				OrderID: "s1", Type: orderbookv1.OrderTypeStopLimit, Size: 1, StopPrice: 100,
+			},
+			expectedErr: stopbookv1.ErrInvalidLimitPrice,
+		},
+		{
+			name: "non-stop order type",
+			stop: &stopbookv1.StopOrder{
+				OrderID: "s1", Type: orderbookv1.OrderTypeLimit, Size: 1, StopPrice: 100,
+			},
+			expectedErr: stopbookv1.ErrInvalidStopType,
+		},
+		{
+			name:        "missing stop price",
+			stop:        createTestStop("s1", true, 0),
+			expectedErr: stopbookv1.ErrInvalidStopPrice,
+		},
+		{
+			name: "valid stop order",
+			stop: createTestStop("s1", true, 100),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sb := NewStopBook()
+
+			err := sb.AddStopOrder(tc.stop)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Empty(t, sb.Orders)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, sb.HasStopOrder(tc.stop.OrderID))
+			assert.Equal(t, int64(1), tc.stop.Sequence)
+		})
+	}
+}
+
+func TestStopBook_DuplicateAndCancel(t *testing.T) {
+	sb := NewStopBook()
+
+	require.NoError(t, sb.AddStopOrder(createTestStop("s1", false, 90)))
+	assert.ErrorIs(t, sb.AddStopOrder(createTestStop("s1", false, 95)), stopbookv1.ErrStopOrderExists)
+
+	stop, err := sb.CancelStopOrder("s1")
+	require.NoError(t, err)
+	assert.Equal(t, "s1", stop.OrderID)
+	assert.False(t, sb.HasStopOrder("s1"))
+	assert.Empty(t, sb.SellStops)
+
+	_, err = sb.CancelStopOrder("s1")
+	assert.ErrorIs(t, err, stopbookv1.ErrStopOrderNotFound)
+}
+
+func TestStopBook_UserStopOrders(t *testing.T) {
+	sb := NewStopBook()
+
+	for _, stop := range []*stopbookv1.StopOrder{
+		createTestStop("s1", false, 90),
+		createTestStop("s2", true, 110),
+		createTestStop("s3", false, 95),
+	} {
+		stop.UserID = "alice"
+		if stop.OrderID == "s2" {
+			stop.UserID = "bob"
+		}
+		require.NoError(t, sb.AddStopOrder(stop))
+	}
+
+	assert.Equal(t, []string{"s1", "s3"}, stopIDs(sb.UserStopOrders("alice")))
+	assert.Equal(t, []string{"s2"}, stopIDs(sb.UserStopOrders("bob")))
+
+	// Cancelled and triggered stops leave the index
+	_, err := sb.CancelStopOrder("s1")
+	require.NoError(t, err)
+	sb.TriggerStops(120)
+	assert.Equal(t, []string{"s3"}, stopIDs(sb.UserStopOrders("alice")))
+	assert.Empty(t, sb.UserStopOrders("bob"))
+	assert.NotContains(t, sb.UserOrders, "bob")
+
+	// The index is rebuilt from a snapshot
+	restored := NewStopBook()
+	require.NoError(t, restored.RestoreStopBook(sb.CreateSnapshot()))
+	assert.Equal(t, []string{"s3"}, stopIDs(restored.UserStopOrders("alice")))
+}
+
+func TestStopBook_TriggerStops(t *testing.T) {
+	testCases := []struct {
+		name        string
+		lastPrice   int64
+		expectedIDs []string
+		remaining   int
+	}{
+		{
+			name:      "no trade price yet",
+			lastPrice: 0,
+			remaining: 6,
+		},
+		{
+			name:      "price between the stops",
+			lastPrice: 100,
+			remaining: 6,
+		},
+		{
+			name:        "price rises through buy stops",
+			lastPrice:   110,
+			expectedIDs: []string{"buy105", "buy110a", "buy110b"},
+			remaining:   3,
+		},
+		{
+			name:        "price falls through sell stops",
+			lastPrice:   90,
+			expectedIDs: []string{"sell95", "sell90"},
+			remaining:   4,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sb := NewStopBook()
+			for _, stop := range []*stopbookv1.StopOrder{
+				createTestStop("buy110a", true, 110),
+				createTestStop("buy105", true, 105),
+				createTestStop("buy120", true, 120),
+				createTestStop("buy110b", true, 110),
+				createTestStop("sell90", false, 90),
+				createTestStop("sell95", false, 95),
+			} {
+				require.NoError(t, sb.AddStopOrder(stop))
+			}
+
+			triggered := sb.TriggerStops(tc.lastPrice)
+
+			assert.Equal(t, len(tc.expectedIDs), len(triggered))
+			if len(tc.expectedIDs) > 0 {
+				assert.Equal(t, tc.expectedIDs, stopIDs(triggered))
+			}
+			assert.Equal(t, tc.remaining, len(sb.Orders))
+			for _, id := range tc.expectedIDs {
+				assert.False(t, sb.HasStopOrder(id))
+			}
+		})
+	}
+}
+
+func TestStopBook_ExpireStopOrders(t *testing.T) {
+	sb := NewStopBook()
+
+	gtd := func(orderID string, expireAt int64) *stopbookv1.StopOrder {
+		stop := createTestStop(orderID, true, 100)
+		stop.TimeInForce = orderbookv1.TimeInForceGTD
+		stop.ExpireAt = expireAt
+		return stop
+	}
+	require.NoError(t, sb.AddStopOrder(gtd("later", 300)))
+	require.NoError(t, sb.AddStopOrder(gtd("first", 100)))
+	require.NoError(t, sb.AddStopOrder(gtd("second", 100)))
+	require.NoError(t, sb.AddStopOrder(createTestStop("gtc", false, 90)))
+
+	assert.Empty(t, sb.ExpireStopOrders(99))
+
+	expired := sb.ExpireStopOrders(200)
+	assert.Equal(t, []string{"first", "second"}, stopIDs(expired))
+	assert.False(t, sb.HasStopOrder("first"))
+	assert.False(t, sb.HasStopOrder("second"))
+	assert.True(t, sb.HasStopOrder("later"))
+	assert.True(t, sb.HasStopOrder("gtc"))
+	assert.Len(t, sb.BuyStops[100], 1)
+}
+
+func TestStopBook_SnapshotRoundTrip(t *testing.T) {
+	sb := NewStopBook()
+
+	stopLimit := createTestStop("s2", false, 95)
+	stopLimit.Type = orderbookv1.OrderTypeStopLimit
+	stopLimit.LimitPrice = 94
+	stopLimit.TimeInForce = orderbookv1.TimeInForceIOC
+	stop := createTestStop("s1", false, 95)
+	stop.WorstPrice = 90
+	stop.MaxSlippageBps = 50
+
+	require.NoError(t, sb.AddStopOrder(stop))
+	require.NoError(t, sb.AddStopOrder(stopLimit))
+	require.NoError(t, sb.AddStopOrder(createTestStop("s3", true, 105)))
+
+	snapshot := sb.CreateSnapshot()
+	require.Len(t, snapshot, 3)
+
+	restored := NewStopBook()
+	require.NoError(t, restored.RestoreStopBook(snapshot))
+
+	assert.Equal(t, 3, len(restored.Orders))
+	assert.Equal(t, orderbookv1.OrderTypeStopLimit, restored.Orders["s2"].Type)
+	assert.Equal(t, int64(94), restored.Orders["s2"].LimitPrice)
+	assert.Equal(t, orderbookv1.TimeInForceIOC, restored.Orders["s2"].TimeInForce)
+	assert.Equal(t, int64(90), restored.Orders["s1"].WorstPrice)
+	assert.Equal(t, int64(50), restored.Orders["s1"].MaxSlippageBps)
+
+	// Arrival order survives the round trip and new stops continue the sequence
+	assert.Equal(t, []string{"s1", "s2"}, stopIDs(restored.TriggerStops(95)))
+	next := createTestStop("s4", true, 110)
+	require.NoError(t, restored.AddStopOrder(next))
+	assert.Equal(t, int64(4), next.Sequence)
+}
@@ -1,6 +1,11 @@
 package config
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
 )
@@ -27,10 +32,163 @@ func Load[T any](cfg T) error {
 
 // Config holds the configuration for the application
 type Config struct {
-	Pair                 string                         `env:"PAIR,required"` // Trading pair, e.g., BTC/USD
+	Pair                 string                         `env:"PAIR"`                   // Trading pair of a single-pair process, e.g., BTC-USD
+	Pairs                []string                       `env:"PAIRS" envSeparator:","` // Pairs hosted by the process, see PairConfigs; overrides PAIR
 	KafkaConfig          `envPrefix:"KAFKA_"`           // Kafka configuration
 	RedisConfig          `envPrefix:"REDIS_"`           // Redis configuration
 	MatchPublisherConfig `envPrefix:"MATCH_PUBLISHER_"` // Match publisher configuration
+	OrderPublisherConfig `envPrefix:"ORDER_PUBLISHER_"` // Order event publisher configuration
+	JournalConfig        `envPrefix:"JOURNAL_"`         // Order journal configuration
+	DeadLetterConfig     `envPrefix:"DEAD_LETTER_"`     // Failed order message handling
+	AdminConfig          `envPrefix:"ADMIN_"`           // Operator gRPC API
+	PriceBandConfig      `envPrefix:"PRICE_BAND_"`      // Price band of the pair
+	CircuitBreakerConfig `envPrefix:"CIRCUIT_BREAKER_"` // Volatility halts of the pair
+	FeeConfig            `envPrefix:"FEE_"`             // Maker and taker fees stamped on trades
+	AllocationConfig     `envPrefix:"ALLOCATION_"`      // How price levels split incoming orders
+
+	InstrumentConfig           // Instrument specification of the pair
+	SelfTradePrevention string `env:"SELF_TRADE_PREVENTION" envDefault:"none"` // Default self-trade prevention mode of the pair
+	MarketState         string `env:"MARKET_STATE" envDefault:"continuous"`    // State a pair starts in until its snapshot records one
+
+	// Trading rules of single pairs, PAIR:SETTING=VALUE[;SETTING=VALUE...], see PairConfigs
+	PairOverrides []string `env:"PAIR_OVERRIDES" envSeparator:","`
+}
+
+// InstrumentConfig holds the instrument specification of the pair. Values are decimals
+// and must be representable at the pair's precision; a zero rule is not enforced.
+type InstrumentConfig struct {
+	PriceDecimals int32   `env:"PRICE_DECIMALS" envDefault:"2"` // Decimals of the pair's prices, fixes the integer price unit
+	SizeDecimals  int32   `env:"SIZE_DECIMALS" envDefault:"8"`  // Decimals of the pair's sizes, fixes the integer size unit
+	TickSize      float64 `env:"TICK_SIZE" envDefault:"0.01"`   // Minimum price increment
+	LotSize       float64 `env:"LOT_SIZE" envDefault:"0"`       // Quantity step, sizes must be a multiple of it
+	MinSize       float64 `env:"MIN_SIZE" envDefault:"0"`       // Minimum order size
+	MinNotional   float64 `env:"MIN_NOTIONAL" envDefault:"0"`   // Minimum price times size of orders with a price
+	MaxNotional   float64 `env:"MAX_NOTIONAL" envDefault:"0"`   // Maximum price times size of orders with a price
+}
+
+// PriceBandConfig holds the price band of the pair: limit orders more than Percent away
+// from the reference price are rejected, and market orders do not fill beyond it.
+type PriceBandConfig struct {
+	Percent       float64 `env:"PERCENT" envDefault:"0"`            // Width either side of the reference, 0 disables the band
+	Reference     string  `env:"REFERENCE" envDefault:"last_trade"` // last_trade or moving_average
+	AverageTrades int     `env:"AVERAGE_TRADES" envDefault:"20"`    // Trades in the moving average
+}
+
+// CircuitBreakerConfig holds when the pair halts on a fast price move: a move of more than
+// MovePercent within Window halts it for CoolDown.
+type CircuitBreakerConfig struct {
+	MovePercent float64       `env:"MOVE_PERCENT" envDefault:"0"` // 0 disables the breaker
+	Window      time.Duration `env:"WINDOW" envDefault:"60s"`
+	CoolDown    time.Duration `env:"COOL_DOWN" envDefault:"5m"`
+}
+
+// AllocationConfig holds how the resting orders of a price level share an incoming order:
+// fifo, pro_rata or hybrid, which fills the oldest order first and the others pro-rata.
+type AllocationConfig struct {
+	Strategy      string   `env:"STRATEGY" envDefault:"fifo"`    // Strategy of pairs without one of their own
+	Pairs         []string `env:"PAIRS" envSeparator:","`        // Strategy of single pairs, PAIR:STRATEGY
+	MinAllocation float64  `env:"MIN_ALLOCATION" envDefault:"0"` // Smallest pro-rata share, smaller shares go to the remainder
+	Remainder     string   `env:"REMAINDER" envDefault:"fifo"`   // Who gets what rounding shares left: fifo or largest
+}
+
+// StrategyOf returns the allocation strategy of the pair: its entry in ALLOCATION_PAIRS,
+// or ALLOCATION_STRATEGY without one.
+func (c AllocationConfig) StrategyOf(pair string) (string, error) {
+	strategy := c.Strategy
+	seen := make(map[string]bool, len(c.Pairs))
+	for _, entry := range c.Pairs {
+		fields := strings.Split(strings.TrimSpace(entry), ":")
+		if len(fields) != 2 || fields[0] == "" || fields[1] == "" {
+			return "", fmt.Errorf("invalid allocation pair %q, want PAIR:STRATEGY", entry)
+		}
+		if seen[fields[0]] {
+			return "", fmt.Errorf("allocation of pair %s is configured twice", fields[0])
+		}
+		seen[fields[0]] = true
+		if fields[0] == pair {
+			strategy = fields[1]
+		}
+	}
+	return strategy, nil
+}
+
+// FeeConfig holds the fee schedule of the pairs. Rates are in basis points of the notional
+// and a negative maker rate is a rebate. A pair's rates apply to every user without a tier.
+type FeeConfig struct {
+	MakerBps float64  `env:"MAKER_BPS" envDefault:"0"` // Maker rate of pairs without rates of their own
+	TakerBps float64  `env:"TAKER_BPS" envDefault:"0"` // Taker rate of pairs without rates of their own
+	Pairs    []string `env:"PAIRS" envSeparator:","`   // Rates of single pairs, PAIR:MAKER_BPS:TAKER_BPS
+	Tiers    []string `env:"TIERS" envSeparator:","`   // Rates of each tier, TIER:MAKER_BPS:TAKER_BPS
+	Users    []string `env:"USERS" envSeparator:","`   // Tier of each user, USER:TIER
+	StoreKey string   `env:"STORE_KEY" envDefault:""`  // Redis key of a JSON object of user tiers overriding USERS; empty disables it
+}
+
+// FeeRates holds a maker and a taker rate in basis points.
+type FeeRates struct {
+	MakerBps float64
+	TakerBps float64
+}
+
+// PairRates returns the rates of the pair: its entry in FEE_PAIRS, or FEE_MAKER_BPS and
+// FEE_TAKER_BPS without one.
+func (c FeeConfig) PairRates(pair string) (FeeRates, error) {
+	rates, err := parseFeeRates(c.Pairs)
+	if err != nil {
+		return FeeRates{}, fmt.Errorf("fee pairs: %w", err)
+	}
+	if r, ok := rates[pair]; ok {
+		return r, nil
+	}
+	return FeeRates{MakerBps: c.MakerBps, TakerBps: c.TakerBps}, nil
+}
+
+// TierRates returns the rates of each tier by name.
+func (c FeeConfig) TierRates() (map[string]FeeRates, error) {
+	rates, err := parseFeeRates(c.Tiers)
+	if err != nil {
+		return nil, fmt.Errorf("fee tiers: %w", err)
+	}
+	return rates, nil
+}
+
+// UserTiers returns the tier of each user by user ID.
+func (c FeeConfig) UserTiers() (map[string]string, error) {
+	tiers := make(map[string]string, len(c.Users))
+	for _, entry := range c.Users {
+		fields := strings.Split(strings.TrimSpace(entry), ":")
+		if len(fields) != 2 || fields[0] == "" || fields[1] == "" {
+			return nil, fmt.Errorf("invalid fee user %q, want USER:TIER", entry)
+		}
+		if _, ok := tiers[fields[0]]; ok {
+			return nil, fmt.Errorf("fee user %s is configured twice", fields[0])
+		}
+		tiers[fields[0]] = fields[1]
+	}
+	return tiers, nil
+}
+
+// parseFeeRates parses NAME:MAKER_BPS:TAKER_BPS entries into rates by name.
+func parseFeeRates(entries []string) (map[string]FeeRates, error) {
+	rates := make(map[string]FeeRates, len(entries))
+	for _, entry := range entries {
+		fields := strings.Split(strings.TrimSpace(entry), ":")
+		if len(fields) != 3 || fields[0] == "" {
+			return nil, fmt.Errorf("invalid entry %q, want NAME:MAKER_BPS:TAKER_BPS", entry)
+		}
+		maker, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maker rate of %q", entry)
+		}
+		taker, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid taker rate of %q", entry)
+		}
+		if _, ok := rates[fields[0]]; ok {
+			return nil, fmt.Errorf("%s is configured twice", fields[0])
+		}
+		rates[fields[0]] = FeeRates{MakerBps: maker, TakerBps: taker}
+	}
+	return rates, nil
 }
 
 // MatchPublisherConfig holds the configuration for the match publisher.
@@ -39,11 +197,50 @@ type MatchPublisherConfig struct {
 	Brokers []string `env:"BROKER" envDefault:"localhost:9092"`
 }
 
+// OrderPublisherConfig holds the configuration for the order event publisher.
+type OrderPublisherConfig struct {
+	Topic   string   `env:"TOPIC" envDefault:"order_events"`
+	Brokers []string `env:"BROKER" envDefault:"localhost:9092"`
+}
+
+// JournalConfig holds the configuration for the append-only order journal.
+type JournalConfig struct {
+	Path string `env:"PATH" envDefault:""`     // Journal file, empty disables journaling
+	Sync bool   `env:"SYNC" envDefault:"true"` // Fsync every append before the order is processed
+}
+
+// DeadLetterConfig holds the dead-letter topic and the policy for order messages that
+// fail. Policies are skip, retry, dlq or halt; decode failures cannot be retried.
+type DeadLetterConfig struct {
+	Topic         string        `env:"TOPIC" envDefault:"order_dead_letters"`
+	Brokers       []string      `env:"BROKER" envDefault:"localhost:9092"`
+	DecodePolicy  string        `env:"DECODE_POLICY" envDefault:"dlq"`   // Messages that cannot be decoded as an order
+	ProcessPolicy string        `env:"PROCESS_POLICY" envDefault:"dlq"`  // Orders the engine failed to process
+	MaxRetries    int           `env:"MAX_RETRIES" envDefault:"3"`       // Retries before a retried message is dead-lettered
+	RetryBackoff  time.Duration `env:"RETRY_BACKOFF" envDefault:"100ms"` // Wait before the first retry, doubled after each
+}
+
+// AdminConfig holds the configuration of the operator gRPC API. The API is only served
+// when an address is set, and then requires a token.
+type AdminConfig struct {
+	Address string `env:"ADDRESS" envDefault:""` // Listen address, e.g. :9090; empty disables the API
+	Token   string `env:"TOKEN" envDefault:""`   // Operator token every call must carry as a bearer token
+}
+
+// Validate checks that an enabled API is protected by a token.
+func (c AdminConfig) Validate() error {
+	if c.Address != "" && c.Token == "" {
+		return fmt.Errorf("admin API on %s requires ADMIN_TOKEN", c.Address)
+	}
+	return nil
+}
+
 // KafkaConfig holds the configuration for Kafka consumer and producer.
 type KafkaConfig struct {
-	Topic   string   `env:"TOPIC,required"`
-	GroupID string   `env:"GROUP_ID" envDefault:"default_group"`
-	Brokers []string `env:"BROKER,required"`
+	Topic     string   `env:"TOPIC,required"`
+	Partition int      `env:"PARTITION" envDefault:"0"` // Partition the orders of the pair are read from
+	GroupID   string   `env:"GROUP_ID" envDefault:"default_group"`
+	Brokers   []string `env:"BROKER,required"`
 }
 
 // RedisConfig holds the configuration for Redis client.
@@ -54,3 +251,222 @@ type RedisConfig struct {
 	DB             int    `env:"DB" envDefault:"0"`
 	DefaultChannel string `env:"DEFAULT_CHANNEL" envDefault:"exchange"`
 }
+
+// PairConfig holds where a pair hosted by the process reads its orders from and journals
+// them to, and the rules it trades by.
+type PairConfig struct {
+	Pair        string
+	Topic       string
+	Partition   int
+	JournalPath string
+
+	Instrument          InstrumentConfig
+	PriceBand           PriceBandConfig
+	CircuitBreaker      CircuitBreakerConfig
+	SelfTradePrevention string
+}
+
+// override sets one trading rule of the pair from its PAIR_OVERRIDES entry. Settings are
+// named like the process-wide variables they override.
+func (p *PairConfig) override(setting, value string) error {
+	var err error
+	switch setting {
+	case "PRICE_DECIMALS":
+		err = parseInt32(value, &p.Instrument.PriceDecimals)
+	case "SIZE_DECIMALS":
+		err = parseInt32(value, &p.Instrument.SizeDecimals)
+	case "TICK_SIZE":
+		p.Instrument.TickSize, err = strconv.ParseFloat(value, 64)
+	case "LOT_SIZE":
+		p.Instrument.LotSize, err = strconv.ParseFloat(value, 64)
+	case "MIN_SIZE":
+		p.Instrument.MinSize, err = strconv.ParseFloat(value, 64)
+	case "MIN_NOTIONAL":
+		p.Instrument.MinNotional, err = strconv.ParseFloat(value, 64)
+	case "MAX_NOTIONAL":
+		p.Instrument.MaxNotional, err = strconv.ParseFloat(value, 64)
+	case "PRICE_BAND_PERCENT":
+		p.PriceBand.Percent, err = strconv.ParseFloat(value, 64)
+	case "PRICE_BAND_REFERENCE":
+		p.PriceBand.Reference = value
+	case "PRICE_BAND_AVERAGE_TRADES":
+		p.PriceBand.AverageTrades, err = strconv.Atoi(value)
+	case "CIRCUIT_BREAKER_MOVE_PERCENT":
+		p.CircuitBreaker.MovePercent, err = strconv.ParseFloat(value, 64)
+	case "CIRCUIT_BREAKER_WINDOW":
+		p.CircuitBreaker.Window, err = time.ParseDuration(value)
+	case "CIRCUIT_BREAKER_COOL_DOWN":
+		p.CircuitBreaker.CoolDown, err = time.ParseDuration(value)
+	case "SELF_TRADE_PREVENTION":
+		p.SelfTradePrevention = value
+	default:
+		return fmt.Errorf("unknown setting %s of pair %s", setting, p.Pair)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s %q of pair %s", setting, value, p.Pair)
+	}
+	return nil
+}
+
+// parseInt32 parses a decimal 32-bit integer into dst.
+func parseInt32(value string, dst *int32) error {
+	n, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return err
+	}
+	*dst = int32(n)
+	return nil
+}
+
+// pairOverrides returns the settings of each PAIR_OVERRIDES entry by pair.
+func (c *Config) pairOverrides() (map[string]map[string]string, error) {
+	overrides := make(map[string]map[string]string, len(c.PairOverrides))
+	for _, entry := range c.PairOverrides {
+		pair, list, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || pair == "" || list == "" {
+			return nil, fmt.Errorf("invalid pair override %q, want PAIR:SETTING=VALUE[;SETTING=VALUE...]", entry)
+		}
+		if _, ok := overrides[pair]; ok {
+			return nil, fmt.Errorf("overrides of pair %s are configured twice", pair)
+		}
+
+		settings := make(map[string]string)
+		for _, field := range strings.Split(list, ";") {
+			setting, value, ok := strings.Cut(field, "=")
+			if !ok || setting == "" || value == "" {
+				return nil, fmt.Errorf("invalid pair override %q, want PAIR:SETTING=VALUE[;SETTING=VALUE...]", entry)
+			}
+			if _, ok := settings[setting]; ok {
+				return nil, fmt.Errorf("%s of pair %s is configured twice", setting, pair)
+			}
+			settings[setting] = value
+		}
+		overrides[pair] = settings
+	}
+	return overrides, nil
+}
+
+// withRules gives every pair the process-wide trading rules with the settings of its
+// PAIR_OVERRIDES entry applied. Overrides of a pair that is not hosted are refused.
+func (c *Config) withRules(pairs []PairConfig) ([]PairConfig, error) {
+	overrides, err := c.pairOverrides()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range pairs {
+		pair := &pairs[i]
+		pair.Instrument = c.InstrumentConfig
+		pair.PriceBand = c.PriceBandConfig
+		pair.CircuitBreaker = c.CircuitBreakerConfig
+		pair.SelfTradePrevention = c.SelfTradePrevention
+
+		settings := overrides[pair.Pair]
+		delete(overrides, pair.Pair)
+		for setting, value := range settings {
+			if err := pair.override(setting, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for pair := range overrides {
+		return nil, fmt.Errorf("overrides of pair %s, which is not hosted", pair)
+	}
+	return pairs, nil
+}
+
+// PairConfigs returns the pairs hosted by the process. Every entry of PAIRS is
+// PAIR[:TOPIC[:PARTITION]]; the topic defaults to KAFKA_TOPIC and the partition to 0.
+// Without PAIRS the process hosts PAIR, read from KAFKA_TOPIC and KAFKA_PARTITION. Each
+// pair needs a topic and partition of its own, and with several pairs a journal path
+// must contain {pair}, which is replaced by the pair.
+//
+// A pair trades by the process-wide instrument, price band, circuit breaker and self-trade
+// prevention settings, unless its PAIR_OVERRIDES entry sets some of them, e.g.
+// ETH-USD:TICK_SIZE=0.05;PRICE_BAND_PERCENT=5.
+func (c *Config) PairConfigs() ([]PairConfig, error) {
+	if len(c.Pairs) == 0 {
+		if c.Pair == "" {
+			return nil, fmt.Errorf("no pair configured, set PAIR or PAIRS")
+		}
+		return c.withRules([]PairConfig{{
+			Pair:        c.Pair,
+			Topic:       c.KafkaConfig.Topic,
+			Partition:   c.KafkaConfig.Partition,
+			JournalPath: strings.ReplaceAll(c.JournalConfig.Path, "{pair}", c.Pair),
+		}})
+	}
+
+	if len(c.Pairs) > 1 && c.JournalConfig.Path != "" && !strings.Contains(c.JournalConfig.Path, "{pair}") {
+		return nil, fmt.Errorf("journal path %q is shared by every pair, add {pair} to it", c.JournalConfig.Path)
+	}
+
+	pairs := make([]PairConfig, 0, len(c.Pairs))
+	seen := make(map[string]string, len(c.Pairs))
+	for _, entry := range c.Pairs {
+		fields := strings.Split(strings.TrimSpace(entry), ":")
+		if len(fields) > 3 || fields[0] == "" {
+			return nil, fmt.Errorf("invalid pair %q, want PAIR[:TOPIC[:PARTITION]]", entry)
+		}
+
+		pair := PairConfig{
+			Pair:        fields[0],
+			Topic:       c.KafkaConfig.Topic,
+			JournalPath: strings.ReplaceAll(c.JournalConfig.Path, "{pair}", fields[0]),
+		}
+		if len(fields) > 1 && fields[1] != "" {
+			pair.Topic = fields[1]
+		}
+		if len(fields) > 2 {
+			partition, err := strconv.Atoi(fields[2])
+			if err != nil || partition < 0 {
+				return nil, fmt.Errorf("invalid partition of pair %q", entry)
+			}
+			pair.Partition = partition
+		}
+
+		if _, ok := seen[pair.Pair]; ok {
+			return nil, fmt.Errorf("pair %s is configured twice", pair.Pair)
+		}
+		source := fmt.Sprintf("%s:%d", pair.Topic, pair.Partition)
+		for other, otherSource := range seen {
+			if otherSource == source {
+				return nil, fmt.Errorf("pairs %s and %s both read %s", other, pair.Pair, source)
+			}
+		}
+		seen[pair.Pair] = source
+		pairs = append(pairs, pair)
+	}
+	return c.withRules(pairs)
+}
+
+// LookupPair returns the configuration of the hosted pair with the given name.
+func (c *Config) LookupPair(pair string) (PairConfig, error) {
+	pairs, err := c.PairConfigs()
+	if err != nil {
+		return PairConfig{}, err
+	}
+	for _, p := range pairs {
+		if p.Pair == pair {
+			return p, nil
+		}
+	}
+	return PairConfig{}, fmt.Errorf("pair %s is not configured", pair)
+}
+
+// ForPair returns a copy of the configuration for a single hosted pair, with the pair's
+// trading rules in place of the process-wide ones.
+func (c *Config) ForPair(pair PairConfig) *Config {
+	cfg := *c
+	cfg.Pair = pair.Pair
+	cfg.Pairs = nil
+	cfg.PairOverrides = nil
+	cfg.KafkaConfig.Topic = pair.Topic
+	cfg.KafkaConfig.Partition = pair.Partition
+	cfg.JournalConfig.Path = pair.JournalPath
+	cfg.InstrumentConfig = pair.Instrument
+	cfg.PriceBandConfig = pair.PriceBand
+	cfg.CircuitBreakerConfig = pair.CircuitBreaker
+	cfg.SelfTradePrevention = pair.SelfTradePrevention
+	return &cfg
+}
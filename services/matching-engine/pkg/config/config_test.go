@@ -0,0 +1,219 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_PairConfigs(t *testing.T) {
+	testCases := []struct {
+		name        string
+		config      Config
+		expected    []PairConfig
+		expectedErr string
+	}{
+		{
+			name: "single pair",
+			config: Config{
+				Pair:          "BTC-USD",
+				KafkaConfig:   KafkaConfig{Topic: "orders", Partition: 2},
+				JournalConfig: JournalConfig{Path: "/data/orders.journal"},
+			},
+			expected: []PairConfig{{Pair: "BTC-USD", Topic: "orders", Partition: 2, JournalPath: "/data/orders.journal"}},
+		},
+		{
+			name: "pairs with own partitions and topics",
+			config: Config{
+				Pairs:         []string{"BTC-USD", "ETH-USD::1", "SOL-USD:sol_orders"},
+				KafkaConfig:   KafkaConfig{Topic: "orders"},
+				JournalConfig: JournalConfig{Path: "/data/{pair}.journal"},
+			},
+			expected: []PairConfig{
+				{Pair: "BTC-USD", Topic: "orders", Partition: 0, JournalPath: "/data/BTC-USD.journal"},
+				{Pair: "ETH-USD", Topic: "orders", Partition: 1, JournalPath: "/data/ETH-USD.journal"},
+				{Pair: "SOL-USD", Topic: "sol_orders", Partition: 0, JournalPath: "/data/SOL-USD.journal"},
+			},
+		},
+		{
+			name:        "no pair",
+			config:      Config{KafkaConfig: KafkaConfig{Topic: "orders"}},
+			expectedErr: "no pair configured",
+		},
+		{
+			name:        "pairs sharing a partition",
+			config:      Config{Pairs: []string{"BTC-USD", "ETH-USD:orders:0"}, KafkaConfig: KafkaConfig{Topic: "orders"}},
+			expectedErr: "both read orders:0",
+		},
+		{
+			name:        "pair configured twice",
+			config:      Config{Pairs: []string{"BTC-USD", "BTC-USD::1"}, KafkaConfig: KafkaConfig{Topic: "orders"}},
+			expectedErr: "configured twice",
+		},
+		{
+			name:        "invalid partition",
+			config:      Config{Pairs: []string{"BTC-USD::x"}, KafkaConfig: KafkaConfig{Topic: "orders"}},
+			expectedErr: "invalid partition",
+		},
+		{
+			name: "shared journal path",
+			config: Config{
+				Pairs:         []string{"BTC-USD", "ETH-USD::1"},
+				KafkaConfig:   KafkaConfig{Topic: "orders"},
+				JournalConfig: JournalConfig{Path: "/data/orders.journal"},
+			},
+			expectedErr: "add {pair}",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pairs, err := tc.config.PairConfigs()
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, pairs)
+		})
+	}
+}
+
+func TestConfig_ForPair(t *testing.T) {
+	cfg := Config{
+		Pairs:         []string{"BTC-USD", "ETH-USD::1"},
+		KafkaConfig:   KafkaConfig{Topic: "orders", Brokers: []string{"kafka:9092"}},
+		JournalConfig: JournalConfig{Path: "/data/{pair}.journal", Sync: true},
+
+		InstrumentConfig:    InstrumentConfig{PriceDecimals: 2, SizeDecimals: 8, TickSize: 0.01},
+		PriceBandConfig:     PriceBandConfig{Percent: 10, Reference: "last_trade"},
+		SelfTradePrevention: "none",
+		PairOverrides:       []string{"ETH-USD:TICK_SIZE=0.05;SELF_TRADE_PREVENTION=cancel_newest"},
+	}
+
+	pair, err := cfg.LookupPair("ETH-USD")
+	require.NoError(t, err)
+	pairCfg := cfg.ForPair(pair)
+
+	assert.Equal(t, "ETH-USD", pairCfg.Pair)
+	assert.Empty(t, pairCfg.Pairs)
+	assert.Equal(t, 1, pairCfg.KafkaConfig.Partition)
+	assert.Equal(t, []string{"kafka:9092"}, pairCfg.KafkaConfig.Brokers)
+	assert.Equal(t, "/data/ETH-USD.journal", pairCfg.JournalConfig.Path)
+	assert.Equal(t, InstrumentConfig{PriceDecimals: 2, SizeDecimals: 8, TickSize: 0.05}, pairCfg.InstrumentConfig)
+	assert.Equal(t, PriceBandConfig{Percent: 10, Reference: "last_trade"}, pairCfg.PriceBandConfig)
+	assert.Equal(t, "cancel_newest", pairCfg.SelfTradePrevention)
+	assert.Empty(t, pairCfg.PairOverrides)
+	assert.Equal(t, 0.01, cfg.TickSize, "the shared configuration is not changed")
+	assert.Equal(t, []string{"BTC-USD", "ETH-USD::1"}, cfg.Pairs, "the shared configuration is not changed")
+
+	_, err = cfg.LookupPair("SOL-USD")
+	assert.Error(t, err)
+}
+
+func TestConfig_PairOverrides(t *testing.T) {
+	cfg := Config{
+		Pairs:                []string{"BTC-USD", "ETH-USD::1"},
+		KafkaConfig:          KafkaConfig{Topic: "orders"},
+		InstrumentConfig:     InstrumentConfig{PriceDecimals: 2, SizeDecimals: 8, TickSize: 0.01, LotSize: 0.001},
+		PriceBandConfig:      PriceBandConfig{Percent: 10, Reference: "last_trade"},
+		CircuitBreakerConfig: CircuitBreakerConfig{MovePercent: 5, Window: time.Minute, CoolDown: time.Minute},
+		SelfTradePrevention:  "none",
+		PairOverrides: []string{
+			"ETH-USD:PRICE_DECIMALS=1;SIZE_DECIMALS=4;TICK_SIZE=0.5;MIN_NOTIONAL=5;PRICE_BAND_PERCENT=3;PRICE_BAND_REFERENCE=moving_average;PRICE_BAND_AVERAGE_TRADES=20;CIRCUIT_BREAKER_COOL_DOWN=30s",
+		},
+	}
+
+	pairs, err := cfg.PairConfigs()
+	require.NoError(t, err)
+	require.Len(t, pairs, 2)
+
+	assert.Equal(t, cfg.InstrumentConfig, pairs[0].Instrument, "pairs without overrides trade by the shared rules")
+	assert.Equal(t, cfg.PriceBandConfig, pairs[0].PriceBand)
+	assert.Equal(t, cfg.CircuitBreakerConfig, pairs[0].CircuitBreaker)
+	assert.Equal(t, "none", pairs[0].SelfTradePrevention)
+
+	assert.Equal(t, InstrumentConfig{PriceDecimals: 1, SizeDecimals: 4, TickSize: 0.5, LotSize: 0.001, MinNotional: 5}, pairs[1].Instrument)
+	assert.Equal(t, PriceBandConfig{Percent: 3, Reference: "moving_average", AverageTrades: 20}, pairs[1].PriceBand)
+	assert.Equal(t, CircuitBreakerConfig{MovePercent: 5, Window: time.Minute, CoolDown: 30 * time.Second}, pairs[1].CircuitBreaker)
+	assert.Equal(t, "none", pairs[1].SelfTradePrevention)
+
+	for _, overrides := range [][]string{
+		{"SOL-USD:TICK_SIZE=0.5"},
+		{"ETH-USD:TICK=0.5"},
+		{"ETH-USD:TICK_SIZE=fast"},
+		{"ETH-USD:PRICE_DECIMALS=1.5"},
+		{"ETH-USD:TICK_SIZE=0.5;TICK_SIZE=0.1"},
+		{"ETH-USD:TICK_SIZE=0.5", "ETH-USD:LOT_SIZE=1"},
+		{"ETH-USD"},
+		{"ETH-USD:TICK_SIZE"},
+	} {
+		cfg.PairOverrides = overrides
+		_, err := cfg.PairConfigs()
+		assert.Error(t, err, "%v", overrides)
+	}
+}
+
+func TestAdminConfig_Validate(t *testing.T) {
+	assert.NoError(t, AdminConfig{}.Validate())
+	assert.NoError(t, AdminConfig{Address: ":9090", Token: "secret"}.Validate())
+	assert.Error(t, AdminConfig{Address: ":9090"}.Validate())
+}
+
+func TestFeeConfig(t *testing.T) {
+	config := FeeConfig{
+		MakerBps: 2,
+		TakerBps: 5,
+		Pairs:    []string{"ETH-USD:-1:3.5"},
+		Tiers:    []string{"vip:-0.5:2", "pro:1:4"},
+		Users:    []string{"alice:vip", "bob:pro"},
+	}
+
+	rates, err := config.PairRates("ETH-USD")
+	require.NoError(t, err)
+	assert.Equal(t, FeeRates{MakerBps: -1, TakerBps: 3.5}, rates)
+
+	rates, err = config.PairRates("BTC-USD")
+	require.NoError(t, err)
+	assert.Equal(t, FeeRates{MakerBps: 2, TakerBps: 5}, rates)
+
+	tiers, err := config.TierRates()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]FeeRates{"vip": {MakerBps: -0.5, TakerBps: 2}, "pro": {MakerBps: 1, TakerBps: 4}}, tiers)
+
+	users, err := config.UserTiers()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"alice": "vip", "bob": "pro"}, users)
+
+	for _, invalid := range []FeeConfig{
+		{Pairs: []string{"ETH-USD:1"}},
+		{Pairs: []string{"ETH-USD:x:1"}},
+		{Tiers: []string{"vip:1:2", "vip:1:3"}},
+		{Users: []string{"alice"}},
+		{Users: []string{"alice:vip", "alice:pro"}},
+	} {
+		_, pairErr := invalid.PairRates("ETH-USD")
+		_, tierErr := invalid.TierRates()
+		_, userErr := invalid.UserTiers()
+		assert.Error(t, errors.Join(pairErr, tierErr, userErr), "%+v", invalid)
+	}
+}
+
+func TestAllocationConfig_StrategyOf(t *testing.T) {
+	config := AllocationConfig{Strategy: "fifo", Pairs: []string{"BTC-PERP:pro_rata", "ETH-PERP:hybrid"}}
+
+	for pair, expected := range map[string]string{"BTC-PERP": "pro_rata", "ETH-PERP": "hybrid", "BTC-USD": "fifo"} {
+		strategy, err := config.StrategyOf(pair)
+		require.NoError(t, err)
+		assert.Equal(t, expected, strategy, pair)
+	}
+
+	_, err := AllocationConfig{Pairs: []string{"BTC-PERP"}}.StrategyOf("BTC-PERP")
+	assert.Error(t, err)
+	_, err = AllocationConfig{Pairs: []string{"BTC-PERP:fifo", "BTC-PERP:hybrid"}}.StrategyOf("ETH-PERP")
+	assert.Error(t, err)
+}